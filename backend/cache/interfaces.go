@@ -26,6 +26,12 @@ type QueryCache interface {
 	// Clear removes all cached queries
 	Clear() error
 
+	// InvalidateChunks removes entries whose results include any of the chunk IDs
+	InvalidateChunks(chunkIDs []string) int
+
+	// InvalidateFile removes entries whose results came from the file
+	InvalidateFile(fileID string) int
+
 	// Stats returns cache statistics
 	Stats() CacheStats
 }
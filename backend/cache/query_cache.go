@@ -286,3 +286,59 @@ func (c *InMemoryQueryCache) FindSimilarQuery(embedding []float64, threshold flo
 	return nil, "", false
 }
 
+// InvalidateChunks removes entries that retrieved any of the given chunks, so answers are
+// not served from text that has since changed. Returns the number of removed entries.
+func (c *InMemoryQueryCache) InvalidateChunks(chunkIDs []string) int {
+	if len(chunkIDs) == 0 {
+		return 0
+	}
+	changed := make(map[string]bool, len(chunkIDs))
+	for _, id := range chunkIDs {
+		changed[id] = true
+	}
+
+	return c.invalidateWhere(func(entry *CachedQuery) bool {
+		for _, id := range entry.ChunkIDs {
+			if changed[id] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// InvalidateFile removes entries whose results came from the file (chunk IDs are
+// "<fileID>_<index>"; entries may also carry a file_id in their metadata)
+func (c *InMemoryQueryCache) InvalidateFile(fileID string) int {
+	prefix := fileID + "_"
+	return c.invalidateWhere(func(entry *CachedQuery) bool {
+		if id, ok := entry.Metadata["file_id"].(string); ok && id == fileID {
+			return true
+		}
+		for _, id := range entry.ChunkIDs {
+			if strings.HasPrefix(id, prefix) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (c *InMemoryQueryCache) invalidateWhere(match func(entry *CachedQuery) bool) int {
+	var keys []interface{}
+	c.cache.Range(func(key, value interface{}) bool {
+		if match(value.(*cacheEntry).value) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+
+	removed := 0
+	for _, key := range keys {
+		if _, loaded := c.cache.LoadAndDelete(key); loaded {
+			c.decrementSize()
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,655 @@
+package chunks
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// CodeSplitter chunks source code on top-level declarations (functions, classes, methods)
+// instead of sentence boundaries, so a function body is never cut in half
+type CodeSplitter struct {
+	config ChunkerConfig
+}
+
+// NewCodeSplitter creates a code splitter; TargetTokens * CharsPerToken is the size above
+// which a declaration is broken into its members or, failing that, into line ranges
+func NewCodeSplitter(config ChunkerConfig) *CodeSplitter {
+	if config.TargetTokens <= 0 {
+		config.TargetTokens = 1000
+	}
+	if config.CharsPerToken <= 0 {
+		config.CharsPerToken = 4
+	}
+	if config.MinChunkSize <= 0 {
+		config.MinChunkSize = 100
+	}
+
+	return &CodeSplitter{
+		config: config,
+	}
+}
+
+// codeLanguages maps source file extensions to the language names stored in chunk metadata
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".rb":    "ruby",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".cs":    "csharp",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".hpp":   "cpp",
+	".kt":    "kotlin",
+	".scala": "scala",
+	".rs":    "rust",
+	".php":   "php",
+}
+
+// DetectLanguage returns the programming language of a file, or "" when it is not source code
+func DetectLanguage(filename string) string {
+	return codeLanguages[strings.ToLower(filepath.Ext(filename))]
+}
+
+// codeUnit is a run of whole lines (0-based, inclusive) that belongs to one declaration
+type codeUnit struct {
+	name    string
+	kind    string
+	start   int
+	end     int
+	part    int        // 1-based part number when an oversized declaration was split by lines
+	members []codeUnit // Nested declarations (methods of a class), used when the unit is too large
+}
+
+// sourceLines indexes the line boundaries of a source file
+type sourceLines struct {
+	lines  []string // Lines without the trailing newline
+	starts []int    // Byte offset of each line, plus len(text) as a sentinel
+}
+
+func newSourceLines(text string) *sourceLines {
+	lines := strings.Split(text, "\n")
+	starts := make([]int, 0, len(lines)+1)
+	offset := 0
+	for _, line := range lines {
+		starts = append(starts, offset)
+		offset += len(line) + 1
+	}
+	starts = append(starts, len(text))
+
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	return &sourceLines{lines: lines, starts: starts}
+}
+
+func (s *sourceLines) size(start, end int) int {
+	return s.starts[end+1] - s.starts[start]
+}
+
+func (s *sourceLines) blank(i int) bool {
+	return strings.TrimSpace(s.lines[i]) == ""
+}
+
+func (s *sourceLines) indent(i int) int {
+	line := s.lines[i]
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// Split chunks source code of the given language (see DetectLanguage)
+func (s *CodeSplitter) Split(text, language string) []Chunk {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	src := newSourceLines(text)
+	last := len(src.lines) - 1
+
+	var units []codeUnit
+	switch language {
+	case "go":
+		var err error
+		if units, err = goUnits(text); err != nil {
+			// Files that do not compile still get the heuristic splitter
+			depthStarts, depthEnds := braceDepths(src, language)
+			units = braceUnits(src, depthStarts, depthEnds, 0, last, 0, "")
+		}
+	case "python":
+		units = indentUnits(src, 0, last, 0, "", pythonDeclPattern, false)
+	case "ruby":
+		units = indentUnits(src, 0, last, 0, "", rubyDeclPattern, true)
+	default:
+		depthStarts, depthEnds := braceDepths(src, language)
+		units = braceUnits(src, depthStarts, depthEnds, 0, last, 0, "")
+	}
+
+	units = fillGaps(src, units, 0, last, "", "code")
+	units = s.merge(src, s.expand(src, units))
+
+	var result []Chunk
+	for _, unit := range units {
+		start := src.starts[unit.start]
+		chunkText := strings.TrimRight(text[start:src.starts[unit.end+1]], " \t\r\n")
+		if strings.TrimSpace(chunkText) == "" {
+			continue
+		}
+
+		metadata := map[string]interface{}{
+			"chunk_type":  "code",
+			"language":    language,
+			"symbol_kind": unit.kind,
+			"line_start":  unit.start + 1,
+			"line_end":    unit.end + 1,
+		}
+		if unit.name != "" {
+			metadata["symbol_name"] = unit.name
+		}
+		if unit.part > 0 {
+			metadata["symbol_part"] = unit.part
+		}
+
+		result = append(result, Chunk{
+			Index:     len(result),
+			Text:      chunkText,
+			Metadata:  metadata,
+			StartChar: start,
+			EndChar:   start + len(chunkText),
+		})
+	}
+
+	return result
+}
+
+func (s *CodeSplitter) maxChars() int {
+	return s.config.TargetTokens * s.config.CharsPerToken
+}
+
+// expand replaces oversized units with their members, or with line ranges when they have none
+func (s *CodeSplitter) expand(src *sourceLines, units []codeUnit) []codeUnit {
+	var result []codeUnit
+	for _, unit := range units {
+		if src.size(unit.start, unit.end) <= s.maxChars() {
+			result = append(result, unit)
+			continue
+		}
+		if len(unit.members) > 0 {
+			// The class header and fields between methods keep the class name
+			members := fillGaps(src, unit.members, unit.start, unit.end, unit.name, unit.kind)
+			result = append(result, s.expand(src, members)...)
+			continue
+		}
+		result = append(result, s.splitLines(src, unit)...)
+	}
+	return result
+}
+
+// splitLines packs the lines of a unit into parts no larger than maxChars.
+// A single line longer than that (minified code) becomes its own part.
+func (s *CodeSplitter) splitLines(src *sourceLines, unit codeUnit) []codeUnit {
+	var parts []codeUnit
+	start := unit.start
+	for i := unit.start; i <= unit.end; i++ {
+		if i > start && src.size(start, i) > s.maxChars() {
+			parts = append(parts, codeUnit{name: unit.name, kind: unit.kind, start: start, end: i - 1, part: len(parts) + 1})
+			start = i
+		}
+	}
+	return append(parts, codeUnit{name: unit.name, kind: unit.kind, start: start, end: unit.end, part: len(parts) + 1})
+}
+
+// merge joins runs of units smaller than MinChunkSize (imports, constants, one-line
+// helpers) so they do not become chunks too small to embed meaningfully. Declarations
+// of a normal size are never merged, keeping one symbol per chunk.
+func (s *CodeSplitter) merge(src *sourceLines, units []codeUnit) []codeUnit {
+	var result []codeUnit
+	for _, unit := range units {
+		if n := len(result); n > 0 {
+			prev := &result[n-1]
+			small := src.size(prev.start, prev.end) < s.config.MinChunkSize &&
+				src.size(unit.start, unit.end) < s.config.MinChunkSize
+			fits := prev.part == 0 && unit.part == 0 && src.size(prev.start, unit.end) <= s.maxChars()
+			if fits && onlyPunctuation(src, unit) {
+				prev.end = unit.end
+				continue
+			}
+			if fits && small {
+				prev.name = joinSymbolNames(prev.name, unit.name)
+				if prev.kind != unit.kind {
+					prev.kind = "declarations"
+				}
+				prev.end = unit.end
+				continue
+			}
+		}
+		result = append(result, unit)
+	}
+	return result
+}
+
+// onlyPunctuation reports units such as a lone "};" left over after a container was split
+func onlyPunctuation(src *sourceLines, unit codeUnit) bool {
+	for i := unit.start; i <= unit.end; i++ {
+		for _, r := range src.lines[i] {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func joinSymbolNames(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "" || a == b:
+		return a
+	}
+	return a + ", " + b
+}
+
+// fillGaps adds units for non-blank lines between declarations (package clause, imports,
+// top-level statements) so the chunks cover the whole file
+func fillGaps(src *sourceLines, units []codeUnit, from, to int, name, kind string) []codeUnit {
+	var result []codeUnit
+	addGap := func(start, end int) {
+		for start <= end && src.blank(start) {
+			start++
+		}
+		for end >= start && src.blank(end) {
+			end--
+		}
+		if start > end {
+			return
+		}
+		gapKind := kind
+		if start == 0 && name == "" {
+			gapKind = "header"
+		}
+		result = append(result, codeUnit{name: name, kind: gapKind, start: start, end: end})
+	}
+
+	next := from
+	for _, unit := range units {
+		addGap(next, unit.start-1)
+		result = append(result, unit)
+		next = unit.end + 1
+	}
+	addGap(next, to)
+
+	return result
+}
+
+// goUnits uses the Go parser so every top-level declaration gets its exact name and range,
+// including its doc comment
+func goUnits(text string) ([]codeUnit, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", text, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []codeUnit
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		unit := codeUnit{}
+
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			unit.name = d.Name.Name
+			unit.kind = "function"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				unit.kind = "method"
+				if receiver := goReceiverName(d.Recv.List[0].Type); receiver != "" {
+					unit.name = receiver + "." + unit.name
+				}
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			unit.kind = d.Tok.String() // import, const, var, type
+			var names []string
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, ident := range s.Names {
+						names = append(names, ident.Name)
+					}
+				}
+			}
+			unit.name = strings.Join(names, ", ")
+		}
+
+		unit.start = fset.Position(start).Line - 1
+		unit.end = fset.Position(decl.End()).Line - 1
+		units = append(units, unit)
+	}
+
+	return units, nil
+}
+
+func goReceiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexListExpr:
+		return goReceiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// charLiteralLanguages use ' only for single characters (or Rust lifetimes), never for strings
+var charLiteralLanguages = map[string]bool{
+	"go": true, "c": true, "cpp": true, "java": true, "csharp": true, "kotlin": true, "scala": true, "rust": true,
+}
+
+// braceDepths returns the brace depth at the start and end of every line,
+// ignoring braces inside strings and comments
+func braceDepths(src *sourceLines, language string) (starts []int, ends []int) {
+	starts = make([]int, len(src.lines))
+	ends = make([]int, len(src.lines))
+
+	depth := 0
+	inBlockComment := false
+	var quote byte // Open multi-line string (backtick), 0 when none
+
+	for i, line := range src.lines {
+		starts[i] = depth
+		for j := 0; j < len(line); j++ {
+			ch := line[j]
+
+			if inBlockComment {
+				if ch == '*' && j+1 < len(line) && line[j+1] == '/' {
+					inBlockComment = false
+					j++
+				}
+				continue
+			}
+			if quote != 0 {
+				if ch == '\\' {
+					j++
+				} else if ch == quote {
+					quote = 0
+				}
+				continue
+			}
+
+			switch {
+			case ch == '/' && j+1 < len(line) && line[j+1] == '/':
+				j = len(line)
+			case ch == '/' && j+1 < len(line) && line[j+1] == '*':
+				inBlockComment = true
+				j++
+			case ch == '`':
+				quote = '`'
+			case ch == '"' || (ch == '\'' && !charLiteralLanguages[language]):
+				j = skipQuoted(line, j)
+			case ch == '\'':
+				// 'x' or '\n'; anything else is a lifetime or generic marker
+				if end := strings.IndexByte(line[j+1:], '\''); end >= 0 && end <= 2 {
+					j += end + 1
+				}
+			case ch == '{':
+				depth++
+			case ch == '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+		ends[i] = depth
+	}
+
+	return starts, ends
+}
+
+// skipQuoted returns the index of the closing quote of the string starting at i,
+// or the end of the line for unterminated strings
+func skipQuoted(line string, i int) int {
+	quote := line[i]
+	for j := i + 1; j < len(line); j++ {
+		if line[j] == '\\' {
+			j++
+			continue
+		}
+		if line[j] == quote {
+			return j
+		}
+	}
+	return len(line)
+}
+
+var (
+	braceTypePattern      = regexp.MustCompile(`\b(class|interface|enum|struct|trait|object|record|namespace|module|impl|union)\s+([A-Za-z_$][\w$]*)`)
+	braceTypeAliasPattern = regexp.MustCompile(`^(?:export\s+)?type\s+([A-Za-z_$][\w$]*)`)
+	braceFuncPattern      = regexp.MustCompile(`\b(?:function\*?|fn|fun|def|func)\s+(?:\([^)]*\)\s*)?([A-Za-z_$][\w$]*)`)
+	braceAssignPattern    = regexp.MustCompile(`^(?:export\s+)?(?:(?:const|let|var|val|static|public|private|protected|readonly)\s+)+([A-Za-z_$][\w$]*)\s*(?::[^=]*)?=\s*(.*)$`)
+	braceMemberPattern    = regexp.MustCompile(`^['"]?([A-Za-z_$][\w$]*)['"]?\s*:\s*(.*)$`)
+	braceArrowPattern     = regexp.MustCompile(`^(?:\([^)]*\)|[A-Za-z_$][\w$]*)\s*=>`)
+	braceMethodPattern    = regexp.MustCompile(`^(?:[\w$<>\[\]*&:,?.]+\s+)*\**&?([A-Za-z_$][\w$]*)\s*\(`)
+	braceTypedFuncPattern = regexp.MustCompile(`^(?:[\w$<>\[\]*&:,?.]+\s+)+\**&?([A-Za-z_$][\w$]*)\s*\(`)
+)
+
+// controlKeywords are never declaration names when followed by "("
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "foreach": true, "while": true, "switch": true, "catch": true, "return": true,
+	"new": true, "typeof": true, "sizeof": true, "function": true, "else": true, "do": true, "try": true,
+	"with": true, "await": true, "synchronized": true, "using": true, "lock": true, "match": true,
+}
+
+// braceDeclaration names the declaration whose header (signature lines up to the opening brace) is given
+func braceDeclaration(header string, nested bool) (string, string) {
+	header = strings.TrimSpace(header)
+
+	if m := braceTypePattern.FindStringSubmatch(header); m != nil {
+		return m[2], m[1]
+	}
+	if m := braceTypeAliasPattern.FindStringSubmatch(header); m != nil {
+		return m[1], "type"
+	}
+
+	functionKind := "function"
+	if nested {
+		functionKind = "method"
+	}
+
+	if m := braceFuncPattern.FindStringSubmatch(header); m != nil {
+		return m[1], functionKind
+	}
+	if m := braceMemberPattern.FindStringSubmatch(header); m != nil && nested {
+		// Object literal member: "load: async (id) => {" or "options: {"
+		if braceArrowPattern.MatchString(m[2]) || strings.HasPrefix(m[2], "function") || strings.HasPrefix(m[2], "async") {
+			return m[1], functionKind
+		}
+		return m[1], "property"
+	}
+	if m := braceAssignPattern.FindStringSubmatch(header); m != nil {
+		value := m[2]
+		if strings.Contains(value, "=>") || strings.HasPrefix(value, "function") || strings.HasPrefix(value, "async") {
+			return m[1], functionKind
+		}
+		return m[1], "variable"
+	}
+
+	// Top-level functions need a return type or modifier before the name (C, Java, C#);
+	// class members may be bare ("render() {")
+	pattern := braceTypedFuncPattern
+	if nested {
+		pattern = braceMethodPattern
+	}
+	if m := pattern.FindStringSubmatch(header); m != nil && !controlKeywords[m[1]] {
+		return m[1], functionKind
+	}
+
+	return "", "block"
+}
+
+// isLeadingLine reports comment and annotation lines that belong to the declaration below them
+func isLeadingLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "/*", "*", "@", "#[", "#"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachLeading moves a unit start up over the comments and annotations directly above it
+func attachLeading(src *sourceLines, start, from int) int {
+	for start > from && !src.blank(start-1) && isLeadingLine(src.lines[start-1]) && src.indent(start-1) == src.indent(start) {
+		start--
+	}
+	return start
+}
+
+// braceUnits finds the blocks opened at the given depth between lines from and to.
+// Class-like blocks get their members (depth + 1) so they can be split per method.
+func braceUnits(src *sourceLines, depthStarts, depthEnds []int, from, to, depth int, parent string) []codeUnit {
+	var units []codeUnit
+
+	for i := from; i <= to; i++ {
+		if src.blank(i) || depthStarts[i] != depth || isLeadingLine(src.lines[i]) {
+			continue
+		}
+
+		// Find the line that opens the block: the same line, a multi-line signature or
+		// an Allman-style brace on the next line. A terminated statement ends the search.
+		open := -1
+		parens := 0
+		for k := i; k <= to && k < i+10; k++ {
+			if depthEnds[k] > depth {
+				open = k
+				break
+			}
+			trimmed := strings.TrimSpace(src.lines[k])
+			parens += strings.Count(trimmed, "(") - strings.Count(trimmed, ")")
+			if strings.HasSuffix(trimmed, ";") {
+				break
+			}
+			continueSignature := parens > 0 || strings.HasSuffix(trimmed, ",")
+			if k+1 <= to && strings.HasPrefix(strings.TrimSpace(src.lines[k+1]), "{") {
+				continueSignature = true
+			}
+			if !continueSignature {
+				break
+			}
+		}
+		if open < 0 {
+			continue
+		}
+
+		end := open
+		for end < to && depthEnds[end] > depth {
+			end++
+		}
+
+		name, kind := braceDeclaration(strings.Join(src.lines[i:open+1], " "), depth > 0)
+		if parent != "" && name != "" {
+			name = parent + "." + name
+		}
+
+		unit := codeUnit{name: name, kind: kind, start: attachLeading(src, i, from), end: end}
+		// Function bodies are split by lines; only containers are split per member
+		if depth < 2 && end-open > 1 && kind != "function" && kind != "method" && kind != "block" {
+			memberParent := name
+			if memberParent == "" {
+				memberParent = parent
+			}
+			unit.members = braceUnits(src, depthStarts, depthEnds, open+1, end-1, depth+1, memberParent)
+		}
+
+		units = append(units, unit)
+		i = end
+	}
+
+	return units
+}
+
+var (
+	pythonDeclPattern = regexp.MustCompile(`^(?:async\s+)?(def|class)\s+([A-Za-z_]\w*)`)
+	rubyDeclPattern   = regexp.MustCompile(`^(def|class|module)\s+(?:self\.)?([\w:?!=]+)`)
+)
+
+// indentUnits finds declarations at the given indentation for indentation-scoped languages.
+// Ruby blocks also own the "end" line that closes them.
+func indentUnits(src *sourceLines, from, to, indent int, parent string, pattern *regexp.Regexp, ruby bool) []codeUnit {
+	var units []codeUnit
+
+	for i := from; i <= to; i++ {
+		if src.blank(i) || src.indent(i) != indent {
+			continue
+		}
+		m := pattern.FindStringSubmatch(strings.TrimSpace(src.lines[i]))
+		if m == nil {
+			continue
+		}
+
+		end := i
+		for j := i + 1; j <= to; j++ {
+			if src.blank(j) {
+				continue
+			}
+			trimmed := strings.TrimSpace(src.lines[j])
+			if src.indent(j) > indent {
+				end = j
+				continue
+			}
+			if ruby && trimmed == "end" {
+				end = j
+			} else if !ruby && strings.HasPrefix(trimmed, ")") {
+				// Closing line of a multi-line signature
+				end = j
+				continue
+			}
+			break
+		}
+
+		name, kind := m[2], m[1]
+		if kind == "def" {
+			kind = "function"
+			if parent != "" {
+				kind = "method"
+			}
+		}
+		if parent != "" {
+			name = parent + "." + name
+		}
+
+		unit := codeUnit{name: name, kind: kind, start: attachLeading(src, i, from), end: end}
+		if kind == "class" || kind == "module" {
+			for j := i + 1; j <= end; j++ {
+				if !src.blank(j) && src.indent(j) > indent {
+					unit.members = indentUnits(src, j, end, src.indent(j), name, pattern, ruby)
+					break
+				}
+			}
+		}
+
+		units = append(units, unit)
+		i = end
+	}
+
+	return units
+}
@@ -0,0 +1,129 @@
+package chunks
+
+import (
+	"strings"
+	"testing"
+)
+
+func symbols(chunks []Chunk) []string {
+	var names []string
+	for _, c := range chunks {
+		name, _ := c.Metadata["symbol_name"].(string)
+		names = append(names, c.Metadata["symbol_kind"].(string)+":"+name)
+	}
+	return names
+}
+
+func TestCodeSplitterGo(t *testing.T) {
+	source := `package search
+
+import "sort"
+
+// HybridSearch combines keyword and vector results.
+// It keeps the best score for every document.
+func HybridSearch(keyword, vector []Result) []Result {
+	merged := make(map[string]Result)
+	for _, r := range append(keyword, vector...) {
+		if existing, ok := merged[r.ID]; !ok || r.Score > existing.Score {
+			merged[r.ID] = r
+		}
+	}
+	return sortResults(merged)
+}
+
+// Index stores documents in memory so they can be searched by keyword later on.
+type Index struct {
+	docs map[string]string
+}
+
+// Add puts a document into the index, replacing any previous version with the same ID.
+func (i *Index) Add(id, text string) {
+	i.docs[id] = text
+}
+`
+	splitter := NewCodeSplitter(DefaultChunkerConfig())
+	chunks := splitter.Split(source, "go")
+
+	var hybrid *Chunk
+	for i := range chunks {
+		if chunks[i].Metadata["symbol_name"] == "HybridSearch" {
+			hybrid = &chunks[i]
+		}
+	}
+	if hybrid == nil {
+		t.Fatalf("no HybridSearch chunk in %v", symbols(chunks))
+	}
+	if hybrid.Metadata["symbol_kind"] != "function" {
+		t.Errorf("expected function kind, got %v", hybrid.Metadata["symbol_kind"])
+	}
+	if !strings.HasPrefix(hybrid.Text, "// HybridSearch combines") || !strings.HasSuffix(hybrid.Text, "}") {
+		t.Errorf("chunk should span doc comment to closing brace: %q", hybrid.Text)
+	}
+	if hybrid.Metadata["line_start"] != 5 || hybrid.Metadata["line_end"] != 15 {
+		t.Errorf("unexpected line range %v-%v", hybrid.Metadata["line_start"], hybrid.Metadata["line_end"])
+	}
+	if source[hybrid.StartChar:hybrid.EndChar] != hybrid.Text {
+		t.Error("offsets do not point at the chunk text")
+	}
+
+	found := strings.Join(symbols(chunks), " ")
+	if !strings.Contains(found, "method:Index.Add") {
+		t.Errorf("method with receiver not found in %s", found)
+	}
+}
+
+func TestCodeSplitterSplitsLargeClassIntoMethods(t *testing.T) {
+	body := strings.Repeat("    const value = compute();\n", 20)
+	source := "export class Service {\n" +
+		"  constructor(client) {\n    this.client = client;\n  }\n\n" +
+		"  async fetchAll() {\n" + body + "  }\n\n" +
+		"  render() {\n" + body + "  }\n" +
+		"}\n"
+
+	config := DefaultChunkerConfig()
+	config.TargetTokens = 200 // 800 chars: each method fits, the class does not
+	chunks := NewCodeSplitter(config).Split(source, "typescript")
+
+	found := strings.Join(symbols(chunks), " ")
+	for _, want := range []string{"method:Service.fetchAll", "method:Service.render"} {
+		if !strings.Contains(found, want) {
+			t.Errorf("missing %s in %s", want, found)
+		}
+	}
+}
+
+func TestCodeSplitterPython(t *testing.T) {
+	source := `import os
+
+
+@cache
+def load(path):
+    with open(path) as f:
+        return f.read()
+
+
+class Reader:
+    def read(self):
+        return load(os.environ["FILE"])
+`
+	chunks := NewCodeSplitter(DefaultChunkerConfig()).Split(source, "python")
+
+	found := strings.Join(symbols(chunks), " ")
+	if !strings.Contains(found, "load") || !strings.Contains(found, "Reader") {
+		t.Errorf("python declarations not found: %s", found)
+	}
+	for _, c := range chunks {
+		if name, _ := c.Metadata["symbol_name"].(string); strings.Contains(name, "load") && !strings.Contains(c.Text, "@cache") {
+			t.Errorf("decorator should stay with its function: %q", c.Text)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	if DetectLanguage("main.GO") != "go" || DetectLanguage("app.tsx") != "typescript" {
+		t.Error("known extensions not detected")
+	}
+	if DetectLanguage("notes.md") != "" {
+		t.Error("markdown is not source code")
+	}
+}
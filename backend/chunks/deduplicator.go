@@ -40,9 +40,9 @@ func ComputeFileHash(fileBytes []byte) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// CheckDuplicate checks if a file with the same hash already exists
+// CheckDuplicate checks if a file with the same hash, embedded by embedModel, already exists
 // Returns duplicate info if found, nil if unique
-func (d *FileDeduplicator) CheckDuplicate(fileHash string) (*DuplicateFileInfo, error) {
+func (d *FileDeduplicator) CheckDuplicate(fileHash, embedModel string) (*DuplicateFileInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
@@ -59,6 +59,7 @@ func (d *FileDeduplicator) CheckDuplicate(fileHash string) (*DuplicateFileInfo,
 	filter := bson.M{
 		"file_hash":         fileHash,
 		"processing_status": "completed", // Only reuse successfully processed files
+		"embed_model":       embedModel,  // Never link vectors of another model
 	}
 	
 	err := d.fileCollection.FindOne(ctx, filter).Decode(&result)
@@ -110,7 +111,7 @@ func (d *FileDeduplicator) StoreFileHash(fileID, fileHash string) error {
 
 // LinkToExistingEmbeddings creates a reference to existing file's embeddings
 // This allows a new file record to reuse embeddings without re-processing
-func (d *FileDeduplicator) LinkToExistingEmbeddings(newFileID, sourceFileID string, chunkCount int) error {
+func (d *FileDeduplicator) LinkToExistingEmbeddings(newFileID, sourceFileID string, chunkCount int, embedModel string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
@@ -127,6 +128,7 @@ func (d *FileDeduplicator) LinkToExistingEmbeddings(newFileID, sourceFileID stri
 			"chunk_count":       chunkCount,
 			"processed_at":      &now,
 			"deduplication_hit": true, // Flag for tracking
+			"embed_model":       embedModel,
 		},
 	}
 	
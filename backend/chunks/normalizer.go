@@ -58,14 +58,39 @@ func NewTextNormalizer(config NormalizerConfig) *TextNormalizer {
 
 // Normalize applies all normalization steps to text
 func (n *TextNormalizer) Normalize(text string) string {
-	// Step 1: Normalize line endings
 	if n.normalizeLineEndings {
 		text = n.normalizeLineEndingsFunc(text)
 	}
+	return n.normalize(text, lineCounts(strings.Split(text, "\n")))
+}
+
+// NormalizePages normalizes each page separately and joins them with blank lines, returning
+// where every page ended up in the combined text. Repeated headers are still detected
+// across the whole document, not per page.
+func (n *TextNormalizer) NormalizePages(pages []string) (string, PageMap) {
+	normalized := make([]string, len(pages))
+	var allLines []string
+	for i, page := range pages {
+		if n.normalizeLineEndings {
+			page = n.normalizeLineEndingsFunc(page)
+		}
+		normalized[i] = page
+		allLines = append(allLines, strings.Split(page, "\n")...)
+	}
+	counts := lineCounts(allLines)
+
+	for i, page := range normalized {
+		normalized[i] = n.normalize(page, counts)
+	}
+
+	return JoinPages(normalized)
+}
 
+// normalize runs the normalization steps; counts holds document-wide line frequencies
+func (n *TextNormalizer) normalize(text string, counts map[string]int) string {
 	// Step 2: Strip layout artifacts (page numbers, repeated headers, etc.)
 	if n.stripLayoutArtifacts {
-		text = n.stripArtifacts(text)
+		text = n.stripArtifacts(text, counts)
 	}
 
 	// Step 3: Remove excessive whitespace
@@ -125,7 +150,7 @@ func (n *TextNormalizer) removeExcessiveWhitespaceFunc(text string) string {
 }
 
 // stripArtifacts removes common layout artifacts from PDF extraction
-func (n *TextNormalizer) stripArtifacts(text string) string {
+func (n *TextNormalizer) stripArtifacts(text string, counts map[string]int) string {
 	lines := strings.Split(text, "\n")
 	cleanedLines := []string{}
 
@@ -155,7 +180,7 @@ func (n *TextNormalizer) stripArtifacts(text string) string {
 
 		// Remove repeated header lines (same line appears multiple times)
 		if i > 0 && i < len(lines)-1 {
-			if isRepeatedHeader(line, lines, i) {
+			if isRepeatedHeader(line, counts) {
 				continue
 			}
 		}
@@ -298,24 +323,22 @@ func isOnlySpecialChars(line string) bool {
 }
 
 // isRepeatedHeader checks if a line is repeated (common in PDFs with headers)
-func isRepeatedHeader(line string, allLines []string, currentIndex int) bool {
+func isRepeatedHeader(line string, counts map[string]int) bool {
 	if len(line) > 100 {
 		return false // Too long to be a repeated header
 	}
 
-	// Check if this exact line appears multiple times in document
-	occurrences := 0
-	for i, l := range allLines {
-		if i == currentIndex {
-			continue
-		}
-		if strings.TrimSpace(l) == line {
-			occurrences++
-		}
-	}
+	// If the line appears 3+ more times elsewhere in the document, likely a repeated header
+	return counts[line]-1 >= 3
+}
 
-	// If appears 3+ times, likely a repeated header
-	return occurrences >= 3
+// lineCounts counts how often each trimmed line appears in the document
+func lineCounts(lines []string) map[string]int {
+	counts := make(map[string]int, len(lines))
+	for _, l := range lines {
+		counts[strings.TrimSpace(l)]++
+	}
+	return counts
 }
 
 // NormalizeForEmbedding performs minimal normalization suitable for embeddings
@@ -0,0 +1,79 @@
+package chunks
+
+import "strings"
+
+// pageSeparator is placed between pages when they are joined into one text
+const pageSeparator = "\n\n"
+
+// PageSpan marks where a page's text lives in the combined document text
+type PageSpan struct {
+	Page      int // 1-based page number
+	StartChar int // Start position in combined text
+	EndChar   int // End position in combined text (exclusive)
+}
+
+// PageMap lists the page spans of a document in order. An empty map means the
+// source format has no pages (DOCX, plain text) and every lookup returns 0.
+type PageMap []PageSpan
+
+// JoinPages concatenates page texts and records each page's span. Empty pages are
+// skipped but keep their number, so page 3 is still page 3 when page 2 is blank.
+func JoinPages(pages []string) (string, PageMap) {
+	var sb strings.Builder
+	var pageMap PageMap
+
+	for i, page := range pages {
+		if strings.TrimSpace(page) == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString(pageSeparator)
+		}
+		start := sb.Len()
+		sb.WriteString(page)
+		pageMap = append(pageMap, PageSpan{
+			Page:      i + 1,
+			StartChar: start,
+			EndChar:   sb.Len(),
+		})
+	}
+
+	return sb.String(), pageMap
+}
+
+// Range returns the first and last page overlapping [start, end), or 0, 0 if unknown
+func (m PageMap) Range(start, end int) (int, int) {
+	if len(m) == 0 {
+		return 0, 0
+	}
+	if end <= start {
+		end = start + 1
+	}
+
+	first, last := 0, 0
+	for _, span := range m {
+		if span.EndChar <= start {
+			continue
+		}
+		if span.StartChar >= end {
+			break
+		}
+		if first == 0 {
+			first = span.Page
+		}
+		last = span.Page
+	}
+
+	// Offsets inside a separator belong to the following page
+	if first == 0 {
+		for _, span := range m {
+			if span.StartChar >= start {
+				return span.Page, span.Page
+			}
+		}
+		lastPage := m[len(m)-1].Page
+		return lastPage, lastPage
+	}
+
+	return first, last
+}
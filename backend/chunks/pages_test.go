@@ -0,0 +1,39 @@
+package chunks
+
+import "testing"
+
+func TestJoinPagesAndRange(t *testing.T) {
+	text, pages := JoinPages([]string{"first page", "", "third page"})
+
+	if text != "first page\n\nthird page" {
+		t.Fatalf("Unexpected joined text: %q", text)
+	}
+	if len(pages) != 2 || pages[1].Page != 3 {
+		t.Fatalf("Expected blank page to be skipped but numbered, got %+v", pages)
+	}
+
+	if start, end := pages.Range(0, 5); start != 1 || end != 1 {
+		t.Errorf("Expected page 1, got %d-%d", start, end)
+	}
+	if start, end := pages.Range(3, len(text)); start != 1 || end != 3 {
+		t.Errorf("Expected pages 1-3, got %d-%d", start, end)
+	}
+	if start, end := pages.Range(10, 11); start != 3 || end != 3 {
+		t.Errorf("Expected separator offset to map to page 3, got %d-%d", start, end)
+	}
+	if start, end := PageMap(nil).Range(0, 10); start != 0 || end != 0 {
+		t.Errorf("Expected 0-0 for empty page map, got %d-%d", start, end)
+	}
+}
+
+func TestTableProcessorCarriesPages(t *testing.T) {
+	text, pages := JoinPages([]string{"Intro text on the first page.", "Closing text on the second page."})
+
+	segments := NewTableProcessor().WithPages(pages).Process(text)
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(segments))
+	}
+	if segments[0].PageStart != 1 || segments[0].PageEnd != 2 {
+		t.Errorf("Expected segment to span pages 1-2, got %d-%d", segments[0].PageStart, segments[0].PageEnd)
+	}
+}
@@ -0,0 +1,106 @@
+package chunks
+
+import (
+	"strings"
+
+	"nimbus-backend/tables"
+)
+
+// RowGroupSplitter stores spreadsheet rows in groups that each repeat the sheet name and
+// column headers, so every chunk is readable on its own and the rows stay intact
+type RowGroupSplitter struct {
+	config ChunkerConfig
+}
+
+// NewRowGroupSplitter creates a row group splitter; TargetTokens * CharsPerToken bounds a group
+func NewRowGroupSplitter(config ChunkerConfig) *RowGroupSplitter {
+	if config.TargetTokens <= 0 {
+		config.TargetTokens = 1000
+	}
+	if config.CharsPerToken <= 0 {
+		config.CharsPerToken = 4
+	}
+
+	return &RowGroupSplitter{
+		config: config,
+	}
+}
+
+// Split turns every table into row group chunks. Row numbers in metadata are 1-based
+// data rows (the header row is not counted).
+func (s *RowGroupSplitter) Split(tbls []tables.Table) []Chunk {
+	maxChars := s.config.TargetTokens * s.config.CharsPerToken
+	var result []Chunk
+
+	for _, table := range tbls {
+		header := tableHeader(table)
+
+		var sb strings.Builder
+		firstRow := 0
+		flush := func(lastRow int) {
+			if sb.Len() == 0 {
+				return
+			}
+			result = append(result, Chunk{
+				Index:    len(result),
+				Text:     header + sb.String(),
+				Metadata: rowGroupMetadata(table, firstRow+1, lastRow+1),
+			})
+			sb.Reset()
+		}
+
+		for i, row := range table.Rows {
+			line := renderRow(table.Headers, row)
+			if line == "" {
+				continue
+			}
+			if sb.Len() > 0 && len(header)+sb.Len()+len(line) > maxChars {
+				flush(i - 1)
+			}
+			if sb.Len() == 0 {
+				firstRow = i
+			}
+			sb.WriteString(line + "\n")
+		}
+		flush(len(table.Rows) - 1)
+	}
+
+	return result
+}
+
+func tableHeader(table tables.Table) string {
+	var sb strings.Builder
+	if table.Name != "" {
+		sb.WriteString("Sheet: " + table.Name + "\n")
+	}
+	sb.WriteString("Columns: " + strings.Join(table.Headers, ", ") + "\n\n")
+	return sb.String()
+}
+
+// renderRow writes "Header: value" pairs, skipping empty cells
+func renderRow(headers, row []string) string {
+	cells := make([]string, 0, len(row))
+	for i, value := range row {
+		if value != "" {
+			cells = append(cells, headers[i]+": "+value)
+		}
+	}
+	return strings.Join(cells, " | ")
+}
+
+func rowGroupMetadata(table tables.Table, rowStart, rowEnd int) map[string]interface{} {
+	types := make([]string, len(table.Types))
+	for i, t := range table.Types {
+		types[i] = string(t)
+	}
+
+	// Chroma only accepts scalar metadata, so lists are comma-joined
+	return map[string]interface{}{
+		"chunk_type":   "table_rows",
+		"sheet":        table.Name,
+		"columns":      strings.Join(table.Headers, ","),
+		"column_types": strings.Join(types, ","),
+		"row_start":    rowStart,
+		"row_end":      rowEnd,
+	}
+}
@@ -22,6 +22,8 @@ type Chunk struct {
 	Metadata  map[string]interface{} // Optional metadata
 	StartChar int                    // Start position in original text
 	EndChar   int                    // End position in original text
+	PageStart int                    // First page of the chunk (0 = unknown)
+	PageEnd   int                    // Last page of the chunk (0 = unknown)
 }
 
 // DefaultChunkerConfig returns default configuration
@@ -77,6 +79,8 @@ func (s *SemanticTextSplitter) SplitSegments(segments []TextSegment) []Chunk {
 				Text:      segment.Text,
 				StartChar: segment.StartChar,
 				EndChar:   segment.EndChar,
+				PageStart: segment.PageStart,
+				PageEnd:   segment.PageEnd,
 				Metadata:  s.extractChunkMetadata(segment.Text),
 			})
 			chunkIndex++
@@ -85,6 +89,7 @@ func (s *SemanticTextSplitter) SplitSegments(segments []TextSegment) []Chunk {
 			chunks := s.splitTextSegment(segment.Text, segment.StartChar)
 			for i := range chunks {
 				chunks[i].Index = chunkIndex
+				chunks[i].PageStart, chunks[i].PageEnd = segment.pages.Range(chunks[i].StartChar, chunks[i].EndChar)
 				chunkIndex++
 			}
 			allChunks = append(allChunks, chunks...)
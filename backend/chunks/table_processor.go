@@ -8,7 +8,7 @@ import (
 
 // TableProcessor handles detection and restructuring of tables in text
 type TableProcessor struct {
-	// Configuration could go here
+	pages PageMap // Optional page boundaries of the text passed to Process
 }
 
 // NewTableProcessor creates a new table processor
@@ -16,12 +16,21 @@ func NewTableProcessor() *TableProcessor {
 	return &TableProcessor{}
 }
 
+// WithPages makes Process annotate segments with the pages they span
+func (tp *TableProcessor) WithPages(pages PageMap) *TableProcessor {
+	tp.pages = pages
+	return tp
+}
+
 // TextSegment represents a segment of text (either table or regular text)
 type TextSegment struct {
 	Text      string
 	StartChar int
 	EndChar   int
 	IsTable   bool
+	PageStart int     // First page of the segment (0 = unknown)
+	PageEnd   int     // Last page of the segment (0 = unknown)
+	pages     PageMap // Lets the splitter resolve pages of sub-chunks
 }
 
 // Process analyzes text and returns segments (tables are restructured)
@@ -108,6 +117,12 @@ func (tp *TableProcessor) Process(text string) []TextSegment {
 		})
 	}
 
+	// Carry page ranges so chunks can cite "page 12"
+	for i := range segments {
+		segments[i].PageStart, segments[i].PageEnd = tp.pages.Range(segments[i].StartChar, segments[i].EndChar)
+		segments[i].pages = tp.pages
+	}
+
 	return segments
 }
 
@@ -0,0 +1,122 @@
+// Command rageval runs the offline RAG evaluation (package eval) on a golden dataset and
+// prints recall@k, MRR, context precision and answer overlap.
+//
+// Providers and retrieval settings come from the environment like for the server
+// (EMBED_PROVIDER, CHAT_PROVIDER, RERANKER, ENABLE_FILE_ROUTING, ...). Documents are
+// indexed into a temporary embedded vector store, so no Chroma or MongoDB is needed.
+//
+//	go run ./cmd/rageval -fake
+//	go run ./cmd/rageval -out before.json
+//	go run ./cmd/rageval -baseline before.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"nimbus-backend/config"
+	"nimbus-backend/eval"
+	"nimbus-backend/llm"
+	"nimbus-backend/vectorstore"
+)
+
+func main() {
+	datasetDir := flag.String("dataset", "eval/testdata/nimbus", "dataset directory containing "+eval.GoldenFile)
+	ks := flag.String("k", "1,3,5,10", "comma separated recall cutoffs")
+	fake := flag.Bool("fake", false, "use the deterministic fake embedder and the extractive fake chat model")
+	skipAnswers := flag.Bool("skip-answers", false, "only measure retrieval")
+	out := flag.String("out", "", "write the report as JSON to this file")
+	baseline := flag.String("baseline", "", "compare with a report written by -out")
+	verbose := flag.Bool("v", false, "show pipeline logs")
+	flag.Parse()
+
+	cutoffs, err := parseKs(*ks)
+	if err != nil {
+		log.Fatalf("rageval: %v", err)
+	}
+
+	cfg := config.FromEnv()
+	if *fake {
+		cfg.EmbedProvider = llm.ProviderFake
+		cfg.ChatProvider = llm.ProviderFake
+	}
+	storeDir, err := os.MkdirTemp("", "rageval-*")
+	if err != nil {
+		log.Fatalf("rageval: %v", err)
+	}
+	defer os.RemoveAll(storeDir)
+	cfg.VectorStore = vectorstore.BackendDisk
+	cfg.VectorStorePath = storeDir
+	cfg.KeywordIndexPath = "" // In memory
+
+	dataset, err := eval.LoadDataset(*datasetDir)
+	if err != nil {
+		log.Fatalf("rageval: %v", err)
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	runner, err := eval.NewRunner(cfg)
+	if err != nil {
+		fatal(err)
+	}
+	defer runner.Close()
+
+	report, err := runner.Run(dataset, eval.Options{Ks: cutoffs, SkipAnswers: *skipAnswers})
+	if err != nil {
+		fatal(err)
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		fatal(err)
+	}
+
+	if *baseline != "" {
+		previous, err := eval.LoadReport(*baseline)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println()
+		if err := report.WriteComparison(os.Stdout, previous); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// fatal reports an error even when pipeline logs are silenced
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "rageval: %v\n", err)
+	os.Exit(1)
+}
+
+// parseKs parses the -k flag into ascending cutoffs
+func parseKs(value string) ([]int, error) {
+	var ks []int
+	for _, part := range strings.Split(value, ",") {
+		k, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || k < 1 {
+			return nil, fmt.Errorf("invalid cutoff %q in -k", part)
+		}
+		if len(ks) > 0 && k <= ks[len(ks)-1] {
+			return nil, fmt.Errorf("cutoffs in -k must be ascending")
+		}
+		ks = append(ks, k)
+	}
+	return ks, nil
+}
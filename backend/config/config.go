@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -51,9 +52,83 @@ type Config struct {
 	MinSimilThreshold  float64 // Minimum similarity to include
 	ContextWindowSize  int     // Max tokens for LLM context
 	MaxRAGChunks       int     // Max chunks to retrieve for RAG
+	MaxScopeFiles      int     // Max files a folder / multi-file / "all" query searches
+	HistoryMessages    int     // Previous messages used to rewrite follow-up questions
+	HistoryTokenBudget int     // Max tokens of chat history in the answer prompt
+	EnableQueryRewrite bool    // Rewrite follow-ups into standalone questions before retrieval
+
+	// Comma-separated e-mails allowed to query the full audit log
+	AdminEmails []string
+
+	// Access expiry sweeper interval in minutes (0 disables)
+	AccessSweepInterval int
+
+	// Mailer Settings
+	MailerDriver    string // "smtp" or "file" (dev)
+	MailerOutputDir string // Output directory for the file mailer
+	SMTPHost        string
+	SMTPPort        int
+	SMTPUsername    string
+	SMTPPassword    string
+	SMTPFrom        string
+
+	// Document Processing Queue
+	JobWorkers          int // Concurrent processing workers (bounds embedding load)
+	JobMaxAttempts      int // Attempts before a job is dead-lettered
+	JobLeaseSeconds     int // A job whose lease expires is picked up again by another worker
+	JobRetryBaseSeconds int // First retry delay, doubled on every attempt
+
+	// Text extraction
+	ExtractMaxZipEntryMB int // Largest uncompressed part of an office document (zip bomb guard)
+
+	// Embedding generation
+	EmbedBatchSize   int // Chunks per /api/embed request
+	EmbedConcurrency int // Concurrent embedding requests per document
+
+	// AI providers: "ollama", "openai" (any OpenAI-compatible server) or "fake" (offline).
+	// Chunks record the embedding model, so switching EMBED_PROVIDER or the embed model
+	// re-indexes every file. A model with a different vector size also needs a new
+	// CHROMA_COLLECTION, since a Chroma collection has a fixed dimension.
+	EmbedProvider    string
+	ChatProvider     string
+	OpenAIBaseURL    string // API root including the version, e.g. http://localhost:8000/v1
+	OpenAIAPIKey     string // Optional bearer token
+	OpenAIEmbedModel string
+	OpenAIChatModel  string
+
+	// Vector store: "chroma" or "disk" (embedded, persisted under VectorStorePath; no
+	// external vector DB needed)
+	VectorStore     string
+	VectorStorePath string
+	// BM25 keyword index, one JSON file per document
+	KeywordIndexPath string
+
+	// Reranking after retrieval: "heuristic" (intent rules), "llm" (chat model scores the
+	// first RerankTopN chunks; one model call per chunk) or "none"
+	Reranker   string
+	RerankTopN int
+
+	// File router ANN index (HNSW). Larger M/ef raise recall at the cost of memory and
+	// latency; VectorEncoding "int8" stores vectors in a quarter of the float32 size.
+	HNSWM              int
+	HNSWEfConstruction int
+	HNSWEfSearch       int
+	VectorEncoding     string
 }
 
 func Load() *Config {
+	cfg := FromEnv()
+
+	if cfg.GoogleClientID == "" || cfg.GoogleSecret == "" {
+		log.Fatal("❌ GOOGLE_CLIENT_ID ve GOOGLE_CLIENT_SECRET environment variables gerekli!")
+	}
+
+	return cfg
+}
+
+// FromEnv - Ayarları .env ve ortam değişkenlerinden oku. Sunucunun zorunlu alanlarını kontrol
+// etmez; OAuth ayarı gerekmeyen araçlar (cmd/rageval) için.
+func FromEnv() *Config {
 	// .env dosyasını yükle
 	if err := godotenv.Load(); err != nil {
 		log.Println("⚠️ .env dosyası bulunamadı veya yüklenemedi")
@@ -95,10 +170,41 @@ func Load() *Config {
 		MinSimilThreshold:     getEnvAsFloat("RAG_MIN_THRESHOLD", 0.3),
 		ContextWindowSize:     getEnvAsInt("RAG_CONTEXT_WINDOW", 4000),
 		MaxRAGChunks:          getEnvAsInt("RAG_MAX_CHUNKS", 10),
-	}
-
-	if cfg.GoogleClientID == "" || cfg.GoogleSecret == "" {
-		log.Fatal("❌ GOOGLE_CLIENT_ID ve GOOGLE_CLIENT_SECRET environment variables gerekli!")
+		MaxScopeFiles:         getEnvAsInt("RAG_MAX_SCOPE_FILES", 50),
+		HistoryMessages:       getEnvAsInt("RAG_HISTORY_MESSAGES", 6),
+		HistoryTokenBudget:    getEnvAsInt("RAG_HISTORY_TOKENS", 800),
+		EnableQueryRewrite:    getEnvAsBool("ENABLE_QUERY_REWRITE", true),
+		AdminEmails:           getEnvAsList("ADMIN_EMAILS"),
+		AccessSweepInterval:   getEnvAsInt("ACCESS_SWEEP_INTERVAL_MINUTES", 15),
+		MailerDriver:          getEnv("MAILER_DRIVER", "file"),
+		MailerOutputDir:       getEnv("MAILER_OUTPUT_DIR", "./mail-outbox"),
+		SMTPHost:              getEnv("SMTP_HOST", ""),
+		SMTPPort:              getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:          getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:          getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:              getEnv("SMTP_FROM", "Nimbus <no-reply@nimbus.local>"),
+		JobWorkers:            getEnvAsInt("JOB_WORKERS", 2),
+		JobMaxAttempts:        getEnvAsInt("JOB_MAX_ATTEMPTS", 5),
+		JobLeaseSeconds:       getEnvAsInt("JOB_LEASE_SECONDS", 300),
+		JobRetryBaseSeconds:   getEnvAsInt("JOB_RETRY_BASE_SECONDS", 30),
+		ExtractMaxZipEntryMB:  getEnvAsInt("EXTRACT_MAX_ZIP_ENTRY_MB", 64),
+		EmbedBatchSize:        getEnvAsInt("EMBED_BATCH_SIZE", 16),
+		EmbedConcurrency:      getEnvAsInt("EMBED_CONCURRENCY", 2),
+		EmbedProvider:         getEnv("EMBED_PROVIDER", "ollama"),
+		ChatProvider:          getEnv("CHAT_PROVIDER", "ollama"),
+		OpenAIBaseURL:         getEnv("OPENAI_BASE_URL", "http://localhost:8000/v1"),
+		OpenAIAPIKey:          getEnv("OPENAI_API_KEY", ""),
+		OpenAIEmbedModel:      getEnv("OPENAI_EMBED_MODEL", ""),
+		OpenAIChatModel:       getEnv("OPENAI_CHAT_MODEL", ""),
+		VectorStore:           getEnv("VECTOR_STORE", "chroma"),
+		VectorStorePath:       getEnv("VECTOR_STORE_PATH", "./data/vectors"),
+		KeywordIndexPath:      getEnv("KEYWORD_INDEX_PATH", "./data/keywords"),
+		Reranker:              getEnv("RERANKER", "heuristic"),
+		RerankTopN:            getEnvAsInt("RERANK_TOP_N", 10),
+		HNSWM:                 getEnvAsInt("HNSW_M", 16),
+		HNSWEfConstruction:    getEnvAsInt("HNSW_EF_CONSTRUCTION", 100),
+		HNSWEfSearch:          getEnvAsInt("HNSW_EF_SEARCH", 64),
+		VectorEncoding:        getEnv("VECTOR_ENCODING", "float32"),
 	}
 
 	return cfg
@@ -119,6 +225,27 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return valueStr == "true" || valueStr == "1" || valueStr == "yes"
 }
 
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, part := range strings.Split(getEnv(key, ""), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, strings.ToLower(part))
+		}
+	}
+	return values
+}
+
+// IsAdmin reports whether the e-mail belongs to a configured admin
+func (c *Config) IsAdmin(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, admin := range c.AdminEmails {
+		if admin == email {
+			return true
+		}
+	}
+	return false
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
 	if valueStr == "" {
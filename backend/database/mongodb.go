@@ -15,6 +15,11 @@ var UserCollection *mongo.Collection
 var FileCollection *mongo.Collection
 var FolderCollection *mongo.Collection
 var ConversationCollection *mongo.Collection
+var MessageCollection *mongo.Collection
+var TransferCollection *mongo.Collection
+var InvitationCollection *mongo.Collection
+var AuditCollection *mongo.Collection
+var JobCollection *mongo.Collection
 var Client *mongo.Client
 
 func Connect(cfg *config.Config) error {
@@ -38,6 +43,11 @@ func Connect(cfg *config.Config) error {
 	FileCollection = DB.Collection("files")
 	FolderCollection = DB.Collection("folders")
 	ConversationCollection = DB.Collection("conversations")
+	MessageCollection = DB.Collection("conversation_messages")
+	TransferCollection = DB.Collection("ownership_transfers")
+	InvitationCollection = DB.Collection("share_invitations")
+	AuditCollection = DB.Collection("audit_events")
+	JobCollection = DB.Collection("processing_jobs")
 
 	log.Println("✅ MongoDB bağlantısı başarılı!")
 	return nil
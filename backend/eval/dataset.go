@@ -0,0 +1,116 @@
+// Package eval measures retrieval and answer quality of the RAG pipeline offline. A dataset
+// is a directory of fixture documents plus golden.json, which lists questions with their
+// reference answers and the evidence passages retrieval is expected to find.
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GoldenFile is the name of the dataset manifest inside a dataset directory
+const GoldenFile = "golden.json"
+
+// Dataset is a set of documents and golden questions about them
+type Dataset struct {
+	Name      string     `json:"name"`
+	Documents []Document `json:"documents"`
+	Cases     []Case     `json:"cases"`
+
+	dir string
+}
+
+// Document is a fixture file, indexed under its ID the way an upload is indexed under its file ID
+type Document struct {
+	ID   string `json:"id"`
+	Path string `json:"path"` // Relative to the dataset directory
+}
+
+// Case is a golden question. Evidence lists short passages of the document that a correct
+// answer relies on; a retrieved chunk counts as relevant when it contains one of them.
+// Passages are matched word by word, ignoring case and punctuation, so they survive
+// changes to chunk boundaries and normalization.
+type Case struct {
+	ID       string   `json:"id"`
+	Document string   `json:"document"`
+	Question string   `json:"question"`
+	Answer   string   `json:"answer"`
+	Evidence []string `json:"evidence"`
+}
+
+// LoadDataset reads and validates golden.json from a dataset directory
+func LoadDataset(dir string) (*Dataset, error) {
+	data, err := os.ReadFile(filepath.Join(dir, GoldenFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	var dataset Dataset
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", GoldenFile, err)
+	}
+	dataset.dir = dir
+	if dataset.Name == "" {
+		dataset.Name = filepath.Base(dir)
+	}
+
+	if err := dataset.validate(); err != nil {
+		return nil, fmt.Errorf("invalid dataset %s: %w", dataset.Name, err)
+	}
+	return &dataset, nil
+}
+
+// DocumentPath returns where a fixture document is on disk
+func (d *Dataset) DocumentPath(doc Document) string {
+	return filepath.Join(d.dir, doc.Path)
+}
+
+func (d *Dataset) validate() error {
+	if len(d.Documents) == 0 {
+		return fmt.Errorf("no documents")
+	}
+	if len(d.Cases) == 0 {
+		return fmt.Errorf("no cases")
+	}
+
+	documents := make(map[string]bool, len(d.Documents))
+	for _, doc := range d.Documents {
+		if doc.ID == "" || doc.Path == "" {
+			return fmt.Errorf("documents need an id and a path")
+		}
+		if documents[doc.ID] {
+			return fmt.Errorf("duplicate document %q", doc.ID)
+		}
+		documents[doc.ID] = true
+	}
+
+	cases := make(map[string]bool, len(d.Cases))
+	for _, c := range d.Cases {
+		if c.ID == "" {
+			return fmt.Errorf("cases need an id")
+		}
+		if cases[c.ID] {
+			return fmt.Errorf("duplicate case %q", c.ID)
+		}
+		cases[c.ID] = true
+
+		if !documents[c.Document] {
+			return fmt.Errorf("case %s refers to unknown document %q", c.ID, c.Document)
+		}
+		if strings.TrimSpace(c.Question) == "" {
+			return fmt.Errorf("case %s has no question", c.ID)
+		}
+		if len(c.Evidence) == 0 {
+			return fmt.Errorf("case %s has no evidence", c.ID)
+		}
+		for _, passage := range c.Evidence {
+			if len(tokenize(passage)) == 0 {
+				return fmt.Errorf("case %s has an empty evidence passage", c.ID)
+			}
+		}
+	}
+	return nil
+}
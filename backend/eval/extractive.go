@@ -0,0 +1,79 @@
+package eval
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Markers of the prompt built by services.LLMService.BuildRAGPrompt
+var (
+	sourceHeaderPattern = regexp.MustCompile(`(?m)^--- Source \[(\d+)\][^\n]*---$`)
+	sentencePattern     = regexp.MustCompile(`[^.!?\n]+[.!?]?`)
+)
+
+const (
+	contextEndMarker = "===================================================="
+	questionMarker   = "USER QUESTION:"
+)
+
+// notFoundAnswer is what the prompt tells the model to reply when the context has no answer
+const notFoundAnswer = "Sorry, this question is not related to the document content. Please ask a question that is related to the document."
+
+// ExtractiveReply is the answer function of the fake chat model used for evaluation runs.
+// It reads the sources and the question from the RAG prompt and answers with the source
+// sentence sharing the most distinct words with the question, cited like a real answer.
+// This gives answer metrics an offline, deterministic baseline that depends on what was
+// retrieved.
+func ExtractiveReply(prompt string) string {
+	questionStart := strings.LastIndex(prompt, questionMarker)
+	if questionStart < 0 {
+		return notFoundAnswer
+	}
+	question := prompt[questionStart+len(questionMarker):]
+	if end := strings.Index(question, "\n"); end >= 0 {
+		question = question[:end]
+	}
+
+	// Short words ("the", "and", "bu") would match almost every sentence
+	questionWords := make(map[string]bool)
+	for _, word := range tokenize(question) {
+		if len([]rune(word)) > 3 {
+			questionWords[word] = true
+		}
+	}
+
+	bestScore, bestSentence, bestSource := 0, "", ""
+	headers := sourceHeaderPattern.FindAllStringSubmatchIndex(prompt, -1)
+	for i, header := range headers {
+		end := len(prompt)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		body := prompt[header[1]:end]
+		if cut := strings.Index(body, contextEndMarker); cut >= 0 {
+			body = body[:cut]
+		}
+
+		for _, sentence := range sentencePattern.FindAllString(body, -1) {
+			matched := make(map[string]bool)
+			for _, word := range tokenize(sentence) {
+				if questionWords[word] {
+					matched[word] = true
+				}
+			}
+			score := len(matched)
+			// Earlier sources win ties, they were ranked higher
+			if score > bestScore {
+				bestScore = score
+				bestSentence = strings.TrimSpace(sentence)
+				bestSource = prompt[header[2]:header[3]]
+			}
+		}
+	}
+
+	if bestScore == 0 {
+		return notFoundAnswer
+	}
+	return fmt.Sprintf("%s [%s]", bestSentence, bestSource)
+}
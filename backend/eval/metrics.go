@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenize lowercases text and splits it into words, dropping punctuation
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsWords reports whether needle occurs in haystack as a contiguous word sequence
+func containsWords(haystack, needle []string) bool {
+	if len(needle) == 0 || len(needle) > len(haystack) {
+		return false
+	}
+	for start := 0; start+len(needle) <= len(haystack); start++ {
+		match := true
+		for i, word := range needle {
+			if haystack[start+i] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEvidence returns, for every retrieved text in rank order, the indexes of the evidence
+// passages it contains
+func matchEvidence(retrieved []string, evidence []string) [][]int {
+	passages := make([][]string, len(evidence))
+	for i, passage := range evidence {
+		passages[i] = tokenize(passage)
+	}
+
+	matches := make([][]int, len(retrieved))
+	for rank, text := range retrieved {
+		words := tokenize(text)
+		for i, passage := range passages {
+			if containsWords(words, passage) {
+				matches[rank] = append(matches[rank], i)
+			}
+		}
+	}
+	return matches
+}
+
+// RecallAtK is the share of evidence passages found in the first k retrieved chunks
+func RecallAtK(matches [][]int, evidenceCount, k int) float64 {
+	if evidenceCount == 0 {
+		return 0
+	}
+	found := make(map[int]bool)
+	for rank := 0; rank < k && rank < len(matches); rank++ {
+		for _, passage := range matches[rank] {
+			found[passage] = true
+		}
+	}
+	return float64(len(found)) / float64(evidenceCount)
+}
+
+// ReciprocalRank is 1/rank of the first relevant chunk, 0 when none was retrieved.
+// Averaged over cases it is the mean reciprocal rank (MRR).
+func ReciprocalRank(matches [][]int) float64 {
+	for rank, passages := range matches {
+		if len(passages) > 0 {
+			return 1 / float64(rank+1)
+		}
+	}
+	return 0
+}
+
+// ContextPrecision is the average precision of the retrieved list: the mean of precision@i
+// over the ranks i that hold a relevant chunk. It rewards putting the relevant chunks first
+// and is 0 when none was retrieved.
+func ContextPrecision(matches [][]int) float64 {
+	relevant := 0
+	var sum float64
+	for rank, passages := range matches {
+		if len(passages) == 0 {
+			continue
+		}
+		relevant++
+		sum += float64(relevant) / float64(rank+1)
+	}
+	if relevant == 0 {
+		return 0
+	}
+	return sum / float64(relevant)
+}
+
+// AnswerOverlap compares an answer with the reference answer word by word and returns the
+// F1 score and the recall (share of reference words the answer contains), counting
+// repeated words at most as often as they occur in both
+func AnswerOverlap(answer, reference string) (f1, recall float64) {
+	answerWords := tokenize(answer)
+	referenceWords := tokenize(reference)
+	if len(answerWords) == 0 || len(referenceWords) == 0 {
+		return 0, 0
+	}
+
+	counts := make(map[string]int, len(referenceWords))
+	for _, word := range referenceWords {
+		counts[word]++
+	}
+	common := 0
+	for _, word := range answerWords {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+	if common == 0 {
+		return 0, 0
+	}
+
+	precision := float64(common) / float64(len(answerWords))
+	recall = float64(common) / float64(len(referenceWords))
+	return 2 * precision * recall / (precision + recall), recall
+}
@@ -0,0 +1,94 @@
+package eval
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMatchEvidenceIgnoresCaseAndPunctuation(t *testing.T) {
+	retrieved := []string{
+		"Backups run nightly.",
+		"The RPO is SIX hours -- for metadata; contents are replicated.",
+		"Legal hold: suspends every purge.",
+	}
+	evidence := []string{"legal hold suspends", "rpo is six hours, for metadata", "not in any chunk"}
+
+	matches := matchEvidence(retrieved, evidence)
+	if len(matches[0]) != 0 || len(matches[1]) != 1 || matches[1][0] != 1 || len(matches[2]) != 1 || matches[2][0] != 0 {
+		t.Fatalf("unexpected matches: %v", matches)
+	}
+
+	// Word sequences must be contiguous and whole words
+	if containsWords(tokenize("six hours"), tokenize("six")) != true || containsWords(tokenize("sixty hours"), tokenize("six hours")) {
+		t.Error("containsWords must match whole words only")
+	}
+}
+
+func TestRetrievalMetrics(t *testing.T) {
+	// Ranks 2 and 4 are relevant; the chunk at rank 4 holds two passages
+	matches := [][]int{nil, {0}, nil, {1, 2}}
+
+	if got := RecallAtK(matches, 4, 1); got != 0 {
+		t.Errorf("recall@1 = %v, want 0", got)
+	}
+	if got := RecallAtK(matches, 4, 2); !almostEqual(got, 0.25) {
+		t.Errorf("recall@2 = %v, want 0.25", got)
+	}
+	if got := RecallAtK(matches, 4, 10); !almostEqual(got, 0.75) {
+		t.Errorf("recall@10 = %v, want 0.75", got)
+	}
+	if got := ReciprocalRank(matches); !almostEqual(got, 0.5) {
+		t.Errorf("reciprocal rank = %v, want 0.5", got)
+	}
+	// (1/2 + 2/4) / 2
+	if got := ContextPrecision(matches); !almostEqual(got, 0.5) {
+		t.Errorf("context precision = %v, want 0.5", got)
+	}
+
+	if ReciprocalRank([][]int{nil, nil}) != 0 || ContextPrecision(nil) != 0 {
+		t.Error("no relevant chunk must score 0")
+	}
+}
+
+func TestAnswerOverlap(t *testing.T) {
+	f1, recall := AnswerOverlap("Files stay in the trash for 90 days.", "Deleted files are kept for 90 days")
+	// common: files, for, 90, days -> precision 4/8, recall 4/7
+	if !almostEqual(recall, 4.0/7) || !almostEqual(f1, 2*(0.5*4.0/7)/(0.5+4.0/7)) {
+		t.Errorf("f1 = %v, recall = %v", f1, recall)
+	}
+
+	// Repeated words only count as often as the reference has them
+	if _, recall := AnswerOverlap("days days days", "90 days"); !almostEqual(recall, 0.5) {
+		t.Errorf("recall = %v, want 0.5", recall)
+	}
+	if f1, _ := AnswerOverlap("", "90 days"); f1 != 0 {
+		t.Errorf("empty answer f1 = %v", f1)
+	}
+}
+
+func TestExtractiveReplyPicksBestSentence(t *testing.T) {
+	prompt := "DOCUMENT CONTEXT:\n====================================================\n\n" +
+		"--- Source [1] ---\nBackups are taken every six hours. Snapshots are kept for 35 days.\n\n" +
+		"--- Source [2] (definition of legal hold) ---\nLegal hold suspends every purge for the selected folders.\n\n" +
+		"====================================================\n\n" +
+		"USER QUESTION: How long are snapshots kept?\n\nINSTRUCTIONS:\n1. Keep snapshots short.\n"
+
+	if got := ExtractiveReply(prompt); got != "Snapshots are kept for 35 days. [1]" {
+		t.Errorf("unexpected answer: %q", got)
+	}
+
+	prompt = strings.Replace(prompt, "How long are snapshots kept?", "Which folders does legal hold affect?", 1)
+	if got := ExtractiveReply(prompt); got != "Legal hold suspends every purge for the selected folders. [2]" {
+		t.Errorf("unexpected answer: %q", got)
+	}
+
+	prompt = strings.Replace(prompt, "Which folders does legal hold affect?", "Who won the match?", 1)
+	if got := ExtractiveReply(prompt); got != notFoundAnswer {
+		t.Errorf("expected the not found answer, got %q", got)
+	}
+}
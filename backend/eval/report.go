@@ -0,0 +1,229 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// CaseResult holds the metrics of one golden question
+type CaseResult struct {
+	ID               string          `json:"id"`
+	Question         string          `json:"question"`
+	Intent           string          `json:"intent"`
+	Path             string          `json:"path"`
+	Retrieved        []string        `json:"retrieved"`                // Chunk IDs, best first
+	RelevantRanks    []int           `json:"relevant_ranks,omitempty"` // 1-based ranks of chunks containing evidence
+	Recall           map[int]float64 `json:"recall_at_k"`
+	ReciprocalRank   float64         `json:"reciprocal_rank"`
+	ContextPrecision float64         `json:"context_precision"`
+	Answer           string          `json:"answer,omitempty"`
+	AnswerF1         *float64        `json:"answer_f1,omitempty"` // Nil when no answer was generated
+	AnswerRecall     *float64        `json:"answer_recall,omitempty"`
+}
+
+// Summary averages case metrics. Answer metrics are averaged over the answered cases only.
+type Summary struct {
+	Cases            int             `json:"cases"`
+	Recall           map[int]float64 `json:"recall_at_k"`
+	MRR              float64         `json:"mrr"`
+	ContextPrecision float64         `json:"context_precision"`
+	Answered         int             `json:"answered"`
+	AnswerF1         float64         `json:"answer_f1"`
+	AnswerRecall     float64         `json:"answer_recall"`
+}
+
+// Report is the outcome of an evaluation run. Saved as JSON it serves as the baseline of
+// the next run (see WriteComparison).
+type Report struct {
+	Dataset    string             `json:"dataset"`
+	EmbedModel string             `json:"embed_model"`
+	Chat       string             `json:"chat"`
+	Reranker   string             `json:"reranker"`
+	Ks         []int              `json:"ks"`
+	Chunks     map[string]int     `json:"chunks"` // Chunk count per document
+	Answers    bool               `json:"answers"`
+	Summary    Summary            `json:"summary"`
+	ByIntent   map[string]Summary `json:"by_intent"`
+	Cases      []CaseResult       `json:"cases"`
+	Duration   time.Duration      `json:"duration_ns"`
+}
+
+// LoadReport reads a report saved as JSON
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// summarize fills in the overall and per-intent averages
+func (r *Report) summarize() {
+	r.Summary = summarizeCases(r.Cases, r.Ks)
+
+	byIntent := make(map[string][]CaseResult)
+	for _, c := range r.Cases {
+		byIntent[c.Intent] = append(byIntent[c.Intent], c)
+	}
+	r.ByIntent = make(map[string]Summary, len(byIntent))
+	for intent, cases := range byIntent {
+		r.ByIntent[intent] = summarizeCases(cases, r.Ks)
+	}
+}
+
+func summarizeCases(cases []CaseResult, ks []int) Summary {
+	summary := Summary{Cases: len(cases), Recall: make(map[int]float64, len(ks))}
+	if len(cases) == 0 {
+		return summary
+	}
+
+	for _, c := range cases {
+		for _, k := range ks {
+			summary.Recall[k] += c.Recall[k]
+		}
+		summary.MRR += c.ReciprocalRank
+		summary.ContextPrecision += c.ContextPrecision
+		if c.AnswerF1 != nil {
+			summary.Answered++
+			summary.AnswerF1 += *c.AnswerF1
+			summary.AnswerRecall += *c.AnswerRecall
+		}
+	}
+
+	n := float64(len(cases))
+	for _, k := range ks {
+		summary.Recall[k] /= n
+	}
+	summary.MRR /= n
+	summary.ContextPrecision /= n
+	if summary.Answered > 0 {
+		summary.AnswerF1 /= float64(summary.Answered)
+		summary.AnswerRecall /= float64(summary.Answered)
+	}
+	return summary
+}
+
+// metric is a named summary value, in report order
+type metric struct {
+	name  string
+	value func(Summary) float64
+}
+
+func (r *Report) metrics() []metric {
+	var metrics []metric
+	for _, k := range r.Ks {
+		metrics = append(metrics, metric{fmt.Sprintf("recall@%d", k), func(s Summary) float64 { return s.Recall[k] }})
+	}
+	metrics = append(metrics,
+		metric{"mrr", func(s Summary) float64 { return s.MRR }},
+		metric{"context_precision", func(s Summary) float64 { return s.ContextPrecision }},
+	)
+	if r.Answers {
+		metrics = append(metrics,
+			metric{"answer_f1", func(s Summary) float64 { return s.AnswerF1 }},
+			metric{"answer_recall", func(s Summary) float64 { return s.AnswerRecall }},
+		)
+	}
+	return metrics
+}
+
+// WriteText prints the run settings, the summary, the per-intent breakdown and the cases
+// whose evidence was not retrieved at the largest cutoff
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Dataset %s: %d cases, embedder %s, chat %s, reranker %s (%v)\n",
+		r.Dataset, r.Summary.Cases, r.EmbedModel, r.Chat, r.Reranker, r.Duration.Round(time.Millisecond))
+
+	documents := make([]string, 0, len(r.Chunks))
+	for doc := range r.Chunks {
+		documents = append(documents, doc)
+	}
+	sort.Strings(documents)
+	for _, doc := range documents {
+		fmt.Fprintf(w, "  %s: %d chunks\n", doc, r.Chunks[doc])
+	}
+	fmt.Fprintln(w)
+
+	metrics := r.metrics()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "\tcases")
+	for _, m := range metrics {
+		fmt.Fprintf(tw, "\t%s", m.name)
+	}
+	fmt.Fprintln(tw)
+	writeRow := func(label string, summary Summary) {
+		fmt.Fprintf(tw, "%s\t%d", label, summary.Cases)
+		for _, m := range metrics {
+			fmt.Fprintf(tw, "\t%.3f", m.value(summary))
+		}
+		fmt.Fprintln(tw)
+	}
+	writeRow("all", r.Summary)
+	intents := make([]string, 0, len(r.ByIntent))
+	for intent := range r.ByIntent {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+	for _, intent := range intents {
+		writeRow("intent "+intent, r.ByIntent[intent])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Ks) > 0 {
+		maxK := r.Ks[len(r.Ks)-1]
+		missed := false
+		for _, c := range r.Cases {
+			if c.Recall[maxK] >= 1 {
+				continue
+			}
+			if !missed {
+				fmt.Fprintln(w)
+				missed = true
+			}
+			fmt.Fprintf(w, "Missed evidence: %s (recall@%d %.2f, %s via %s) %q\n", c.ID, maxK, c.Recall[maxK], c.Intent, c.Path, c.Question)
+		}
+	}
+	return nil
+}
+
+// WriteComparison prints the summary metrics next to a baseline report, and the cases
+// whose reciprocal rank or context precision dropped
+func (r *Report) WriteComparison(w io.Writer, baseline *Report) error {
+	fmt.Fprintf(w, "Compared with baseline (embedder %s, reranker %s):\n", baseline.EmbedModel, baseline.Reranker)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric\tbaseline\tcurrent\tdelta")
+	for _, m := range r.metrics() {
+		before, after := m.value(baseline.Summary), m.value(r.Summary)
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%+.3f\n", m.name, before, after, after-before)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	baselineCases := make(map[string]CaseResult, len(baseline.Cases))
+	for _, c := range baseline.Cases {
+		baselineCases[c.ID] = c
+	}
+	for _, c := range r.Cases {
+		before, ok := baselineCases[c.ID]
+		if !ok {
+			continue
+		}
+		if c.ReciprocalRank < before.ReciprocalRank || c.ContextPrecision < before.ContextPrecision {
+			fmt.Fprintf(w, "Regressed: %s (reciprocal rank %.2f -> %.2f, context precision %.2f -> %.2f)\n",
+				c.ID, before.ReciprocalRank, c.ReciprocalRank, before.ContextPrecision, c.ContextPrecision)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"nimbus-backend/config"
+	"nimbus-backend/extract"
+	"nimbus-backend/llm"
+	"nimbus-backend/retrieval"
+	"nimbus-backend/services"
+)
+
+// DefaultKs are the cutoffs recall is reported at
+var DefaultKs = []int{1, 3, 5, 10}
+
+// Options tune an evaluation run
+type Options struct {
+	Ks          []int // Recall cutoffs, DefaultKs when empty
+	SkipAnswers bool  // Only measure retrieval, do not call the chat model
+}
+
+// Runner indexes dataset documents and asks the golden questions through the same code
+// the server uses: services.SplitDocument and BuildChunkRecords for indexing, the
+// configured vector store and keyword index, services.RetrieveFileChunks for retrieval
+// and reranking, and LLMService.GenerateRAGResponse for answers.
+type Runner struct {
+	cfg           *config.Config
+	llmService    *services.LLMService
+	vectorService *services.VectorService
+}
+
+// NewRunner sets up the providers, reranker and vector store selected by cfg. It replaces
+// the service singletons, so it must not be used inside the server process. Caches are
+// turned off so every question is measured against the index. With CHAT_PROVIDER=fake the
+// answers come from ExtractiveReply.
+func NewRunner(cfg *config.Config) (*Runner, error) {
+	runCfg := *cfg
+	runCfg.EnableQueryCache = false
+	runCfg.EnableChunkCache = false
+
+	embedder, err := llm.NewEmbedder(&runCfg)
+	if err != nil {
+		return nil, err
+	}
+	var chat llm.ChatModel
+	if strings.EqualFold(runCfg.ChatProvider, llm.ProviderFake) {
+		chat = llm.NewFakeChatModel(ExtractiveReply)
+	} else if chat, err = llm.NewChatModel(&runCfg); err != nil {
+		return nil, err
+	}
+	reranker, err := retrieval.NewReranker(runCfg.Reranker, chat, runCfg.RerankTopN)
+	if err != nil {
+		return nil, err
+	}
+	services.EmbedderInstance = embedder
+	services.ChatModelInstance = chat
+	services.RerankerInstance = reranker
+
+	if err := services.InitVectorStore(&runCfg); err != nil {
+		return nil, err
+	}
+
+	return &Runner{
+		cfg:           &runCfg,
+		llmService:    services.NewLLMService(&runCfg),
+		vectorService: services.NewVectorService(&runCfg),
+	}, nil
+}
+
+// Close releases the vector store
+func (r *Runner) Close() error {
+	return services.VectorStoreInstance.Close()
+}
+
+// Run indexes the dataset documents and evaluates every case
+func (r *Runner) Run(dataset *Dataset, opts Options) (*Report, error) {
+	ks := opts.Ks
+	if len(ks) == 0 {
+		ks = DefaultKs
+	}
+
+	started := time.Now()
+	chunkCounts, err := r.index(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Dataset:    dataset.Name,
+		EmbedModel: services.EmbedModelID(),
+		Chat:       r.cfg.ChatProvider,
+		Reranker:   services.RerankerName(),
+		Ks:         ks,
+		Chunks:     chunkCounts,
+		Answers:    !opts.SkipAnswers,
+	}
+	for _, c := range dataset.Cases {
+		result, err := r.evaluateCase(c, ks, opts.SkipAnswers)
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", c.ID, err)
+		}
+		report.Cases = append(report.Cases, *result)
+	}
+	report.summarize()
+	report.Duration = time.Since(started)
+	return report, nil
+}
+
+// index extracts, chunks and embeds every document and replaces its chunks in the store.
+// Evidence passages missing from their document fail the run, since they could never be found.
+func (r *Runner) index(dataset *Dataset) (map[string]int, error) {
+	chunkCounts := make(map[string]int, len(dataset.Documents))
+	for _, doc := range dataset.Documents {
+		data, err := os.ReadFile(dataset.DocumentPath(doc))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document %s: %w", doc.ID, err)
+		}
+		extracted, err := extract.Default.Extract(data, "", doc.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract document %s: %w", doc.ID, err)
+		}
+
+		documentWords := tokenize(extracted.Text)
+		for _, c := range dataset.Cases {
+			if c.Document != doc.ID {
+				continue
+			}
+			for _, passage := range c.Evidence {
+				if !containsWords(documentWords, tokenize(passage)) {
+					return nil, fmt.Errorf("evidence %q of case %s is not in document %s", passage, c.ID, doc.ID)
+				}
+			}
+		}
+
+		semanticChunks := services.SplitDocument(extracted, doc.Path)
+		if len(semanticChunks) == 0 {
+			return nil, fmt.Errorf("no chunks created from document %s", doc.ID)
+		}
+		records, texts := services.BuildChunkRecords(doc.ID, services.EmbedModelID(), semanticChunks)
+		if err := r.embed(records, texts); err != nil {
+			return nil, fmt.Errorf("failed to embed document %s: %w", doc.ID, err)
+		}
+
+		if err := r.vectorService.DeleteDocumentChunks(doc.ID); err != nil {
+			return nil, fmt.Errorf("failed to clear document %s: %w", doc.ID, err)
+		}
+		if err := r.vectorService.ApplyFileChunks(doc.ID, records, records, nil); err != nil {
+			return nil, fmt.Errorf("failed to index document %s: %w", doc.ID, err)
+		}
+		chunkCounts[doc.ID] = len(records)
+	}
+	return chunkCounts, nil
+}
+
+// embed fills in the embeddings of the records in batches of EMBED_BATCH_SIZE
+func (r *Runner) embed(records []services.ChunkData, texts []string) error {
+	batchSize := r.cfg.EmbedBatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		embeddings, err := services.EmbedderInstance.Embed(context.Background(), texts[start:end])
+		if err != nil {
+			return err
+		}
+		for i, embedding := range embeddings {
+			records[start+i].Embedding = embedding
+		}
+	}
+	return nil
+}
+
+// evaluateCase retrieves chunks for a golden question, scores them against the evidence
+// and, unless skipAnswers is set, scores the generated answer against the reference answer
+func (r *Runner) evaluateCase(c Case, ks []int, skipAnswers bool) (*CaseResult, error) {
+	result := &CaseResult{
+		ID:       c.ID,
+		Question: c.Question,
+		Recall:   make(map[int]float64, len(ks)),
+	}
+
+	retrieved, err := services.RetrieveFileChunks(context.Background(), r.llmService, r.vectorService, c.Question, c.Document)
+	if err != nil && !errors.Is(err, services.ErrNoRelevantChunks) {
+		return nil, err
+	}
+	result.Intent = string(retrieved.Intent.Intent)
+	result.Path = retrieved.Path
+
+	texts := make([]string, len(retrieved.Chunks))
+	for i, chunk := range retrieved.Chunks {
+		texts[i] = chunk.Text
+		result.Retrieved = append(result.Retrieved, chunk.ID)
+	}
+
+	matches := matchEvidence(texts, c.Evidence)
+	for rank, passages := range matches {
+		if len(passages) > 0 {
+			result.RelevantRanks = append(result.RelevantRanks, rank+1)
+		}
+	}
+	for _, k := range ks {
+		result.Recall[k] = RecallAtK(matches, len(c.Evidence), k)
+	}
+	result.ReciprocalRank = ReciprocalRank(matches)
+	result.ContextPrecision = ContextPrecision(matches)
+
+	if skipAnswers || c.Answer == "" {
+		return result, nil
+	}
+	answer, err := r.llmService.GenerateRAGResponse(c.Question, texts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+	result.Answer = strings.TrimSpace(answer)
+	f1, recall := AnswerOverlap(result.Answer, c.Answer)
+	result.AnswerF1 = &f1
+	result.AnswerRecall = &recall
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nimbus-backend/config"
+	"nimbus-backend/llm"
+	"nimbus-backend/vectorstore"
+)
+
+// testConfig mirrors the server defaults with offline providers and a temporary store
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	return &config.Config{
+		EmbedProvider:      llm.ProviderFake,
+		ChatProvider:       llm.ProviderFake,
+		Reranker:           "heuristic",
+		RerankTopN:         10,
+		VectorStore:        vectorstore.BackendDisk,
+		VectorStorePath:    t.TempDir(),
+		EnableFileRouting:  true,
+		HNSWM:              16,
+		HNSWEfConstruction: 100,
+		HNSWEfSearch:       64,
+		VectorEncoding:     "float32",
+		EmbedBatchSize:     16,
+		ContextWindowSize:  4000,
+		HistoryTokenBudget: 800,
+	}
+}
+
+// TestGoldenDataset runs the bundled dataset through the pipeline with the fake providers.
+// The floors catch chunking or retrieval changes that make the golden answers unreachable;
+// raise them when a change improves the scores.
+func TestGoldenDataset(t *testing.T) {
+	dataset, err := LoadDataset("testdata/nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := NewRunner(testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Close()
+
+	report, err := runner.Run(dataset, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary.Cases != len(dataset.Cases) || report.Summary.Answered != len(dataset.Cases) {
+		t.Fatalf("expected %d evaluated and answered cases, got %+v", len(dataset.Cases), report.Summary)
+	}
+
+	floors := map[string]float64{
+		"recall@1":          0.6,
+		"recall@3":          0.95,
+		"mrr":               0.8,
+		"context_precision": 0.8,
+		"answer_f1":         0.5,
+	}
+	for _, m := range report.metrics() {
+		if floor, ok := floors[m.name]; ok && m.value(report.Summary) < floor {
+			t.Errorf("%s = %.3f, below the floor of %.2f", m.name, m.value(report.Summary), floor)
+		}
+	}
+
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "intent specific") {
+		t.Errorf("report misses the per-intent breakdown:\n%s", text.String())
+	}
+}
+
+func TestLoadDatasetValidates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(golden string) {
+		if err := os.WriteFile(filepath.Join(dir, GoldenFile), []byte(golden), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"documents": [{"id": "a", "path": "a.md"}], "cases": [{"id": "q", "document": "b", "question": "?", "evidence": ["x"]}]}`)
+	if _, err := LoadDataset(dir); err == nil || !strings.Contains(err.Error(), "unknown document") {
+		t.Errorf("expected an unknown document error, got %v", err)
+	}
+
+	write(`{"documents": [{"id": "a", "path": "a.md"}], "cases": [{"id": "q", "document": "a", "question": "?", "evidence": []}]}`)
+	if _, err := LoadDataset(dir); err == nil || !strings.Contains(err.Error(), "no evidence") {
+		t.Errorf("expected a missing evidence error, got %v", err)
+	}
+
+	write(`{"documents": [{"id": "a", "path": "a.md"}], "cases": [{"id": "q", "document": "a", "question": "?", "evidence": ["x"]}]}`)
+	dataset, err := LoadDataset(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataset.Name != filepath.Base(dir) {
+		t.Errorf("name should default to the directory, got %q", dataset.Name)
+	}
+}
@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLookupPrefersExtension(t *testing.T) {
+	if !Supports("application/vnd.ms-excel", "report.csv") {
+		t.Error("CSV uploaded with an Excel MIME type should be supported")
+	}
+	if !Supports("text/x-unknown", "") {
+		t.Error("unknown text/* types should fall back to plain text")
+	}
+	if Supports("image/png", "photo.png") {
+		t.Error("images must not be askable")
+	}
+}
+
+func TestExtractXLSX(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"xl/workbook.xml":      `<workbook><sheets><sheet name="Sales"/></sheets></workbook>`,
+		"xl/sharedStrings.xml": `<sst><si><t>City</t></si><si><t>Revenue</t></si><si><r><t>Ank</t></r><r><t>ara</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+			<row><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+			<row><c r="A2" t="s"><v>2</v></c><c r="B2"><v>1500</v></c></row>
+			<row><c r="B3"><v>20</v></c></row>
+		</sheetData></worksheet>`,
+	})
+
+	doc, err := Default.Extract(data, "", "sales.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Sheet: Sales", "City: Ankara | Revenue: 1500", "Revenue: 20"} {
+		if !strings.Contains(doc.Text, want) {
+			t.Errorf("missing %q in %q", want, doc.Text)
+		}
+	}
+}
+
+func TestExtractPPTXSlidesArePages(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"ppt/slides/slide10.xml": `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Last</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Second</a:t></a:r></a:p></p:sld>`,
+	})
+
+	doc, err := Default.Extract(data, "", "deck.pptx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Pages) != 2 || !strings.Contains(doc.Pages[0], "Second") || !strings.Contains(doc.Pages[1], "Last") {
+		t.Errorf("slides not in numeric order: %q", doc.Pages)
+	}
+}
+
+func TestExtractRejectsOversizedEntries(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:p><w:r><w:t>` + strings.Repeat("a", 2048) + `</w:t></w:r></w:p></w:document>`,
+	})
+
+	defer func(limit int64) { MaxZipEntrySize = limit }(MaxZipEntrySize)
+	MaxZipEntrySize = 1024
+	if _, err := Default.Extract(data, "", "big.docx"); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("expected ErrEntryTooLarge, got %v", err)
+	}
+
+	MaxZipEntrySize = 4096
+	if _, err := Default.Extract(data, "", "big.docx"); err != nil {
+		t.Errorf("entry under the limit failed: %v", err)
+	}
+}
+
+func TestExtractRTF(t *testing.T) {
+	rtf := `{\rtf1\ansi{\fonttbl{\f0 Arial;}}{\*\generator Word;}\f0 Merhaba d\u252?nya\par G\'fczel}`
+
+	doc, err := Default.Extract([]byte(rtf), "application/rtf", "note.rtf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != "Merhaba dünya\nGüzel" {
+		t.Errorf("unexpected RTF text: %q", doc.Text)
+	}
+}
+
+func TestExtractHTMLSkipsScripts(t *testing.T) {
+	page := `<html><head><title>Guide</title><style>p{}</style></head><body><script>var x=1;</script><p>Hello</p><p>World</p></body></html>`
+
+	doc, err := Default.Extract([]byte(page), "text/html", "guide.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(doc.Text, "var x") || strings.Contains(doc.Text, "p{}") {
+		t.Errorf("script or style leaked into text: %q", doc.Text)
+	}
+	for _, want := range []string{"Guide", "Hello", "World"} {
+		if !strings.Contains(doc.Text, want) {
+			t.Errorf("missing %q in %q", want, doc.Text)
+		}
+	}
+}
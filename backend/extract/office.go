@@ -0,0 +1,381 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nimbus-backend/chunks"
+	"nimbus-backend/tables"
+)
+
+// MaxZipEntrySize caps the uncompressed size of a single archive part read from an office
+// document, so a small zip bomb cannot exhaust memory. Set from EXTRACT_MAX_ZIP_ENTRY_MB.
+var MaxZipEntrySize int64 = 64 << 20
+
+// ErrEntryTooLarge is returned when an archive part exceeds MaxZipEntrySize
+var ErrEntryTooLarge = errors.New("archive entry exceeds the size limit")
+
+// extractDOCX reads word/document.xml from a DOCX archive
+func extractDOCX(data []byte) (*Document, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX as ZIP: %w", err)
+	}
+
+	documentXML, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document.xml from DOCX: %w", err)
+	}
+
+	text, err := xmlText(documentXML, ooxmlText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document.xml: %w", err)
+	}
+	return &Document{Text: text}, nil
+}
+
+// extractPPTX reads every slide in order; each slide becomes a page so citations can point to it
+func extractPPTX(data []byte) (*Document, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PPTX as ZIP: %w", err)
+	}
+
+	slideNames := numberedParts(archive, "ppt/slides/slide")
+	if len(slideNames) == 0 {
+		return nil, fmt.Errorf("no slides found in PPTX")
+	}
+
+	pages := make([]string, 0, len(slideNames))
+	for _, name := range slideNames {
+		slideXML, err := readZipFile(archive, name)
+		if err != nil {
+			return nil, err
+		}
+		text, err := xmlText(slideXML, ooxmlText)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		pages = append(pages, text)
+	}
+
+	text, _ := chunks.JoinPages(pages)
+	return &Document{Text: text, Pages: pages}, nil
+}
+
+// extractXLSX renders every sheet as "Header: value" rows
+func extractXLSX(data []byte) (*Document, error) {
+	sheets, err := ParseXLSX(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	var sb strings.Builder
+	for _, sheet := range sheets {
+		sb.WriteString(renderTable(sheet.Name, sheet.Rows))
+		if table := tables.FromRows(sheet.Name, sheet.Rows); len(table.Headers) > 0 {
+			doc.Tables = append(doc.Tables, table)
+		}
+	}
+	doc.Text = sb.String()
+	return doc, nil
+}
+
+// Sheet is a worksheet with its cell values by row
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// ParseXLSX reads the cell values of every worksheet in an XLSX file
+func ParseXLSX(data []byte) ([]Sheet, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX as ZIP: %w", err)
+	}
+
+	sharedStrings, err := xlsxSharedStrings(archive)
+	if err != nil {
+		return nil, err
+	}
+	sheetNames := xlsxSheetNames(archive)
+
+	var sheets []Sheet
+	for i, name := range numberedParts(archive, "xl/worksheets/sheet") {
+		sheetXML, err := readZipFile(archive, name)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := xlsxRows(sheetXML, sharedStrings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		sheetName := fmt.Sprintf("Sheet%d", i+1)
+		if i < len(sheetNames) {
+			sheetName = sheetNames[i]
+		}
+		sheets = append(sheets, Sheet{Name: sheetName, Rows: rows})
+	}
+
+	return sheets, nil
+}
+
+// extractODF reads content.xml of OpenDocument text, spreadsheet and presentation files
+func extractODF(data []byte) (*Document, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OpenDocument as ZIP: %w", err)
+	}
+
+	contentXML, err := readZipFile(archive, "content.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content.xml from OpenDocument: %w", err)
+	}
+
+	text, err := xmlText(contentXML, odfText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse content.xml: %w", err)
+	}
+	return &Document{Text: text}, nil
+}
+
+// xmlTextRules describes which elements hold text and which ones end a line
+type xmlTextRules struct {
+	text      map[string]bool   // Elements whose character data is kept
+	separator map[string]string // Written when the element ends (paragraphs, cells)
+	inline    map[string]string // Empty elements that stand for a character (tabs, breaks)
+}
+
+var ooxmlText = xmlTextRules{
+	text:      map[string]bool{"t": true},
+	separator: map[string]string{"p": "\n\n", "tc": " | ", "tr": "\n"},
+	inline:    map[string]string{"tab": "\t", "br": "\n"},
+}
+
+var odfText = xmlTextRules{
+	text:      map[string]bool{"p": true, "h": true, "span": true, "a": true},
+	separator: map[string]string{"p": "\n\n", "h": "\n\n", "table-cell": " | ", "table-row": "\n"},
+	inline:    map[string]string{"tab": "\t", "line-break": "\n", "s": " "},
+}
+
+// xmlText walks an XML document and collects the text of the configured elements
+func xmlText(data []byte, rules xmlTextRules) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var sb strings.Builder
+	depth := 0 // Number of open text elements
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if rules.text[t.Name.Local] {
+				depth++
+			}
+			if s, ok := rules.inline[t.Name.Local]; ok {
+				sb.WriteString(s)
+			}
+		case xml.EndElement:
+			if rules.text[t.Name.Local] && depth > 0 {
+				depth--
+			}
+			if s, ok := rules.separator[t.Name.Local]; ok {
+				sb.WriteString(s)
+			}
+		case xml.CharData:
+			if depth > 0 {
+				sb.Write(t)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func xlsxSharedStrings(archive *zip.Reader) ([]string, error) {
+	data, err := readZipFile(archive, "xl/sharedStrings.xml")
+	if errors.Is(err, ErrEntryTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, nil // Workbooks without text cells have no shared strings
+	}
+
+	var table struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse sharedStrings.xml: %w", err)
+	}
+
+	strs := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		if item.Text != "" {
+			strs[i] = item.Text
+			continue
+		}
+		var sb strings.Builder
+		for _, run := range item.Runs {
+			sb.WriteString(run.Text)
+		}
+		strs[i] = sb.String()
+	}
+	return strs, nil
+}
+
+func xlsxSheetNames(archive *zip.Reader) []string {
+	data, err := readZipFile(archive, "xl/workbook.xml")
+	if err != nil {
+		return nil
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(data, &workbook); err != nil {
+		return nil
+	}
+
+	names := make([]string, len(workbook.Sheets))
+	for i, sheet := range workbook.Sheets {
+		names[i] = sheet.Name
+	}
+	return names
+}
+
+var cellColumnPattern = regexp.MustCompile(`^[A-Z]+`)
+
+func xlsxRows(data []byte, sharedStrings []string) ([][]string, error) {
+	var worksheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref       string `xml:"r,attr"`
+				Type      string `xml:"t,attr"`
+				Value     string `xml:"v"`
+				InlineStr string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &worksheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(worksheet.Rows))
+	for _, row := range worksheet.Rows {
+		var values []string
+		for i, cell := range row.Cells {
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				if idx, err := strconv.Atoi(cell.Value); err == nil && idx < len(sharedStrings) {
+					value = sharedStrings[idx]
+				}
+			case "inlineStr":
+				value = cell.InlineStr
+			case "b":
+				value = map[string]string{"0": "FALSE", "1": "TRUE"}[cell.Value]
+			}
+
+			// Place the value in its real column so sparse rows stay aligned with the header
+			column := i
+			if letters := cellColumnPattern.FindString(cell.Ref); letters != "" {
+				column = columnIndex(letters)
+			}
+			for len(values) < column {
+				values = append(values, "")
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// columnIndex converts "A" -> 0, "Z" -> 25, "AA" -> 26
+func columnIndex(letters string) int {
+	index := 0
+	for _, r := range letters {
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
+
+func openZip(data []byte) (*zip.Reader, error) {
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name == name {
+			// The header size can lie, so the read is limited as well
+			if file.UncompressedSize64 > uint64(MaxZipEntrySize) {
+				return nil, fmt.Errorf("%s: %w", name, ErrEntryTooLarge)
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", name, err)
+			}
+			defer rc.Close()
+			data, err := io.ReadAll(io.LimitReader(rc, MaxZipEntrySize+1))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			if int64(len(data)) > MaxZipEntrySize {
+				return nil, fmt.Errorf("%s: %w", name, ErrEntryTooLarge)
+			}
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+// numberedParts returns archive entries like prefix1.xml, prefix2.xml ordered by number
+func numberedParts(archive *zip.Reader, prefix string) []string {
+	type part struct {
+		name   string
+		number int
+	}
+	var parts []part
+	for _, file := range archive.File {
+		if !strings.HasPrefix(file.Name, prefix) || path.Ext(file.Name) != ".xml" {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(file.Name, prefix), ".xml"))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, part{name: file.Name, number: number})
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].number < parts[j].number })
+
+	names := make([]string, len(parts))
+	for i, p := range parts {
+		names[i] = p.name
+	}
+	return names
+}
@@ -0,0 +1,41 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"github.com/ledongthuc/pdf"
+
+	"nimbus-backend/chunks"
+)
+
+// extractPDF extracts the text of every PDF page separately so chunks keep page numbers.
+// Pages that fail or have no content are kept as empty strings to keep numbering intact.
+func extractPDF(data []byte) (*Document, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PDF reader: %w", err)
+	}
+
+	numPages := reader.NumPage()
+	pages := make([]string, numPages)
+
+	for i := 1; i <= numPages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			log.Printf("Warning: failed to extract text from page %d: %v", i, err)
+			continue
+		}
+
+		pages[i-1] = text
+	}
+
+	text, _ := chunks.JoinPages(pages)
+	return &Document{Text: text, Pages: pages}, nil
+}
@@ -0,0 +1,164 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"nimbus-backend/tables"
+)
+
+// Document is the plain text extracted from an uploaded file
+type Document struct {
+	Text   string
+	Pages  []string       // Text of each page (PDF) or slide (PPTX), index 0 = 1; nil for unpaginated formats
+	Tables []tables.Table // Typed sheets of spreadsheet formats (XLSX, CSV, TSV)
+}
+
+// Extractor turns raw file bytes into text
+type Extractor func(data []byte) (*Document, error)
+
+// Registry maps file extensions and MIME types to extractors
+type Registry struct {
+	byExtension map[string]Extractor
+	byMimeType  map[string]Extractor
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		byExtension: make(map[string]Extractor),
+		byMimeType:  make(map[string]Extractor),
+	}
+}
+
+// Default is the registry used by the document processor
+var Default = newDefaultRegistry()
+
+// Register adds an extractor for the given extensions (with leading dot) and MIME types
+func (r *Registry) Register(extractor Extractor, extensions []string, mimeTypes []string) {
+	for _, ext := range extensions {
+		r.byExtension[strings.ToLower(ext)] = extractor
+	}
+	for _, mimeType := range mimeTypes {
+		r.byMimeType[strings.ToLower(mimeType)] = extractor
+	}
+}
+
+// Lookup finds the extractor for a file. The extension wins over the MIME type because
+// browsers report inconsistent types (e.g. CSV uploaded as application/vnd.ms-excel).
+func (r *Registry) Lookup(contentType, filename string) (Extractor, bool) {
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" {
+		if extractor, ok := r.byExtension[ext]; ok {
+			return extractor, true
+		}
+	}
+
+	mimeType := normalizeMimeType(contentType)
+	if extractor, ok := r.byMimeType[mimeType]; ok {
+		return extractor, true
+	}
+
+	// Unknown text/* types are still readable as plain text
+	if strings.HasPrefix(mimeType, "text/") {
+		return extractPlainText, true
+	}
+
+	return nil, false
+}
+
+// Supports reports whether the file can be processed for the Ask feature
+func (r *Registry) Supports(contentType, filename string) bool {
+	_, ok := r.Lookup(contentType, filename)
+	return ok
+}
+
+// Extract runs the matching extractor for the file
+func (r *Registry) Extract(data []byte, contentType, filename string) (*Document, error) {
+	extractor, ok := r.Lookup(contentType, filename)
+	if !ok {
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+	return extractor(data)
+}
+
+// Supports reports whether the default registry can process the file
+func Supports(contentType, filename string) bool {
+	return Default.Supports(contentType, filename)
+}
+
+// spreadsheetExtensions are the formats whose rows can be queried with tables.Evaluate
+var spreadsheetExtensions = map[string]bool{".xlsx": true, ".csv": true, ".tsv": true}
+
+var spreadsheetMimeTypes = map[string]bool{
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": true,
+	"text/csv":                  true,
+	"text/tab-separated-values": true,
+}
+
+// IsSpreadsheet reports whether extraction yields Document.Tables for the file
+func IsSpreadsheet(contentType, filename string) bool {
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" {
+		if _, known := Default.byExtension[ext]; known {
+			return spreadsheetExtensions[ext]
+		}
+	}
+	return spreadsheetMimeTypes[normalizeMimeType(contentType)]
+}
+
+// normalizeMimeType lowercases the type and strips parameters such as "; charset=utf-8"
+func normalizeMimeType(contentType string) string {
+	mimeType := strings.ToLower(strings.TrimSpace(contentType))
+	if idx := strings.Index(mimeType, ";"); idx >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:idx])
+	}
+	return mimeType
+}
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	r.Register(extractPDF, []string{".pdf"}, []string{"application/pdf"})
+	r.Register(extractDOCX, []string{".docx"}, []string{
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/msword",
+	})
+	r.Register(extractXLSX, []string{".xlsx"}, []string{
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	})
+	r.Register(extractPPTX, []string{".pptx"}, []string{
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	})
+	r.Register(extractODF, []string{".odt", ".ods", ".odp"}, []string{
+		"application/vnd.oasis.opendocument.text",
+		"application/vnd.oasis.opendocument.spreadsheet",
+		"application/vnd.oasis.opendocument.presentation",
+	})
+	r.Register(extractRTF, []string{".rtf"}, []string{"application/rtf", "text/rtf"})
+	r.Register(extractHTML, []string{".html", ".htm"}, []string{"text/html", "application/xhtml+xml"})
+	r.Register(extractCSV, []string{".csv"}, []string{"text/csv"})
+	r.Register(extractTSV, []string{".tsv"}, []string{"text/tab-separated-values"})
+	r.Register(extractPlainText, plainTextExtensions, plainTextMimeTypes)
+
+	return r
+}
+
+// plainTextExtensions covers Markdown, configuration and source code files that are read as-is
+var plainTextExtensions = []string{
+	".txt", ".log", ".md", ".markdown",
+	".json", ".xml", ".yaml", ".yml", ".toml", ".ini", ".sql",
+	".go", ".py", ".java", ".cs", ".c", ".h", ".cpp", ".cc", ".hpp",
+	".js", ".jsx", ".mjs", ".ts", ".tsx", ".css", ".scss",
+	".kt", ".scala", ".rs", ".php", ".rb", ".pl", ".sh", ".bash",
+}
+
+var plainTextMimeTypes = []string{
+	"text/plain", "text/markdown", "text/x-markdown",
+	"application/json", "text/json", "text/xml", "application/xml",
+	"application/x-yaml", "text/yaml", "text/x-yaml",
+	"text/x-sql", "application/x-sql",
+	"text/javascript", "application/javascript", "text/typescript", "application/typescript",
+	"text/css", "application/x-sh", "text/x-sh",
+	"text/x-python", "text/x-java", "text/x-csharp", "text/x-c++", "text/x-c",
+	"text/x-kotlin", "text/x-scala", "text/x-go", "text/x-rust", "text/x-php", "text/x-ruby", "text/x-perl",
+}
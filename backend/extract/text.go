@@ -0,0 +1,354 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"nimbus-backend/tables"
+)
+
+// extractPlainText returns text, Markdown and source files as they are
+func extractPlainText(data []byte) (*Document, error) {
+	return &Document{Text: decodeText(data)}, nil
+}
+
+// extractCSV renders comma separated rows as "Header: value" lines
+func extractCSV(data []byte) (*Document, error) {
+	return extractDelimited(data, ',')
+}
+
+// extractTSV renders tab separated rows as "Header: value" lines
+func extractTSV(data []byte) (*Document, error) {
+	return extractDelimited(data, '\t')
+}
+
+func extractDelimited(data []byte, delimiter rune) (*Document, error) {
+	rows, err := ParseDelimited(data, delimiter)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Text: renderTable("", rows)}
+	if table := tables.FromRows("", rows); len(table.Headers) > 0 {
+		doc.Tables = []tables.Table{table}
+	}
+	return doc, nil
+}
+
+// ParseDelimited reads CSV/TSV rows; ragged rows and stray quotes are tolerated
+func ParseDelimited(data []byte, delimiter rune) ([][]string, error) {
+	reader := csv.NewReader(strings.NewReader(decodeText(data)))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse delimited file: %w", err)
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// renderTable writes each data row with its header names so a chunk stays
+// understandable without the header row, e.g. "Name: Ali | City: Ankara"
+func renderTable(name string, rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	if name != "" {
+		sb.WriteString("Sheet: " + name + "\n\n")
+	}
+
+	header := rows[0]
+	if len(rows) == 1 {
+		sb.WriteString(strings.Join(header, " | ") + "\n\n")
+		return sb.String()
+	}
+
+	for _, row := range rows[1:] {
+		var cells []string
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			column := "Column " + strconv.Itoa(i+1)
+			if i < len(header) && strings.TrimSpace(header[i]) != "" {
+				column = strings.TrimSpace(header[i])
+			}
+			cells = append(cells, column+": "+value)
+		}
+		if len(cells) > 0 {
+			sb.WriteString(strings.Join(cells, " | ") + "\n")
+		}
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// htmlSkippedElements never contain readable document text
+var htmlSkippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "head": true,
+}
+
+// htmlBlockElements start on a new line so paragraphs and table rows stay apart
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "header": true, "footer": true, "blockquote": true, "pre": true,
+}
+
+// extractHTML strips markup, scripts and styles from an HTML page
+func extractHTML(data []byte) (*Document, error) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	var sb strings.Builder
+	var title string
+	skipDepth := 0
+	inTitle := false
+
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, fmt.Errorf("failed to parse HTML: %w", err)
+			}
+			text := sb.String()
+			if title != "" && !strings.Contains(text, title) {
+				text = title + "\n\n" + text
+			}
+			return &Document{Text: text}, nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "title" {
+				inTitle = true
+			}
+			if htmlSkippedElements[tag] && tokenType == html.StartTagToken {
+				skipDepth++
+			}
+			if htmlBlockElements[tag] {
+				sb.WriteString("\n")
+			}
+			if tag == "td" || tag == "th" {
+				sb.WriteString(" | ")
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "title" {
+				inTitle = false
+			}
+			if htmlSkippedElements[tag] && skipDepth > 0 {
+				skipDepth--
+			}
+			if htmlBlockElements[tag] {
+				sb.WriteString("\n")
+			}
+
+		case html.TextToken:
+			text := string(tokenizer.Text())
+			if inTitle {
+				title = strings.TrimSpace(text)
+				continue
+			}
+			if skipDepth == 0 {
+				sb.WriteString(text)
+			}
+		}
+	}
+}
+
+// rtfSkippedDestinations are groups holding formatting tables or binary data rather than text
+var rtfSkippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true,
+	"header": true, "footer": true, "listtable": true, "listoverridetable": true,
+	"themedata": true, "colorschememapping": true, "datastore": true, "latentstyles": true,
+	"generator": true, "xmlnstbl": true, "rsidtbl": true, "object": true,
+}
+
+// extractRTF drops control words and formatting groups, keeping paragraphs and Unicode escapes
+func extractRTF(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte(`{\rtf`)) {
+		return nil, fmt.Errorf("not an RTF document")
+	}
+
+	type group struct {
+		skip      bool
+		ucSkip    int // Fallback characters to drop after \uN
+		firstWord bool
+	}
+
+	var sb strings.Builder
+	stack := []group{{ucSkip: 1}}
+	pendingSkip := 0
+
+	for i := 0; i < len(data); i++ {
+		current := &stack[len(stack)-1]
+		ch := data[i]
+
+		switch ch {
+		case '{':
+			stack = append(stack, group{skip: current.skip, ucSkip: current.ucSkip, firstWord: true})
+			continue
+		case '}':
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		case '\r', '\n':
+			continue
+		case '\\':
+		default:
+			current.firstWord = false
+			if pendingSkip > 0 {
+				pendingSkip--
+				continue
+			}
+			if !current.skip {
+				sb.WriteByte(ch)
+			}
+			continue
+		}
+
+		// Control symbol or control word
+		if i+1 >= len(data) {
+			break
+		}
+		next := data[i+1]
+
+		switch {
+		case next == '\\' || next == '{' || next == '}':
+			if !current.skip {
+				sb.WriteByte(next)
+			}
+			i++
+		case next == '*':
+			// {\*\dest ...} marks an optional destination we do not understand
+			current.skip = true
+			i++
+		case next == '\'':
+			if i+3 < len(data) {
+				if code, err := strconv.ParseUint(string(data[i+2:i+4]), 16, 8); err == nil {
+					if pendingSkip > 0 {
+						pendingSkip--
+					} else if !current.skip {
+						sb.WriteRune(cp1252Rune(byte(code)))
+					}
+				}
+			}
+			i += 3
+		case next == '~':
+			if !current.skip {
+				sb.WriteString(" ")
+			}
+			i++
+		case isASCIILetter(next):
+			j := i + 1
+			for j < len(data) && isASCIILetter(data[j]) {
+				j++
+			}
+			word := string(data[i+1 : j])
+
+			k := j
+			if k < len(data) && (data[k] == '-' || isASCIIDigit(data[k])) {
+				k++
+				for k < len(data) && isASCIIDigit(data[k]) {
+					k++
+				}
+			}
+			param, hasParam := 0, k > j
+			if hasParam {
+				param, _ = strconv.Atoi(string(data[j:k]))
+			}
+			// A single space terminating the control word belongs to it
+			if k < len(data) && data[k] == ' ' {
+				k++
+			}
+			i = k - 1
+
+			if current.firstWord && rtfSkippedDestinations[word] {
+				current.skip = true
+			}
+			current.firstWord = false
+			if current.skip {
+				continue
+			}
+
+			switch word {
+			case "par", "line", "sect", "page":
+				sb.WriteString("\n")
+			case "tab", "cell":
+				sb.WriteString("\t")
+			case "row":
+				sb.WriteString("\n")
+			case "uc":
+				current.ucSkip = param
+			case "u":
+				if param < 0 {
+					param += 65536
+				}
+				sb.WriteRune(rune(param))
+				pendingSkip = current.ucSkip
+			}
+		default:
+			i++
+		}
+	}
+
+	return &Document{Text: sb.String()}, nil
+}
+
+// decodeText drops a UTF-8 BOM and falls back to Windows-1252 for legacy 8-bit files
+func decodeText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for _, b := range data {
+		sb.WriteRune(cp1252Rune(b))
+	}
+	return sb.String()
+}
+
+// cp1252High maps the 0x80-0x9F range of Windows-1252, which differs from Latin-1
+var cp1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+func cp1252Rune(b byte) rune {
+	if b >= 0x80 && b <= 0x9F {
+		return cp1252High[b-0x80]
+	}
+	return rune(b)
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"nimbus-backend/config"
@@ -8,348 +12,268 @@ import (
 	"nimbus-backend/models"
 	"nimbus-backend/retrieval"
 	"nimbus-backend/services"
+	"nimbus-backend/tables"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// QueryDocumentRequest - Exactly one of file_id, file_ids, folder_id or scope="all" selects what to ask
 type QueryDocumentRequest struct {
-	FileID   string `json:"file_id" validate:"required"`
-	Question string `json:"question" validate:"required"`
+	FileID   string   `json:"file_id"`
+	FileIDs  []string `json:"file_ids,omitempty"`
+	FolderID string   `json:"folder_id,omitempty"`
+	Scope    string   `json:"scope,omitempty"` // "all" = every document the user can read
+	Question string   `json:"question" validate:"required"`
+	// Thread to continue; empty = the most recently active thread of the scope
+	ConversationID string `json:"conversation_id,omitempty"`
 }
 
 type QueryDocumentResponse struct {
-	Answer     string   `json:"answer"`
-	Sources    []string `json:"sources"`
-	ChunkCount int      `json:"chunk_count"`
+	Answer     string            `json:"answer"`
+	Sources    []string          `json:"sources"`
+	Citations  []models.Citation `json:"citations"` // Numbered to match [n] markers in the answer
+	ChunkCount int               `json:"chunk_count"`
+	FileCount  int               `json:"file_count,omitempty"` // Only for multi-file scopes
+	// Readable files in the scope when it had more than RAG_MAX_SCOPE_FILES; only the most
+	// recently updated FileCount of them were searched
+	TotalFileCount int `json:"total_file_count,omitempty"`
+	// Thread the exchange was saved to; send it back to continue the thread
+	ConversationID string `json:"conversation_id"`
+	// Stored answer, rated via /ai/threads/:id/messages/:messageId/feedback
+	MessageID string `json:"message_id,omitempty"`
+	// Follow-up question rewritten with the conversation, only when it differs from the question
+	StandaloneQuestion string `json:"standalone_question,omitempty"`
 }
 
-// QueryDocument - Query a processed document using RAG
-func QueryDocument(cfg *config.Config) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		userID, err := helpers.GetCurrentUserID(c)
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
+// documentQuery holds a validated query request together with the files it will search
+type documentQuery struct {
+	UserID     string
+	Request    QueryDocumentRequest
+	Scope      models.ConversationScope
+	Files      []models.File
+	TotalFiles int                   // Readable files in the scope; more than len(Files) when cut to RAG_MAX_SCOPE_FILES
+	Thread     *models.Conversation  // Thread the exchange is saved to
+	History    []models.Message      // Previous messages of the thread, oldest first
+	Retrieval  *models.RetrievalInfo // Set by retrieveForScope, stored with the answer
+	// SearchQuestion is the question used for retrieval: the follow-up rewritten into a
+	// standalone question, or the question itself
+	SearchQuestion string
+}
 
-		var req QueryDocumentRequest
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Geçersiz istek verisi",
-			})
-		}
+// standaloneQuestion returns the rewritten question if it differs from the asked one
+func (q *documentQuery) standaloneQuestion() string {
+	if q.SearchQuestion == q.Request.Question {
+		return ""
+	}
+	return q.SearchQuestion
+}
 
-		if req.FileID == "" || req.Question == "" {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "file_id ve question parametreleri gerekli",
-			})
-		}
+// resolveQueryScope turns the selector fields shared by the query and history endpoints into a scope
+func resolveQueryScope(fileID string, fileIDs []string, folderID, scope string) (models.ConversationScope, bool) {
+	if scope == models.ScopeAll {
+		return models.ConversationScope{Type: models.ScopeAll}, true
+	}
+	if folderID != "" {
+		return models.ConversationScope{Type: models.ScopeFolder, ID: folderID}, true
+	}
 
-		// Get file record
-		file, err := services.FileServiceInstance.GetFileByID(req.FileID)
-		if err != nil {
-			return c.Status(404).JSON(fiber.Map{
-				"error": "Dosya bulunamadı",
-			})
+	// Deduplicate the selection so ["a", "a"] behaves like a single file
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range append(fileIDs, fileID) {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
 		}
+	}
 
-		// Check if user has access to the file (using proper access control with hierarchical checks)
-		hasAccess, err := helpers.CanUserAccess(userID, "file", req.FileID, helpers.AccessLevelRead)
-		if err != nil || !hasAccess {
-			return c.Status(403).JSON(fiber.Map{
-				"error": "Bu dosyaya erişim yetkiniz yok",
-			})
-		}
+	switch len(ids) {
+	case 0:
+		return models.ConversationScope{}, false
+	case 1:
+		return models.ConversationScope{Type: models.ScopeFile, ID: ids[0]}, true
+	}
+	return models.ConversationScope{Type: models.ScopeFiles, FileIDs: ids}, true
+}
 
-		// Check if file has been processed
-		if file.ProcessingStatus != "completed" {
-			return c.Status(409).JSON(fiber.Map{
-				"error":  "Dosya henüz işlenmedi. Lütfen işlem tamamlanana kadar bekleyin.",
-				"status": file.ProcessingStatus,
-			})
+// scopeFromQuery reads the conversation scope from query parameters (file_ids is comma separated)
+func scopeFromQuery(c *fiber.Ctx) (models.ConversationScope, bool) {
+	var fileIDs []string
+	if raw := c.Query("file_ids"); raw != "" {
+		fileIDs = strings.Split(raw, ",")
+	}
+	return resolveQueryScope(c.Query("file_id"), fileIDs, c.Query("folder_id"), c.Query("scope"))
+}
+
+// QueryDocument - Query a processed document using RAG
+func QueryDocument(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query, err := prepareDocumentQuery(c, cfg)
+		if err != nil {
+			return queryErrorResponse(c, err)
 		}
+		req := query.Request
 
 		// Initialize services
-		ollamaService := services.NewOllamaService(cfg)
-		chromaService := services.NewChromaService(cfg)
-
-		// Initialize retrieval components
-		intentClassifier := retrieval.NewIntentClassifier()
-		termExtractor := retrieval.NewKeyTermExtractor()
-
-		// Step 1: Analyze query intent
-		intentMetadata := intentClassifier.AnalyzeQuery(req.Question)
-		log.Printf("Query intent: %s (confidence: %.2f) - %s",
-			intentMetadata.Intent, intentMetadata.Confidence, intentMetadata.Explanation)
-
-		// Step 2: Extract key terms from query
-		keyTerms := termExtractor.ExtractNamedTerms(req.Question)
-		log.Printf("Extracted key terms: %v", keyTerms)
-
-		// Step 3: Determine retrieval strategy based on intent
-		var chunks []services.ChunkResult
-		var retrievalErr error
-
-		// Use hybrid search for comparison queries (keyword + semantic) to ensure we find comparison tables
-		if intentMetadata.Intent == retrieval.IntentComparison && len(keyTerms) >= 2 {
-			log.Printf("Using hybrid search for comparison query with %d terms", len(keyTerms))
-			chunks, retrievalErr = performHybridRetrieval(
-				ollamaService, chromaService,
-				req.Question, keyTerms, req.FileID, intentMetadata.RecommendedTopK)
-		} else if intentMetadata.Intent == retrieval.IntentDefinition && len(keyTerms) > 0 {
-			// For definition queries, use hybrid search (keyword + semantic)
-			log.Printf("Using hybrid search for definition query")
-			chunks, retrievalErr = performHybridRetrieval(
-				ollamaService, chromaService,
-				req.Question, keyTerms, req.FileID, intentMetadata.RecommendedTopK)
-		} else if intentMetadata.Intent == retrieval.IntentSummary {
-			// For summary queries, retrieve more chunks for comprehensive overview
-			topK := intentMetadata.RecommendedTopK
-			if topK < 10 {
-				topK = 10 // Minimum 10 chunks for summary
-			}
-			log.Printf("Using standard semantic search for summary query with top-k=%d", topK)
-			questionEmbedding, embErr := ollamaService.GenerateEmbedding(req.Question)
-			if embErr != nil {
-				log.Printf("Failed to generate embedding for question: %v", embErr)
-				return c.Status(500).JSON(fiber.Map{
-					"error": "Soru işlenirken hata oluştu",
-				})
-			}
-			chunks, retrievalErr = chromaService.QuerySimilar(questionEmbedding, req.FileID, topK)
-		} else {
-			// Standard semantic search with dynamic top-k based on intent
-			log.Printf("Using standard semantic search with top-k=%d", intentMetadata.RecommendedTopK)
-			questionEmbedding, embErr := ollamaService.GenerateEmbedding(req.Question)
-			if embErr != nil {
-				log.Printf("Failed to generate embedding for question: %v", embErr)
-				return c.Status(500).JSON(fiber.Map{
-					"error": "Soru işlenirken hata oluştu",
-				})
-			}
+		llmService := services.NewLLMService(cfg)
+		vectorService := services.NewVectorService(cfg)
 
-			chunks, retrievalErr = chromaService.QuerySimilar(questionEmbedding, req.FileID, intentMetadata.RecommendedTopK)
+		chunks, _, err := retrieveForScope(c.UserContext(), cfg, llmService, vectorService, query)
+		if err != nil {
+			return queryErrorResponse(c, err)
 		}
 
-		if retrievalErr != nil {
-			log.Printf("Failed to retrieve chunks: %v", retrievalErr)
-			return c.Status(500).JSON(fiber.Map{
-				"error": "İçerik arama işlemi başarısız oldu",
-			})
-		}
+		// Step 4: Extract chunk texts for context
+		contextChunks, sources := buildSourcePreviews(chunks)
 
-		if len(chunks) == 0 {
-			return c.Status(404).JSON(fiber.Map{
-				"error": "Dosyada ilgili içerik bulunamadı",
-			})
-		}
+		log.Printf("Found %d relevant chunks for question: %s", len(chunks), req.Question)
 
-		// Debug: Log which chunks were retrieved
-		chunkIDs := make([]string, len(chunks))
-		for i, chunk := range chunks {
-			chunkIDs[i] = chunk.ID
+		// Step 5: Generate answer using LLM with context
+		answer, err := llmService.GenerateRAGResponse(req.Question, contextChunks, query.History)
+		if err != nil {
+			log.Printf("Failed to generate answer: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Cevap oluşturulurken hata oluştu",
+			})
 		}
-		log.Printf("Retrieved %d relevant chunks for query: %v", len(chunks), chunkIDs)
 
-		// If this is a comparison query, prioritize chunks with comparison type
-		if intentMetadata.Intent == "comparison" {
-			// Separate chunks by type
-			var comparisonChunks []services.ChunkResult
-			var otherChunks []services.ChunkResult
-			for _, chunk := range chunks {
-				if chunkType, ok := chunk.Metadata["chunk_type"].(string); ok && chunkType == "comparison" {
-					comparisonChunks = append(comparisonChunks, chunk)
-				} else {
-					otherChunks = append(otherChunks, chunk)
-				}
-			}
-			// Reorder: comparison chunks first, then others
-			if len(comparisonChunks) > 0 {
-				chunks = append(comparisonChunks, otherChunks...)
-				log.Printf("Reordered chunks: %d comparison chunks prioritized", len(comparisonChunks))
-			}
+		// Clean up answer
+		answer = strings.TrimSpace(answer)
+		citations := services.BuildCitations(chunks, answer)
 
-			// SECOND-LEVEL REORDERING: Find perfect match (comparison table with ALL query terms)
-			// and move it to absolute position 0
-			var perfectMatchIdx = -1
-			for i, chunk := range chunks {
-				textLower := strings.ToLower(chunk.Text)
-				// Check if this chunk is a comparison table
-				if strings.Contains(textLower, "comparison table:") || strings.Contains(textLower, "comparison of") {
-					// Check if it contains ALL key terms
-					allTermsFound := true
-					for _, term := range keyTerms {
-						if !strings.Contains(textLower, strings.ToLower(term)) {
-							allTermsFound = false
-							break
-						}
-					}
-					if allTermsFound {
-						perfectMatchIdx = i
-						break
-					}
-				}
-			}
+		messageID := saveQueryExchange(query, answer, sources, citations)
 
-			// Move perfect match to position 0
-			if perfectMatchIdx > 0 {
-				perfectMatch := chunks[perfectMatchIdx]
-				// Remove from current position
-				chunks = append(chunks[:perfectMatchIdx], chunks[perfectMatchIdx+1:]...)
-				// Insert at position 0
-				chunks = append([]services.ChunkResult{perfectMatch}, chunks...)
-				log.Printf("🎯 Perfect match comparison table moved to position 1 (was at position %d)", perfectMatchIdx+1)
-			}
+		// Return response
+		return c.JSON(QueryDocumentResponse{
+			Answer:         answer,
+			Sources:        sources,
+			Citations:      citations,
+			ChunkCount:     len(chunks),
+			FileCount:      scopedFileCount(query),
+			TotalFileCount: truncatedFileCount(query),
+
+			ConversationID:     query.Thread.ID.Hex(),
+			MessageID:          messageID,
+			StandaloneQuestion: query.standaloneQuestion(),
+		})
+	}
+}
 
-			// SMART OPTIMIZATION: Only reduce to single chunk if:
-			// 1. Perfect match found
-			// 2. Perfect match contains "COMPARISON TABLE" marker
-			// 3. Query is a specific table query (not a general multi-chunk question)
-			if perfectMatchIdx >= 0 {
-				perfectMatch := chunks[0] // It's now at position 0
-				textLower := strings.ToLower(perfectMatch.Text)
-				hasComparisonTableMarker := strings.Contains(textLower, "comparison table:") ||
-					strings.Contains(textLower, "comparison of")
-
-				if hasComparisonTableMarker && isSpecificTableQuery(req.Question, keyTerms) {
-					chunks = []services.ChunkResult{perfectMatch}
-					log.Printf("🔥 Reduced to ONLY perfect match chunk (specific table query)")
-				} else {
-					log.Printf("✅ Keeping all chunks (multi-chunk question or general comparison)")
-				}
-			}
+// QueryDocumentStream - Same as QueryDocument but streams the answer as Server-Sent Events.
+// Event order: "metadata" (retrieval info), "token" (answer deltas), "sources", "done".
+// Failures after the stream has started are reported with an "error" event.
+func QueryDocumentStream(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query, err := prepareDocumentQuery(c, cfg)
+		if err != nil {
+			return queryErrorResponse(c, err)
 		}
+		req := query.Request
 
-		// If this is a definition query, prioritize chunks that contain the term prominently
-		if intentMetadata.Intent == "definition" && len(keyTerms) > 0 {
-			primaryTerm := strings.ToLower(keyTerms[0]) // First key term is usually the term being defined
-
-			// Find the best matching chunk (one that contains the term)
-			var bestMatchIdx = -1
-			var bestScore = 0
-
-			for i, chunk := range chunks {
-				textLower := strings.ToLower(chunk.Text)
-				score := 0
-
-				// Check if term appears in chunk
-				if !strings.Contains(textLower, primaryTerm) {
-					continue
-				}
-
-				// Score based on position and prominence
-				// Higher score = better match
-				if strings.HasPrefix(textLower, primaryTerm) {
-					score += 100 // Term at start of chunk
-				}
+		llmService := services.NewLLMService(cfg)
+		vectorService := services.NewVectorService(cfg)
 
-				// Check first 200 chars for prominence
-				first200 := textLower
-				if len(first200) > 200 {
-					first200 = first200[:200]
-				}
-				if strings.Contains(first200, primaryTerm) {
-					score += 50 // Term in first 200 chars
-				}
+		// Retrieval runs before the stream opens so its errors keep proper status codes
+		chunks, intentMetadata, err := retrieveForScope(c.UserContext(), cfg, llmService, vectorService, query)
+		if err != nil {
+			return queryErrorResponse(c, err)
+		}
 
-				// Check if term appears as standalone word (not part of another word)
-				if strings.Contains(first200, primaryTerm+" ") ||
-					strings.Contains(first200, primaryTerm+"\n") ||
-					strings.Contains(first200, " "+primaryTerm+" ") {
-					score += 30 // Term as standalone word
-				}
+		contextChunks, sources := buildSourcePreviews(chunks)
 
-				// Prefer longer chunks (more complete definitions)
-				if len(chunk.Text) > 200 {
-					score += 10
-				}
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
 
-				// Count occurrences (more mentions = more relevant)
-				occurrences := strings.Count(textLower, primaryTerm)
-				score += occurrences * 5
+		// fiber.Ctx is recycled once the handler returns, so the writer must only use captured values
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
 
-				if score > bestScore {
-					bestScore = score
-					bestMatchIdx = i
+			send := func(event string, data interface{}) error {
+				if err := writeSSEEvent(w, event, data); err != nil {
+					// Client disconnected - stop the upstream generation
+					cancel()
+					return err
 				}
+				return nil
 			}
 
-			// Move best match to position 0
-			if bestMatchIdx > 0 {
-				bestMatch := chunks[bestMatchIdx]
-				// Remove from current position
-				chunks = append(chunks[:bestMatchIdx], chunks[bestMatchIdx+1:]...)
-				// Insert at position 0
-				chunks = append([]services.ChunkResult{bestMatch}, chunks...)
-				log.Printf("📖 Definition term '%s' chunk moved to position 1 (was at position %d, score: %d)", primaryTerm, bestMatchIdx+1, bestScore)
-			} else if bestMatchIdx == 0 {
-				log.Printf("📖 Definition term '%s' already at position 1 (score: %d)", primaryTerm, bestScore)
+			if err := send("metadata", fiber.Map{
+				"scope":            query.Scope,
+				"file_count":       len(query.Files),
+				"total_file_count": query.TotalFiles,
+				"scope_truncated":  query.TotalFiles > len(query.Files), // Only the latest file_count files were searched
+				"intent":           intentMetadata.Intent,
+				"confidence":       intentMetadata.Confidence,
+				"chunk_count":      len(chunks),
+
+				"conversation_id":     query.Thread.ID.Hex(),
+				"standalone_question": query.standaloneQuestion(),
+			}); err != nil {
+				return
 			}
-		}
 
-		// Step 4: Extract chunk texts for context
-		var contextChunks []string
-		var sources []string
-		for _, chunk := range chunks {
-			contextChunks = append(contextChunks, chunk.Text)
-			// Keep sources short for response
-			chunkPreview := chunk.Text
-			if len(chunkPreview) > 200 {
-				chunkPreview = chunkPreview[:200] + "..."
+			answer, err := llmService.GenerateRAGResponseStream(ctx, req.Question, contextChunks, query.History, func(delta string) error {
+				return send("token", fiber.Map{"delta": delta})
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Printf("Stream cancelled by client for scope %s", query.Scope.Key())
+					return
+				}
+				log.Printf("Failed to generate streaming answer: %v", err)
+				send("error", fiber.Map{"error": "Cevap oluşturulurken hata oluştu"})
+				return
 			}
-			sources = append(sources, chunkPreview)
-		}
 
-		log.Printf("Found %d relevant chunks for question: %s", len(chunks), req.Question)
+			answer = strings.TrimSpace(answer)
+			citations := services.BuildCitations(chunks, answer)
+			messageID := saveQueryExchange(query, answer, sources, citations)
 
-		// Step 4: Generate answer using LLM with context
-		answer, err := ollamaService.GenerateRAGResponse(req.Question, contextChunks)
-		if err != nil {
-			log.Printf("Failed to generate answer: %v", err)
-			return c.Status(500).JSON(fiber.Map{
-				"error": "Cevap oluşturulurken hata oluştu",
+			if err := send("sources", fiber.Map{"sources": sources, "citations": citations}); err != nil {
+				return
+			}
+			send("done", QueryDocumentResponse{
+				Answer:         answer,
+				Sources:        sources,
+				Citations:      citations,
+				ChunkCount:     len(chunks),
+				FileCount:      scopedFileCount(query),
+				TotalFileCount: truncatedFileCount(query),
+
+				ConversationID:     query.Thread.ID.Hex(),
+				MessageID:          messageID,
+				StandaloneQuestion: query.standaloneQuestion(),
 			})
-		}
-
-		// Clean up answer
-		answer = strings.TrimSpace(answer)
-
-		// Save user question to conversation history
-		userMessage := models.Message{
-			Role:      "user",
-			Content:   req.Question,
-			Timestamp: time.Now(),
-		}
-		if err := services.ConversationServiceInstance.AddMessage(userID, req.FileID, userMessage); err != nil {
-			log.Printf("Warning: Failed to save user message: %v", err)
-			// Don't fail the request, just log the error
-		}
+		})
 
-		// Save assistant answer to conversation history
-		assistantMessage := models.Message{
-			Role:      "assistant",
-			Content:   answer,
-			Sources:   sources,
-			Timestamp: time.Now(),
-		}
-		if err := services.ConversationServiceInstance.AddMessage(userID, req.FileID, assistantMessage); err != nil {
-			log.Printf("Warning: Failed to save assistant message: %v", err)
-			// Don't fail the request, just log the error
-		}
+		return nil
+	}
+}
 
-		// Return response
-		return c.JSON(QueryDocumentResponse{
-			Answer:     answer,
-			Sources:    sources,
-			ChunkCount: len(chunks),
-		})
+// writeSSEEvent writes a single Server-Sent Event and flushes it to the client
+func writeSSEEvent(w *bufio.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
 	}
+	return w.Flush()
 }
 
-// GetConversationHistory retrieves chat history for a specific file
+// GetConversationHistory retrieves the latest messages of a thread for a file, folder,
+// multi-file selection or "all". conversation_id selects the thread, otherwise the most
+// recently active one is returned; older messages are paged via GetThreadMessages.
 func GetConversationHistory(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID, err := helpers.GetCurrentUserID(c)
@@ -359,8 +283,8 @@ func GetConversationHistory(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		fileID := c.Query("file_id")
-		if fileID == "" {
+		scope, ok := scopeFromQuery(c)
+		if !ok {
 			return c.Status(400).JSON(fiber.Map{
 				"error": "file_id gerekli",
 			})
@@ -368,33 +292,65 @@ func GetConversationHistory(cfg *config.Config) fiber.Handler {
 
 		// Verify access (using proper access control with hierarchical checks)
 		// This also checks if file exists
-		hasAccess, err := helpers.CanUserAccess(userID, "file", fileID, helpers.AccessLevelRead)
+		hasAccess, err := services.QueryScopeServiceInstance.Authorize(userID, scope)
 		if err != nil || !hasAccess {
 			return c.Status(403).JSON(fiber.Map{
 				"error": "Bu dosyaya erişim yetkiniz yok",
 			})
 		}
 
-		// Get conversation
-		conversation, err := services.ConversationServiceInstance.GetConversation(userID, fileID)
+		fileID := ""
+		responseScope := &scope
+		if scope.Type == models.ScopeFile {
+			fileID = scope.ID
+			responseScope = nil
+		}
+
+		conversation, err := services.ConversationServiceInstance.ResolveThread(userID, scope, c.Query("conversation_id"))
+		if errors.Is(err, services.ErrThreadNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Sohbet bulunamadı",
+			})
+		}
 		if err != nil {
+			log.Printf("Failed to get conversation: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Sohbet geçmişi alınamadı",
+			})
+		}
+		if conversation == nil {
 			// No conversation yet, return empty
 			return c.JSON(models.ConversationResponse{
 				ID:        "",
 				FileID:    fileID,
+				Scope:     responseScope,
 				Messages:  []models.Message{},
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			})
 		}
 
+		limit := parseMessageLimit(c)
+		messages, total, err := services.ConversationServiceInstance.GetMessages(conversation.ID, 1, limit)
+		if err != nil {
+			log.Printf("Failed to get conversation messages: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Sohbet geçmişi alınamadı",
+			})
+		}
+
 		// Return conversation
 		return c.JSON(models.ConversationResponse{
-			ID:        conversation.ID.Hex(),
-			FileID:    conversation.FileID,
-			Messages:  conversation.Messages,
-			CreatedAt: conversation.CreatedAt,
-			UpdatedAt: conversation.UpdatedAt,
+			ID:           conversation.ID.Hex(),
+			FileID:       conversation.FileID,
+			Scope:        conversation.Scope,
+			Title:        conversation.Title,
+			Pinned:       conversation.Pinned,
+			MessageCount: int(total),
+			Messages:     messages,
+			HasMore:      total > int64(len(messages)),
+			CreatedAt:    conversation.CreatedAt,
+			UpdatedAt:    conversation.UpdatedAt,
 		})
 	}
 }
@@ -424,7 +380,8 @@ func GetUserConversations(cfg *config.Config) fiber.Handler {
 	}
 }
 
-// ClearConversationHistory clears all messages from a conversation
+// ClearConversationHistory clears all messages from a thread (conversation_id, or the most
+// recently active thread of the scope)
 func ClearConversationHistory(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID, err := helpers.GetCurrentUserID(c)
@@ -434,23 +391,33 @@ func ClearConversationHistory(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		fileID := c.Query("file_id")
-		if fileID == "" {
+		scope, ok := scopeFromQuery(c)
+		if !ok {
 			return c.Status(400).JSON(fiber.Map{
 				"error": "file_id gerekli",
 			})
 		}
 
 		// Check if user has access to the file (using proper access control)
-		hasAccess, err := helpers.CanUserAccess(userID, "file", fileID, helpers.AccessLevelRead)
+		hasAccess, err := services.QueryScopeServiceInstance.Authorize(userID, scope)
 		if err != nil || !hasAccess {
 			return c.Status(403).JSON(fiber.Map{
 				"error": "Bu dosyaya erişim yetkiniz yok",
 			})
 		}
 
+		conversation, err := services.ConversationServiceInstance.ResolveThread(userID, scope, c.Query("conversation_id"))
+		if errors.Is(err, services.ErrThreadNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Sohbet bulunamadı",
+			})
+		}
+
 		// Clear conversation
-		if err := services.ConversationServiceInstance.ClearConversation(userID, fileID); err != nil {
+		if err == nil && conversation != nil {
+			err = services.ConversationServiceInstance.ClearThread(conversation)
+		}
+		if err != nil {
 			log.Printf("Failed to clear conversation: %v", err)
 			return c.Status(500).JSON(fiber.Map{
 				"error": "Sohbet geçmişi temizlenemedi",
@@ -463,80 +430,451 @@ func ClearConversationHistory(cfg *config.Config) fiber.Handler {
 	}
 }
 
-// performHybridRetrieval combines semantic and keyword search
-func performHybridRetrieval(
-	ollamaService *services.OllamaService,
-	chromaService *services.ChromaService,
-	query string,
-	keywords []string,
-	fileID string,
-	topK int,
-) ([]services.ChunkResult, error) {
-	// Generate query embedding
-	queryEmbedding, err := ollamaService.GenerateEmbedding(query)
+// prepareDocumentQuery parses the request body, resolves its scope and verifies that the
+// caller can query it. Single-file requests keep their original status codes.
+func prepareDocumentQuery(c *fiber.Ctx, cfg *config.Config) (*documentQuery, error) {
+	userID, err := helpers.GetCurrentUserID(c)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+		return nil, fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+
+	var req QueryDocumentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(400, "Geçersiz istek verisi")
 	}
 
-	// Perform hybrid search (semantic + keyword)
-	chunks, err := chromaService.HybridSearch(queryEmbedding, keywords, fileID, topK)
+	scope, ok := resolveQueryScope(req.FileID, req.FileIDs, req.FolderID, req.Scope)
+	if !ok || req.Question == "" {
+		return nil, fiber.NewError(400, "file_id (veya file_ids, folder_id, scope=all) ve question parametreleri gerekli")
+	}
+
+	query := &documentQuery{UserID: userID, Request: req, Scope: scope}
+
+	if scope.Type == models.ScopeFile {
+		// Get file record
+		file, err := services.FileServiceInstance.GetFileByID(scope.ID)
+		if err != nil {
+			return nil, fiber.NewError(404, "Dosya bulunamadı")
+		}
+
+		// Check if user has access to the file (using proper access control with hierarchical checks)
+		hasAccess, err := helpers.CanUserAccess(userID, "file", scope.ID, helpers.AccessLevelRead)
+		if err != nil || !hasAccess {
+			return nil, fiber.NewError(403, "Bu dosyaya erişim yetkiniz yok")
+		}
+
+		// Check if file has been processed
+		if !models.IsQueryableStatus(file.ProcessingStatus) {
+			return nil, &queryNotReadyError{status: file.ProcessingStatus}
+		}
+
+		query.Files = []models.File{*file}
+		query.TotalFiles = 1
+		return query, resolveQueryThread(query)
+	}
+
+	hasAccess, err := services.QueryScopeServiceInstance.Authorize(userID, scope)
+	if err != nil || !hasAccess {
+		return nil, fiber.NewError(403, "Bu kapsama erişim yetkiniz yok")
+	}
+
+	files, total, err := services.QueryScopeServiceInstance.ResolveFiles(userID, scope, cfg.MaxScopeFiles)
 	if err != nil {
-		return nil, fmt.Errorf("hybrid search failed: %w", err)
+		log.Printf("Failed to resolve query scope: %v", err)
+		return nil, fiber.NewError(400, "Sorgu kapsamı çözümlenemedi")
+	}
+	if len(files) == 0 {
+		return nil, fiber.NewError(404, "Bu kapsamda işlenmiş dosya bulunamadı")
+	}
+
+	if total > len(files) {
+		log.Printf("Query scope %s has %d readable files, searching the latest %d", scope.Key(), total, len(files))
 	}
 
-	log.Printf("Hybrid retrieval returned %d chunks", len(chunks))
-	return chunks, nil
+	query.Files = files
+	query.TotalFiles = total
+	return query, resolveQueryThread(query)
 }
 
-// isSpecificTableQuery checks if the query is asking for a specific comparison table
-// Returns true if the query is clearly asking for a specific comparison table (e.g., "comparison of X vs Y")
-// Returns false for general questions that might need multiple chunks (e.g., "tell me about X and Y")
-func isSpecificTableQuery(question string, keyTerms []string) bool {
-	qLower := strings.ToLower(question)
+// resolveQueryThread picks the thread a query continues and opens one if the scope has none
+func resolveQueryThread(query *documentQuery) error {
+	thread, err := services.ConversationServiceInstance.ResolveThread(query.UserID, query.Scope, query.Request.ConversationID)
+	if errors.Is(err, services.ErrThreadNotFound) {
+		return fiber.NewError(404, "Sohbet bulunamadı")
+	}
+	if err != nil {
+		log.Printf("Failed to resolve conversation thread: %v", err)
+		return fiber.NewError(500, "Sohbet alınamadı")
+	}
 
-	// Check for specific comparison table patterns
-	hasComparisonOf := strings.Contains(qLower, "comparison of")
-	hasCompareVs := strings.Contains(qLower, "compare") && (strings.Contains(qLower, " vs ") || strings.Contains(qLower, " vs. ") || strings.Contains(qLower, " versus "))
-	hasVsComparison := (strings.Contains(qLower, " vs ") && strings.Contains(qLower, "comparison")) ||
-		(strings.Contains(qLower, " vs. ") && strings.Contains(qLower, "comparison")) ||
-		(strings.Contains(qLower, " versus ") && strings.Contains(qLower, "comparison"))
+	if thread == nil {
+		thread, err = services.ConversationServiceInstance.CreateThread(query.UserID, query.Scope, "")
+		if err != nil {
+			log.Printf("Failed to create conversation thread: %v", err)
+			return fiber.NewError(500, "Sohbet oluşturulamadı")
+		}
+	}
+
+	query.Thread = thread
+	return nil
+}
+
+// scopedFileCount reports how many files were searched, omitted for single-file queries
+func scopedFileCount(query *documentQuery) int {
+	if query.Scope.Type == models.ScopeFile {
+		return 0
+	}
+	return len(query.Files)
+}
+
+// truncatedFileCount reports the scope's readable files when only part of them were searched
+func truncatedFileCount(query *documentQuery) int {
+	if query.TotalFiles > len(query.Files) {
+		return query.TotalFiles
+	}
+	return 0
+}
+
+// queryNotReadyError is returned when the file exists but has not finished processing
+type queryNotReadyError struct {
+	status string
+}
+
+func (e *queryNotReadyError) Error() string {
+	return "Dosya henüz işlenmedi. Lütfen işlem tamamlanana kadar bekleyin."
+}
+
+// queryErrorResponse maps errors from prepareDocumentQuery / retrieveRelevantChunks to a JSON response
+func queryErrorResponse(c *fiber.Ctx, err error) error {
+	if notReady, ok := err.(*queryNotReadyError); ok {
+		return c.Status(409).JSON(fiber.Map{
+			"error":  notReady.Error(),
+			"status": notReady.status,
+		})
+	}
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// buildSourcePreviews extracts chunk texts for the prompt and short previews for the response
+func buildSourcePreviews(chunks []services.ChunkResult) ([]string, []string) {
+	var contextChunks []string
+	var sources []string
+	for _, chunk := range chunks {
+		// Multi-file retrieval labels each chunk with its filename, PDFs add page numbers
+		label := sourceLabel(chunk)
+
+		if label != "" {
+			contextChunks = append(contextChunks, fmt.Sprintf("Source: %s\n%s", label, chunk.Text))
+		} else {
+			contextChunks = append(contextChunks, chunk.Text)
+		}
 
-	// Check if query has comparison indicators with key terms
-	hasComparisonIndicators := strings.Contains(qLower, "comparison") ||
-		strings.Contains(qLower, " vs ") ||
-		strings.Contains(qLower, " vs. ") ||
-		strings.Contains(qLower, " versus ")
+		// Keep sources short for response
+		chunkPreview := chunk.Text
+		if len(chunkPreview) > 200 {
+			chunkPreview = chunkPreview[:200] + "..."
+		}
+		if label != "" {
+			chunkPreview = fmt.Sprintf("[%s] %s", label, chunkPreview)
+		}
+		sources = append(sources, chunkPreview)
+	}
+	return contextChunks, sources
+}
 
-	// If it's a specific comparison pattern with key terms, it's a table query
-	if (hasComparisonOf || hasCompareVs || hasVsComparison) && hasComparisonIndicators && len(keyTerms) >= 2 {
-		return true
+// sourceLabel describes where a chunk came from, e.g. "report.pdf, page 12", "pages 3-4",
+// "sales.xlsx, sheet Q3, rows 1-40" or "search.go, function HybridSearch, lines 40-72"
+func sourceLabel(chunk services.ChunkResult) string {
+	var parts []string
+	if filename, _ := chunk.Metadata["filename"].(string); filename != "" {
+		parts = append(parts, filename)
 	}
 
-	// Exclude general questions that need multiple chunks
-	// These patterns indicate the user wants information from multiple sources/chunks
-	// Examples: "tell me about X and Y", "what is the difference between X and Y", "explain X and Y"
-	// If X is in chunk A (page 10) and Y is in chunk B (page 100), we need BOTH chunks
-	excludePatterns := []string{
-		"tell me about",
-		"what is the difference between",
-		"what are the differences between",
-		"explain",
-		"describe",
-		"what are the",
-		"how are",
-		"what about",
+	pageStart, pageEnd := chunk.PageRange()
+	if pageStart > 0 {
+		if pageEnd > pageStart {
+			parts = append(parts, fmt.Sprintf("pages %d-%d", pageStart, pageEnd))
+		} else {
+			parts = append(parts, fmt.Sprintf("page %d", pageStart))
+		}
 	}
 
-	for _, pattern := range excludePatterns {
-		if strings.Contains(qLower, pattern) && strings.Contains(qLower, " and ") {
-			return false // General question, needs multiple chunks (X in one chunk, Y in another)
+	// Spreadsheet chunks: the computed expression or the row range of the sheet
+	if expression, _ := chunk.Metadata["expression"].(string); expression != "" {
+		parts = append(parts, "computed "+expression)
+	} else if rowStart, rowEnd := chunk.RowRange(); rowStart > 0 {
+		if sheet, _ := chunk.Metadata["sheet"].(string); sheet != "" {
+			parts = append(parts, "sheet "+sheet)
 		}
+		parts = append(parts, fmt.Sprintf("rows %d-%d", rowStart, rowEnd))
 	}
 
-	// If query mentions page numbers or ranges, it's likely a multi-chunk question
-	if strings.Contains(qLower, "page") || strings.Contains(qLower, "section") || strings.Contains(qLower, "chapter") {
-		return false
+	// Source code chunks carry the declaration they belong to
+	if symbol, _ := chunk.Metadata["symbol_name"].(string); symbol != "" {
+		parts = append(parts, fmt.Sprintf("%v %s", chunk.Metadata["symbol_kind"], symbol))
+	}
+	if lineStart, lineEnd := chunk.LineRange(); lineStart > 0 {
+		parts = append(parts, fmt.Sprintf("lines %d-%d", lineStart, lineEnd))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// saveQueryExchange stores the question and the assistant answer in the thread and returns
+// the ID of the answer ("" if it could not be saved), which feedback refers to
+func saveQueryExchange(query *documentQuery, answer string, sources []string, citations []models.Citation) string {
+	// Save user question to conversation history
+	userMessage := models.Message{
+		Role:      "user",
+		Content:   query.Request.Question,
+		Timestamp: time.Now(),
+	}
+	if err := services.ConversationServiceInstance.AddMessage(query.Thread, &userMessage); err != nil {
+		log.Printf("Warning: Failed to save user message: %v", err)
+		// Don't fail the request, just log the error
+	}
+
+	// Save assistant answer to conversation history
+	assistantMessage := models.Message{
+		Role:      "assistant",
+		Content:   answer,
+		Sources:   sources,
+		Citations: citations,
+		Timestamp: time.Now(),
+		Retrieval: query.Retrieval,
+	}
+	if err := services.ConversationServiceInstance.AddMessage(query.Thread, &assistantMessage); err != nil {
+		log.Printf("Warning: Failed to save assistant message: %v", err)
+		// Don't fail the request, just log the error
+		return ""
+	}
+	return assistantMessage.ID.Hex()
+}
+
+// retrieveRelevantChunks runs services.RetrieveFileChunks for a question against a single
+// processed file. Errors are returned as *fiber.Error so callers can map them to the right
+// status code.
+func retrieveRelevantChunks(
+	ctx context.Context,
+	llmService *services.LLMService,
+	vectorService *services.VectorService,
+	question string,
+	fileID string,
+) ([]services.ChunkResult, retrieval.IntentMetadata, string, error) {
+	result, err := services.RetrieveFileChunks(ctx, llmService, vectorService, question, fileID)
+	switch {
+	case errors.Is(err, services.ErrQuestionEmbedding):
+		return nil, result.Intent, "", fiber.NewError(500, "Soru işlenirken hata oluştu")
+	case errors.Is(err, services.ErrNoRelevantChunks):
+		return nil, result.Intent, "", fiber.NewError(404, "Dosyada ilgili içerik bulunamadı")
+	case err != nil:
+		return nil, result.Intent, "", fiber.NewError(500, "İçerik arama işlemi başarısız oldu")
+	}
+
+	return result.Chunks, result.Intent, result.Path, nil
+}
+
+// retrieveForScope picks single-file or fan-out retrieval depending on the query scope and,
+// for aggregate questions over spreadsheets, prepends the exactly computed result. Follow-up
+// questions are searched in their standalone form (see loadQueryHistory). How the chunks
+// were found is recorded in query.Retrieval for answer feedback analytics.
+func retrieveForScope(
+	ctx context.Context,
+	cfg *config.Config,
+	llmService *services.LLMService,
+	vectorService *services.VectorService,
+	query *documentQuery,
+) ([]services.ChunkResult, retrieval.IntentMetadata, error) {
+	loadQueryHistory(ctx, cfg, llmService, query)
+
+	var chunks []services.ChunkResult
+	var intentMetadata retrieval.IntentMetadata
+	var path string
+	var err error
+	if query.Scope.Type == models.ScopeFile {
+		chunks, intentMetadata, path, err = retrieveRelevantChunks(ctx, llmService, vectorService, query.SearchQuestion, query.Scope.ID)
+	} else {
+		chunks, intentMetadata, path, err = retrieveAcrossFiles(ctx, cfg, llmService, vectorService, query.SearchQuestion, query.Files)
+	}
+	if err != nil {
+		return nil, intentMetadata, err
+	}
+
+	query.Retrieval = &models.RetrievalInfo{
+		Intent:    string(intentMetadata.Intent),
+		Path:      path,
+		Reranker:  services.RerankerName(),
+		FileTypes: sourceFileTypes(chunks, query.Files),
+		Rewritten: query.standaloneQuestion() != "",
+	}
+
+	// Aggregate questions over spreadsheets get an exact computed result as the first source
+	if tables.LooksAggregate(query.SearchQuestion) {
+		structured, err := services.TableQueryServiceInstance.Answer(llmService, query.SearchQuestion, query.Files)
+		if err != nil {
+			log.Printf("Structured table query failed, falling back to text retrieval: %v", err)
+		} else if structured != nil {
+			chunks = append([]services.ChunkResult{*structured}, chunks...)
+			query.Retrieval.Structured = true
+		}
+	}
+
+	return chunks, intentMetadata, nil
+}
+
+// sourceFileTypes lists the extensions of the files the chunks came from, e.g. ["pdf", "xlsx"]
+func sourceFileTypes(chunks []services.ChunkResult, files []models.File) []string {
+	typeByID := make(map[string]string, len(files))
+	for _, file := range files {
+		fileType := strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+		if fileType == "" {
+			fileType = "unknown"
+		}
+		typeByID[file.ID.Hex()] = fileType
+	}
+
+	seen := make(map[string]bool)
+	var types []string
+	for _, chunk := range chunks {
+		fileID, _ := chunk.Metadata["file_id"].(string)
+		if fileType, ok := typeByID[fileID]; ok && !seen[fileType] {
+			seen[fileType] = true
+			types = append(types, fileType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// condenseTimeout bounds the follow-up rewrite; retrieval falls back to the question itself
+const condenseTimeout = 30 * time.Second
+
+// loadQueryHistory loads the last messages of the query's thread and,
+// when query rewriting is enabled, turns a follow-up question into a standalone one so that
+// "what about the second one?" is embedded with the subject it refers to
+func loadQueryHistory(ctx context.Context, cfg *config.Config, llmService *services.LLMService, query *documentQuery) {
+	query.SearchQuestion = query.Request.Question
+
+	history, err := services.ConversationServiceInstance.GetRecentMessages(query.Thread.ID, cfg.HistoryMessages)
+	if err != nil {
+		log.Printf("Warning: failed to load conversation history: %v", err)
+		return
+	}
+	query.History = history
+	if !cfg.EnableQueryRewrite || len(history) == 0 {
+		return
+	}
+
+	condenseCtx, cancel := context.WithTimeout(ctx, condenseTimeout)
+	defer cancel()
+	standalone, err := llmService.CondenseQuestion(condenseCtx, history, query.Request.Question)
+	if err != nil {
+		log.Printf("Warning: follow-up rewrite failed, searching with the original question: %v", err)
+		return
+	}
+	if standalone != query.Request.Question {
+		log.Printf("Follow-up rewritten: %q -> %q", query.Request.Question, standalone)
+	}
+	query.SearchQuestion = standalone
+}
+
+// retrieveAcrossFiles searches every file in parallel with a single query embedding and
+// merges the per-file rankings with Reciprocal Rank Fusion. Each chunk is labelled with its filename.
+func retrieveAcrossFiles(
+	ctx context.Context,
+	cfg *config.Config,
+	llmService *services.LLMService,
+	vectorService *services.VectorService,
+	question string,
+	files []models.File,
+) ([]services.ChunkResult, retrieval.IntentMetadata, string, error) {
+	intentMetadata := retrieval.NewIntentClassifier().AnalyzeQuery(question)
+	keyTerms := retrieval.NewKeyTermExtractor().ExtractNamedTerms(question)
+	log.Printf("Multi-file query over %d files, intent: %s", len(files), intentMetadata.Intent)
+
+	topK := intentMetadata.RecommendedTopK
+	if intentMetadata.Intent == retrieval.IntentSummary && topK < 10 {
+		topK = 10 // Minimum 10 chunks for summary
+	}
+
+	useHybrid := (intentMetadata.Intent == retrieval.IntentComparison && len(keyTerms) >= 2) ||
+		(intentMetadata.Intent == retrieval.IntentDefinition && len(keyTerms) > 0)
+
+	// Semantic searches count as file router searches only if every file was served from it
+	path := models.RetrievalPathHybrid
+	if !useHybrid {
+		path = models.RetrievalPathFileRouter
+		for _, file := range files {
+			if !vectorService.RoutesFile(file.ID.Hex()) {
+				path = models.RetrievalPathSemantic
+				break
+			}
+		}
+	}
+
+	questionEmbedding, err := llmService.GenerateEmbedding(question)
+	if err != nil {
+		log.Printf("Failed to generate embedding for question: %v", err)
+		return nil, intentMetadata, "", fiber.NewError(500, "Soru işlenirken hata oluştu")
+	}
+
+	const maxParallelSearches = 4
+	resultSets := make([][]services.ChunkResult, len(files))
+	semaphore := make(chan struct{}, maxParallelSearches)
+	var wg sync.WaitGroup
+
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file models.File) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			fileID := file.ID.Hex()
+
+			var results []services.ChunkResult
+			var err error
+			if useHybrid {
+				results, err = vectorService.HybridSearch(questionEmbedding, keyTerms, fileID, topK)
+			} else {
+				results, err = vectorService.QuerySimilar(questionEmbedding, fileID, topK)
+			}
+			if err != nil {
+				log.Printf("Search failed for file %s: %v", fileID, err)
+				return
+			}
+
+			for j := range results {
+				if results[j].Metadata == nil {
+					results[j].Metadata = map[string]interface{}{}
+				}
+				results[j].Metadata["filename"] = file.Filename
+			}
+			resultSets[i] = results
+		}(i, file)
+	}
+	wg.Wait()
+
+	merged, err := vectorService.ReciprocalRankFusionMulti(resultSets, topK)
+	if err != nil {
+		return nil, intentMetadata, "", fiber.NewError(500, "İçerik arama işlemi başarısız oldu")
+	}
+	if len(merged) == 0 {
+		return nil, intentMetadata, "", fiber.NewError(404, "Seçilen dosyalarda ilgili içerik bulunamadı")
 	}
 
-	return false // Default: not a specific table query
+	log.Printf("Merged %d chunks from %d files", len(merged), len(files))
+	merged = services.RerankChunks(ctx, retrieval.RerankQuery{
+		Question: question,
+		Intent:   intentMetadata.Intent,
+		KeyTerms: keyTerms,
+	}, merged)
+	return merged, intentMetadata, path, nil
 }
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"nimbus-backend/config"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+	"nimbus-backend/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// recordAudit - İstek bağlamından (IP, user agent) audit event'i yaz
+func recordAudit(c *fiber.Ctx, actorID, action, resourceType, resourceID, ownerID string, before, after interface{}) {
+	services.AuditServiceInstance.Record(models.AuditEvent{
+		ActorID:      actorID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		OwnerID:      ownerID,
+		IP:           c.IP(),
+		UserAgent:    c.Get(fiber.HeaderUserAgent),
+		Before:       before,
+		After:        after,
+	})
+}
+
+// resourceOwnerID - Audit kaydı için kaynağın sahibini bul
+func resourceOwnerID(resourceType, resourceID string) string {
+	if resourceType == "file" {
+		if file, err := services.FileServiceInstance.GetFileByID(resourceID); err == nil {
+			return file.UserID
+		}
+		return ""
+	}
+	if folder, err := services.FolderServiceInstance.GetFolderByID(resourceID); err == nil {
+		return folder.UserID
+	}
+	return ""
+}
+
+// parseAuditFilter - Query parametrelerinden filtre oluştur. Admin olmayan kullanıcılar
+// sadece sahibi oldukları kaynakların kayıtlarını görebilir.
+func parseAuditFilter(c *fiber.Ctx, cfg *config.Config) (models.AuditFilter, error) {
+	claims, err := helpers.GetCurrentUser(c)
+	if err != nil {
+		return models.AuditFilter{}, err
+	}
+
+	filter := models.AuditFilter{
+		ActorID:      c.Query("actor_id"),
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+		OwnerID:      c.Query("owner_id"),
+	}
+
+	if !cfg.IsAdmin(claims.Email) {
+		filter.OwnerID = claims.UserID
+	}
+
+	for key, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(key); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s RFC3339 formatında olmalı", key)
+			}
+			*target = &parsed
+		}
+	}
+
+	return filter, nil
+}
+
+// GetAuditEvents - Sayfalanmış audit kayıtları
+func GetAuditEvents(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		filter, err := parseAuditFilter(c, cfg)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+		limit, _ := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 500 {
+			limit = 50
+		}
+
+		events, total, err := services.AuditServiceInstance.Query(filter, page, limit)
+		if err != nil {
+			log.Printf("Audit sorgu hatası: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Audit kayıtları alınamadı",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"events": events,
+			"total":  total,
+			"page":   page,
+			"limit":  limit,
+		})
+	}
+}
+
+// ExportAuditEvents - Filtreye uyan kayıtları JSONL olarak indir
+func ExportAuditEvents(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		filter, err := parseAuditFilter(c, cfg)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="audit-%s.jsonl"`, time.Now().Format("20060102-150405")))
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			encoder := json.NewEncoder(w)
+			err := services.AuditServiceInstance.Stream(context.Background(), filter, func(event models.AuditEvent) error {
+				if err := encoder.Encode(event); err != nil {
+					return err
+				}
+				return w.Flush()
+			})
+			if err != nil {
+				log.Printf("Audit export hatası: %v", err)
+			}
+		})
+
+		return nil
+	}
+}
@@ -12,11 +12,13 @@ import (
 	"nimbus-backend/config"
 	"nimbus-backend/database"
 	"nimbus-backend/models"
+	"nimbus-backend/services"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -145,6 +147,8 @@ func GoogleCallback(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, user.ID.Hex(), "auth.login", "user", user.ID.Hex(), user.ID.Hex(), nil, nil)
+
 		redirectURL := fmt.Sprintf("%s/dashboard?token=%s", cfg.FrontendURL, jwtToken)
 		return c.Redirect(redirectURL)
 	}
@@ -230,6 +234,7 @@ func findOrCreateUser(googleUser *GoogleUser) (*models.User, error) {
 
 	// Yeni kullanıcı oluştur
 	newUser := models.User{
+		ID:        primitive.NewObjectID(),
 		GoogleID:  googleUser.ID,
 		Email:     googleUser.Email,
 		Name:      googleUser.Name,
@@ -243,6 +248,11 @@ func findOrCreateUser(googleUser *GoogleUser) (*models.User, error) {
 		return nil, err
 	}
 
+	// E-posta ile yapılmış bekleyen paylaşımları erişime dönüştür
+	if err := services.InvitationServiceInstance.MaterializeInvitations(&newUser); err != nil {
+		log.Printf("Bekleyen davetler işlenemedi: %v", err)
+	}
+
 	return &newUser, nil
 }
 
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"nimbus-backend/config"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+	"nimbus-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxCorrectedAnswerRunes - Düzeltilmiş cevap için üst sınır
+const maxCorrectedAnswerRunes = 10000
+
+// feedbackErrorResponse - Değerlendirme servis hatasını HTTP cevabına çevir
+func feedbackErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrThreadNotFound) || errors.Is(err, services.ErrMessageNotFound) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Mesaj bulunamadı",
+		})
+	}
+	log.Printf("Değerlendirme hatası: %v", err)
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Değerlendirme kaydedilemedi",
+	})
+}
+
+// validFeedbackReason - Neden boş veya tanımlı nedenlerden biri olmalı
+func validFeedbackReason(reason string) bool {
+	if reason == "" {
+		return true
+	}
+	for _, allowed := range models.FeedbackReasons {
+		if reason == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMessageFeedback - Asistan cevabını değerlendir (up/down, neden, düzeltilmiş cevap). Tekrar gönderim öncekinin yerine geçer.
+func SetMessageFeedback(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+
+		var req models.MessageFeedbackRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Geçersiz istek verisi",
+			})
+		}
+
+		if req.Rating != models.FeedbackUp && req.Rating != models.FeedbackDown {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "rating 'up' veya 'down' olmalı",
+			})
+		}
+		if !validFeedbackReason(req.Reason) {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Geçersiz neden",
+				"reasons": models.FeedbackReasons,
+			})
+		}
+		correctedAnswer := strings.TrimSpace(req.CorrectedAnswer)
+		if utf8.RuneCountInString(correctedAnswer) > maxCorrectedAnswerRunes {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Düzeltilmiş cevap çok uzun",
+			})
+		}
+
+		message, err := services.FeedbackServiceInstance.SetFeedback(userID, c.Params("id"), c.Params("messageId"), models.MessageFeedback{
+			Rating:          req.Rating,
+			Reason:          req.Reason,
+			CorrectedAnswer: correctedAnswer,
+		})
+		if err != nil {
+			return feedbackErrorResponse(c, err)
+		}
+
+		return c.JSON(message)
+	}
+}
+
+// ClearMessageFeedback - Asistan cevabının değerlendirmesini kaldır
+func ClearMessageFeedback(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+
+		if err := services.FeedbackServiceInstance.ClearFeedback(userID, c.Params("id"), c.Params("messageId")); err != nil {
+			return feedbackErrorResponse(c, err)
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Değerlendirme kaldırıldı",
+		})
+	}
+}
+
+// GetFeedbackStats - Cevap değerlendirmelerinin intent, retrieval yolu, reranker ve dosya türüne göre özeti (sadece admin)
+func GetFeedbackStats(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := helpers.GetCurrentUser(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+		if !cfg.IsAdmin(claims.Email) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Bu işlem için admin yetkisi gerekli",
+			})
+		}
+
+		var from, to *time.Time
+		for key, target := range map[string]**time.Time{"from": &from, "to": &to} {
+			if value := c.Query(key); value != "" {
+				parsed, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					return c.Status(400).JSON(fiber.Map{
+						"error": key + " RFC3339 formatında olmalı",
+					})
+				}
+				*target = &parsed
+			}
+		}
+
+		stats, err := services.FeedbackServiceInstance.Stats(from, to)
+		if err != nil {
+			log.Printf("Değerlendirme istatistik hatası: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "İstatistikler alınamadı",
+			})
+		}
+
+		return c.JSON(stats)
+	}
+}
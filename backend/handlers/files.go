@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/hmac"
@@ -12,6 +13,7 @@ import (
 	"log"
 	"net/http"
 	"nimbus-backend/config"
+	"nimbus-backend/extract"
 	"nimbus-backend/helpers"
 	"nimbus-backend/middleware"
 	"nimbus-backend/models"
@@ -105,17 +107,20 @@ func CreateFile(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		// Auto-trigger processing for PDF/DOCX files
-		contentTypeLower := strings.ToLower(file.ContentType)
-		isAskable := strings.Contains(contentTypeLower, "pdf") ||
-			strings.Contains(contentTypeLower, "wordprocessingml") ||
-			strings.Contains(contentTypeLower, "msword")
-
-		if isAskable && services.DocumentProcessorInstance != nil {
+		// Auto-trigger processing for every format the extractor registry can read
+		if extract.Supports(file.ContentType, file.Filename) && services.DocumentProcessorInstance != nil {
 			log.Printf("Auto-triggering document processing for file %s (%s)", file.ID.Hex(), file.Filename)
-			services.DocumentProcessorInstance.ProcessDocumentAsync(file.ID.Hex(), file.MinioPath, file.ContentType)
+			if _, err := services.DocumentProcessorInstance.ProcessDocumentAsync(file); err != nil {
+				log.Printf("İşleme kuyruğuna eklenemedi %s: %v", file.ID.Hex(), err)
+			}
 		}
 
+		recordAudit(c, userID, "file.create", "file", file.ID.Hex(), file.UserID, nil, fiber.Map{
+			"filename":  file.Filename,
+			"size":      file.Size,
+			"folder_id": file.FolderID,
+		})
+
 		return c.Status(201).JSON(fiber.Map{
 			"message": "Dosya başarıyla kaydedildi",
 			"file": models.FileResponse{
@@ -131,6 +136,8 @@ func CreateFile(cfg *config.Config) fiber.Handler {
 				ProcessingError:  file.ProcessingError,
 				ProcessedAt:      file.ProcessedAt,
 				ChunkCount:       file.ChunkCount,
+				FailedChunkCount: file.FailedChunkCount,
+				ContentStale:     file.ContentStale,
 				CreatedAt:        file.CreatedAt,
 				UpdatedAt:        file.UpdatedAt,
 			},
@@ -171,27 +178,22 @@ func ProcessDocument(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		// Check if file is PDF or DOCX
-		contentTypeLower := strings.ToLower(file.ContentType)
-		isAskable := strings.Contains(contentTypeLower, "pdf") ||
-			strings.Contains(contentTypeLower, "wordprocessingml") ||
-			strings.Contains(contentTypeLower, "msword")
-
-		if !isAskable {
+		// Check if the file type has a text extractor
+		if !extract.Supports(file.ContentType, file.Filename) {
 			return c.Status(400).JSON(fiber.Map{
-				"error": "Bu dosya türü işlenemiyor. Sadece PDF ve DOCX dosyaları desteklenmektedir.",
+				"error": "Bu dosya türü işlenemiyor. Desteklenen türler: PDF, Office (DOCX, XLSX, PPTX), OpenDocument, RTF, HTML, Markdown, CSV, metin ve kod dosyaları.",
 			})
 		}
 
 		// Check if already processing or completed
-		if file.ProcessingStatus == "processing" {
+		if file.ProcessingStatus == "processing" || file.ProcessingStatus == "pending" {
 			return c.Status(409).JSON(fiber.Map{
 				"error":  "Dosya zaten işleniyor",
 				"status": file.ProcessingStatus,
 			})
 		}
 
-		if file.ProcessingStatus == "completed" {
+		if file.ProcessingStatus == "completed" && !file.ContentStale {
 			return c.JSON(fiber.Map{
 				"message":     "Dosya zaten işlenmiş",
 				"status":      file.ProcessingStatus,
@@ -199,16 +201,150 @@ func ProcessDocument(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		// Start async processing
-		services.DocumentProcessorInstance.ProcessDocumentAsync(fileID, file.MinioPath, file.ContentType)
+		// İşleme kuyruğuna ekle
+		job, err := services.DocumentProcessorInstance.ProcessDocumentAsync(file)
+		if err != nil {
+			log.Printf("İşleme kuyruğuna eklenemedi %s: %v", fileID, err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Dosya işleme başlatılamadı",
+			})
+		}
 
 		return c.Status(202).JSON(fiber.Map{
 			"message": "Dosya işleme başlatıldı",
 			"status":  "pending",
+			"job_id":  job.ID.Hex(),
 		})
 	}
 }
 
+// reindexChangedFile - İçerik değişince dosyayı yeniden indeksle. Eski chunk'lar iş bitene
+// kadar sorgulanabilir kalır; sadece değişen chunk'lar yeniden embed edilir.
+func reindexChangedFile(file *models.File) {
+	if services.DocumentProcessorInstance == nil || !extract.Supports(file.ContentType, file.Filename) {
+		return
+	}
+	if _, err := services.DocumentProcessorInstance.ReindexAsync(file); err != nil {
+		log.Printf("Yeniden indeksleme kuyruğa eklenemedi %s: %v", file.ID.Hex(), err)
+	}
+}
+
+// loadProcessingFile - Dosyayı getir ve okuma yetkisini kontrol et
+func loadProcessingFile(c *fiber.Ctx) (*models.File, error) {
+	userID, err := helpers.GetCurrentUserID(c)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+
+	fileID := c.Params("id")
+	file, err := services.FileServiceInstance.GetFileByID(fileID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Dosya bulunamadı")
+	}
+
+	hasAccess, err := helpers.CanUserAccess(userID, "file", fileID, helpers.AccessLevelRead)
+	if err != nil || !hasAccess {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Bu dosyaya erişim yetkiniz yok")
+	}
+
+	return file, nil
+}
+
+// GetProcessingStatus - Dosyanın işleme aşaması, ilerlemesi ve tahmini kalan süresi
+func GetProcessingStatus(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		file, err := loadProcessingFile(c)
+		if err != nil {
+			return fiberErrorResponse(c, err)
+		}
+
+		state, err := services.ProcessingState(file)
+		if err != nil {
+			log.Printf("İşleme durumu alınamadı %s: %v", file.ID.Hex(), err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "İşleme durumu alınamadı",
+			})
+		}
+
+		return c.JSON(state)
+	}
+}
+
+// StreamProcessingStatus - İşleme ilerlemesini SSE ile yayınla; dosya işlendiğinde veya
+// hata aldığında "done" event'i gönderip bağlantıyı kapatır
+func StreamProcessingStatus(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		file, err := loadProcessingFile(c)
+		if err != nil {
+			return fiberErrorResponse(c, err)
+		}
+		fileID := file.ID.Hex()
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		// fiber.Ctx handler dönünce geri dönüştürülür; writer sadece yakalanan değerleri kullanır
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			var lastPayload []byte
+			lastSent := time.Now()
+			for {
+				current, err := services.FileServiceInstance.GetFileByID(fileID)
+				if err != nil {
+					writeSSEEvent(w, "error", fiber.Map{"error": "Dosya bulunamadı"})
+					return
+				}
+				state, err := services.ProcessingState(current)
+				if err != nil {
+					log.Printf("İşleme durumu alınamadı %s: %v", fileID, err)
+					writeSSEEvent(w, "error", fiber.Map{"error": "İşleme durumu alınamadı"})
+					return
+				}
+
+				if isFinalProcessingState(state) {
+					writeSSEEvent(w, "done", state)
+					return
+				}
+
+				// Sadece değişiklik olduğunda gönder; arada bağlantı kopmasını fark etmek için ping
+				payload, _ := json.Marshal(state)
+				if !bytes.Equal(payload, lastPayload) {
+					if err := writeSSEEvent(w, "progress", state); err != nil {
+						return
+					}
+					lastPayload = payload
+					lastSent = time.Now()
+				} else if time.Since(lastSent) >= 15*time.Second {
+					if _, err := w.WriteString(": ping\n\n"); err != nil {
+						return
+					}
+					if err := w.Flush(); err != nil {
+						return
+					}
+					lastSent = time.Now()
+				}
+
+				<-ticker.C
+			}
+		})
+
+		return nil
+	}
+}
+
+// isFinalProcessingState - İlerleme akışının bittiği durumlar. Yeniden indekslenen dosyalar
+// "completed" kalır, bu yüzden işin de bitmiş olması gerekir.
+func isFinalProcessingState(state *models.ProcessingState) bool {
+	if state.Status == "pending" || state.Status == "processing" {
+		return false
+	}
+	return state.Job == nil || (state.Job.Status != models.JobStatusQueued && state.Job.Status != models.JobStatusRunning)
+}
+
 // Download için presigned URL al
 func GetDownloadPresignedURL(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -238,6 +374,13 @@ func GetDownloadPresignedURL(cfg *config.Config) fiber.Handler {
 				})
 			}
 
+			// Eski istemciler file_id göndermiyor; audit kaydı için dosyayı MinIO path'inden bul
+			resourceID := services.MinioService.GetUserFilePath(userID, filename)
+			if file, err := services.FileServiceInstance.GetFileByMinioPath(resourceID); err == nil {
+				resourceID = file.ID.Hex()
+			}
+			recordAudit(c, userID, "file.download", "file", resourceID, userID, nil, fiber.Map{"filename": filename})
+
 			return c.JSON(fiber.Map{
 				"presigned_url": presignedURL,
 				"filename":      filename,
@@ -262,12 +405,12 @@ func GetDownloadPresignedURL(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		// Use file owner's UserID to generate presigned URL (file is stored in owner's folder)
-		presignedURL, err := services.MinioService.GenerateDownloadPresignedURL(
-			file.UserID,
-			file.Filename,
-			time.Hour,
-		)
+		// Kayıtlı MinIO path'ini kullan (sahiplik devrinden sonra owner prefix'i değişmiş olabilir)
+		objectName := file.MinioPath
+		if objectName == "" {
+			objectName = services.MinioService.GetUserFilePath(file.UserID, file.Filename)
+		}
+		presignedURL, err := services.MinioService.GenerateObjectPresignedURL(objectName, time.Hour)
 		if err != nil {
 			log.Printf("Download presigned URL oluşturma hatası: %v", err)
 			return c.Status(404).JSON(fiber.Map{
@@ -275,6 +418,8 @@ func GetDownloadPresignedURL(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, userID, "file.download", "file", fileID, file.UserID, nil, nil)
+
 		return c.JSON(fiber.Map{
 			"presigned_url": presignedURL,
 			"filename":      file.Filename,
@@ -334,12 +479,12 @@ func GetPreviewPresignedURL(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		// Use file owner's UserID to generate presigned URL (file is stored in owner's folder)
-		presignedURL, err := services.MinioService.GenerateDownloadPresignedURL(
-			file.UserID,
-			file.Filename,
-			time.Hour,
-		)
+		// Kayıtlı MinIO path'ini kullan (sahiplik devrinden sonra owner prefix'i değişmiş olabilir)
+		objectName := file.MinioPath
+		if objectName == "" {
+			objectName = services.MinioService.GetUserFilePath(file.UserID, file.Filename)
+		}
+		presignedURL, err := services.MinioService.GenerateObjectPresignedURL(objectName, time.Hour)
 		if err != nil {
 			log.Printf("Preview presigned URL oluşturma hatası: %v", err)
 			return middleware.InternalServerErrorResponse(c, "Preview URL oluşturulamadı")
@@ -390,6 +535,8 @@ func ListUserFiles(cfg *config.Config) fiber.Handler {
 				ProcessingError:  file.ProcessingError,
 				ProcessedAt:      file.ProcessedAt,
 				ChunkCount:       file.ChunkCount,
+				FailedChunkCount: file.FailedChunkCount,
+				ContentStale:     file.ContentStale,
 				CreatedAt:        file.CreatedAt,
 				UpdatedAt:        file.UpdatedAt,
 				Owner:            services.UserServiceInstance.GetUserResponse(file.UserID),
@@ -451,11 +598,11 @@ func DeleteFile(cfg *config.Config) fiber.Handler {
 				log.Printf("MinIO'dan dosya silme hatası: %v (kayıt silindi)", err)
 			}
 
-			// Chroma'dan sil (if document was processed)
-			if file.ProcessingStatus == "completed" && services.DocumentProcessorInstance != nil {
-				chromaService := services.NewChromaService(cfg)
-				if err := chromaService.DeleteDocumentChunks(fileID); err != nil {
-					log.Printf("Chroma'dan chunks silme hatası: %v (kayıt silindi)", err)
+			// Vektör deposundan sil (if document was processed)
+			if models.IsQueryableStatus(file.ProcessingStatus) && services.DocumentProcessorInstance != nil {
+				vectorService := services.NewVectorService(cfg)
+				if err := vectorService.DeleteDocumentChunks(fileID); err != nil {
+					log.Printf("Vektör deposundan chunks silme hatası: %v (kayıt silindi)", err)
 				}
 			}
 
@@ -464,6 +611,8 @@ func DeleteFile(cfg *config.Config) fiber.Handler {
 				log.Printf("Sohbet geçmişi silme hatası: %v (kayıt silindi)", err)
 			}
 
+			recordAudit(c, userID, "file.delete_permanent", "file", fileID, file.UserID, file, nil)
+
 			return c.JSON(fiber.Map{
 				"message": "Dosya kalıcı olarak silindi",
 			})
@@ -476,6 +625,8 @@ func DeleteFile(cfg *config.Config) fiber.Handler {
 				})
 			}
 
+			recordAudit(c, userID, "file.delete", "file", fileID, file.UserID, fiber.Map{"deleted_at": file.DeletedAt}, nil)
+
 			return c.JSON(fiber.Map{
 				"message": "Dosya çöp kutusuna taşındı",
 			})
@@ -642,6 +793,8 @@ func RestoreFile(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, userID, "file.restore", "file", fileID, file.UserID, fiber.Map{"deleted_at": file.DeletedAt}, nil)
+
 		return c.JSON(fiber.Map{
 			"message": "Dosya geri yüklendi",
 		})
@@ -689,6 +842,9 @@ func MoveFile(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, userID, "file.move", "file", fileID, file.UserID,
+			fiber.Map{"folder_id": file.FolderID}, fiber.Map{"folder_id": req.FolderID})
+
 		return c.JSON(fiber.Map{
 			"message": "Dosya taşındı",
 		})
@@ -714,6 +870,8 @@ func formatFileResponse(files []models.File) []models.FileResponse {
 			ProcessingError:  file.ProcessingError,
 			ProcessedAt:      file.ProcessedAt,
 			ChunkCount:       file.ChunkCount,
+			FailedChunkCount: file.FailedChunkCount,
+			ContentStale:     file.ContentStale,
 			DeletedAt:        file.DeletedAt,
 			CreatedAt:        file.CreatedAt,
 			UpdatedAt:        file.UpdatedAt,
@@ -826,14 +984,16 @@ func GetOnlyOfficeConfig(cfg *config.Config) fiber.Handler {
 			return middleware.NotFoundResponse(c, "Dosya bulunamadı")
 		}
 
-		hasWriteAccess, err := helpers.CheckFileAccessWithOwnerFallback(userID, fileID, file.UserID, helpers.AccessLevelWrite)
+		// Commenter rolü editörü sadece yorum izniyle açabilir
+		hasCommentAccess, err := helpers.CheckFileAccessWithOwnerFallback(userID, fileID, file.UserID, helpers.AccessLevelComment)
 		if err != nil {
 			log.Printf("Access check hatası: %v", err)
 			return middleware.InternalServerErrorResponse(c, "Erişim kontrolü yapılamadı")
 		}
-		if !hasWriteAccess {
+		if !hasCommentAccess {
 			return middleware.ForbiddenResponse(c, "Bu dosyayı düzenleme yetkiniz yok")
 		}
+		hasWriteAccess, _ := helpers.CheckFileAccessWithOwnerFallback(userID, fileID, file.UserID, helpers.AccessLevelWrite)
 
 		user, err := services.UserServiceInstance.GetUserByID(userID)
 		userName := userID
@@ -881,6 +1041,10 @@ func GetOnlyOfficeConfig(cfg *config.Config) fiber.Handler {
 			"key":      docKey,
 			"title":    file.Filename,
 			"url":      docURL,
+			"permissions": map[string]interface{}{
+				"edit":    hasWriteAccess,
+				"comment": true,
+			},
 		}
 
 		config := map[string]interface{}{
@@ -1004,6 +1168,18 @@ func OnlyOfficeCallback(cfg *config.Config) fiber.Handler {
 			}
 
 			log.Printf("Dosya başarıyla kaydedildi: fileID=%s, size=%d", fileID, len(fileContent))
+
+			reindexChangedFile(file)
+
+			// OnlyOffice callback'inde JWT yok, düzenleyen kullanıcıyı callback içeriğinden al
+			actorID := ""
+			if len(req.Users) > 0 {
+				actorID = req.Users[0]
+			} else if len(req.Actions) > 0 {
+				actorID = req.Actions[0].UserID
+			}
+			recordAudit(c, actorID, "file.onlyoffice_save", "file", fileID, file.UserID,
+				fiber.Map{"size": file.Size}, fiber.Map{"size": len(fileContent), "status": req.Status})
 		}
 
 		return c.JSON(fiber.Map{"error": 0})
@@ -1106,6 +1282,8 @@ func GetOnlyOfficeDocument(cfg *config.Config) fiber.Handler {
 		}
 		defer object.Close()
 
+		recordAudit(c, userID, "file.onlyoffice_open", "file", fileID, file.UserID, nil, nil)
+
 		c.Set("Content-Type", file.ContentType)
 		c.Set("Content-Length", fmt.Sprintf("%d", objInfo.Size))
 		c.Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, file.Filename))
@@ -1168,6 +1346,8 @@ func GetFileContent(cfg *config.Config) fiber.Handler {
 			return middleware.InternalServerErrorResponse(c, "Dosya içeriği okunamadı")
 		}
 
+		recordAudit(c, userID, "file.read_content", "file", fileID, file.UserID, nil, nil)
+
 		c.Set("Content-Type", "text/plain; charset=utf-8")
 		c.Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		c.Set("Pragma", "no-cache")
@@ -1241,6 +1421,11 @@ func UpdateFileContent(cfg *config.Config) fiber.Handler {
 			log.Printf("Dosya metadata güncelleme hatası: %v", err)
 		}
 
+		recordAudit(c, userID, "file.update_content", "file", fileID, file.UserID,
+			fiber.Map{"size": file.Size}, fiber.Map{"size": len(fileContent)})
+
+		reindexChangedFile(file)
+
 		return c.JSON(fiber.Map{
 			"success": true,
 			"message": "Dosya başarıyla güncellendi",
@@ -44,6 +44,11 @@ func CreateFolder(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, userID, "folder.create", "folder", folder.ID.Hex(), folder.UserID, nil, fiber.Map{
+			"name":      folder.Name,
+			"folder_id": folder.FolderID,
+		})
+
 		return c.Status(201).JSON(fiber.Map{
 			"message": "Klasör başarıyla oluşturuldu",
 			"folder": models.FolderResponse{
@@ -229,6 +234,8 @@ func GetFolderContents(cfg *config.Config) fiber.Handler {
 				ProcessingError:  file.ProcessingError,
 				ProcessedAt:      file.ProcessedAt,
 				ChunkCount:       file.ChunkCount,
+				FailedChunkCount: file.FailedChunkCount,
+				ContentStale:     file.ContentStale,
 				DeletedAt:        file.DeletedAt,
 				CreatedAt:        file.CreatedAt,
 				UpdatedAt:        file.UpdatedAt,
@@ -369,6 +376,8 @@ func GetRootContents(cfg *config.Config) fiber.Handler {
 				ProcessingError:  file.ProcessingError,
 				ProcessedAt:      file.ProcessedAt,
 				ChunkCount:       file.ChunkCount,
+				FailedChunkCount: file.FailedChunkCount,
+				ContentStale:     file.ContentStale,
 				DeletedAt:        file.DeletedAt,
 				CreatedAt:        file.CreatedAt,
 				UpdatedAt:        file.UpdatedAt,
@@ -444,6 +453,9 @@ func UpdateFolder(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, userID, "folder.update", "folder", folderID, folder.UserID,
+			fiber.Map{"name": folder.Name, "color": folder.Color}, updates)
+
 		return c.JSON(fiber.Map{
 			"message": "Klasör başarıyla güncellendi",
 		})
@@ -491,6 +503,7 @@ func DeleteFolder(cfg *config.Config) fiber.Handler {
 					"error": err.Error(),
 				})
 			}
+			recordAudit(c, userID, "folder.delete_permanent", "folder", folderID, folder.UserID, folder, nil)
 			return c.JSON(fiber.Map{
 				"message": "Klasör kalıcı olarak silindi",
 			})
@@ -502,6 +515,7 @@ func DeleteFolder(cfg *config.Config) fiber.Handler {
 					"error": err.Error(),
 				})
 			}
+			recordAudit(c, userID, "folder.delete", "folder", folderID, folder.UserID, fiber.Map{"deleted_at": folder.DeletedAt}, nil)
 			return c.JSON(fiber.Map{
 				"message": "Klasör çöp kutusuna taşındı",
 			})
@@ -636,6 +650,8 @@ func RestoreFolder(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, userID, "folder.restore", "folder", folderID, folder.UserID, fiber.Map{"deleted_at": folder.DeletedAt}, nil)
+
 		return c.JSON(fiber.Map{
 			"message": "Klasör geri yüklendi",
 		})
@@ -683,6 +699,9 @@ func MoveFolder(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		recordAudit(c, userID, "folder.move", "folder", folderID, folder.UserID,
+			fiber.Map{"folder_id": folder.FolderID}, fiber.Map{"folder_id": req.FolderID})
+
 		return c.JSON(fiber.Map{
 			"message": "Klasör taşındı",
 		})
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"nimbus-backend/config"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+	"nimbus-backend/services"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// jobQueueUnavailable - Kuyruk başlatılmadıysa 503 dön
+func jobQueueUnavailable(c *fiber.Ctx) error {
+	return c.Status(503).JSON(fiber.Map{
+		"error": "İşleme kuyruğu kullanılamıyor",
+	})
+}
+
+// loadOwnedJob - İşi getir; sadece dosya sahibi veya admin erişebilir
+func loadOwnedJob(c *fiber.Ctx, cfg *config.Config) (*models.ProcessingJob, *models.Claims, error) {
+	claims, err := helpers.GetCurrentUser(c)
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+
+	job, err := services.JobQueueInstance.GetJob(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrJobNotFound) {
+			return nil, nil, fiber.NewError(fiber.StatusNotFound, "İş bulunamadı")
+		}
+		log.Printf("İş getirme hatası: %v", err)
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "İş alınamadı")
+	}
+
+	if job.UserID != claims.UserID && !cfg.IsAdmin(claims.Email) {
+		return nil, nil, fiber.NewError(fiber.StatusForbidden, "Bu işe erişim yetkiniz yok")
+	}
+
+	return job, claims, nil
+}
+
+// fiberErrorResponse - fiber.Error'ı JSON hata cevabına çevir
+func fiberErrorResponse(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// ListJobs - Sayfalanmış işleme işleri. Kullanıcılar kendi dosyalarının işlerini görür,
+// admin all=true ile tüm işleri görebilir.
+func ListJobs(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if services.JobQueueInstance == nil {
+			return jobQueueUnavailable(c)
+		}
+
+		claims, err := helpers.GetCurrentUser(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		filter := models.JobFilter{
+			UserID: claims.UserID,
+			FileID: c.Query("file_id"),
+			Status: c.Query("status"),
+		}
+		if c.QueryBool("all") && cfg.IsAdmin(claims.Email) {
+			filter.UserID = c.Query("user_id")
+		}
+
+		page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+		limit, _ := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 500 {
+			limit = 50
+		}
+
+		jobs, total, err := services.JobQueueInstance.List(filter, page, limit)
+		if err != nil {
+			log.Printf("İş listeleme hatası: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "İşler alınamadı",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"jobs":  jobs,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// GetJob - Tek bir işin detayı
+func GetJob(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if services.JobQueueInstance == nil {
+			return jobQueueUnavailable(c)
+		}
+
+		job, _, err := loadOwnedJob(c, cfg)
+		if err != nil {
+			return fiberErrorResponse(c, err)
+		}
+
+		return c.JSON(fiber.Map{
+			"job": job,
+		})
+	}
+}
+
+// RetryJob - Dead-letter veya iptal edilmiş işi tekrar kuyruğa al
+func RetryJob(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if services.JobQueueInstance == nil {
+			return jobQueueUnavailable(c)
+		}
+
+		job, claims, err := loadOwnedJob(c, cfg)
+		if err != nil {
+			return fiberErrorResponse(c, err)
+		}
+
+		updated, err := services.JobQueueInstance.Retry(job.ID.Hex())
+		if err != nil {
+			if errors.Is(err, services.ErrJobState) {
+				return c.Status(409).JSON(fiber.Map{
+					"error":  "Sadece başarısız veya iptal edilmiş işler tekrar denenebilir",
+					"status": job.Status,
+				})
+			}
+			log.Printf("İş tekrar deneme hatası: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "İş tekrar kuyruğa alınamadı",
+			})
+		}
+
+		recordAudit(c, claims.UserID, "job.retry", "file", job.FileID, job.UserID,
+			fiber.Map{"job_id": job.ID.Hex(), "status": job.Status, "attempts": job.Attempts},
+			fiber.Map{"job_id": updated.ID.Hex(), "status": updated.Status},
+		)
+
+		return c.JSON(fiber.Map{
+			"message": "İş tekrar kuyruğa alındı",
+			"job":     updated,
+		})
+	}
+}
+
+// CancelJob - Bekleyen veya çalışan işi iptal et
+func CancelJob(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if services.JobQueueInstance == nil {
+			return jobQueueUnavailable(c)
+		}
+
+		job, claims, err := loadOwnedJob(c, cfg)
+		if err != nil {
+			return fiberErrorResponse(c, err)
+		}
+
+		updated, err := services.JobQueueInstance.Cancel(job.ID.Hex())
+		if err != nil {
+			if errors.Is(err, services.ErrJobState) {
+				return c.Status(409).JSON(fiber.Map{
+					"error":  "Sadece bekleyen veya çalışan işler iptal edilebilir",
+					"status": job.Status,
+				})
+			}
+			log.Printf("İş iptal hatası: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "İş iptal edilemedi",
+			})
+		}
+
+		recordAudit(c, claims.UserID, "job.cancel", "file", job.FileID, job.UserID,
+			fiber.Map{"job_id": job.ID.Hex(), "status": job.Status},
+			fiber.Map{"job_id": updated.ID.Hex(), "status": updated.Status},
+		)
+
+		return c.JSON(fiber.Map{
+			"message": "İş iptal edildi",
+			"job":     updated,
+		})
+	}
+}
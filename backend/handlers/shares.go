@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -39,8 +42,8 @@ func GetResourceShares() fiber.Handler {
 		err = database.FileCollection.FindOne(context.Background(), bson.M{
 			"_id": resourceOID,
 			"$or": []bson.M{
-				{"user_id": userID},             // Owner
-				{"access_list.user_id": userID}, // Has access
+				{"user_id": userID},                // Owner
+				helpers.ActiveAccessFilter(userID), // Has active access
 			},
 		}).Decode(&file)
 
@@ -49,8 +52,8 @@ func GetResourceShares() fiber.Handler {
 			err = database.FolderCollection.FindOne(context.Background(), bson.M{
 				"_id": resourceOID,
 				"$or": []bson.M{
-					{"user_id": userID},             // Owner
-					{"access_list.user_id": userID}, // Has access
+					{"user_id": userID},                // Owner
+					helpers.ActiveAccessFilter(userID), // Has active access
 				},
 			}).Decode(&folder)
 			if err != nil {
@@ -75,13 +78,20 @@ func GetResourceShares() fiber.Handler {
 				})
 			}
 
+			invitations, err := services.InvitationServiceInstance.GetPendingInvitations(resourceOID)
+			if err != nil {
+				log.Printf("Error getting pending invitations: %v", err)
+				invitations = []models.ShareInvitation{}
+			}
+
 			return c.JSON(fiber.Map{
-				"resource_id":   resourceOID.Hex(),
-				"resource_type": "folder",
-				"user_id":       folder.UserID,
-				"public_link":   folder.PublicLink,
-				"access_list":   folder.AccessList,
-				"shared_with":   sharedUsers,
+				"resource_id":         resourceOID.Hex(),
+				"resource_type":       "folder",
+				"user_id":             folder.UserID,
+				"public_link":         folder.PublicLink,
+				"access_list":         helpers.FilterActiveAccess(folder.AccessList),
+				"shared_with":         sharedUsers,
+				"pending_invitations": invitations,
 			})
 		}
 
@@ -101,13 +111,20 @@ func GetResourceShares() fiber.Handler {
 			})
 		}
 
+		invitations, err := services.InvitationServiceInstance.GetPendingInvitations(resourceOID)
+		if err != nil {
+			log.Printf("Error getting pending invitations: %v", err)
+			invitations = []models.ShareInvitation{}
+		}
+
 		return c.JSON(fiber.Map{
-			"resource_id":   resourceOID.Hex(),
-			"resource_type": "file",
-			"user_id":       file.UserID,
-			"public_link":   file.PublicLink,
-			"access_list":   file.AccessList,
-			"shared_with":   sharedUsers,
+			"resource_id":         resourceOID.Hex(),
+			"resource_type":       "file",
+			"user_id":             file.UserID,
+			"public_link":         file.PublicLink,
+			"access_list":         helpers.FilterActiveAccess(file.AccessList),
+			"shared_with":         sharedUsers,
+			"pending_invitations": invitations,
 		})
 	}
 }
@@ -160,9 +177,7 @@ func GetSharedWithMe() fiber.Handler {
 		var sharedItems []fiber.Map
 
 		// Get files shared with this user (from access_list)
-		fileCursor, err := database.FileCollection.Find(context.Background(), bson.M{
-			"access_list.user_id": userID,
-		})
+		fileCursor, err := database.FileCollection.Find(context.Background(), helpers.ActiveAccessFilter(userID))
 		if err == nil {
 			defer fileCursor.Close(context.Background())
 			for fileCursor.Next(context.Background()) {
@@ -177,7 +192,7 @@ func GetSharedWithMe() fiber.Handler {
 							ContentType:      file.ContentType,
 							MinioPath:        file.MinioPath,
 							PublicLink:       file.PublicLink,
-							AccessList:       file.AccessList,
+							AccessList:       helpers.FilterActiveAccess(file.AccessList),
 							ParentID:         file.ParentID,
 							Ancestors:        file.Ancestors,
 							IsStarred:        file.IsStarred,
@@ -185,6 +200,8 @@ func GetSharedWithMe() fiber.Handler {
 							ProcessingError:  file.ProcessingError,
 							ProcessedAt:      file.ProcessedAt,
 							ChunkCount:       file.ChunkCount,
+							FailedChunkCount: file.FailedChunkCount,
+							ContentStale:     file.ContentStale,
 							DeletedAt:        file.DeletedAt,
 							CreatedAt:        file.CreatedAt,
 							UpdatedAt:        file.UpdatedAt,
@@ -199,9 +216,7 @@ func GetSharedWithMe() fiber.Handler {
 		}
 
 		// Get folders shared with this user (from access_list)
-		folderCursor, err := database.FolderCollection.Find(context.Background(), bson.M{
-			"access_list.user_id": userID,
-		})
+		folderCursor, err := database.FolderCollection.Find(context.Background(), helpers.ActiveAccessFilter(userID))
 		if err == nil {
 			defer folderCursor.Close(context.Background())
 			for folderCursor.Next(context.Background()) {
@@ -346,6 +361,8 @@ func GetSharedFolderContents() fiber.Handler {
 						ProcessingError:  file.ProcessingError,
 						ProcessedAt:      file.ProcessedAt,
 						ChunkCount:       file.ChunkCount,
+						FailedChunkCount: file.FailedChunkCount,
+						ContentStale:     file.ContentStale,
 						DeletedAt:        file.DeletedAt,
 						CreatedAt:        file.CreatedAt,
 						UpdatedAt:        file.UpdatedAt,
@@ -420,8 +437,11 @@ func UpdateAccessPermission() fiber.Handler {
 		}
 
 		var req struct {
-			UserID     string `json:"user_id" validate:"required"`
-			Permission string `json:"permission" validate:"required"`
+			UserID     string     `json:"user_id"`
+			Email      string     `json:"email"` // Hesabı olmayan kullanıcılar için davet
+			Permission string     `json:"permission" validate:"required"`
+			ExpiresIn  string     `json:"expires_in"` // Süreli erişim: "72h", "30m", "7d"
+			ExpiresAt  *time.Time `json:"expires_at"` // veya mutlak bitiş zamanı (RFC3339)
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -430,6 +450,24 @@ func UpdateAccessPermission() fiber.Handler {
 			})
 		}
 
+		// Rol doğrulama - "none" erişimi kaldırır, eski read/write değerleri viewer/editor'a çevrilir
+		if req.Permission != "none" {
+			role := helpers.NormalizeRole(req.Permission)
+			if role == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid permission: must be one of viewer, commenter, editor, manager",
+				})
+			}
+			req.Permission = role
+		}
+
+		expiresAt, err := parseAccessExpiry(req.ExpiresIn, req.ExpiresAt)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
 		resourceID := c.Params("resourceId")
 		resourceOID, err := primitive.ObjectIDFromHex(resourceID)
 		if err != nil {
@@ -438,10 +476,18 @@ func UpdateAccessPermission() fiber.Handler {
 			})
 		}
 
+		if req.UserID == "" && req.Email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "user_id or email is required",
+			})
+		}
+
 		// Check if user can share this resource (requires write access or owner)
+		resourceType := "file"
 		canShare, err := helpers.CanUserShare(userID, "file", resourceID)
 		if err != nil || !canShare {
 			// Try folder
+			resourceType = "folder"
 			canShare, err = helpers.CanUserShare(userID, "folder", resourceID)
 			if err != nil || !canShare {
 				fmt.Printf("DEBUG: User %s cannot share this resource\n", userID)
@@ -451,6 +497,46 @@ func UpdateAccessPermission() fiber.Handler {
 			}
 		}
 
+		// E-posta ile paylaşım: hesap varsa doğrudan erişim ver, yoksa bekleyen davet oluştur
+		if req.UserID == "" {
+			existingUser, err := services.UserServiceInstance.GetUserByEmail(req.Email)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("Error looking up user by email: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to look up user",
+				})
+			}
+			if err != nil {
+				if req.Permission == "none" {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+						"error": "Invalid permission for invitation",
+					})
+				}
+
+				invitation, err := services.InvitationServiceInstance.CreateInvitation(resourceOID, resourceType, req.Email, req.Permission, userID, expiresAt)
+				if err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+						"error": err.Error(),
+					})
+				}
+
+				recordAudit(c, userID, "share.invite", resourceType, resourceID, resourceOwnerID(resourceType, resourceID), nil, fiber.Map{
+					"email":      invitation.Email,
+					"permission": invitation.AccessType,
+					"expires_at": invitation.ExpiresAt,
+				})
+
+				return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+					"message":    "Invitation sent, access will be granted on first sign in",
+					"invitation": invitation,
+				})
+			}
+			req.UserID = existingUser.ID.Hex()
+		}
+
+		// Audit kaydı için güncelleme öncesi erişimi oku
+		before := accessAuditSnapshot(resourceType, resourceID, req.UserID)
+
 		// Initialize variables
 		var updateResult *mongo.UpdateResult
 		var accessEntry models.AccessEntry
@@ -469,6 +555,7 @@ func UpdateAccessPermission() fiber.Handler {
 					"access_list.$.access_type": req.Permission,
 					"access_list.$.granted_at":  time.Now(),
 					"access_list.$.granted_by":  userID,
+					"access_list.$.expires_at":  expiresAt,
 					"updated_at":                time.Now(),
 				},
 			},
@@ -485,6 +572,7 @@ func UpdateAccessPermission() fiber.Handler {
 				AccessType: req.Permission,
 				GrantedAt:  time.Now(),
 				GrantedBy:  userID,
+				ExpiresAt:  expiresAt,
 			}
 
 			// First, ensure access_list field exists (if null, set to empty array)
@@ -530,6 +618,7 @@ func UpdateAccessPermission() fiber.Handler {
 						"access_list.$.access_type": req.Permission,
 						"access_list.$.granted_at":  time.Now(),
 						"access_list.$.granted_by":  userID,
+						"access_list.$.expires_at":  expiresAt,
 						"updated_at":                time.Now(),
 					},
 				},
@@ -586,12 +675,18 @@ func UpdateAccessPermission() fiber.Handler {
 		// Hiyerarşik propagation - tüm alt öğeleri güncelle
 		if req.Permission != "none" {
 			// Yeni erişim ekleme veya güncelleme - tüm alt öğeleri de güncelle
-			err = helpers.PropagateAccessToChildren(resourceOID, req.UserID, req.Permission, userID)
+			err = helpers.PropagateAccessToChildrenWithExpiry(resourceOID, req.UserID, req.Permission, userID, expiresAt)
 			if err != nil {
 				// Propagation başarısız olsa da ana işlem başarılı, devam et
 			}
 		}
 
+		recordAudit(c, userID, "share.update", resourceType, resourceID, resourceOwnerID(resourceType, resourceID), before, fiber.Map{
+			"user_id":    req.UserID,
+			"permission": req.Permission,
+			"expires_at": expiresAt,
+		})
+
 		return c.JSON(fiber.Map{
 			"message": "Access permission updated successfully",
 		})
@@ -683,12 +778,60 @@ func RemoveUserAccess() fiber.Handler {
 			// Propagation başarısız olsa da ana işlem başarılı, devam et
 		}
 
+		resourceType := "file"
+		ownerID := resourceOwnerID("file", resourceID)
+		if ownerID == "" {
+			resourceType = "folder"
+			ownerID = resourceOwnerID("folder", resourceID)
+		}
+		recordAudit(c, userID, "share.remove", resourceType, resourceID, ownerID, fiber.Map{"user_id": userIDToRemove}, nil)
+
 		return c.JSON(fiber.Map{
 			"message": "User access removed successfully",
 		})
 	}
 }
 
+// CancelInvitation - Bekleyen e-posta davetini iptal et
+func CancelInvitation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		invitation, err := services.InvitationServiceInstance.GetInvitationByID(c.Params("invitationId"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Invitation not found",
+			})
+		}
+
+		canShare, err := helpers.CanUserShare(userID, invitation.ResourceType, invitation.ResourceID.Hex())
+		if err != nil || !canShare {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "You don't have permission to modify access for this resource",
+			})
+		}
+
+		if err := services.InvitationServiceInstance.DeleteInvitation(invitation.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		recordAudit(c, userID, "share.invitation_cancel", invitation.ResourceType, invitation.ResourceID.Hex(),
+			resourceOwnerID(invitation.ResourceType, invitation.ResourceID.Hex()),
+			fiber.Map{"email": invitation.Email, "permission": invitation.AccessType}, nil)
+
+		return c.JSON(fiber.Map{
+			"message": "Invitation cancelled successfully",
+		})
+	}
+}
+
 func SearchUsers() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID, err := helpers.GetCurrentUserID(c)
@@ -740,14 +883,79 @@ func SearchUsers() fiber.Handler {
 	}
 }
 
+// parseAccessExpiry - expires_in (süre) veya expires_at (mutlak zaman) değerinden bitiş zamanı üretir.
+// İkisi de boşsa erişim süresizdir (nil).
+func parseAccessExpiry(expiresIn string, expiresAt *time.Time) (*time.Time, error) {
+	if expiresIn != "" && expiresAt != nil {
+		return nil, fmt.Errorf("expires_in and expires_at cannot be used together")
+	}
+
+	if expiresIn != "" {
+		var duration time.Duration
+		if strings.HasSuffix(expiresIn, "d") {
+			days, err := strconv.Atoi(strings.TrimSuffix(expiresIn, "d"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid expires_in: %s", expiresIn)
+			}
+			duration = time.Duration(days) * 24 * time.Hour
+		} else {
+			parsed, err := time.ParseDuration(expiresIn)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expires_in: %s", expiresIn)
+			}
+			duration = parsed
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("expires_in must be positive")
+		}
+		expiry := time.Now().Add(duration)
+		return &expiry, nil
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	return expiresAt, nil
+}
+
+// accessAuditSnapshot - Kullanıcının kaynaktaki mevcut erişimini audit kaydı için döndür, yoksa nil
+func accessAuditSnapshot(resourceType, resourceID, userID string) interface{} {
+	var accessList []models.AccessEntry
+	if resourceType == "file" {
+		file, err := services.FileServiceInstance.GetFileByID(resourceID)
+		if err != nil {
+			return nil
+		}
+		accessList = file.AccessList
+	} else {
+		folder, err := services.FolderServiceInstance.GetFolderByID(resourceID)
+		if err != nil {
+			return nil
+		}
+		accessList = folder.AccessList
+	}
+
+	for _, access := range accessList {
+		if access.UserID == userID {
+			return fiber.Map{
+				"user_id":    access.UserID,
+				"permission": access.AccessType,
+				"expires_at": access.ExpiresAt,
+			}
+		}
+	}
+	return nil
+}
+
 // Helper function to get access type from access list
 func getAccessTypeFromList(accessList []models.AccessEntry, userID string) string {
 	for _, access := range accessList {
-		if access.UserID == userID {
-			return access.AccessType
+		if access.UserID == userID && helpers.IsAccessEntryActive(access) {
+			return helpers.NormalizeRole(access.AccessType)
 		}
 	}
-	return "read" // default
+	return helpers.RoleViewer // default
 }
 
 // GetResourceByPublicLink - Public link ile resource'a erişim sağla ve kullanıcıyı otomatik ekle
@@ -776,7 +984,7 @@ func GetResourceByPublicLink() fiber.Handler {
 			// File bulundu, kullanıcıyı access list'e ekle
 			accessEntry := models.AccessEntry{
 				UserID:     userID,
-				AccessType: "read",
+				AccessType: helpers.RoleViewer,
 				GrantedAt:  time.Now(),
 				GrantedBy:  file.UserID,
 			}
@@ -801,6 +1009,7 @@ func GetResourceByPublicLink() fiber.Handler {
 						"error": "Kullanıcı erişim listesine eklenemedi",
 					})
 				}
+				recordAudit(c, userID, "share.public_link_join", "file", file.ID.Hex(), file.UserID, nil, accessEntry)
 			}
 
 			return c.JSON(fiber.Map{
@@ -830,7 +1039,7 @@ func GetResourceByPublicLink() fiber.Handler {
 		// Folder bulundu, kullanıcıyı access list'e ekle
 		accessEntry := models.AccessEntry{
 			UserID:     userID,
-			AccessType: "read",
+			AccessType: helpers.RoleViewer,
 			GrantedAt:  time.Now(),
 			GrantedBy:  folder.UserID,
 		}
@@ -855,6 +1064,7 @@ func GetResourceByPublicLink() fiber.Handler {
 					"error": "Kullanıcı erişim listesine eklenemedi",
 				})
 			}
+			recordAudit(c, userID, "share.public_link_join", "folder", folder.ID.Hex(), folder.UserID, nil, accessEntry)
 		}
 
 		return c.JSON(fiber.Map{
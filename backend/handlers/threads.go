@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"nimbus-backend/config"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+	"nimbus-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxThreadTitleRunes - Elle verilen sohbet başlığı için üst sınır
+const maxThreadTitleRunes = 200
+
+// parseMessageLimit - Sayfa başına mesaj sayısı (varsayılan 50, en fazla 200)
+func parseMessageLimit(c *fiber.Ctx) int64 {
+	limit, _ := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return limit
+}
+
+// validThreadTitle - Başlık boş olmamalı ve sınırı aşmamalı
+func validThreadTitle(title string) bool {
+	title = strings.TrimSpace(title)
+	return title != "" && utf8.RuneCountInString(title) <= maxThreadTitleRunes
+}
+
+// threadErrorResponse - Sohbet servis hatasını HTTP cevabına çevir
+func threadErrorResponse(c *fiber.Ctx, err error, action string) error {
+	if errors.Is(err, services.ErrThreadNotFound) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Sohbet bulunamadı",
+		})
+	}
+	log.Printf("Sohbet %s hatası: %v", action, err)
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Sohbet işlemi başarısız",
+	})
+}
+
+// ListThreads - Bir kapsamdaki (file_id, file_ids, folder_id veya scope=all) sohbetleri listele, sabitlenenler önce
+func ListThreads(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+
+		scope, ok := scopeFromQuery(c)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "file_id (veya file_ids, folder_id, scope=all) gerekli",
+			})
+		}
+
+		hasAccess, err := services.QueryScopeServiceInstance.Authorize(userID, scope)
+		if err != nil || !hasAccess {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Bu kapsama erişim yetkiniz yok",
+			})
+		}
+
+		threads, err := services.ConversationServiceInstance.ListThreads(userID, scope)
+		if err != nil {
+			return threadErrorResponse(c, err, "listeleme")
+		}
+
+		return c.JSON(fiber.Map{
+			"threads": threads,
+			"count":   len(threads),
+		})
+	}
+}
+
+// CreateThread - Kapsam için yeni bir sohbet başlat; başlık verilmezse ilk sorudan üretilir
+func CreateThread(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+
+		var req models.CreateThreadRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Geçersiz istek verisi",
+			})
+		}
+
+		scope, ok := resolveQueryScope(req.FileID, req.FileIDs, req.FolderID, req.Scope)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "file_id (veya file_ids, folder_id, scope=all) gerekli",
+			})
+		}
+		if req.Title != "" && !validThreadTitle(req.Title) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Başlık en fazla 200 karakter olabilir",
+			})
+		}
+
+		hasAccess, err := services.QueryScopeServiceInstance.Authorize(userID, scope)
+		if err != nil || !hasAccess {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Bu kapsama erişim yetkiniz yok",
+			})
+		}
+
+		thread, err := services.ConversationServiceInstance.CreateThread(userID, scope, req.Title)
+		if err != nil {
+			return threadErrorResponse(c, err, "oluşturma")
+		}
+
+		return c.Status(201).JSON(thread)
+	}
+}
+
+// UpdateThread - Sohbeti yeniden adlandır ve/veya sabitle
+func UpdateThread(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+
+		var req models.UpdateThreadRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Geçersiz istek verisi",
+			})
+		}
+		if req.Title != nil && !validThreadTitle(*req.Title) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Başlık boş olamaz ve en fazla 200 karakter olabilir",
+			})
+		}
+
+		thread, err := services.ConversationServiceInstance.UpdateThread(userID, c.Params("id"), req)
+		if err != nil {
+			return threadErrorResponse(c, err, "güncelleme")
+		}
+
+		return c.JSON(thread)
+	}
+}
+
+// DeleteThread - Sohbeti mesajlarıyla birlikte sil
+func DeleteThread(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+
+		if err := services.ConversationServiceInstance.DeleteThread(userID, c.Params("id")); err != nil {
+			return threadErrorResponse(c, err, "silme")
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Sohbet silindi",
+		})
+	}
+}
+
+// GetThreadMessages - Sohbet mesajlarını sayfalı getir. page=1 en yeni mesajları içerir,
+// her sayfa eskiden yeniye sıralıdır.
+func GetThreadMessages(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Yetkisiz erişim",
+			})
+		}
+
+		thread, err := services.ConversationServiceInstance.GetThread(userID, c.Params("id"))
+		if err != nil {
+			return threadErrorResponse(c, err, "getirme")
+		}
+
+		// Mesajlar kapsamdaki dosyalardan alıntı içerir; erişimi sonradan kaldırılan kullanıcı okuyamasın
+		hasAccess, err := services.QueryScopeServiceInstance.Authorize(userID, thread.ThreadScope())
+		if err != nil || !hasAccess {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Bu kapsama erişim yetkiniz yok",
+			})
+		}
+
+		page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+		if page < 1 {
+			page = 1
+		}
+		limit := parseMessageLimit(c)
+
+		messages, total, err := services.ConversationServiceInstance.GetMessages(thread.ID, page, limit)
+		if err != nil {
+			return threadErrorResponse(c, err, "mesaj getirme")
+		}
+
+		return c.JSON(fiber.Map{
+			"messages": messages,
+			"total":    total,
+			"page":     page,
+			"limit":    limit,
+			"has_more": page*limit < total,
+		})
+	}
+}
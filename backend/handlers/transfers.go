@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"log"
+	"nimbus-backend/config"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+	"nimbus-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateOwnershipTransfer - Sahiplik devri daveti oluştur
+func CreateOwnershipTransfer(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var req models.CreateTransferRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Geçersiz istek verisi",
+			})
+		}
+
+		if req.ResourceID == "" || req.ResourceType == "" || req.ToUserID == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "resource_id, resource_type ve to_user_id gerekli",
+			})
+		}
+
+		transfer, err := services.OwnershipTransferServiceInstance.CreateTransfer(userID, req)
+		if err != nil {
+			log.Printf("Sahiplik devri oluşturma hatası: %v", err)
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		recordAudit(c, userID, "transfer.create", transfer.ResourceType, transfer.ResourceID.Hex(), transfer.FromUserID, nil, fiber.Map{
+			"transfer_id": transfer.ID.Hex(),
+			"to_user_id":  transfer.ToUserID,
+		})
+
+		return c.Status(201).JSON(fiber.Map{
+			"message":  "Sahiplik devri daveti gönderildi",
+			"transfer": formatTransferResponse(transfer),
+		})
+	}
+}
+
+// GetIncomingTransfers - Kullanıcıya gelen bekleyen devir davetleri
+func GetIncomingTransfers(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		transfers, err := services.OwnershipTransferServiceInstance.GetIncomingTransfers(userID)
+		if err != nil {
+			log.Printf("Gelen devir davetleri alma hatası: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Devir davetleri listelenemedi",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"transfers": formatTransferList(transfers),
+			"count":     len(transfers),
+		})
+	}
+}
+
+// GetOutgoingTransfers - Kullanıcının gönderdiği bekleyen devir davetleri
+func GetOutgoingTransfers(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		transfers, err := services.OwnershipTransferServiceInstance.GetOutgoingTransfers(userID)
+		if err != nil {
+			log.Printf("Giden devir davetleri alma hatası: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Devir davetleri listelenemedi",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"transfers": formatTransferList(transfers),
+			"count":     len(transfers),
+		})
+	}
+}
+
+// AcceptOwnershipTransfer - Daveti kabul et ve sahipliği devral
+func AcceptOwnershipTransfer(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		transfer, err := services.OwnershipTransferServiceInstance.AcceptTransfer(c.Params("id"), userID)
+		if err != nil {
+			log.Printf("Sahiplik devri kabul hatası: %v", err)
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		recordAudit(c, userID, "transfer.accept", transfer.ResourceType, transfer.ResourceID.Hex(), transfer.FromUserID,
+			fiber.Map{"user_id": transfer.FromUserID}, fiber.Map{"user_id": transfer.ToUserID, "transfer_id": transfer.ID.Hex()})
+
+		return c.JSON(fiber.Map{
+			"message":  "Sahiplik devralındı",
+			"transfer": formatTransferResponse(transfer),
+		})
+	}
+}
+
+// DeclineOwnershipTransfer - Daveti reddet
+func DeclineOwnershipTransfer(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		transfer, err := services.OwnershipTransferServiceInstance.DeclineTransfer(c.Params("id"), userID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		recordAudit(c, userID, "transfer.decline", transfer.ResourceType, transfer.ResourceID.Hex(), transfer.FromUserID,
+			nil, fiber.Map{"transfer_id": transfer.ID.Hex()})
+
+		return c.JSON(fiber.Map{
+			"message":  "Sahiplik devri reddedildi",
+			"transfer": formatTransferResponse(transfer),
+		})
+	}
+}
+
+// CancelOwnershipTransfer - Gönderilen daveti iptal et
+func CancelOwnershipTransfer(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := helpers.GetCurrentUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		transfer, err := services.OwnershipTransferServiceInstance.CancelTransfer(c.Params("id"), userID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		recordAudit(c, userID, "transfer.cancel", transfer.ResourceType, transfer.ResourceID.Hex(), transfer.FromUserID,
+			nil, fiber.Map{"transfer_id": transfer.ID.Hex()})
+
+		return c.JSON(fiber.Map{
+			"message":  "Sahiplik devri iptal edildi",
+			"transfer": formatTransferResponse(transfer),
+		})
+	}
+}
+
+func formatTransferResponse(transfer *models.OwnershipTransfer) models.OwnershipTransferResponse {
+	return models.OwnershipTransferResponse{
+		OwnershipTransfer: *transfer,
+		FromUser:          services.UserServiceInstance.GetUserResponse(transfer.FromUserID),
+		ToUser:            services.UserServiceInstance.GetUserResponse(transfer.ToUserID),
+	}
+}
+
+func formatTransferList(transfers []models.OwnershipTransfer) []models.OwnershipTransferResponse {
+	result := make([]models.OwnershipTransferResponse, 0, len(transfers))
+	for i := range transfers {
+		result = append(result, formatTransferResponse(&transfers[i]))
+	}
+	return result
+}
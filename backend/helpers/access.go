@@ -3,6 +3,8 @@ package helpers
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"nimbus-backend/database"
 
@@ -14,12 +16,94 @@ import (
 type AccessLevel string
 
 const (
-	AccessLevelNone  AccessLevel = "none"
-	AccessLevelRead  AccessLevel = "read"
-	AccessLevelWrite AccessLevel = "write"
-	AccessLevelOwner AccessLevel = "owner"
+	AccessLevelNone    AccessLevel = "none"
+	AccessLevelRead    AccessLevel = "read"
+	AccessLevelComment AccessLevel = "comment"
+	AccessLevelWrite   AccessLevel = "write"
+	AccessLevelManage  AccessLevel = "manage" // can reshare
+	AccessLevelOwner   AccessLevel = "owner"
 )
 
+// Roles stored in AccessEntry.AccessType
+const (
+	RoleViewer    = "viewer"
+	RoleCommenter = "commenter"
+	RoleEditor    = "editor"
+	RoleManager   = "manager"
+)
+
+// accessLevelRank orders access levels from weakest to strongest
+var accessLevelRank = map[AccessLevel]int{
+	AccessLevelNone:    0,
+	AccessLevelRead:    1,
+	AccessLevelComment: 2,
+	AccessLevelWrite:   3,
+	AccessLevelManage:  4,
+	AccessLevelOwner:   5,
+}
+
+// NormalizeRole maps legacy "read"/"write" values to roles and lowercases input.
+// Returns an empty string for unknown roles.
+func NormalizeRole(role string) string {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case RoleViewer, "read":
+		return RoleViewer
+	case RoleCommenter:
+		return RoleCommenter
+	case RoleEditor, "write":
+		return RoleEditor
+	case RoleManager:
+		return RoleManager
+	}
+	return ""
+}
+
+// IsValidRole reports whether role is one of the supported roles (legacy aliases excluded)
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleViewer, RoleCommenter, RoleEditor, RoleManager:
+		return true
+	}
+	return false
+}
+
+// RoleToAccessLevel converts a stored role to the access level it grants
+func RoleToAccessLevel(role string) AccessLevel {
+	switch NormalizeRole(role) {
+	case RoleViewer:
+		return AccessLevelRead
+	case RoleCommenter:
+		return AccessLevelComment
+	case RoleEditor:
+		return AccessLevelWrite
+	case RoleManager:
+		return AccessLevelManage
+	}
+	return AccessLevelNone
+}
+
+// accessListEntry is the subset of models.AccessEntry needed for access checks
+type accessListEntry struct {
+	UserID     string     `bson:"user_id"`
+	AccessType string     `bson:"access_type"`
+	ExpiresAt  *time.Time `bson:"expires_at"`
+}
+
+// isActive reports whether the grant has no expiry or has not expired yet
+func (e accessListEntry) isActive() bool {
+	return e.ExpiresAt == nil || e.ExpiresAt.After(time.Now())
+}
+
+// roleSatisfies checks whether a stored role grants at least the required level.
+// Owner level can never be granted through an access entry.
+func roleSatisfies(role string, requiredLevel AccessLevel) bool {
+	if requiredLevel == AccessLevelOwner {
+		return false
+	}
+	level := RoleToAccessLevel(role)
+	return level != AccessLevelNone && accessLevelRank[level] >= accessLevelRank[requiredLevel]
+}
+
 // CanUserAccess checks if a user can access a resource with a specific access level
 func CanUserAccess(userID string, resourceType string, resourceID string, requiredLevel AccessLevel) (bool, error) {
 	resourceOID, err := primitive.ObjectIDFromHex(resourceID)
@@ -29,11 +113,8 @@ func CanUserAccess(userID string, resourceType string, resourceID string, requir
 
 	if resourceType == "file" {
 		var file struct {
-			UserID     string `bson:"user_id"`
-			AccessList []struct {
-				UserID     string `bson:"user_id"`
-				AccessType string `bson:"access_type"`
-			} `bson:"access_list"`
+			UserID     string            `bson:"user_id"`
+			AccessList []accessListEntry `bson:"access_list"`
 		}
 
 		err = database.FileCollection.FindOne(context.Background(), bson.M{
@@ -46,18 +127,13 @@ func CanUserAccess(userID string, resourceType string, resourceID string, requir
 
 		// Check if user is the owner
 		if file.UserID == userID {
-			return requiredLevel == AccessLevelOwner || requiredLevel == AccessLevelWrite || requiredLevel == AccessLevelRead, nil
+			return requiredLevel != AccessLevelNone, nil
 		}
 
 		// Check access list - doğrudan erişim kontrolü
 		for _, access := range file.AccessList {
-			if access.UserID == userID {
-				if requiredLevel == AccessLevelRead && (access.AccessType == "read" || access.AccessType == "write") {
-					return true, nil
-				}
-				if requiredLevel == AccessLevelWrite && access.AccessType == "write" {
-					return true, nil
-				}
+			if access.UserID == userID && access.isActive() && roleSatisfies(access.AccessType, requiredLevel) {
+				return true, nil
 			}
 		}
 
@@ -81,22 +157,14 @@ func CanUserAccess(userID string, resourceType string, resourceID string, requir
 				defer folderCursor.Close(context.Background())
 				for folderCursor.Next(context.Background()) {
 					var folder struct {
-						AccessList []struct {
-							UserID     string `bson:"user_id"`
-							AccessType string `bson:"access_type"`
-						} `bson:"access_list"`
+						AccessList []accessListEntry `bson:"access_list"`
 					}
 					if err := folderCursor.Decode(&folder); err != nil {
 						continue
 					}
 					for _, access := range folder.AccessList {
-						if access.UserID == userID {
-							if requiredLevel == AccessLevelRead && (access.AccessType == "read" || access.AccessType == "write") {
-								return true, nil
-							}
-							if requiredLevel == AccessLevelWrite && access.AccessType == "write" {
-								return true, nil
-							}
+						if access.UserID == userID && access.isActive() && roleSatisfies(access.AccessType, requiredLevel) {
+							return true, nil
 						}
 					}
 				}
@@ -107,11 +175,8 @@ func CanUserAccess(userID string, resourceType string, resourceID string, requir
 
 	} else if resourceType == "folder" {
 		var folder struct {
-			UserID     string `bson:"user_id"`
-			AccessList []struct {
-				UserID     string `bson:"user_id"`
-				AccessType string `bson:"access_type"`
-			} `bson:"access_list"`
+			UserID     string            `bson:"user_id"`
+			AccessList []accessListEntry `bson:"access_list"`
 		}
 
 		err = database.FolderCollection.FindOne(context.Background(), bson.M{
@@ -124,18 +189,13 @@ func CanUserAccess(userID string, resourceType string, resourceID string, requir
 
 		// Check if user is the owner
 		if folder.UserID == userID {
-			return requiredLevel == AccessLevelOwner || requiredLevel == AccessLevelWrite || requiredLevel == AccessLevelRead, nil
+			return requiredLevel != AccessLevelNone, nil
 		}
 
 		// Check access list - doğrudan erişim kontrolü
 		for _, access := range folder.AccessList {
-			if access.UserID == userID {
-				if requiredLevel == AccessLevelRead && (access.AccessType == "read" || access.AccessType == "write") {
-					return true, nil
-				}
-				if requiredLevel == AccessLevelWrite && access.AccessType == "write" {
-					return true, nil
-				}
+			if access.UserID == userID && access.isActive() && roleSatisfies(access.AccessType, requiredLevel) {
+				return true, nil
 			}
 		}
 
@@ -159,22 +219,14 @@ func CanUserAccess(userID string, resourceType string, resourceID string, requir
 				defer folderCursor.Close(context.Background())
 				for folderCursor.Next(context.Background()) {
 					var parentFolder struct {
-						AccessList []struct {
-							UserID     string `bson:"user_id"`
-							AccessType string `bson:"access_type"`
-						} `bson:"access_list"`
+						AccessList []accessListEntry `bson:"access_list"`
 					}
 					if err := folderCursor.Decode(&parentFolder); err != nil {
 						continue
 					}
 					for _, access := range parentFolder.AccessList {
-						if access.UserID == userID {
-							if requiredLevel == AccessLevelRead && (access.AccessType == "read" || access.AccessType == "write") {
-								return true, nil
-							}
-							if requiredLevel == AccessLevelWrite && access.AccessType == "write" {
-								return true, nil
-							}
+						if access.UserID == userID && access.isActive() && roleSatisfies(access.AccessType, requiredLevel) {
+							return true, nil
 						}
 					}
 				}
@@ -187,9 +239,9 @@ func CanUserAccess(userID string, resourceType string, resourceID string, requir
 	return false, errors.New("invalid resource type")
 }
 
-// CanUserShare checks if a user can share a resource (requires write access)
+// CanUserShare checks if a user can share a resource (requires manager role or owner)
 func CanUserShare(userID string, resourceType string, resourceID string) (bool, error) {
-	return CanUserAccess(userID, resourceType, resourceID, AccessLevelWrite)
+	return CanUserAccess(userID, resourceType, resourceID, AccessLevelManage)
 }
 
 // CheckFileAccessWithOwnerFallback checks file access with owner fallback
@@ -219,11 +271,8 @@ func GetUserAccessLevel(userID string, resourceType string, resourceID string) (
 
 	if resourceType == "file" {
 		var file struct {
-			UserID     string `bson:"user_id"`
-			AccessList []struct {
-				UserID     string `bson:"user_id"`
-				AccessType string `bson:"access_type"`
-			} `bson:"access_list"`
+			UserID     string            `bson:"user_id"`
+			AccessList []accessListEntry `bson:"access_list"`
 		}
 
 		err = database.FileCollection.FindOne(context.Background(), bson.M{
@@ -241,12 +290,8 @@ func GetUserAccessLevel(userID string, resourceType string, resourceID string) (
 
 		// Check access list
 		for _, access := range file.AccessList {
-			if access.UserID == userID {
-				if access.AccessType == "write" {
-					return AccessLevelWrite, nil
-				} else if access.AccessType == "read" {
-					return AccessLevelRead, nil
-				}
+			if access.UserID == userID && access.isActive() {
+				return RoleToAccessLevel(access.AccessType), nil
 			}
 		}
 
@@ -254,11 +299,8 @@ func GetUserAccessLevel(userID string, resourceType string, resourceID string) (
 
 	} else if resourceType == "folder" {
 		var folder struct {
-			UserID     string `bson:"user_id"`
-			AccessList []struct {
-				UserID     string `bson:"user_id"`
-				AccessType string `bson:"access_type"`
-			} `bson:"access_list"`
+			UserID     string            `bson:"user_id"`
+			AccessList []accessListEntry `bson:"access_list"`
 		}
 
 		err = database.FolderCollection.FindOne(context.Background(), bson.M{
@@ -276,12 +318,8 @@ func GetUserAccessLevel(userID string, resourceType string, resourceID string) (
 
 		// Check access list
 		for _, access := range folder.AccessList {
-			if access.UserID == userID {
-				if access.AccessType == "write" {
-					return AccessLevelWrite, nil
-				} else if access.AccessType == "read" {
-					return AccessLevelRead, nil
-				}
+			if access.UserID == userID && access.isActive() {
+				return RoleToAccessLevel(access.AccessType), nil
 			}
 		}
 
@@ -118,16 +118,10 @@ func MergeAccessLevels(accessEntries []models.AccessEntry) string {
 		return "none"
 	}
 
-	priority := map[string]int{
-		"none":  0,
-		"read":  1,
-		"write": 2,
-	}
-
 	maxLevel := "none"
 	for _, entry := range accessEntries {
-		if priority[entry.AccessType] > priority[maxLevel] {
-			maxLevel = entry.AccessType
+		if accessLevelRank[RoleToAccessLevel(entry.AccessType)] > accessLevelRank[RoleToAccessLevel(maxLevel)] {
+			maxLevel = NormalizeRole(entry.AccessType)
 		}
 	}
 
@@ -317,6 +311,13 @@ func RemoveUserFromResourceAccess(resourceID primitive.ObjectID, userID string)
 
 // PropagateAccessToChildren - Ana klasör paylaşıldığında tüm alt öğeleri günceller
 func PropagateAccessToChildren(parentID primitive.ObjectID, userID, accessType string, grantedBy string) error {
+	return PropagateAccessToChildrenWithExpiry(parentID, userID, accessType, grantedBy, nil)
+}
+
+// PropagateAccessToChildrenWithExpiry - Alt öğelere süreli erişim yayar.
+// Aynı klasörden daha önce miras alınmış giriş varsa önce kaldırılır, böylece
+// rol veya süre değişikliği alt öğelerde tekrar eden kayıt bırakmaz.
+func PropagateAccessToChildrenWithExpiry(parentID primitive.ObjectID, userID, accessType string, grantedBy string, expiresAt *time.Time) error {
 	ctx := context.Background()
 
 	// Tüm alt öğeleri bul
@@ -325,61 +326,84 @@ func PropagateAccessToChildren(parentID primitive.ObjectID, userID, accessType s
 		return err
 	}
 
-	// Bulk operations için array oluştur
-	var operations []mongo.WriteModel
+	entry := models.AccessEntry{
+		UserID:        userID,
+		AccessType:    accessType,
+		GrantedAt:     time.Now(),
+		GrantedBy:     grantedBy,
+		InheritedFrom: &parentID,
+		ExpiresAt:     expiresAt,
+	}
+
+	buildOps := func(id primitive.ObjectID) []mongo.WriteModel {
+		return []mongo.WriteModel{
+			mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{"$pull": bson.M{"access_list": bson.M{"user_id": userID, "inherited_from": parentID}}}),
+			mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{
+					"$push": bson.M{"access_list": entry},
+					"$set":  bson.M{"updated_at": time.Now()},
+				}),
+		}
+	}
 
 	// Tüm alt klasörleri güncelle
+	var folderOps []mongo.WriteModel
 	for _, folder := range childrenFolders {
-		operations = append(operations, mongo.NewUpdateOneModel().
-			SetFilter(bson.M{"_id": folder.ID}).
-			SetUpdate(bson.M{
-				"$push": bson.M{"access_list": models.AccessEntry{
-					UserID:        userID,
-					AccessType:    accessType,
-					GrantedAt:     time.Now(),
-					GrantedBy:     grantedBy,
-					InheritedFrom: &parentID,
-				}},
-				"$set": bson.M{"updated_at": time.Now()},
-			}),
-		)
+		folderOps = append(folderOps, buildOps(folder.ID)...)
 	}
 
 	// Tüm alt dosyaları güncelle
+	var fileOps []mongo.WriteModel
 	for _, file := range childrenFiles {
-		operations = append(operations, mongo.NewUpdateOneModel().
-			SetFilter(bson.M{"_id": file.ID}).
-			SetUpdate(bson.M{
-				"$push": bson.M{"access_list": models.AccessEntry{
-					UserID:        userID,
-					AccessType:    accessType,
-					GrantedAt:     time.Now(),
-					GrantedBy:     grantedBy,
-					InheritedFrom: &parentID,
-				}},
-				"$set": bson.M{"updated_at": time.Now()},
-			}),
-		)
+		fileOps = append(fileOps, buildOps(file.ID)...)
 	}
 
-	// Bulk update yap
-	if len(operations) > 0 {
-		_, err = database.FolderCollection.BulkWrite(ctx, operations[:len(childrenFolders)])
-		if err != nil {
+	// Bulk update yap (sıralı - pull push'tan önce çalışmalı)
+	if len(folderOps) > 0 {
+		if _, err := database.FolderCollection.BulkWrite(ctx, folderOps); err != nil {
 			return err
 		}
-
-		if len(childrenFiles) > 0 {
-			_, err = database.FileCollection.BulkWrite(ctx, operations[len(childrenFolders):])
-			if err != nil {
-				return err
-			}
+	}
+	if len(fileOps) > 0 {
+		if _, err := database.FileCollection.BulkWrite(ctx, fileOps); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// IsAccessEntryActive - Giriş süresiz veya süresi henüz dolmamışsa true döner
+func IsAccessEntryActive(entry models.AccessEntry) bool {
+	return entry.ExpiresAt == nil || entry.ExpiresAt.After(time.Now())
+}
+
+// FilterActiveAccess - Süresi dolmuş girişleri listeden çıkarır
+func FilterActiveAccess(accessList []models.AccessEntry) []models.AccessEntry {
+	active := make([]models.AccessEntry, 0, len(accessList))
+	for _, entry := range accessList {
+		if IsAccessEntryActive(entry) {
+			active = append(active, entry)
+		}
+	}
+	return active
+}
+
+// ActiveAccessFilter - Kullanıcının süresi dolmamış bir access_list girişi olan kaynaklar için Mongo filtresi
+func ActiveAccessFilter(userID string) bson.M {
+	return bson.M{"access_list": bson.M{"$elemMatch": bson.M{
+		"user_id": userID,
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": nil},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}}}
+}
+
 // RemoveAccessFromChildren - Erişim kaldırıldığında tüm alt öğelerden de kaldırır
 func RemoveAccessFromChildren(parentID primitive.ObjectID, userID string) error {
 	ctx := context.Background()
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// FakeEmbeddingDim is the vector size of the fake embedder
+const FakeEmbeddingDim = 256
+
+// FakeEmbedder is a deterministic, offline embedder. It hashes the words of a text into a
+// fixed-size bag-of-words vector (L2-normalized), so texts sharing words end up close
+// together, which is enough for retrieval tests.
+type FakeEmbedder struct {
+	dim int
+}
+
+// NewFakeEmbedder creates a fake embedder producing vectors of size dim
+func NewFakeEmbedder(dim int) *FakeEmbedder {
+	if dim < 1 {
+		dim = FakeEmbeddingDim
+	}
+	return &FakeEmbedder{dim: dim}
+}
+
+// ModelID implements Embedder
+func (f *FakeEmbedder) ModelID() string {
+	return fmt.Sprintf("%s/bow-%d", ProviderFake, f.dim)
+}
+
+// Embed implements Embedder
+func (f *FakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i] = f.embed(text)
+	}
+	return embeddings, nil
+}
+
+func (f *FakeEmbedder) embed(text string) []float64 {
+	vector := make([]float64, f.dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		// The top bit picks the sign, so unrelated words cancel out instead of piling up
+		if sum>>63 == 0 {
+			vector[sum%uint64(f.dim)]++
+		} else {
+			vector[sum%uint64(f.dim)]--
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		vector[0] = 1 // Empty text still needs a valid unit vector
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// FakeChatModel is a deterministic, offline chat model. Reply computes the answer from the
+// prompt; streaming emits it word by word.
+type FakeChatModel struct {
+	Reply func(prompt string) string
+}
+
+// NewFakeChatModel creates a fake chat model. A nil reply answers with a fixed text that
+// identifies the prompt by hash.
+func NewFakeChatModel(reply func(prompt string) string) *FakeChatModel {
+	if reply == nil {
+		reply = func(prompt string) string {
+			h := fnv.New32a()
+			h.Write([]byte(prompt))
+			return fmt.Sprintf("Fake answer %08x [1]", h.Sum32())
+		}
+	}
+	return &FakeChatModel{Reply: reply}
+}
+
+// Generate implements ChatModel
+func (f *FakeChatModel) Generate(ctx context.Context, prompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.Reply(prompt), nil
+}
+
+// GenerateStream implements ChatModel
+func (f *FakeChatModel) GenerateStream(ctx context.Context, prompt string, onToken func(string) error) (string, error) {
+	answer := f.Reply(prompt)
+	var sent strings.Builder
+	for _, word := range strings.SplitAfter(answer, " ") {
+		if err := ctx.Err(); err != nil {
+			return sent.String(), err
+		}
+		if word == "" {
+			continue
+		}
+		sent.WriteString(word)
+		if err := onToken(word); err != nil {
+			return sent.String(), err
+		}
+	}
+	return answer, nil
+}
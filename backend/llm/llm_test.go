@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func TestFakeEmbedderIsDeterministicAndSimilarityAware(t *testing.T) {
+	embedder := NewFakeEmbedder(64)
+	texts := []string{"invoice total amount", "Invoice total amount!", "mountain hiking trail"}
+
+	first, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := embedder.Embed(context.Background(), texts)
+	for i := range first {
+		for j := range first[i] {
+			if first[i][j] != second[i][j] {
+				t.Fatalf("embedding %d differs between runs", i)
+			}
+		}
+	}
+
+	if got := cosine(first[0], first[1]); math.Abs(got-1) > 1e-9 {
+		t.Errorf("case and punctuation should not matter, cosine = %f", got)
+	}
+	if cosine(first[0], first[2]) >= cosine(first[0], first[1]) {
+		t.Error("unrelated text should be further away than the same words")
+	}
+	if embedder.ModelID() != "fake/bow-64" {
+		t.Errorf("unexpected model id %q", embedder.ModelID())
+	}
+}
+
+func TestFakeChatModelStreamsTheFullReply(t *testing.T) {
+	chat := NewFakeChatModel(func(prompt string) string { return "echo: " + prompt })
+
+	var tokens []string
+	answer, err := chat.GenerateStream(context.Background(), "hello world", func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "echo: hello world" || strings.Join(tokens, "") != answer {
+		t.Errorf("answer %q, tokens %q", answer, tokens)
+	}
+}
+
+func TestOpenAIEmbedOrdersByIndexAndSendsKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("missing bearer token")
+		}
+		var req openAIEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "bge-small" || len(req.Input) != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		// Out of order on purpose
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenAI(server.URL+"/v1/", "secret", "bge-small", "")
+	embeddings, err := client.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embeddings[0][0] != 1 || embeddings[1][1] != 1 {
+		t.Errorf("embeddings not ordered by index: %v", embeddings)
+	}
+	if client.ModelID() != "openai/bge-small" {
+		t.Errorf("unexpected model id %q", client.ModelID())
+	}
+}
+
+func TestOpenAIGenerateStreamParsesServerSentEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream || req.Messages[0].Content != "question" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewOpenAI(server.URL, "", "", "llama")
+	var tokens []string
+	answer, err := client.GenerateStream(context.Background(), "question", func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "Hello" || len(tokens) != 2 {
+		t.Errorf("answer %q, tokens %q", answer, tokens)
+	}
+}
+
+func TestOpenAIGenerateStreamFailsWithoutDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+	}))
+	defer server.Close()
+
+	answer, err := NewOpenAI(server.URL, "", "", "llama").GenerateStream(context.Background(), "q", func(string) error { return nil })
+	if err == nil || answer != "partial" {
+		t.Errorf("expected truncated stream error with partial answer, got %q, %v", answer, err)
+	}
+}
@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Ollama talks to an Ollama server; it implements both Embedder and ChatModel
+type Ollama struct {
+	baseURL    string
+	embedModel string
+	chatModel  string
+	httpClient *http.Client
+}
+
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+type ollamaGenerateRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream"`
+}
+
+type ollamaGenerateResponse struct {
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+}
+
+// NewOllama creates a client for the Ollama server at baseURL
+func NewOllama(baseURL, embedModel, chatModel string) *Ollama {
+	return &Ollama{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		embedModel: embedModel,
+		chatModel:  chatModel,
+		httpClient: defaultHTTPClient(),
+	}
+}
+
+// ModelID implements Embedder
+func (o *Ollama) ModelID() string {
+	return ProviderOllama + "/" + o.embedModel
+}
+
+// Embed embeds several texts in one round-trip using /api/embed's input array. Queries and
+// documents both go through it, so their vectors are produced (and normalized) the same way.
+func (o *Ollama) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	var embResp ollamaEmbedResponse
+	if err := o.post(ctx, "/api/embed", ollamaEmbedRequest{Model: o.embedModel, Input: texts}, "embedding", func(body io.Reader) error {
+		if err := json.NewDecoder(body).Decode(&embResp); err != nil {
+			return fmt.Errorf("failed to decode embedding response: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(embResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(embResp.Embeddings), len(texts))
+	}
+	for i, embedding := range embResp.Embeddings {
+		if len(embedding) == 0 {
+			return nil, fmt.Errorf("received empty embedding from Ollama for input %d", i)
+		}
+	}
+	return embResp.Embeddings, nil
+}
+
+// Generate implements ChatModel using the non-streaming /api/generate
+func (o *Ollama) Generate(ctx context.Context, prompt string) (string, error) {
+	var genResp ollamaGenerateResponse
+	if err := o.post(ctx, "/api/generate", ollamaGenerateRequest{Model: o.chatModel, Prompt: prompt}, "generate", func(body io.Reader) error {
+		if err := json.NewDecoder(body).Decode(&genResp); err != nil {
+			return fmt.Errorf("failed to decode generate response: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	return genResp.Response, nil
+}
+
+// GenerateStream implements ChatModel. Ollama streams NDJSON, one response object per line;
+// cancelling ctx (e.g. when the client disconnects) aborts the upstream request.
+func (o *Ollama) GenerateStream(ctx context.Context, prompt string, onToken func(string) error) (string, error) {
+	var answer strings.Builder
+	err := o.post(ctx, "/api/generate", ollamaGenerateRequest{Model: o.chatModel, Prompt: prompt, Stream: true}, "generate", func(body io.Reader) error {
+		decoder := json.NewDecoder(body)
+		for {
+			var genResp ollamaGenerateResponse
+			if err := decoder.Decode(&genResp); err != nil {
+				if err == io.EOF {
+					return fmt.Errorf("ollama stream ended before completion")
+				}
+				return fmt.Errorf("failed to decode generate stream: %w", err)
+			}
+
+			if genResp.Response != "" {
+				answer.WriteString(genResp.Response)
+				if err := onToken(genResp.Response); err != nil {
+					return err
+				}
+			}
+
+			if genResp.Done {
+				return nil
+			}
+		}
+	})
+	return answer.String(), err
+}
+
+// post sends a JSON request and hands the body of a 200 response to decode. kind names
+// the endpoint in error messages.
+func (o *Ollama) post(ctx context.Context, path string, payload interface{}, kind string, decode func(io.Reader) error) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", kind, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", kind, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call ollama %s api: %w", kind, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ollama %s api returned status %d: %s", kind, resp.StatusCode, string(bodyBytes))
+	}
+
+	return decode(resp.Body)
+}
@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// OpenAI talks to an OpenAI-compatible API (/embeddings and /chat/completions). baseURL
+// includes the version prefix, e.g. http://localhost:8000/v1 for vLLM or llama.cpp's server.
+type OpenAI struct {
+	baseURL    string
+	apiKey     string
+	embedModel string
+	chatModel  string
+	httpClient *http.Client
+}
+
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason *string       `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// NewOpenAI creates a client for an OpenAI-compatible server. apiKey may be empty for
+// local servers.
+func NewOpenAI(baseURL, apiKey, embedModel, chatModel string) *OpenAI {
+	return &OpenAI{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		embedModel: embedModel,
+		chatModel:  chatModel,
+		httpClient: defaultHTTPClient(),
+	}
+}
+
+// ModelID implements Embedder
+func (o *OpenAI) ModelID() string {
+	return ProviderOpenAI + "/" + o.embedModel
+}
+
+// Embed implements Embedder. The response may list vectors out of order, so they are
+// placed by their index.
+func (o *OpenAI) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	var embResp openAIEmbeddingResponse
+	if err := o.post(ctx, "/embeddings", openAIEmbeddingRequest{Model: o.embedModel, Input: texts}, "embedding", func(body io.Reader) error {
+		if err := json.NewDecoder(body).Decode(&embResp); err != nil {
+			return fmt.Errorf("failed to decode embedding response: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("openai api returned %d embeddings for %d inputs", len(embResp.Data), len(texts))
+	}
+	sort.SliceStable(embResp.Data, func(i, j int) bool { return embResp.Data[i].Index < embResp.Data[j].Index })
+
+	embeddings := make([][]float64, len(texts))
+	for i, item := range embResp.Data {
+		if len(item.Embedding) == 0 {
+			return nil, fmt.Errorf("received empty embedding for input %d", i)
+		}
+		embeddings[i] = item.Embedding
+	}
+	return embeddings, nil
+}
+
+// Generate implements ChatModel. The prompt is sent as a single user message.
+func (o *OpenAI) Generate(ctx context.Context, prompt string) (string, error) {
+	var chatResp openAIChatResponse
+	if err := o.post(ctx, "/chat/completions", o.chatRequest(prompt, false), "chat", func(body io.Reader) error {
+		if err := json.NewDecoder(body).Decode(&chatResp); err != nil {
+			return fmt.Errorf("failed to decode chat response: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("openai api returned no choices")
+	}
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// GenerateStream implements ChatModel. The server sends server-sent events with one
+// "data: {...}" chunk per delta and "data: [DONE]" at the end.
+func (o *OpenAI) GenerateStream(ctx context.Context, prompt string, onToken func(string) error) (string, error) {
+	var answer strings.Builder
+	err := o.post(ctx, "/chat/completions", o.chatRequest(prompt, true), "chat", func(body io.Reader) error {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue // Blank separators, comments and event names
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				return nil
+			}
+
+			var chunk openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				return fmt.Errorf("failed to decode chat stream: %w", err)
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			if delta := chunk.Choices[0].Delta.Content; delta != "" {
+				answer.WriteString(delta)
+				if err := onToken(delta); err != nil {
+					return err
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read chat stream: %w", err)
+		}
+		return fmt.Errorf("openai stream ended before completion")
+	})
+	return answer.String(), err
+}
+
+func (o *OpenAI) chatRequest(prompt string, stream bool) openAIChatRequest {
+	return openAIChatRequest{
+		Model:    o.chatModel,
+		Messages: []openAIMessage{{Role: "user", Content: prompt}},
+		Stream:   stream,
+	}
+}
+
+// post sends a JSON request and hands the body of a 200 response to decode. kind names
+// the endpoint in error messages.
+func (o *OpenAI) post(ctx context.Context, path string, payload interface{}, kind string, decode func(io.Reader) error) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", kind, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", kind, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call openai %s api: %w", kind, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("openai %s api returned status %d: %s", kind, resp.StatusCode, string(bodyBytes))
+	}
+
+	return decode(resp.Body)
+}
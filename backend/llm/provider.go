@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"nimbus-backend/config"
+)
+
+// Provider names accepted by EMBED_PROVIDER and CHAT_PROVIDER
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai" // Any OpenAI-compatible server (llama.cpp, vLLM, LM Studio, ...)
+	ProviderFake   = "fake"   // Deterministic, offline; for tests and evaluation runs
+)
+
+// Embedder turns texts into vectors
+type Embedder interface {
+	// Embed returns one vector per input text, in input order
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+	// ModelID identifies the vector space, e.g. "ollama/all-minilm:l6-v2". It is stored
+	// with every chunk so vectors of different models are never compared.
+	ModelID() string
+}
+
+// ChatModel generates answers from a prompt
+type ChatModel interface {
+	// Generate returns the full answer
+	Generate(ctx context.Context, prompt string) (string, error)
+	// GenerateStream calls onToken for every delta as it arrives and returns the full answer.
+	// An error from onToken aborts the generation.
+	GenerateStream(ctx context.Context, prompt string, onToken func(string) error) (string, error)
+}
+
+// defaultHTTPClient allows long-running generations
+func defaultHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 180 * time.Second, // 3 minutes for long-running operations
+	}
+}
+
+// NewEmbedder returns the embedder selected by cfg.EmbedProvider
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+	switch strings.ToLower(cfg.EmbedProvider) {
+	case "", ProviderOllama:
+		return NewOllama(cfg.OllamaBaseURL, cfg.OllamaEmbedModel, cfg.OllamaLLMModel), nil
+	case ProviderOpenAI:
+		if cfg.OpenAIEmbedModel == "" {
+			return nil, fmt.Errorf("OPENAI_EMBED_MODEL is required for the openai embedding provider")
+		}
+		return NewOpenAI(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.OpenAIEmbedModel, cfg.OpenAIChatModel), nil
+	case ProviderFake:
+		return NewFakeEmbedder(FakeEmbeddingDim), nil
+	}
+	return nil, fmt.Errorf("unknown embedding provider %q", cfg.EmbedProvider)
+}
+
+// NewChatModel returns the chat model selected by cfg.ChatProvider
+func NewChatModel(cfg *config.Config) (ChatModel, error) {
+	switch strings.ToLower(cfg.ChatProvider) {
+	case "", ProviderOllama:
+		return NewOllama(cfg.OllamaBaseURL, cfg.OllamaEmbedModel, cfg.OllamaLLMModel), nil
+	case ProviderOpenAI:
+		if cfg.OpenAIChatModel == "" {
+			return nil, fmt.Errorf("OPENAI_CHAT_MODEL is required for the openai chat provider")
+		}
+		return NewOpenAI(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.OpenAIEmbedModel, cfg.OpenAIChatModel), nil
+	case ProviderFake:
+		return NewFakeChatModel(nil), nil
+	}
+	return nil, fmt.Errorf("unknown chat provider %q", cfg.ChatProvider)
+}
@@ -6,6 +6,7 @@ import (
 	"nimbus-backend/database"
 	"nimbus-backend/routes"
 	"nimbus-backend/services"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -22,16 +23,69 @@ func main() {
 	}
 	defer database.Close()
 
+	// Eski read/write erişim kayıtlarını rollere dönüştür
+	if err := services.AccessControlServiceInstance.MigrateLegacyRoles(); err != nil {
+		log.Printf("⚠️ Rol migrasyonu başarısız: %v", err)
+	}
+
+	if err := services.AuditServiceInstance.EnsureIndexes(); err != nil {
+		log.Printf("⚠️ Audit index'leri oluşturulamadı: %v", err)
+	}
+
+	// Sohbet mesajlarını ayrı koleksiyona taşı (tek dokümanda 16MB sınırı)
+	if err := services.ConversationServiceInstance.MigrateEmbeddedMessages(); err != nil {
+		log.Printf("⚠️ Sohbet mesajı migrasyonu başarısız: %v", err)
+	}
+	if err := services.ConversationServiceInstance.EnsureIndexes(); err != nil {
+		log.Printf("⚠️ Sohbet index'leri oluşturulamadı: %v", err)
+	}
+
 	// MinIO bağlantısı
 	if err := services.InitMinIO(cfg); err != nil {
 		log.Fatal("❌ MinIO bağlantı hatası:", err)
 	}
 
+	// Embedding ve sohbet sağlayıcıları (EMBED_PROVIDER / CHAT_PROVIDER)
+	if err := services.InitAIProviders(cfg); err != nil {
+		log.Fatal("❌ AI sağlayıcı hatası:", err)
+	}
+
+	// Vektör deposu (VECTOR_STORE: chroma veya disk)
+	if err := services.InitVectorStore(cfg); err != nil {
+		log.Fatal("❌ Vektör deposu hatası:", err)
+	}
+	defer services.VectorStoreInstance.Close()
+
 	// Document processor initialization
 	if err := services.InitDocumentProcessor(cfg, database.FileCollection); err != nil {
 		log.Fatal("❌ Document processor başlatma hatası:", err)
 	}
 
+	// Kalıcı işleme kuyruğu (takılı "processing" kayıtlarını da kurtarır)
+	if err := services.InitJobQueue(cfg, services.DocumentProcessorInstance); err != nil {
+		log.Fatal("❌ İşleme kuyruğu başlatma hatası:", err)
+	}
+	if err := services.JobQueueInstance.EnsureIndexes(); err != nil {
+		log.Printf("⚠️ İş kuyruğu index'leri oluşturulamadı: %v", err)
+	}
+	services.JobQueueInstance.Start()
+
+	// Embedding modeli değiştiyse eski vektörlerle indekslenmiş dosyaları yeniden işle
+	if queued, err := services.DocumentProcessorInstance.ReindexOutdatedEmbeddings(); err != nil {
+		log.Printf("⚠️ Eski embedding'ler kontrol edilemedi: %v", err)
+	} else if queued > 0 {
+		log.Printf("🔄 Embedding modeli değişti, %d dosya yeniden indeksleniyor", queued)
+	}
+
+	// Mailer (paylaşım davetleri için)
+	if err := services.InitMailer(cfg); err != nil {
+		log.Fatal("❌ Mailer başlatma hatası:", err)
+	}
+	services.InvitationServiceInstance.FrontendURL = cfg.FrontendURL
+
+	// Süreli erişimleri temizleyen arka plan görevi
+	services.AccessExpiryServiceInstance.StartSweeper(time.Duration(cfg.AccessSweepInterval) * time.Minute)
+
 	// Fiber uygulaması oluşturma
 	app := fiber.New(fiber.Config{
 		ServerHeader: "Nimbus",
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEvent - Güvenlik açısından önemli bir işlemin değiştirilemez kaydı
+type AuditEvent struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Timestamp    time.Time          `json:"timestamp" bson:"timestamp"`
+	ActorID      string             `json:"actor_id" bson:"actor_id"`
+	Action       string             `json:"action" bson:"action"` // e.g. share.update, file.delete, auth.login
+	ResourceType string             `json:"resource_type,omitempty" bson:"resource_type,omitempty"`
+	ResourceID   string             `json:"resource_id,omitempty" bson:"resource_id,omitempty"`
+	OwnerID      string             `json:"owner_id,omitempty" bson:"owner_id,omitempty"` // Kaynağın işlem anındaki sahibi
+	IP           string             `json:"ip" bson:"ip"`
+	UserAgent    string             `json:"user_agent" bson:"user_agent"`
+	Before       interface{}        `json:"before,omitempty" bson:"before,omitempty"`
+	After        interface{}        `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditFilter - Audit sorgu filtreleri
+type AuditFilter struct {
+	ActorID      string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	OwnerID      string
+	From         *time.Time
+	To           *time.Time
+}
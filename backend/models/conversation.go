@@ -1,33 +1,109 @@
 package models
 
 import (
+	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Message struct {
-	Role      string    `json:"role" bson:"role"`                           // "user" or "assistant"
-	Content   string    `json:"content" bson:"content"`                     // Message text
-	Sources   []string  `json:"sources,omitempty" bson:"sources,omitempty"` // For assistant messages
-	Timestamp time.Time `json:"timestamp" bson:"timestamp"`                 // Message time
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`                          // Set once stored in the messages collection
+	ConversationID primitive.ObjectID `json:"conversation_id,omitempty" bson:"conversation_id,omitempty"` // Thread the message belongs to
+	Role           string             `json:"role" bson:"role"`                                           // "user" or "assistant"
+	Content        string             `json:"content" bson:"content"`                                     // Message text
+	Sources        []string           `json:"sources,omitempty" bson:"sources,omitempty"`                 // For assistant messages
+	Citations      []Citation         `json:"citations,omitempty" bson:"citations,omitempty"`             // Structured sources for assistant messages
+	Timestamp      time.Time          `json:"timestamp" bson:"timestamp"`                                 // Message time
+	Retrieval      *RetrievalInfo     `json:"retrieval,omitempty" bson:"retrieval,omitempty"`             // How an assistant answer was retrieved
+	Feedback       *MessageFeedback   `json:"feedback,omitempty" bson:"feedback,omitempty"`               // User rating of an assistant answer
 }
 
+// Citation maps a numbered [n] marker in an answer back to the chunk it came from
+type Citation struct {
+	Number     int     `json:"number" bson:"number"` // Marker number used in the prompt and answer
+	ChunkID    string  `json:"chunk_id" bson:"chunk_id"`
+	FileID     string  `json:"file_id" bson:"file_id"`
+	Filename   string  `json:"filename,omitempty" bson:"filename,omitempty"`
+	ChunkIndex int     `json:"chunk_index" bson:"chunk_index"`
+	StartChar  int     `json:"start_char" bson:"start_char"`
+	EndChar    int     `json:"end_char" bson:"end_char"`
+	PageStart  int     `json:"page_start,omitempty" bson:"page_start,omitempty"` // 0 = unknown
+	PageEnd    int     `json:"page_end,omitempty" bson:"page_end,omitempty"`
+	Symbol     string  `json:"symbol,omitempty" bson:"symbol,omitempty"` // Declaration name for source code chunks
+	LineStart  int     `json:"line_start,omitempty" bson:"line_start,omitempty"`
+	LineEnd    int     `json:"line_end,omitempty" bson:"line_end,omitempty"`
+	Score      float64 `json:"score" bson:"score"`     // Vector similarity or reranker score in [0, 1]; 0 for keyword-only matches
+	Preview    string  `json:"preview" bson:"preview"` // First 200 characters of the chunk
+	Cited      bool    `json:"cited" bson:"cited"`     // True if the answer references this number
+}
+
+// Conversation scope types
+const (
+	ScopeFile   = "file"   // Single file (legacy conversations, keyed by file_id)
+	ScopeFiles  = "files"  // Explicit multi-selection
+	ScopeFolder = "folder" // Every file under a folder
+	ScopeAll    = "all"    // Every document the user can read
+)
+
+// ConversationScope identifies what a conversation is about
+type ConversationScope struct {
+	Type    string   `json:"type" bson:"type"`
+	ID      string   `json:"id,omitempty" bson:"id,omitempty"`             // File or folder ID
+	FileIDs []string `json:"file_ids,omitempty" bson:"file_ids,omitempty"` // Only for "files"
+}
+
+// Conversation is a named chat thread. A scope can have any number of threads; their
+// messages live in the conversation_messages collection.
 type Conversation struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	FileID    string             `json:"file_id" bson:"file_id"`       // Associated file
-	UserID    string             `json:"user_id" bson:"user_id"`       // Owner
-	Messages  []Message          `json:"messages" bson:"messages"`     // Chat history
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"` // First message time
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"` // Last message time
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	FileID       string             `json:"file_id" bson:"file_id"`                         // Associated file (file scope only)
+	Scope        *ConversationScope `json:"scope,omitempty" bson:"scope,omitempty"`         // nil = single file
+	ScopeKey     string             `json:"scope_key,omitempty" bson:"scope_key,omitempty"` // Lookup key for non-file scopes
+	UserID       string             `json:"user_id" bson:"user_id"`                         // Owner
+	Title        string             `json:"title" bson:"title"`                             // Generated from the first question until renamed
+	Pinned       bool               `json:"pinned" bson:"pinned"`                           // Pinned threads are listed first
+	MessageCount int                `json:"message_count" bson:"message_count"`
+	Messages     []Message          `json:"-" bson:"messages,omitempty"`  // Legacy embedded history, moved out by MigrateEmbeddedMessages
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"` // Thread creation time
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"` // Last message time
+}
+
+// ThreadScope returns the scope a thread belongs to
+func (c *Conversation) ThreadScope() ConversationScope {
+	if c.Scope != nil {
+		return *c.Scope
+	}
+	return ConversationScope{Type: ScopeFile, ID: c.FileID}
 }
 
 type ConversationResponse struct {
-	ID        string    `json:"id"`
-	FileID    string    `json:"file_id"`
-	Messages  []Message `json:"messages"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           string             `json:"id"`
+	FileID       string             `json:"file_id"`
+	Scope        *ConversationScope `json:"scope,omitempty"`
+	Title        string             `json:"title"`
+	Pinned       bool               `json:"pinned"`
+	MessageCount int                `json:"message_count"`
+	Messages     []Message          `json:"messages"` // Latest page, oldest first
+	HasMore      bool               `json:"has_more"` // Older messages exist, see /ai/threads/:id/messages
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// CreateThreadRequest opens a new thread for a file, folder, multi-file selection or "all"
+type CreateThreadRequest struct {
+	FileID   string   `json:"file_id,omitempty"`
+	FileIDs  []string `json:"file_ids,omitempty"`
+	FolderID string   `json:"folder_id,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
+	Title    string   `json:"title,omitempty"` // Empty = generated from the first question
+}
+
+// UpdateThreadRequest renames and/or pins a thread; omitted fields are left unchanged
+type UpdateThreadRequest struct {
+	Title  *string `json:"title,omitempty"`
+	Pinned *bool   `json:"pinned,omitempty"`
 }
 
 type AddMessageRequest struct {
@@ -47,10 +123,29 @@ type FileInfo struct {
 
 // ConversationWithFile contains conversation with file information
 type ConversationWithFile struct {
-	ID        string    `json:"id"`
-	FileID    string    `json:"file_id"`
-	Messages  []Message `json:"messages"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	File      FileInfo  `json:"file"`
-}
\ No newline at end of file
+	ID           string             `json:"id"`
+	FileID       string             `json:"file_id"`
+	Scope        *ConversationScope `json:"scope,omitempty"` // Set for folder / multi-file / all conversations
+	Title        string             `json:"title"`
+	Pinned       bool               `json:"pinned"`
+	MessageCount int                `json:"message_count"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	File         FileInfo           `json:"file"` // Empty for non-file scopes
+}
+
+// Key returns the lookup key stored on non-file conversations.
+// File IDs are sorted so the same selection always maps to the same conversation.
+func (s ConversationScope) Key() string {
+	switch s.Type {
+	case ScopeFolder:
+		return ScopeFolder + ":" + s.ID
+	case ScopeFiles:
+		ids := append([]string(nil), s.FileIDs...)
+		sort.Strings(ids)
+		return ScopeFiles + ":" + strings.Join(ids, ",")
+	case ScopeAll:
+		return ScopeAll
+	}
+	return ScopeFile + ":" + s.ID
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Cevap değerlendirmeleri
+const (
+	FeedbackUp   = "up"
+	FeedbackDown = "down"
+)
+
+// FeedbackReasons - Olumsuz değerlendirmede seçilebilecek nedenler
+var FeedbackReasons = []string{
+	"incorrect",          // Cevap yanlış
+	"incomplete",         // Eksik cevap
+	"irrelevant_sources", // Kaynaklar soruyla ilgisiz
+	"not_grounded",       // Dokümanda olmayan bilgi
+	"other",
+}
+
+// Retrieval yolları: cevabın bağlamı hangi aramayla getirildi
+const (
+	RetrievalPathHybrid     = "hybrid"      // Keyword + semantic (RRF)
+	RetrievalPathSemantic   = "semantic"    // Vector store benzerlik araması
+	RetrievalPathFileRouter = "file_router" // In-memory HNSW index
+)
+
+// RetrievalInfo - Asistan cevabının nasıl üretildiği; değerlendirmeleri stratejiye göre gruplamak için
+type RetrievalInfo struct {
+	Intent     string   `json:"intent" bson:"intent"`
+	Path       string   `json:"path" bson:"path"`
+	Reranker   string   `json:"reranker,omitempty" bson:"reranker,omitempty"`
+	FileTypes  []string `json:"file_types,omitempty" bson:"file_types,omitempty"` // Kaynak dosyaların uzantıları
+	Structured bool     `json:"structured,omitempty" bson:"structured,omitempty"` // Tablo sorgusunun hesaplanmış sonucu eklendi
+	Rewritten  bool     `json:"rewritten,omitempty" bson:"rewritten,omitempty"`   // Takip sorusu bağımsız soruya çevrildi
+}
+
+// MessageFeedback - Kullanıcının bir asistan cevabına verdiği değerlendirme
+type MessageFeedback struct {
+	Rating          string    `json:"rating" bson:"rating"` // up / down
+	Reason          string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	CorrectedAnswer string    `json:"corrected_answer,omitempty" bson:"corrected_answer,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// MessageFeedbackRequest - Değerlendirme isteği
+type MessageFeedbackRequest struct {
+	Rating          string `json:"rating"`
+	Reason          string `json:"reason,omitempty"`
+	CorrectedAnswer string `json:"corrected_answer,omitempty"`
+}
+
+// FeedbackBucket - Bir grup (intent, yol, dosya türü) için cevap ve değerlendirme sayıları
+type FeedbackBucket struct {
+	Key              string  `json:"key" bson:"_id"`
+	Answers          int     `json:"answers" bson:"answers"` // Üretilen cevap sayısı
+	Rated            int     `json:"rated" bson:"rated"`
+	Up               int     `json:"up" bson:"up"`
+	Down             int     `json:"down" bson:"down"`
+	Corrected        int     `json:"corrected" bson:"corrected"`                 // Düzeltilmiş cevap gönderilenler
+	SatisfactionRate float64 `json:"satisfaction_rate" bson:"satisfaction_rate"` // up / rated, değerlendirme yoksa 0
+}
+
+// FeedbackReasonCount - Olumsuz değerlendirme nedeni sayısı
+type FeedbackReasonCount struct {
+	Reason string `json:"reason" bson:"_id"`
+	Count  int    `json:"count" bson:"count"`
+}
+
+// FeedbackStats - Admin paneli için retrieval kalite özeti
+type FeedbackStats struct {
+	Total      FeedbackBucket        `json:"total"`
+	ByIntent   []FeedbackBucket      `json:"by_intent"`
+	ByPath     []FeedbackBucket      `json:"by_path"`
+	ByFileType []FeedbackBucket      `json:"by_file_type"`
+	ByReranker []FeedbackBucket      `json:"by_reranker"`
+	Reasons    []FeedbackReasonCount `json:"reasons"`
+}
@@ -9,10 +9,11 @@ import (
 // AccessEntry represents a user's access to a file or folder
 type AccessEntry struct {
 	UserID        string              `json:"user_id" bson:"user_id"`
-	AccessType    string              `json:"access_type" bson:"access_type"` // "read" or "write"
+	AccessType    string              `json:"access_type" bson:"access_type"` // viewer, commenter, editor or manager
 	GrantedAt     time.Time           `json:"granted_at" bson:"granted_at"`
 	GrantedBy     string              `json:"granted_by" bson:"granted_by"`
 	InheritedFrom *primitive.ObjectID `json:"inherited_from,omitempty" bson:"inherited_from,omitempty"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // nil = süresiz
 }
 
 type File struct {
@@ -29,10 +30,13 @@ type File struct {
 	AccessList       []AccessEntry        `json:"access_list" bson:"access_list"`
 	IsStarred        bool                 `json:"is_starred" bson:"is_starred"`
 	DeletedAt        *time.Time           `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
-	ProcessingStatus string               `json:"processing_status" bson:"processing_status"` // none, pending, processing, completed, failed
+	ProcessingStatus string               `json:"processing_status" bson:"processing_status"` // none, pending, processing, completed, partial, failed
 	ProcessingError  string               `json:"processing_error,omitempty" bson:"processing_error,omitempty"`
 	ProcessedAt      *time.Time           `json:"processed_at,omitempty" bson:"processed_at,omitempty"`
 	ChunkCount       int                  `json:"chunk_count" bson:"chunk_count"`
+	FailedChunkCount int                  `json:"failed_chunk_count,omitempty" bson:"failed_chunk_count,omitempty"` // Chunks skipped because embedding failed (status "partial")
+	ContentStale     bool                 `json:"content_stale,omitempty" bson:"content_stale,omitempty"`           // Content changed after indexing; re-indexing is queued or running
+	EmbedModel       string               `json:"embed_model,omitempty" bson:"embed_model,omitempty"`               // Embedding model of the indexed chunks ("provider/model")
 	CreatedAt        time.Time            `json:"created_at" bson:"created_at"`
 	UpdatedAt        time.Time            `json:"updated_at" bson:"updated_at"`
 }
@@ -53,6 +57,8 @@ type FileResponse struct {
 	ProcessingError  string               `json:"processing_error,omitempty"`
 	ProcessedAt      *time.Time           `json:"processed_at,omitempty"`
 	ChunkCount       int                  `json:"chunk_count"`
+	FailedChunkCount int                  `json:"failed_chunk_count,omitempty"`
+	ContentStale     bool                 `json:"content_stale,omitempty"`
 	DeletedAt        *time.Time           `json:"deleted_at,omitempty"`
 	CreatedAt        time.Time            `json:"created_at"`
 	UpdatedAt        time.Time            `json:"updated_at"`
@@ -66,3 +72,17 @@ type CreateFileRequest struct {
 	MinioPath   string  `json:"minio_path" validate:"required"`
 	FolderID    *string `json:"folder_id"`
 }
+
+// QueryableStatuses are the processing statuses whose chunks are indexed and searchable.
+// "partial" files were indexed with some chunks skipped.
+var QueryableStatuses = []string{"completed", "partial"}
+
+// IsQueryableStatus reports whether a file with this processing status can be queried
+func IsQueryableStatus(status string) bool {
+	for _, s := range QueryableStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareInvitation - Henüz hesabı olmayan bir e-posta adresine yapılan paylaşım.
+// Kullanıcı ilk kez giriş yaptığında AccessEntry'ye dönüştürülür.
+type ShareInvitation struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Email          string             `json:"email" bson:"email"` // lowercase
+	ResourceID     primitive.ObjectID `json:"resource_id" bson:"resource_id"`
+	ResourceType   string             `json:"resource_type" bson:"resource_type"` // "file" or "folder"
+	ResourceName   string             `json:"resource_name" bson:"resource_name"`
+	AccessType     string             `json:"access_type" bson:"access_type"`
+	ExpiresAt      *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // Verilecek erişimin bitişi; nil = süresiz
+	InvitedBy      string             `json:"invited_by" bson:"invited_by"`
+	Status         string             `json:"status" bson:"status"` // pending, accepted, expired
+	AcceptedUserID string             `json:"accepted_user_id,omitempty" bson:"accepted_user_id,omitempty"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	AcceptedAt     *time.Time         `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+}
+
+// Davet durumları
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusExpired  = "expired" // Erişim süresi kullanıcı giriş yapmadan doldu
+)
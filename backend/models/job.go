@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job statuses. Başarısız denemeler tekrar "queued" olur; deneme hakkı bitince "dead" (dead-letter).
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusDead      = "dead"
+	JobStatusCancelled = "cancelled"
+)
+
+// ProcessingJob - Kalıcı doküman işleme kuyruğundaki bir iş
+type ProcessingJob struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	FileID         string              `json:"file_id" bson:"file_id"`
+	UserID         string              `json:"user_id" bson:"user_id"` // Dosya sahibi
+	Filename       string              `json:"filename" bson:"filename"`
+	MinioPath      string              `json:"minio_path" bson:"minio_path"`
+	ContentType    string              `json:"content_type" bson:"content_type"`
+	Deduplicate    bool                `json:"deduplicate" bson:"deduplicate"` // Aynı hash'li işlenmiş dosya varsa embedding'leri yeniden kullan
+	Status         string              `json:"status" bson:"status"`
+	Attempts       int                 `json:"attempts" bson:"attempts"`
+	MaxAttempts    int                 `json:"max_attempts" bson:"max_attempts"`
+	RunAt          time.Time           `json:"run_at" bson:"run_at"`                               // Bu zamandan önce alınmaz (retry backoff)
+	LeaseOwner     string              `json:"lease_owner,omitempty" bson:"lease_owner,omitempty"` // İşi tutan worker
+	LeaseUntil     *time.Time          `json:"lease_until,omitempty" bson:"lease_until,omitempty"` // Süresi dolan lease başka worker tarafından alınabilir
+	LastError      string              `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	RerunRequested bool                `json:"rerun_requested,omitempty" bson:"rerun_requested,omitempty"` // Çalışırken içerik değişti; bitince dosya tekrar kuyruğa alınır
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at" bson:"updated_at"`
+	StartedAt      *time.Time          `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt     *time.Time          `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	Progress       *ProcessingProgress `json:"progress,omitempty" bson:"progress,omitempty"` // Son raporlanan ilerleme
+}
+
+// Processing stages, in pipeline order
+const (
+	ProcessingStageQueued      = "queued"
+	ProcessingStageExtracting  = "extracting"
+	ProcessingStageNormalizing = "normalizing"
+	ProcessingStageChunking    = "chunking"
+	ProcessingStageEmbedding   = "embedding"
+	ProcessingStageIndexing    = "indexing"
+	ProcessingStageCompleted   = "completed"
+	ProcessingStageFailed      = "failed"
+)
+
+// ProcessingProgress - Çalışan bir işin aşaması ve ilerlemesi
+type ProcessingProgress struct {
+	Stage          string    `json:"stage" bson:"stage"`
+	Current        int       `json:"current" bson:"current"`                   // Embedding aşamasında işlenen chunk sayısı
+	Total          int       `json:"total" bson:"total"`                       // Toplam chunk sayısı (chunking sonrası bilinir)
+	Failed         int       `json:"failed,omitempty" bson:"failed,omitempty"` // Embedding'i başarısız olan chunk sayısı
+	Percent        float64   `json:"percent" bson:"percent"`                   // 0-100, tüm pipeline için
+	ETASeconds     *int      `json:"eta_seconds,omitempty" bson:"eta_seconds,omitempty"`
+	StartedAt      time.Time `json:"started_at" bson:"started_at"`
+	StageStartedAt time.Time `json:"stage_started_at" bson:"stage_started_at"`
+	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// ProcessingState - GET /files/:id/processing cevabı
+type ProcessingState struct {
+	FileID           string              `json:"file_id"`
+	Status           string              `json:"status"` // Dosyanın processing_status değeri
+	Error            string              `json:"error,omitempty"`
+	ChunkCount       int                 `json:"chunk_count"`
+	FailedChunkCount int                 `json:"failed_chunk_count,omitempty"`
+	Stale            bool                `json:"content_stale,omitempty"` // İçerik değişti, yeniden indeksleniyor
+	Job              *ProcessingJob      `json:"job,omitempty"`
+	Progress         *ProcessingProgress `json:"progress,omitempty"`
+}
+
+// JobFilter - Job listeleme filtreleri
+type JobFilter struct {
+	UserID string
+	FileID string
+	Status string
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ownership transfer durumları
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusAccepting = "accepting" // Kabul edildi, sahiplik devrediliyor
+	TransferStatusAccepted  = "accepted"
+	TransferStatusDeclined  = "declined"
+	TransferStatusCancelled = "cancelled"
+)
+
+// OwnershipTransfer - Bir dosya/klasörün sahipliğini başka bir kullanıcıya devretme daveti
+type OwnershipTransfer struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ResourceID   primitive.ObjectID `json:"resource_id" bson:"resource_id"`
+	ResourceType string             `json:"resource_type" bson:"resource_type"` // "file" or "folder"
+	ResourceName string             `json:"resource_name" bson:"resource_name"`
+	FromUserID   string             `json:"from_user_id" bson:"from_user_id"`
+	ToUserID     string             `json:"to_user_id" bson:"to_user_id"`
+	MoveObjects  bool               `json:"move_objects" bson:"move_objects"` // MinIO objelerini yeni sahibin prefix'ine taşı
+	Status       string             `json:"status" bson:"status"`             // pending, accepting, accepted, declined, cancelled
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	RespondedAt  *time.Time         `json:"responded_at,omitempty" bson:"responded_at,omitempty"`
+}
+
+type CreateTransferRequest struct {
+	ResourceID   string `json:"resource_id" validate:"required"`
+	ResourceType string `json:"resource_type" validate:"required"`
+	ToUserID     string `json:"to_user_id" validate:"required"`
+	MoveObjects  bool   `json:"move_objects"`
+}
+
+type OwnershipTransferResponse struct {
+	OwnershipTransfer
+	FromUser *UserResponse `json:"from_user,omitempty"`
+	ToUser   *UserResponse `json:"to_user,omitempty"`
+}
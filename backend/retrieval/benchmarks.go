@@ -3,6 +3,10 @@ package retrieval
 import (
 	"fmt"
 	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -303,3 +307,198 @@ func LogPerformance(operation string, duration time.Duration, additionalInfo map
 	}
 }
 
+
+// ANNBenchmarkConfig describes a synthetic recall-vs-latency run for the file router index
+type ANNBenchmarkConfig struct {
+	Vectors   int // Chunks in the simulated file
+	Dim       int
+	Queries   int
+	Clusters  int // Vectors are drawn around this many topics, like chunks of a real document
+	K         int
+	EfValues  []int    // EfSearch values to sweep
+	Encodings []string // EncodingFloat32 and/or EncodingInt8
+	Index     HNSWConfig
+	Seed      int64
+}
+
+// ANNBenchmarkPoint is one measured configuration. The brute-force baseline is reported
+// with Encoding "exact" and recall 1.
+type ANNBenchmarkPoint struct {
+	Encoding   string
+	EfSearch   int
+	RecallAtK  float64
+	AvgLatency time.Duration
+	P95Latency time.Duration
+	IndexBytes int
+	BuildTime  time.Duration
+}
+
+// DefaultANNBenchmarkConfig returns a run sized like a large document (5k chunks, 384 dims)
+func DefaultANNBenchmarkConfig() ANNBenchmarkConfig {
+	return ANNBenchmarkConfig{
+		Vectors:   5000,
+		Dim:       384,
+		Queries:   200,
+		Clusters:  50,
+		K:         10,
+		EfValues:  []int{16, 32, 64, 128, 256},
+		Encodings: []string{EncodingFloat32, EncodingInt8},
+		Index:     DefaultHNSWConfig(),
+		Seed:      1,
+	}
+}
+
+// RunANNBenchmark builds an index per encoding over synthetic clustered vectors and
+// measures recall@K against brute-force search for every EfSearch value
+func RunANNBenchmark(cfg ANNBenchmarkConfig) []ANNBenchmarkPoint {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	centers := make([][]float64, maxInt(cfg.Clusters, 1))
+	for i := range centers {
+		centers[i] = randomVector(rng, cfg.Dim, nil, 1)
+	}
+	sample := func() []float64 {
+		return randomVector(rng, cfg.Dim, centers[rng.Intn(len(centers))], 0.6)
+	}
+
+	vectors := make([][]float64, cfg.Vectors)
+	for i := range vectors {
+		vectors[i] = sample()
+	}
+	queries := make([][]float64, cfg.Queries)
+	for i := range queries {
+		queries[i] = sample()
+	}
+
+	// Ground truth and the baseline: float64 cosine over every vector, as the router did
+	// before it had an index
+	truth := make([]map[int]bool, len(queries))
+	exactLatencies := make([]time.Duration, len(queries))
+	for i, query := range queries {
+		started := time.Now()
+		hits := bruteForceCosine(vectors, query, cfg.K)
+		exactLatencies[i] = time.Since(started)
+
+		truth[i] = make(map[int]bool, len(hits))
+		for _, hit := range hits {
+			truth[i][hit.Index] = true
+		}
+	}
+	avg, p95 := latencyStats(exactLatencies)
+	points := []ANNBenchmarkPoint{{
+		Encoding:   "exact",
+		RecallAtK:  1,
+		AvgLatency: avg,
+		P95Latency: p95,
+		IndexBytes: 8 * cfg.Vectors * cfg.Dim,
+	}}
+
+	for _, encoding := range cfg.Encodings {
+		indexCfg := cfg.Index
+		indexCfg.Encoding = encoding
+		indexCfg.ExactBelow = 0
+
+		started := time.Now()
+		index := NewHNSWIndex(cfg.Dim, indexCfg)
+		for _, vector := range vectors {
+			index.Add(vector)
+		}
+		buildTime := time.Since(started)
+
+		for _, ef := range cfg.EfValues {
+			latencies := make([]time.Duration, len(queries))
+			found := 0
+			for i, query := range queries {
+				started := time.Now()
+				hits := index.SearchEf(query, cfg.K, ef)
+				latencies[i] = time.Since(started)
+
+				for _, hit := range hits {
+					if truth[i][hit.Index] {
+						found++
+					}
+				}
+			}
+
+			avg, p95 := latencyStats(latencies)
+			points = append(points, ANNBenchmarkPoint{
+				Encoding:   encoding,
+				EfSearch:   ef,
+				RecallAtK:  float64(found) / float64(maxInt(len(queries)*cfg.K, 1)),
+				AvgLatency: avg,
+				P95Latency: p95,
+				IndexBytes: index.MemoryBytes(),
+				BuildTime:  buildTime,
+			})
+		}
+	}
+	return points
+}
+
+// FormatANNBenchmark renders benchmark points as a table
+func FormatANNBenchmark(points []ANNBenchmarkPoint, k int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%-8s %6s %10s %12s %12s %12s %10s\n", "encoding", "ef", fmt.Sprintf("recall@%d", k), "avg", "p95", "memory", "build")
+	for _, p := range points {
+		ef := "-"
+		if p.EfSearch > 0 {
+			ef = fmt.Sprintf("%d", p.EfSearch)
+		}
+		fmt.Fprintf(&b, "%-8s %6s %10.3f %12v %12v %10.1fMB %10v\n",
+			p.Encoding, ef, p.RecallAtK, p.AvgLatency, p.P95Latency,
+			float64(p.IndexBytes)/(1<<20), p.BuildTime.Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+// randomVector draws a Gaussian vector, offset by center when given
+func randomVector(rng *rand.Rand, dim int, center []float64, spread float64) []float64 {
+	vector := make([]float64, dim)
+	for i := range vector {
+		vector[i] = rng.NormFloat64() * spread
+		if center != nil {
+			vector[i] += center[i]
+		}
+	}
+	return vector
+}
+
+// bruteForceCosine ranks every vector by float64 cosine distance
+func bruteForceCosine(vectors [][]float64, query []float64, k int) []ANNHit {
+	norm := func(v []float64) float64 {
+		var sum float64
+		for _, x := range v {
+			sum += x * x
+		}
+		return math.Sqrt(sum)
+	}
+
+	queryNorm := norm(query)
+	hits := make([]ANNHit, len(vectors))
+	for i, vector := range vectors {
+		var dot float64
+		for j := range vector {
+			dot += vector[j] * query[j]
+		}
+		hits[i] = ANNHit{Index: i, Distance: 1 - dot/(norm(vector)*queryNorm)}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Distance < hits[j].Distance })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// latencyStats returns the mean and 95th percentile
+func latencyStats(latencies []time.Duration) (time.Duration, time.Duration) {
+	if len(latencies) == 0 {
+		return 0, 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	return total / time.Duration(len(sorted)), sorted[(len(sorted)*95)/100]
+}
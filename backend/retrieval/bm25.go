@@ -0,0 +1,337 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters: k1 saturates term frequency, b normalizes for chunk length
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// KeywordDoc is a chunk as stored in the keyword index
+type KeywordDoc struct {
+	ID       string                 `json:"id"`
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// KeywordHit is a search result with its BM25 score (higher is better)
+type KeywordHit struct {
+	KeywordDoc
+	Score float64
+}
+
+// BM25Index is an inverted keyword index with one shard per file, since every keyword
+// search is scoped to a file: term statistics (IDF, average length) are per file as well.
+// Each shard is persisted as <dir>/<file id>.json and rewritten when the file changes.
+type BM25Index struct {
+	mu     sync.RWMutex
+	dir    string // Empty keeps the index in memory only
+	shards map[string]*bm25Shard
+}
+
+type bm25Shard struct {
+	docs     map[string]*bm25Doc
+	postings map[string]map[string]int // term -> chunk ID -> term frequency
+	totalLen int
+}
+
+type bm25Doc struct {
+	KeywordDoc
+	Terms  map[string]int `json:"terms"`
+	Length int            `json:"length"`
+}
+
+// bm25ShardFile is the on-disk form of a shard. Term counts are reused on load unless
+// they were produced by another analyzer version.
+type bm25ShardFile struct {
+	Analyzer int        `json:"analyzer"`
+	Docs     []*bm25Doc `json:"docs"`
+}
+
+// OpenBM25Index loads the shards persisted in dir (created if missing)
+func OpenBM25Index(dir string) (*BM25Index, error) {
+	index := &BM25Index{dir: dir, shards: make(map[string]*bm25Shard)}
+	if dir == "" {
+		return index, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create keyword index directory: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	reanalyzed := 0
+	for _, path := range paths {
+		fileID, err := url.PathUnescape(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			continue
+		}
+		shard, stale, err := loadShard(path)
+		if err != nil {
+			// A broken shard is rebuilt from the vector store on the next search
+			log.Printf("Warning: skipping keyword index %s: %v", path, err)
+			continue
+		}
+		index.shards[fileID] = shard
+		if stale {
+			reanalyzed++
+			if err := index.persist(fileID, shard); err != nil {
+				log.Printf("Warning: failed to rewrite keyword index for file %s: %v", fileID, err)
+			}
+		}
+	}
+	if reanalyzed > 0 {
+		log.Printf("Keyword index: re-analyzed %d files for analyzer version %d", reanalyzed, analyzerVersion)
+	}
+	return index, nil
+}
+
+func loadShard(path string) (*bm25Shard, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var stored bm25ShardFile
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, false, err
+	}
+
+	stale := stored.Analyzer != analyzerVersion
+	shard := newBM25Shard()
+	for _, doc := range stored.Docs {
+		if stale || doc.Terms == nil {
+			doc = newBM25Doc(doc.KeywordDoc)
+		}
+		shard.add(doc)
+	}
+	return shard, stale, nil
+}
+
+func newBM25Shard() *bm25Shard {
+	return &bm25Shard{
+		docs:     make(map[string]*bm25Doc),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// newBM25Doc analyzes the chunk text together with its key_terms metadata
+func newBM25Doc(doc KeywordDoc) *bm25Doc {
+	text := doc.Text
+	if keyTerms, ok := doc.Metadata["key_terms"].(string); ok && keyTerms != "" {
+		text += "\n" + strings.ReplaceAll(keyTerms, ",", " ")
+	}
+
+	terms := analyzeDocument(text)
+	counts := make(map[string]int)
+	for _, term := range terms {
+		counts[term]++
+	}
+	return &bm25Doc{KeywordDoc: doc, Terms: counts, Length: len(terms)}
+}
+
+func (s *bm25Shard) add(doc *bm25Doc) {
+	s.remove(doc.ID)
+	s.docs[doc.ID] = doc
+	s.totalLen += doc.Length
+	for term, tf := range doc.Terms {
+		if s.postings[term] == nil {
+			s.postings[term] = make(map[string]int)
+		}
+		s.postings[term][doc.ID] = tf
+	}
+}
+
+func (s *bm25Shard) remove(id string) {
+	doc, ok := s.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.Terms {
+		delete(s.postings[term], id)
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+		}
+	}
+	s.totalLen -= doc.Length
+	delete(s.docs, id)
+}
+
+// HasFile reports whether the file has been indexed (possibly with zero chunks)
+func (ix *BM25Index) HasFile(fileID string) bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	_, ok := ix.shards[fileID]
+	return ok
+}
+
+// IndexFile replaces every chunk of a file
+func (ix *BM25Index) IndexFile(fileID string, docs []KeywordDoc) error {
+	shard := newBM25Shard()
+	for _, doc := range docs {
+		shard.add(newBM25Doc(doc))
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.shards[fileID] = shard
+	return ix.persist(fileID, shard)
+}
+
+// UpdateFile adds or replaces upserts and removes deleteIDs from a file's shard
+func (ix *BM25Index) UpdateFile(fileID string, upserts []KeywordDoc, deleteIDs []string) error {
+	analyzed := make([]*bm25Doc, len(upserts))
+	for i, doc := range upserts {
+		analyzed[i] = newBM25Doc(doc)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	shard := ix.shards[fileID]
+	if shard == nil {
+		shard = newBM25Shard()
+		ix.shards[fileID] = shard
+	}
+	for _, id := range deleteIDs {
+		shard.remove(id)
+	}
+	for _, doc := range analyzed {
+		shard.add(doc)
+	}
+	return ix.persist(fileID, shard)
+}
+
+// RemoveFile drops a file's shard
+func (ix *BM25Index) RemoveFile(fileID string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	delete(ix.shards, fileID)
+	if ix.dir == "" {
+		return nil
+	}
+	if err := os.Remove(ix.shardPath(fileID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove keyword index: %w", err)
+	}
+	return nil
+}
+
+// Search ranks a file's chunks against free text with BM25. A query word contributes the
+// best score among its stem variants (see analyzeQuery). Chunks without any matching
+// term are not returned.
+func (ix *BM25Index) Search(fileID, query string, topK int) []KeywordHit {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	shard := ix.shards[fileID]
+	if shard == nil || len(shard.docs) == 0 || topK <= 0 {
+		return nil
+	}
+
+	n := float64(len(shard.docs))
+	avgLen := float64(shard.totalLen) / n
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	scores := make(map[string]float64)
+	for _, variants := range analyzeQuery(query) {
+		best := make(map[string]float64)
+		for _, term := range variants {
+			postings := shard.postings[term]
+			if len(postings) == 0 {
+				continue
+			}
+			df := float64(len(postings))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for id, tf := range postings {
+				length := float64(shard.docs[id].Length)
+				score := idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*(1-bm25B+bm25B*length/avgLen))
+				if score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+		}
+	}
+
+	hits := make([]KeywordHit, 0, len(scores))
+	for id, score := range scores {
+		doc := shard.docs[id]
+		hits = append(hits, KeywordHit{KeywordDoc: doc.KeywordDoc, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits
+}
+
+// Stats returns the number of indexed files, chunks and distinct terms
+func (ix *BM25Index) Stats() map[string]interface{} {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	chunks, terms := 0, 0
+	for _, shard := range ix.shards {
+		chunks += len(shard.docs)
+		terms += len(shard.postings)
+	}
+	return map[string]interface{}{
+		"indexed_files": len(ix.shards),
+		"total_chunks":  chunks,
+		"total_terms":   terms,
+	}
+}
+
+func (ix *BM25Index) shardPath(fileID string) string {
+	return filepath.Join(ix.dir, url.PathEscape(fileID)+".json")
+}
+
+// persist writes a shard atomically (temp file + rename). Callers hold the write lock.
+func (ix *BM25Index) persist(fileID string, shard *bm25Shard) error {
+	if ix.dir == "" {
+		return nil
+	}
+
+	stored := bm25ShardFile{Analyzer: analyzerVersion, Docs: make([]*bm25Doc, 0, len(shard.docs))}
+	for _, doc := range shard.docs {
+		stored.Docs = append(stored.Docs, doc)
+	}
+	sort.Slice(stored.Docs, func(i, j int) bool { return stored.Docs[i].ID < stored.Docs[j].ID })
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode keyword index: %w", err)
+	}
+	path := ix.shardPath(fileID)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package retrieval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStemEnglish(t *testing.T) {
+	cases := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"hopping":        "hop",
+		"relational":     "relat",
+		"generalization": "gener",
+		"routers":        "router",
+		"encryption":     "encrypt",
+		"connections":    "connect",
+		"connected":      "connect",
+	}
+	for word, want := range cases {
+		if got := stemEnglish(word); got != want {
+			t.Errorf("stemEnglish(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestStemTurkish(t *testing.T) {
+	// Inflected forms of one word share a stem
+	groups := [][]string{
+		{"güvenlik", "güvenliği", "güvenliğin"},
+		{"kitap", "kitabı", "kitaplar", "kitaplarda"},
+		{"şifre", "şifreler", "şifrelerin"},
+	}
+	for _, group := range groups {
+		want := stemTurkish(tokenize(group[0])[0])
+		for _, word := range group[1:] {
+			if got := stemTurkish(tokenize(word)[0]); got != want {
+				t.Errorf("%q stems to %q, %q to %q", word, got, group[0], want)
+			}
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("İstanbul'da the Wi-Fi 6 ağ ŞİFRESİ")
+	want := []string{"istanbul", "wi", "fi", "6", "ag", "sifresi"}
+	if len(got) != len(want) {
+		t.Fatalf("tokenize = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tokenize = %v, want %v", got, want)
+		}
+	}
+	if detectLanguage("Bu belge ağ güvenliği ve şifreleme hakkındadır.") != LangTurkish {
+		t.Error("expected Turkish")
+	}
+	if detectLanguage("This document is about the network and its encryption.") != LangEnglish {
+		t.Error("expected English")
+	}
+}
+
+func TestBM25Ranking(t *testing.T) {
+	index, _ := OpenBM25Index("")
+	index.IndexFile("f", []KeywordDoc{
+		{ID: "c0", Text: "Firewalls filter network traffic between zones."},
+		{ID: "c1", Text: "Encryption protects data. Encrypted connections use TLS; encryption keys rotate."},
+		{ID: "c2", Text: "The cafeteria menu changes weekly."},
+		{ID: "c3", Text: "Kablosuz ağlarda şifreleme ve güvenlik duvarı ayarları.", Metadata: map[string]interface{}{"key_terms": "WPA3,encryption"}},
+	})
+
+	// c1 matches the English stem, c3 (a Turkish chunk) the unstemmed key term
+	hits := index.Search("f", "encryption connections", 10)
+	if len(hits) != 2 || hits[0].ID != "c1" {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Errorf("scores must decrease: %+v", hits)
+	}
+
+	// Turkish inflections and key_terms metadata are searchable
+	if hits := index.Search("f", "ağ şifrelemesi", 10); len(hits) != 1 || hits[0].ID != "c3" {
+		t.Fatalf("unexpected Turkish hits: %+v", hits)
+	}
+	if hits := index.Search("f", "wpa3", 10); len(hits) != 1 || hits[0].ID != "c3" {
+		t.Fatalf("key_terms not indexed: %+v", hits)
+	}
+	if hits := index.Search("other", "encryption", 10); hits != nil {
+		t.Errorf("search must be scoped to the file: %+v", hits)
+	}
+}
+
+func TestBM25IncrementalUpdateAndPersistence(t *testing.T) {
+	dir := t.TempDir()
+	index, err := OpenBM25Index(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.IndexFile("f", []KeywordDoc{
+		{ID: "c0", Text: "alpha beta"},
+		{ID: "c1", Text: "gamma delta"},
+	})
+	index.UpdateFile("f", []KeywordDoc{{ID: "c1", Text: "epsilon"}, {ID: "c2", Text: "gamma"}}, []string{"c0"})
+	index.IndexFile("g", []KeywordDoc{{ID: "g0", Text: "gamma"}})
+	if err := index.RemoveFile("g"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenBM25Index(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.HasFile("g") || !reopened.HasFile("f") {
+		t.Fatalf("unexpected files after reopen: %v", reopened.Stats())
+	}
+	if hits := reopened.Search("f", "alpha", 5); len(hits) != 0 {
+		t.Errorf("deleted chunk still matches: %+v", hits)
+	}
+	if hits := reopened.Search("f", "gamma", 5); len(hits) != 1 || hits[0].ID != "c2" {
+		t.Errorf("replaced chunk still matches its old text: %+v", hits)
+	}
+
+	// Shards written by another analyzer version are re-analyzed from their text
+	path := filepath.Join(dir, "f.json")
+	os.WriteFile(path, []byte(`{"analyzer":0,"docs":[{"id":"c9","text":"Encryption keys","terms":{"bogus":1},"length":1}]}`), 0o644)
+	reopened, _ = OpenBM25Index(dir)
+	if hits := reopened.Search("f", "encryption", 5); len(hits) != 1 || hits[0].ID != "c9" {
+		t.Errorf("stale shard was not re-analyzed: %+v", hits)
+	}
+}
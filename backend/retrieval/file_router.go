@@ -2,7 +2,6 @@ package retrieval
 
 import (
 	"log"
-	"math"
 	"sync"
 	"time"
 )
@@ -13,15 +12,19 @@ type FileRouter struct {
 	mutex      sync.RWMutex
 	maxAge     time.Duration // TTL for unused indexes
 	lastAccess map[string]time.Time
+	annConfig  HNSWConfig
 }
 
-// FileIndex holds chunk embeddings for a single file
+// FileIndex holds the chunks of a single file and an ANN index over their embeddings.
+// Chunks keep text and metadata only; the vectors live (possibly quantized) in the index.
 type FileIndex struct {
 	FileID     string
 	Chunks     []ChunkEmbedding
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	ChunkCount int
+	ann        *HNSWIndex
+	nodeChunks []int // ANN node -> position in Chunks
 }
 
 // ChunkEmbedding represents a chunk with its embedding vector
@@ -33,7 +36,7 @@ type ChunkEmbedding struct {
 }
 
 // NewFileRouter creates a new file router with TTL-based eviction
-func NewFileRouter(maxAge time.Duration) *FileRouter {
+func NewFileRouter(maxAge time.Duration, annConfig HNSWConfig) *FileRouter {
 	if maxAge == 0 {
 		maxAge = 24 * time.Hour // Default 24 hours
 	}
@@ -42,6 +45,7 @@ func NewFileRouter(maxAge time.Duration) *FileRouter {
 		indexes:    make(map[string]*FileIndex),
 		lastAccess: make(map[string]time.Time),
 		maxAge:     maxAge,
+		annConfig:  annConfig.normalize(),
 	}
 	
 	// Start background cleanup goroutine
@@ -52,17 +56,15 @@ func NewFileRouter(maxAge time.Duration) *FileRouter {
 
 // AddFileIndex adds or updates a file index
 func (fr *FileRouter) AddFileIndex(fileID string, chunks []ChunkEmbedding) {
+	// Build the graph before taking the lock so searches in other files are not blocked
+	index := fr.buildIndex(fileID, chunks)
+	
 	fr.mutex.Lock()
 	defer fr.mutex.Unlock()
 	
 	now := time.Now()
-	index := &FileIndex{
-		FileID:     fileID,
-		Chunks:     chunks,
-		CreatedAt:  now,
-		UpdatedAt:  now,
-		ChunkCount: len(chunks),
-	}
+	index.CreatedAt = now
+	index.UpdatedAt = now
 	
 	fr.indexes[fileID] = index
 	fr.lastAccess[fileID] = now
@@ -95,119 +97,55 @@ func (fr *FileRouter) RemoveFileIndex(fileID string) {
 	log.Printf("File router: removed index for file %s", fileID)
 }
 
-// SearchInFile performs optimized similarity search within a specific file's index
-// Uses parallel goroutines for fast dot product computation
+// SearchInFile performs approximate similarity search within a specific file's index.
+// Files below HNSWConfig.ExactBelow chunks are scanned exhaustively.
 func (fr *FileRouter) SearchInFile(fileID string, queryEmbedding []float64, topK int) []SimilarityResult {
 	index, exists := fr.GetFileIndex(fileID)
-	if !exists {
+	if !exists || index.ann == nil {
 		return []SimilarityResult{}
 	}
 	
-	// Compute similarities in parallel
-	similarities := fr.computeSimilaritiesParallel(queryEmbedding, index.Chunks)
-	
-	// Sort by similarity (descending)
-	sortedResults := fr.sortBySimilarity(similarities)
-	
-	// Return top-k
-	if len(sortedResults) > topK {
-		return sortedResults[:topK]
-	}
-	
-	return sortedResults
-}
-
-// computeSimilaritiesParallel computes cosine similarity using goroutines
-func (fr *FileRouter) computeSimilaritiesParallel(queryEmbedding []float64, chunks []ChunkEmbedding) []SimilarityResult {
-	numChunks := len(chunks)
-	results := make([]SimilarityResult, numChunks)
-	
-	// Determine optimal number of workers (use CPU cores, capped at 8)
-	numWorkers := 4
-	if numChunks < numWorkers {
-		numWorkers = numChunks
-	}
-	
-	// Channel for work distribution
-	type workItem struct {
-		index int
-		chunk ChunkEmbedding
-	}
-	
-	workChan := make(chan workItem, numChunks)
-	var wg sync.WaitGroup
-	
-	// Worker function
-	worker := func() {
-		defer wg.Done()
-		for item := range workChan {
-			similarity := cosineSimilarity(queryEmbedding, item.chunk.Embedding)
-			distance := 1.0 - similarity // Convert to distance
-			
-			results[item.index] = SimilarityResult{
-				ChunkID:  item.chunk.ChunkID,
-				Distance: distance,
-				Text:     item.chunk.Text,
-				Metadata: item.chunk.Metadata,
-			}
+	hits := index.ann.Search(queryEmbedding, topK)
+	results := make([]SimilarityResult, len(hits))
+	for i, hit := range hits {
+		chunk := index.Chunks[index.nodeChunks[hit.Index]]
+		results[i] = SimilarityResult{
+			ChunkID:  chunk.ChunkID,
+			Distance: hit.Distance,
+			Text:     chunk.Text,
+			Metadata: chunk.Metadata,
 		}
 	}
 	
-	// Start workers
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go worker()
-	}
-	
-	// Distribute work
-	for i, chunk := range chunks {
-		workChan <- workItem{index: i, chunk: chunk}
-	}
-	close(workChan)
-	
-	// Wait for completion
-	wg.Wait()
-	
 	return results
 }
 
-// sortBySimilarity sorts results by distance (ascending = most similar first)
-func (fr *FileRouter) sortBySimilarity(results []SimilarityResult) []SimilarityResult {
-	// Simple bubble sort for small lists (efficient enough for typical chunk counts)
-	// For very large lists, could use sort.Slice, but this avoids the overhead
-	n := len(results)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if results[j].Distance > results[j+1].Distance {
-				results[j], results[j+1] = results[j+1], results[j]
-			}
-		}
-	}
-	return results
-}
-
-// cosineSimilarity computes cosine similarity between two vectors
-func cosineSimilarity(a, b []float64) float64 {
-	if len(a) != len(b) {
-		return 0.0
+// buildIndex inserts the chunk embeddings into a new ANN index and drops the float64
+// copies from the stored chunks. Chunks without an embedding of the first chunk's size
+// are left out of the graph; HasIndex then reports the file as not routed, so its
+// queries go to the vector store, which holds every chunk.
+func (fr *FileRouter) buildIndex(fileID string, chunks []ChunkEmbedding) *FileIndex {
+	index := &FileIndex{
+		FileID:     fileID,
+		Chunks:     make([]ChunkEmbedding, len(chunks)),
+		ChunkCount: len(chunks),
 	}
 	
-	var dotProduct, normA, normB float64
-	
-	for i := range a {
-		dotProduct += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
+	for i, chunk := range chunks {
+		if index.ann == nil && len(chunk.Embedding) > 0 {
+			index.ann = NewHNSWIndex(len(chunk.Embedding), fr.annConfig)
+		}
+		if index.ann != nil && index.ann.Add(chunk.Embedding) >= 0 {
+			index.nodeChunks = append(index.nodeChunks, i)
+		}
+		chunk.Embedding = nil
+		index.Chunks[i] = chunk
 	}
 	
-	normA = math.Sqrt(normA)
-	normB = math.Sqrt(normB)
-	
-	if normA == 0 || normB == 0 {
-		return 0.0
+	if index.ann != nil && index.ann.Len() < len(chunks) {
+		log.Printf("File router: %d of %d chunks of file %s have no usable embedding", len(chunks)-index.ann.Len(), len(chunks), fileID)
 	}
-	
-	return dotProduct / (normA * normB)
+	return index
 }
 
 // updateAccessTime updates the last access time for a file (thread-safe)
@@ -258,14 +196,22 @@ func (fr *FileRouter) GetStats() map[string]interface{} {
 	defer fr.mutex.RUnlock()
 	
 	totalChunks := 0
+	indexBytes := 0
 	for _, index := range fr.indexes {
 		totalChunks += index.ChunkCount
+		if index.ann != nil {
+			indexBytes += index.ann.MemoryBytes()
+		}
 	}
 	
 	return map[string]interface{}{
-		"indexed_files": len(fr.indexes),
-		"total_chunks":  totalChunks,
-		"max_age_hours": fr.maxAge.Hours(),
+		"indexed_files":  len(fr.indexes),
+		"total_chunks":   totalChunks,
+		"max_age_hours":  fr.maxAge.Hours(),
+		"index_encoding": fr.annConfig.Encoding,
+		"index_bytes":    indexBytes,
+		"hnsw_m":         fr.annConfig.M,
+		"hnsw_ef_search": fr.annConfig.EfSearch,
 	}
 }
 
@@ -296,25 +242,25 @@ func (fr *FileRouter) Clear() {
 // SyncWithChroma syncs the file index with Chroma (called after document update)
 // This ensures the in-memory index stays consistent with the vector DB
 func (fr *FileRouter) SyncWithChroma(fileID string, chunks []ChunkEmbedding) {
+	// The graph is rebuilt from scratch: HNSW does not support deleting nodes, and
+	// re-indexing replaces most chunks of a file anyway
+	rebuilt := fr.buildIndex(fileID, chunks)
+	
 	fr.mutex.Lock()
 	defer fr.mutex.Unlock()
 	
+	now := time.Now()
 	if index, exists := fr.indexes[fileID]; exists {
-		// Update existing index
-		index.Chunks = chunks
-		index.UpdatedAt = time.Now()
-		index.ChunkCount = len(chunks)
+		// Swap in the new index; readers holding the old one keep a consistent view
+		rebuilt.CreatedAt = index.CreatedAt
+		rebuilt.UpdatedAt = now
+		fr.indexes[fileID] = rebuilt
 		log.Printf("File router: synced index for file %s (updated)", fileID)
 	} else {
 		// Create new index
-		now := time.Now()
-		fr.indexes[fileID] = &FileIndex{
-			FileID:     fileID,
-			Chunks:     chunks,
-			CreatedAt:  now,
-			UpdatedAt:  now,
-			ChunkCount: len(chunks),
-		}
+		rebuilt.CreatedAt = now
+		rebuilt.UpdatedAt = now
+		fr.indexes[fileID] = rebuilt
 		fr.lastAccess[fileID] = now
 		log.Printf("File router: synced index for file %s (created)", fileID)
 	}
@@ -333,12 +279,12 @@ func (fr *FileRouter) GetIndexedFileIDs() []string {
 	return fileIDs
 }
 
-// HasIndex checks if a file has an index
+// HasIndex checks if a file has an index covering all of its chunks
 func (fr *FileRouter) HasIndex(fileID string) bool {
 	fr.mutex.RLock()
 	defer fr.mutex.RUnlock()
 	
-	_, exists := fr.indexes[fileID]
-	return exists
+	index, exists := fr.indexes[fileID]
+	return exists && index.ann != nil && index.ann.Len() == index.ChunkCount
 }
 
@@ -0,0 +1,450 @@
+package retrieval
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Vector encodings for HNSWIndex storage
+const (
+	EncodingFloat32 = "float32" // 4 bytes per dimension
+	EncodingInt8    = "int8"    // 1 byte per dimension plus a per-vector scale, ~4x smaller
+)
+
+// HNSWConfig tunes the approximate nearest-neighbour index
+type HNSWConfig struct {
+	M              int    // Links per node on upper layers (layer 0 keeps 2*M)
+	EfConstruction int    // Candidate list size while inserting; higher builds a better graph
+	EfSearch       int    // Candidate list size while searching; higher trades latency for recall
+	Encoding       string // EncodingFloat32 or EncodingInt8
+	ExactBelow     int    // Indexes with fewer vectors are scanned exhaustively
+	Seed           int64  // Seed of the level generator, for reproducible graphs
+}
+
+// DefaultHNSWConfig returns settings that reach ~0.95+ recall@10 on typical chunk embeddings
+func DefaultHNSWConfig() HNSWConfig {
+	return HNSWConfig{
+		M:              16,
+		EfConstruction: 100,
+		EfSearch:       64,
+		Encoding:       EncodingFloat32,
+		ExactBelow:     128,
+		Seed:           42,
+	}
+}
+
+// normalize fills in defaults for unset values
+func (c HNSWConfig) normalize() HNSWConfig {
+	defaults := DefaultHNSWConfig()
+	if c.M < 2 {
+		c.M = defaults.M
+	}
+	if c.EfConstruction < c.M {
+		c.EfConstruction = defaults.EfConstruction
+	}
+	if c.EfSearch < 1 {
+		c.EfSearch = defaults.EfSearch
+	}
+	if strings.ToLower(c.Encoding) == EncodingInt8 {
+		c.Encoding = EncodingInt8
+	} else {
+		c.Encoding = EncodingFloat32
+	}
+	if c.ExactBelow < 0 {
+		c.ExactBelow = 0
+	}
+	return c
+}
+
+// ANNHit is a search result: the insertion position of the vector and its cosine distance
+type ANNHit struct {
+	Index    int
+	Distance float64
+}
+
+// HNSWIndex is a Hierarchical Navigable Small World graph over unit-normalized vectors, so
+// cosine distance is 1 - dot product. It is built once (Add) and then only searched, which
+// is safe from several goroutines.
+type HNSWIndex struct {
+	cfg       HNSWConfig
+	dim       int
+	storage   vectorStorage
+	links     [][][]int32 // links[node][layer] = neighbours
+	entry     int
+	maxLevel  int
+	levelMult float64
+	rng       *rand.Rand
+}
+
+// NewHNSWIndex creates an empty index for vectors of size dim
+func NewHNSWIndex(dim int, cfg HNSWConfig) *HNSWIndex {
+	cfg = cfg.normalize()
+	var storage vectorStorage
+	if cfg.Encoding == EncodingInt8 {
+		storage = &int8Storage{dim: dim}
+	} else {
+		storage = &float32Storage{dim: dim}
+	}
+	return &HNSWIndex{
+		cfg:       cfg,
+		dim:       dim,
+		storage:   storage,
+		entry:     -1,
+		levelMult: 1 / math.Log(float64(cfg.M)),
+		rng:       rand.New(rand.NewSource(cfg.Seed)),
+	}
+}
+
+// Len returns the number of indexed vectors
+func (h *HNSWIndex) Len() int {
+	return len(h.links)
+}
+
+// Dim returns the vector size
+func (h *HNSWIndex) Dim() int {
+	return h.dim
+}
+
+// MemoryBytes estimates the memory held by vectors and links
+func (h *HNSWIndex) MemoryBytes() int {
+	bytes := h.storage.bytes()
+	for _, layers := range h.links {
+		for _, neighbours := range layers {
+			bytes += 4 * cap(neighbours)
+		}
+	}
+	return bytes
+}
+
+// Add inserts a vector and returns its index (insertion order). Vectors of another size
+// are rejected with -1.
+func (h *HNSWIndex) Add(vector []float64) int {
+	if len(vector) != h.dim {
+		return -1
+	}
+
+	query := normalizeFloat32(vector)
+	id := len(h.links)
+	h.storage.add(query)
+
+	level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+	h.links = append(h.links, make([][]int32, level+1))
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = id, level
+		return id
+	}
+
+	// Descend greedily through the layers above the new node's level
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedyClosest(query, ep, l)
+	}
+
+	for l := minInt(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(query, ep, h.cfg.EfConstruction, l)
+		neighbours := h.selectNeighbours(candidates, h.cfg.M)
+		h.links[id][l] = neighbours
+
+		for _, n := range neighbours {
+			h.links[n][l] = append(h.links[n][l], int32(id))
+			if len(h.links[n][l]) > h.maxLinks(l) {
+				h.pruneLinks(int(n), l)
+			}
+		}
+		ep = candidates[0].Index
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = id, level
+	}
+	return id
+}
+
+// Search returns the k approximate nearest vectors, closest first. Small indexes (see
+// HNSWConfig.ExactBelow) are scanned exhaustively instead.
+func (h *HNSWIndex) Search(vector []float64, k int) []ANNHit {
+	return h.SearchEf(vector, k, h.cfg.EfSearch)
+}
+
+// SearchEf is Search with an explicit candidate list size (at least k is used)
+func (h *HNSWIndex) SearchEf(vector []float64, k, ef int) []ANNHit {
+	if h.entry < 0 || k < 1 || len(vector) != h.dim {
+		return nil
+	}
+	if h.Len() < h.cfg.ExactBelow {
+		return h.Exact(vector, k)
+	}
+
+	query := normalizeFloat32(vector)
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedyClosest(query, ep, l)
+	}
+
+	hits := h.searchLayer(query, ep, maxInt(ef, k), 0)
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Exact compares the query with every vector; it is the ground truth for recall
+func (h *HNSWIndex) Exact(vector []float64, k int) []ANNHit {
+	if len(vector) != h.dim || k < 1 {
+		return nil
+	}
+	query := normalizeFloat32(vector)
+	hits := make([]ANNHit, h.Len())
+	for i := range hits {
+		hits[i] = ANNHit{Index: i, Distance: h.distance(query, i)}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Distance < hits[j].Distance })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+func (h *HNSWIndex) distance(query []float32, node int) float64 {
+	return 1 - float64(h.storage.dot(query, node))
+}
+
+func (h *HNSWIndex) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+// greedyClosest walks one layer towards the query until no neighbour is closer
+func (h *HNSWIndex) greedyClosest(query []float32, ep, level int) int {
+	best := h.distance(query, ep)
+	for changed := true; changed; {
+		changed = false
+		for _, n := range h.links[ep][level] {
+			if d := h.distance(query, int(n)); d < best {
+				best, ep, changed = d, int(n), true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer is the beam search of the HNSW paper: it returns up to ef nodes closest to
+// the query on one layer, sorted by distance
+func (h *HNSWIndex) searchLayer(query []float32, ep, ef, level int) []ANNHit {
+	visited := make([]bool, h.Len())
+	visited[ep] = true
+
+	start := ANNHit{Index: ep, Distance: h.distance(query, ep)}
+	candidates := &hitHeap{less: func(a, b ANNHit) bool { return a.Distance < b.Distance }}
+	results := &hitHeap{less: func(a, b ANNHit) bool { return a.Distance > b.Distance }}
+	heap.Push(candidates, start)
+	heap.Push(results, start)
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(ANNHit)
+		if current.Distance > results.items[0].Distance && results.Len() >= ef {
+			break
+		}
+		for _, n := range h.links[current.Index][level] {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+
+			d := h.distance(query, int(n))
+			if results.Len() < ef || d < results.items[0].Distance {
+				hit := ANNHit{Index: int(n), Distance: d}
+				heap.Push(candidates, hit)
+				heap.Push(results, hit)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	hits := results.items
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Distance < hits[j].Distance })
+	return hits
+}
+
+// selectNeighbours applies the diversity heuristic: a candidate is kept only when it is
+// closer to the new node than to any neighbour kept so far, which keeps links spread over
+// clusters. Remaining slots are filled with the closest discarded candidates.
+func (h *HNSWIndex) selectNeighbours(candidates []ANNHit, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var discarded []int32
+	for _, candidate := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		vector := h.storage.vector(candidate.Index)
+		good := true
+		for _, s := range selected {
+			if h.distance(vector, int(s)) < candidate.Distance {
+				good = false
+				break
+			}
+		}
+		if good {
+			selected = append(selected, int32(candidate.Index))
+		} else {
+			discarded = append(discarded, int32(candidate.Index))
+		}
+	}
+	for _, d := range discarded {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, d)
+	}
+	return selected
+}
+
+// pruneLinks shrinks a node's neighbour list back to the layer maximum
+func (h *HNSWIndex) pruneLinks(node, level int) {
+	vector := h.storage.vector(node)
+	neighbours := h.links[node][level]
+	candidates := make([]ANNHit, len(neighbours))
+	for i, n := range neighbours {
+		candidates[i] = ANNHit{Index: int(n), Distance: h.distance(vector, int(n))}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Distance < candidates[j].Distance })
+	h.links[node][level] = h.selectNeighbours(candidates, h.maxLinks(level))
+}
+
+// hitHeap is a binary heap of hits ordered by less
+type hitHeap struct {
+	items []ANNHit
+	less  func(a, b ANNHit) bool
+}
+
+func (h *hitHeap) Len() int           { return len(h.items) }
+func (h *hitHeap) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *hitHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *hitHeap) Push(x interface{}) { h.items = append(h.items, x.(ANNHit)) }
+func (h *hitHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// vectorStorage holds the unit-normalized vectors of an index
+type vectorStorage interface {
+	add(vector []float32)
+	dot(query []float32, i int) float32
+	vector(i int) []float32 // Decoded copy (or view) of a stored vector
+	bytes() int
+}
+
+type float32Storage struct {
+	dim  int
+	data []float32
+}
+
+func (s *float32Storage) add(vector []float32) {
+	s.data = append(s.data, vector...)
+}
+
+func (s *float32Storage) dot(query []float32, i int) float32 {
+	row := s.data[i*s.dim : (i+1)*s.dim]
+	var sum float32
+	for j, q := range query {
+		sum += q * row[j]
+	}
+	return sum
+}
+
+func (s *float32Storage) vector(i int) []float32 {
+	return s.data[i*s.dim : (i+1)*s.dim]
+}
+
+func (s *float32Storage) bytes() int {
+	return 4 * cap(s.data)
+}
+
+// int8Storage quantizes each vector symmetrically: code = round(v / scale), with
+// scale = max|v| / 127. Queries stay float32, so only the stored side loses precision.
+type int8Storage struct {
+	dim    int
+	codes  []int8
+	scales []float32
+}
+
+func (s *int8Storage) add(vector []float32) {
+	var maxAbs float32
+	for _, v := range vector {
+		if a := float32(math.Abs(float64(v))); a > maxAbs {
+			maxAbs = a
+		}
+	}
+	scale := maxAbs / 127
+	for _, v := range vector {
+		code := int8(0)
+		if scale > 0 {
+			code = int8(math.Round(float64(v / scale)))
+		}
+		s.codes = append(s.codes, code)
+	}
+	s.scales = append(s.scales, scale)
+}
+
+func (s *int8Storage) dot(query []float32, i int) float32 {
+	row := s.codes[i*s.dim : (i+1)*s.dim]
+	var sum float32
+	for j, q := range query {
+		sum += q * float32(row[j])
+	}
+	return sum * s.scales[i]
+}
+
+func (s *int8Storage) vector(i int) []float32 {
+	row := s.codes[i*s.dim : (i+1)*s.dim]
+	decoded := make([]float32, s.dim)
+	for j, code := range row {
+		decoded[j] = float32(code) * s.scales[i]
+	}
+	return decoded
+}
+
+func (s *int8Storage) bytes() int {
+	return cap(s.codes) + 4*cap(s.scales)
+}
+
+// normalizeFloat32 converts to float32 with unit length (zero vectors stay zero)
+func normalizeFloat32(vector []float64) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	normalized := make([]float32, len(vector))
+	if norm == 0 {
+		return normalized
+	}
+	for i, v := range vector {
+		normalized[i] = float32(v / norm)
+	}
+	return normalized
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
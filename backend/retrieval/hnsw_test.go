@@ -0,0 +1,121 @@
+package retrieval
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func smallBenchmarkConfig() ANNBenchmarkConfig {
+	cfg := DefaultANNBenchmarkConfig()
+	cfg.Vectors = 2000
+	cfg.Dim = 64
+	cfg.Queries = 50
+	cfg.Clusters = 20
+	cfg.EfValues = []int{64}
+	return cfg
+}
+
+func TestHNSWRecall(t *testing.T) {
+	cfg := smallBenchmarkConfig()
+	points := RunANNBenchmark(cfg)
+
+	minRecall := map[string]float64{EncodingFloat32: 0.9, EncodingInt8: 0.85}
+	for _, p := range points[1:] {
+		if p.RecallAtK < minRecall[p.Encoding] {
+			t.Errorf("%s ef=%d: recall@%d %.3f below %.2f", p.Encoding, p.EfSearch, cfg.K, p.RecallAtK, minRecall[p.Encoding])
+		}
+	}
+
+	float32Bytes, int8Bytes := points[1].IndexBytes, points[2].IndexBytes
+	if int8Bytes >= float32Bytes {
+		t.Errorf("int8 index (%d bytes) should be smaller than float32 (%d bytes)", int8Bytes, float32Bytes)
+	}
+}
+
+func TestHNSWExactBelowThreshold(t *testing.T) {
+	index := NewHNSWIndex(2, HNSWConfig{ExactBelow: 10})
+	index.Add([]float64{1, 0})
+	index.Add([]float64{0, 1})
+	index.Add([]float64{1, 1})
+	if index.Add([]float64{1, 2, 3}) != -1 {
+		t.Error("vectors of another size must be rejected")
+	}
+
+	hits := index.Search([]float64{1, 0.1}, 2)
+	if len(hits) != 2 || hits[0].Index != 0 || hits[1].Index != 2 {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if hits[0].Distance < 0 || hits[0].Distance >= hits[1].Distance {
+		t.Errorf("distances must be ascending cosine distances: %+v", hits)
+	}
+	if index.Search([]float64{1}, 2) != nil {
+		t.Error("queries of another size should return nothing")
+	}
+}
+
+func TestFileRouterSearchMapsChunks(t *testing.T) {
+	router := &FileRouter{
+		indexes:    make(map[string]*FileIndex),
+		lastAccess: make(map[string]time.Time),
+		annConfig:  HNSWConfig{ExactBelow: 1}.normalize(),
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	var chunks []ChunkEmbedding
+	for i := 0; i < 300; i++ {
+		chunks = append(chunks, ChunkEmbedding{
+			ChunkID:   fmt.Sprintf("f_%d", i),
+			Embedding: randomVector(rng, 16, nil, 1),
+			Text:      fmt.Sprintf("chunk %d", i),
+		})
+	}
+	// A chunk without an embedding must not shift the node mapping
+	chunks = append([]ChunkEmbedding{{ChunkID: "f_none", Text: "no vector"}}, chunks...)
+	query := chunks[42].Embedding
+
+	router.AddFileIndex("f", chunks)
+	results := router.SearchInFile("f", query, 3)
+	if len(results) != 3 || results[0].ChunkID != "f_41" || results[0].Text != "chunk 41" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	index, _ := router.GetFileIndex("f")
+	if index.Chunks[42].Embedding != nil {
+		t.Error("stored chunks should not keep float64 embeddings")
+	}
+	if router.HasIndex("f") {
+		t.Error("a file with chunks missing from the graph must fall back to the vector store")
+	}
+}
+
+func benchmarkSearch(b *testing.B, encoding string) {
+	cfg := DefaultANNBenchmarkConfig()
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	indexCfg := cfg.Index
+	indexCfg.Encoding = encoding
+
+	index := NewHNSWIndex(cfg.Dim, indexCfg)
+	for i := 0; i < cfg.Vectors; i++ {
+		index.Add(randomVector(rng, cfg.Dim, nil, 1))
+	}
+	query := randomVector(rng, cfg.Dim, nil, 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Search(query, cfg.K)
+	}
+}
+
+func BenchmarkHNSWSearchFloat32(b *testing.B) { benchmarkSearch(b, EncodingFloat32) }
+func BenchmarkHNSWSearchInt8(b *testing.B)    { benchmarkSearch(b, EncodingInt8) }
+
+// BenchmarkANNRecallLatency prints the recall-vs-latency table:
+// go test ./retrieval -run '^$' -bench ANNRecallLatency -benchtime 1x -v
+func BenchmarkANNRecallLatency(b *testing.B) {
+	cfg := DefaultANNBenchmarkConfig()
+	for i := 0; i < b.N; i++ {
+		b.Log(FormatANNBenchmark(RunANNBenchmark(cfg), cfg.K))
+	}
+}
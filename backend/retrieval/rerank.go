@@ -0,0 +1,173 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"nimbus-backend/llm"
+)
+
+// Reranker names accepted by RERANKER
+const (
+	RerankerHeuristic = "heuristic" // Intent-specific rules, no model calls
+	RerankerLLM       = "llm"       // Scores each question/chunk pair with the chat model
+	RerankerNone      = "none"      // Keeps the retrieval order
+)
+
+// RerankQuery is what a reranker knows about the question
+type RerankQuery struct {
+	Question string
+	Intent   QueryIntent
+	KeyTerms []string
+}
+
+// Reranker reorders retrieved chunks, best first, before they are put into the prompt.
+// Implementations may drop chunks but never add any.
+type Reranker interface {
+	Rerank(ctx context.Context, query RerankQuery, chunks []ChunkResult) ([]ChunkResult, error)
+	Name() string
+}
+
+// NewReranker returns the reranker selected by name. chat is only used by the LLM reranker,
+// which scores the first topN chunks.
+func NewReranker(name string, chat llm.ChatModel, topN int) (Reranker, error) {
+	switch strings.ToLower(name) {
+	case "", RerankerHeuristic:
+		return NewHeuristicReranker(), nil
+	case RerankerLLM:
+		if chat == nil {
+			return nil, fmt.Errorf("the llm reranker needs a chat model")
+		}
+		return NewLLMReranker(chat, topN), nil
+	case RerankerNone:
+		return noopReranker{}, nil
+	}
+	return nil, fmt.Errorf("unknown reranker %q", name)
+}
+
+type noopReranker struct{}
+
+func (noopReranker) Name() string { return RerankerNone }
+
+func (noopReranker) Rerank(ctx context.Context, query RerankQuery, chunks []ChunkResult) ([]ChunkResult, error) {
+	return chunks, nil
+}
+
+// LLMReranker asks the chat model how well each chunk answers the question (pointwise,
+// 0-10) and sorts by that score. Chunks beyond topN keep their retrieval order after the
+// scored ones; ties and unparsable answers keep retrieval order as well.
+type LLMReranker struct {
+	model       llm.ChatModel
+	topN        int
+	concurrency int
+}
+
+// NewLLMReranker creates an LLM reranker scoring at most topN chunks per question
+func NewLLMReranker(model llm.ChatModel, topN int) *LLMReranker {
+	if topN < 1 {
+		topN = 10
+	}
+	return &LLMReranker{model: model, topN: topN, concurrency: 4}
+}
+
+// Name implements Reranker
+func (r *LLMReranker) Name() string { return RerankerLLM }
+
+const rerankPrompt = `You are grading search results. Rate how useful the passage is for answering the question, from 0 (irrelevant) to 10 (answers it directly). Reply with the number only.
+
+Question: %s
+
+Passage:
+%s
+
+Score:`
+
+// Passages are cut to keep scoring prompts short; the start of a chunk carries most of
+// its topic
+const rerankMaxPassageRunes = 2000
+
+var rerankScorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// Rerank implements Reranker. It fails only when no chunk could be scored.
+func (r *LLMReranker) Rerank(ctx context.Context, query RerankQuery, chunks []ChunkResult) ([]ChunkResult, error) {
+	n := len(chunks)
+	if n > r.topN {
+		n = r.topN
+	}
+	if n < 2 {
+		return chunks, nil
+	}
+
+	scores := make([]float64, n)
+	errs := make([]error, n)
+	semaphore := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			scores[i], errs[i] = r.score(ctx, query.Question, chunks[i].Text)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			scores[i] = -1 // Unscored chunks sink below scored ones
+		}
+	}
+	if failed == n {
+		return chunks, fmt.Errorf("llm reranking failed: %w", errs[0])
+	}
+	if failed > 0 {
+		log.Printf("LLM reranker: %d of %d chunks could not be scored", failed, n)
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	reranked := make([]ChunkResult, 0, len(chunks))
+	for _, i := range order {
+		chunk := chunks[i]
+		if scores[i] >= 0 {
+			chunk.Score = scores[i] / 10
+		}
+		reranked = append(reranked, chunk)
+	}
+	return append(reranked, chunks[n:]...), nil
+}
+
+func (r *LLMReranker) score(ctx context.Context, question, passage string) (float64, error) {
+	if runes := []rune(passage); len(runes) > rerankMaxPassageRunes {
+		passage = string(runes[:rerankMaxPassageRunes])
+	}
+	reply, err := r.model.Generate(ctx, fmt.Sprintf(rerankPrompt, question, passage))
+	if err != nil {
+		return 0, err
+	}
+	match := rerankScorePattern.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("no score in reply %q", reply)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, err
+	}
+	if score > 10 {
+		score = 10
+	}
+	return score, nil
+}
@@ -0,0 +1,195 @@
+package retrieval
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// Definition scoring weights: a chunk that opens with the defined term most likely is its
+// definition; mentions early in the chunk, as a standalone word, and longer chunks
+// (more complete definitions) count as well
+const (
+	definitionScoreAtStart    = 100
+	definitionScoreEarly      = 50
+	definitionScoreStandalone = 30
+	definitionScoreLong       = 10
+	definitionScorePerMention = 5
+
+	definitionEarlyChars = 200
+)
+
+// HeuristicReranker applies intent-specific rules without model calls:
+//   - comparison: comparison chunks first, a comparison table containing every key term
+//     at the top, and only that table for questions asking for a specific table
+//   - definition: the chunk that introduces the first key term most prominently at the top
+//
+// Other intents keep the retrieval order.
+type HeuristicReranker struct{}
+
+// NewHeuristicReranker creates the rule-based reranker
+func NewHeuristicReranker() *HeuristicReranker {
+	return &HeuristicReranker{}
+}
+
+// Name implements Reranker
+func (r *HeuristicReranker) Name() string { return RerankerHeuristic }
+
+// Rerank implements Reranker
+func (r *HeuristicReranker) Rerank(ctx context.Context, query RerankQuery, chunks []ChunkResult) ([]ChunkResult, error) {
+	switch query.Intent {
+	case IntentComparison:
+		return rerankComparison(query, chunks), nil
+	case IntentDefinition:
+		if len(query.KeyTerms) > 0 {
+			return rerankDefinition(query.KeyTerms[0], chunks), nil
+		}
+	}
+	return chunks, nil
+}
+
+func rerankComparison(query RerankQuery, chunks []ChunkResult) []ChunkResult {
+	// Comparison chunks first, then others
+	var comparisonChunks, otherChunks []ChunkResult
+	for _, chunk := range chunks {
+		if chunkType, ok := chunk.Metadata["chunk_type"].(string); ok && chunkType == "comparison" {
+			comparisonChunks = append(comparisonChunks, chunk)
+		} else {
+			otherChunks = append(otherChunks, chunk)
+		}
+	}
+	if len(comparisonChunks) > 0 {
+		chunks = append(comparisonChunks, otherChunks...)
+		log.Printf("Reordered chunks: %d comparison chunks prioritized", len(comparisonChunks))
+	}
+
+	// A comparison table mentioning every key term is the perfect match
+	perfectMatchIdx := -1
+	for i, chunk := range chunks {
+		textLower := strings.ToLower(chunk.Text)
+		if !isComparisonTable(textLower) {
+			continue
+		}
+		allTermsFound := true
+		for _, term := range query.KeyTerms {
+			if !strings.Contains(textLower, strings.ToLower(term)) {
+				allTermsFound = false
+				break
+			}
+		}
+		if allTermsFound {
+			perfectMatchIdx = i
+			break
+		}
+	}
+	if perfectMatchIdx < 0 {
+		return chunks
+	}
+
+	chunks = moveToFront(chunks, perfectMatchIdx)
+	if perfectMatchIdx > 0 {
+		log.Printf("🎯 Perfect match comparison table moved to position 1 (was at position %d)", perfectMatchIdx+1)
+	}
+
+	// Questions asking for exactly this table are answered from it alone
+	if isSpecificTableQuery(query.Question, query.KeyTerms) {
+		log.Printf("🔥 Reduced to ONLY perfect match chunk (specific table query)")
+		return chunks[:1]
+	}
+	log.Printf("✅ Keeping all chunks (multi-chunk question or general comparison)")
+	return chunks
+}
+
+func isComparisonTable(textLower string) bool {
+	return strings.Contains(textLower, "comparison table:") || strings.Contains(textLower, "comparison of")
+}
+
+func rerankDefinition(term string, chunks []ChunkResult) []ChunkResult {
+	primaryTerm := strings.ToLower(term)
+
+	bestMatchIdx, bestScore := -1, 0
+	for i, chunk := range chunks {
+		if score := definitionScore(primaryTerm, chunk.Text); score > bestScore {
+			bestScore = score
+			bestMatchIdx = i
+		}
+	}
+
+	if bestMatchIdx > 0 {
+		log.Printf("📖 Definition term '%s' chunk moved to position 1 (was at position %d, score: %d)", primaryTerm, bestMatchIdx+1, bestScore)
+		return moveToFront(chunks, bestMatchIdx)
+	}
+	if bestMatchIdx == 0 {
+		log.Printf("📖 Definition term '%s' already at position 1 (score: %d)", primaryTerm, bestScore)
+	}
+	return chunks
+}
+
+// definitionScore rates how prominently a chunk introduces term (0 if it does not contain it)
+func definitionScore(term, text string) int {
+	textLower := strings.ToLower(text)
+	if !strings.Contains(textLower, term) {
+		return 0
+	}
+
+	score := 0
+	if strings.HasPrefix(textLower, term) {
+		score += definitionScoreAtStart
+	}
+
+	early := textLower
+	if len(early) > definitionEarlyChars {
+		early = early[:definitionEarlyChars]
+	}
+	if strings.Contains(early, term) {
+		score += definitionScoreEarly
+	}
+	if strings.Contains(early, term+" ") || strings.Contains(early, term+"\n") || strings.Contains(early, " "+term+" ") {
+		score += definitionScoreStandalone
+	}
+
+	if len(text) > definitionEarlyChars {
+		score += definitionScoreLong
+	}
+	return score + strings.Count(textLower, term)*definitionScorePerMention
+}
+
+// moveToFront returns chunks with chunks[i] moved to position 0
+func moveToFront(chunks []ChunkResult, i int) []ChunkResult {
+	if i <= 0 {
+		return chunks
+	}
+	reordered := make([]ChunkResult, 0, len(chunks))
+	reordered = append(reordered, chunks[i])
+	reordered = append(reordered, chunks[:i]...)
+	return append(reordered, chunks[i+1:]...)
+}
+
+// isSpecificTableQuery checks if the query is asking for a specific comparison table
+// Returns true if the query is clearly asking for a specific comparison table (e.g., "comparison of X vs Y")
+// Returns false for general questions that might need multiple chunks (e.g., "tell me about X and Y")
+func isSpecificTableQuery(question string, keyTerms []string) bool {
+	qLower := strings.ToLower(question)
+
+	// Check for specific comparison table patterns
+	hasComparisonOf := strings.Contains(qLower, "comparison of")
+	hasCompareVs := strings.Contains(qLower, "compare") && (strings.Contains(qLower, " vs ") || strings.Contains(qLower, " vs. ") || strings.Contains(qLower, " versus "))
+	hasVsComparison := (strings.Contains(qLower, " vs ") && strings.Contains(qLower, "comparison")) ||
+		(strings.Contains(qLower, " vs. ") && strings.Contains(qLower, "comparison")) ||
+		(strings.Contains(qLower, " versus ") && strings.Contains(qLower, "comparison"))
+
+	// Check if query has comparison indicators with key terms
+	hasComparisonIndicators := strings.Contains(qLower, "comparison") ||
+		strings.Contains(qLower, " vs ") ||
+		strings.Contains(qLower, " vs. ") ||
+		strings.Contains(qLower, " versus ")
+
+	// If it's a specific comparison pattern with key terms, it's a table query
+	if (hasComparisonOf || hasCompareVs || hasVsComparison) && hasComparisonIndicators && len(keyTerms) >= 2 {
+		return true
+	}
+
+	// Everything else (general questions such as "tell me about X and Y", or questions
+	// about pages and sections) may need several chunks
+	return false
+}
@@ -0,0 +1,92 @@
+package retrieval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"nimbus-backend/llm"
+)
+
+func chunkIDs(chunks []ChunkResult) string {
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = chunk.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestHeuristicRerankerComparison(t *testing.T) {
+	chunks := []ChunkResult{
+		{ID: "text", Text: "WiFi 6 is faster than WiFi 5."},
+		{ID: "partial", Text: "Comparison table: wifi 5 and wifi 6", Metadata: map[string]interface{}{"chunk_type": "comparison"}},
+		{ID: "table", Text: "Comparison table: wifi 5, wifi 6, wifi 7 speeds", Metadata: map[string]interface{}{"chunk_type": "comparison"}},
+	}
+	reranker := NewHeuristicReranker()
+
+	query := RerankQuery{Question: "tell me about wifi 6 and 7", Intent: IntentComparison, KeyTerms: []string{"wifi", "7"}}
+	got, _ := reranker.Rerank(context.Background(), query, chunks)
+	if chunkIDs(got) != "table,partial,text" {
+		t.Errorf("unexpected order: %s", chunkIDs(got))
+	}
+
+	// Asking for the table itself keeps only the perfect match
+	query.Question = "comparison of wifi 6 vs wifi 7"
+	got, _ = reranker.Rerank(context.Background(), query, chunks)
+	if chunkIDs(got) != "table" {
+		t.Errorf("expected only the table, got %s", chunkIDs(got))
+	}
+	if chunkIDs(chunks) != "text,partial,table" {
+		t.Error("input slice must not be modified")
+	}
+}
+
+func TestHeuristicRerankerDefinition(t *testing.T) {
+	chunks := []ChunkResult{
+		{ID: "mention", Text: "Attacks include smishing and others."},
+		{ID: "definition", Text: "Phishing is a social engineering attack. Phishing emails imitate trusted senders."},
+	}
+	query := RerankQuery{Question: "what is phishing", Intent: IntentDefinition, KeyTerms: []string{"phishing"}}
+	got, _ := NewHeuristicReranker().Rerank(context.Background(), query, chunks)
+	if chunkIDs(got) != "definition,mention" {
+		t.Errorf("unexpected order: %s", chunkIDs(got))
+	}
+}
+
+func TestLLMReranker(t *testing.T) {
+	model := llm.NewFakeChatModel(func(prompt string) string {
+		switch {
+		case strings.Contains(prompt, "best"):
+			return "9"
+		case strings.Contains(prompt, "good"):
+			return "Score: 6.5"
+		case strings.Contains(prompt, "broken"):
+			return "I cannot rate this"
+		}
+		return "1"
+	})
+	chunks := []ChunkResult{
+		{ID: "a", Text: "weak"},
+		{ID: "b", Text: "broken"},
+		{ID: "c", Text: "good"},
+		{ID: "d", Text: "best"},
+		{ID: "e", Text: "best but beyond topN"},
+	}
+
+	got, err := NewLLMReranker(model, 4).Rerank(context.Background(), RerankQuery{Question: "q"}, chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunkIDs(got) != "d,c,a,b,e" {
+		t.Errorf("unexpected order: %s", chunkIDs(got))
+	}
+	if got[0].Score != 0.9 {
+		t.Errorf("expected normalized score 0.9, got %f", got[0].Score)
+	}
+
+	failing := llm.NewFakeChatModel(func(string) string { return "no idea" })
+	got, err = NewLLMReranker(failing, 4).Rerank(context.Background(), RerankQuery{Question: "q"}, chunks)
+	if err == nil || chunkIDs(got) != "a,b,c,d,e" {
+		t.Errorf("expected an error and the original order, got %v %s", err, chunkIDs(got))
+	}
+}
@@ -0,0 +1,414 @@
+package retrieval
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Languages recognized by the keyword analyzer
+const (
+	LangEnglish = "en"
+	LangTurkish = "tr"
+)
+
+// analyzerVersion changes whenever tokenization or stemming changes, so persisted keyword
+// indexes know their term counts are stale
+const analyzerVersion = 1
+
+// turkishFold maps Turkish letters to their ASCII base, so "şifre" and "sifre" match
+var turkishFold = map[rune]rune{
+	'ç': 'c', 'ğ': 'g', 'ı': 'i', 'ö': 'o', 'ş': 's', 'ü': 'u',
+	'â': 'a', 'î': 'i', 'û': 'u',
+}
+
+// englishStopWords and turkishStopWords are stored folded (see foldRune)
+var englishStopWords = wordSet(`a about above after again against all am an and any are as at be because
+been before being below between both but by can could did do does doing down during each few for from
+further had has have having he her here hers herself him himself his how i if in into is it its itself
+just me more most my myself no nor not of off on once only or other our ours ourselves out over own same
+she should so some such than that the their theirs them themselves then there these they this those
+through to too under until up very was we were what when where which while who whom why will with would
+you your yours yourself yourselves`)
+
+var turkishStopWords = wordSet(`acaba ama ancak artik aslinda bana bazi belki ben beni benim bile bir
+biraz biri birkac birsey biz bize bizi bizim bu buna bunda bundan bunu bunun da daha de defa diye dolayi
+en fakat gibi hem hep hepsi her hic icin ile ise kadar ki kim kime kimi mi mu ne neden nerede nereye
+nasil niye o ona onda ondan onlar onlari onlarin onu onun oysa sanki sen seni senin siz size sizi sizin
+su suna sunda sundan sunu sunun ve veya ya yani yine zaten`)
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// tokenize splits text into folded lowercase tokens. Anything after an apostrophe is
+// dropped, which removes Turkish suffixes on proper names ("Ankara'da") and English
+// possessives alike. Single letters and stop words are skipped; numbers are kept.
+func tokenize(text string) []string {
+	var tokens []string
+	var current strings.Builder
+	skipping := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			token := current.String()
+			if (len(token) > 1 || unicode.IsDigit(rune(token[0]))) && !englishStopWords[token] && !turkishStopWords[token] {
+				tokens = append(tokens, token)
+			}
+			current.Reset()
+		}
+		skipping = false
+	}
+
+	for _, r := range text {
+		switch {
+		case r == '\'' || r == '’':
+			if current.Len() > 0 {
+				skipping = true
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !skipping {
+				current.WriteRune(foldRune(r))
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// foldRune lowercases a rune; "I" and "İ" both become "i" so Turkish and English casing agree
+func foldRune(r rune) rune {
+	if r == 'İ' {
+		return 'i'
+	}
+	r = unicode.ToLower(r)
+	if folded, ok := turkishFold[r]; ok {
+		return folded
+	}
+	return r
+}
+
+// detectLanguage guesses whether text is Turkish or English from Turkish-only letters and
+// stop word hits. Short or ambiguous text counts as English.
+func detectLanguage(text string) string {
+	turkish, english := 0, 0
+	for _, r := range text {
+		switch unicode.ToLower(r) {
+		case 'ç', 'ğ', 'ı', 'ö', 'ş', 'ü':
+			turkish++
+		}
+		if r == 'İ' {
+			turkish++
+		}
+	}
+	for _, word := range strings.Fields(text) {
+		word = strings.ToLower(strings.Trim(word, ".,;:!?()\"'"))
+		if englishStopWords[word] {
+			english += 2
+		}
+		folded := strings.Map(foldRune, word)
+		if turkishStopWords[folded] && !englishStopWords[folded] {
+			turkish += 2
+		}
+	}
+	if turkish > english {
+		return LangTurkish
+	}
+	return LangEnglish
+}
+
+// analyzeDocument turns chunk text into index terms, stemmed for the detected language
+func analyzeDocument(text string) []string {
+	stem := stemEnglish
+	if detectLanguage(text) == LangTurkish {
+		stem = stemTurkish
+	}
+	tokens := tokenize(text)
+	for i, token := range tokens {
+		tokens[i] = stem(token)
+	}
+	return tokens
+}
+
+// analyzeQuery returns the stem variants of every distinct query word. Queries are often
+// in another language than the document (a Turkish question about an English PDF), so
+// each word is stemmed both ways and matches either form.
+func analyzeQuery(text string) [][]string {
+	var words [][]string
+	seen := make(map[string]bool)
+	for _, token := range tokenize(text) {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		variants := []string{stemEnglish(token)}
+		if turkish := stemTurkish(token); turkish != variants[0] {
+			variants = append(variants, turkish)
+		}
+		words = append(words, variants)
+	}
+	return words
+}
+
+// stemTurkish is a light suffix stripper for folded Turkish words: it repeatedly removes
+// the longest known inflectional suffix while a stem of at least three letters remains,
+// and undoes consonant softening (kitab-ı -> kitap) before vowel-initial suffixes.
+func stemTurkish(word string) string {
+	if !isASCIIWord(word) {
+		return word
+	}
+	for round := 0; round < 3; round++ {
+		suffix := longestSuffix(word, turkishSuffixes)
+		if suffix == "" {
+			break
+		}
+		minStem := 3
+		if len(suffix) == 1 {
+			minStem = 4
+		}
+		if len(word)-len(suffix) < minStem {
+			break
+		}
+		word = word[:len(word)-len(suffix)]
+		if strings.ContainsRune("aeiou", rune(suffix[0])) {
+			word = hardenConsonant(word)
+		}
+	}
+	return word
+}
+
+// turkishSuffixes lists folded noun suffixes (plural, possessive, case, "-ki", "-lik",
+// "-li", "-siz", "-ci", copula) and the infinitive; vowel harmony variants collapse
+// after folding (lık/lik/luk/lük -> lik, luk)
+var turkishSuffixes = wordSet(`lar ler im um in un imiz umuz iniz unuz si su i u a e ya ye yi yu
+da de ta te dan den tan ten nda nde ndan nden nin nun na ne ni nu la le yla yle ki lik luk li lu siz
+suz ci cu dir dur tir tur mak mek`)
+
+func hardenConsonant(stem string) string {
+	last := len(stem) - 1
+	switch stem[last] {
+	case 'b':
+		return stem[:last] + "p"
+	case 'd':
+		return stem[:last] + "t"
+	case 'g':
+		return stem[:last] + "k"
+	}
+	return stem
+}
+
+func longestSuffix(word string, suffixes map[string]bool) string {
+	for n := len(word) - 1; n > 0; n-- {
+		if suffixes[word[len(word)-n:]] {
+			return word[len(word)-n:]
+		}
+	}
+	return ""
+}
+
+func isASCIIWord(word string) bool {
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return false
+		}
+	}
+	return len(word) > 2
+}
+
+// stemEnglish implements the Porter stemming algorithm
+func stemEnglish(word string) string {
+	if !isASCIIWord(word) {
+		return word
+	}
+	w := []byte(word)
+	w = porterStep1a(w)
+	w = porterStep1b(w)
+	w = porterStep1c(w)
+	w = porterReplace(w, porterStep2, 0)
+	w = porterReplace(w, porterStep3, 0)
+	w = porterStep4(w)
+	w = porterStep5(w)
+	return string(w)
+}
+
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences of a stem ([C](VC)^m[V])
+func measure(w []byte) int {
+	m, i, n := 0, 0, len(w)
+	for i < n && isConsonant(w, i) {
+		i++
+	}
+	for i < n {
+		for i < n && !isConsonant(w, i) {
+			i++
+		}
+		if i >= n {
+			break
+		}
+		for i < n && isConsonant(w, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports consonant-vowel-consonant endings where the last is not w, x or y
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-1) || isConsonant(w, n-2) || !isConsonant(w, n-3) {
+		return false
+	}
+	return w[n-1] != 'w' && w[n-1] != 'x' && w[n-1] != 'y'
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return len(w) >= len(suffix) && string(w[len(w)-len(suffix):]) == suffix
+}
+
+func porterStep1a(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"), hasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func porterStep1b(w []byte) []byte {
+	if hasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+
+	var stem []byte
+	switch {
+	case hasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case hasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(stem, 'e')
+	case endsDoubleConsonant(stem):
+		if last := stem[len(stem)-1]; last != 'l' && last != 's' && last != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem, 'e')
+	}
+	return stem
+}
+
+func porterStep1c(w []byte) []byte {
+	if hasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		w[len(w)-1] = 'i'
+	}
+	return w
+}
+
+// Suffix replacements of steps 2 and 3, longest first
+var porterStep2 = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"}, {"izer", "ize"},
+	{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"},
+	{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"},
+	{"fulness", "ful"}, {"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var porterStep3 = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"}, {"ical", "ic"},
+	{"ful", ""}, {"ness", ""},
+}
+
+var porterStep4Suffixes = []string{
+	"ement", "ance", "ence", "able", "ible", "ment", "ant", "ent", "ism", "ate", "iti",
+	"ous", "ive", "ize", "ion", "al", "er", "ic", "ou",
+}
+
+// porterReplace applies the longest matching rule when the remaining stem has a measure
+// above minMeasure; only the longest match is considered, as in the original algorithm
+func porterReplace(w []byte, rules [][2]string, minMeasure int) []byte {
+	best := -1
+	for i, rule := range rules {
+		if hasSuffix(w, rule[0]) && (best < 0 || len(rule[0]) > len(rules[best][0])) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return w
+	}
+	stem := w[:len(w)-len(rules[best][0])]
+	if measure(stem) > minMeasure {
+		return append(stem, rules[best][1]...)
+	}
+	return w
+}
+
+func porterStep4(w []byte) []byte {
+	for _, suffix := range porterStep4Suffixes {
+		if !hasSuffix(w, suffix) {
+			continue
+		}
+		stem := w[:len(w)-len(suffix)]
+		if measure(stem) <= 1 {
+			return w
+		}
+		if suffix == "ion" && !hasSuffix(stem, "s") && !hasSuffix(stem, "t") {
+			return w
+		}
+		return stem
+	}
+	return w
+}
+
+func porterStep5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		if m := measure(stem); m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && endsDoubleConsonant(w) && hasSuffix(w, "l") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
@@ -50,6 +50,8 @@ func SetupRoutes(app *fiber.App, cfg *config.Config) {
 		files.Get("/content", handlers.GetFileContent(cfg))                // Get file content as text (for code files)
 		files.Put("/:id/content", handlers.UpdateFileContent(cfg))         // Update file content (for code files)
 		files.Post("/:id/process", handlers.ProcessDocument(cfg))          // Trigger document processing for RAG
+		files.Get("/:id/processing", handlers.GetProcessingStatus(cfg))    // Processing stage, progress and ETA
+		files.Get("/:id/processing/stream", handlers.StreamProcessingStatus(cfg)) // Processing progress via SSE
 	}
 
 	// AI routes (protected)
@@ -57,9 +59,18 @@ func SetupRoutes(app *fiber.App, cfg *config.Config) {
 	ai.Use(middleware.RequireAuth(cfg.JWTSecret))
 	{
 		ai.Post("/query", handlers.QueryDocument(cfg))                     // Query processed document
+		ai.Post("/query/stream", handlers.QueryDocumentStream(cfg))        // Query with SSE token streaming
 		ai.Get("/conversation", handlers.GetConversationHistory(cfg))      // Get chat history for a file
 		ai.Get("/conversations", handlers.GetUserConversations(cfg))       // Get all user conversations
 		ai.Delete("/conversation", handlers.ClearConversationHistory(cfg)) // Clear chat history
+		ai.Get("/threads", handlers.ListThreads(cfg))                      // List chat threads of a scope
+		ai.Post("/threads", handlers.CreateThread(cfg))                    // Start a new chat thread
+		ai.Patch("/threads/:id", handlers.UpdateThread(cfg))               // Rename / pin a thread
+		ai.Delete("/threads/:id", handlers.DeleteThread(cfg))              // Delete a thread and its messages
+		ai.Get("/threads/:id/messages", handlers.GetThreadMessages(cfg))   // Paginated thread messages
+		ai.Put("/threads/:id/messages/:messageId/feedback", handlers.SetMessageFeedback(cfg))      // Rate an answer
+		ai.Delete("/threads/:id/messages/:messageId/feedback", handlers.ClearMessageFeedback(cfg)) // Remove a rating
+		ai.Get("/feedback/stats", handlers.GetFeedbackStats(cfg))          // Answer quality by retrieval strategy (admin)
 	}
 
 	// OnlyOffice callback (public - called by OnlyOffice server)
@@ -95,10 +106,41 @@ func SetupRoutes(app *fiber.App, cfg *config.Config) {
 		shares.Get("/shared-folder/:folderId", handlers.GetSharedFolderContents())
 		shares.Put("/access/:resourceId", handlers.UpdateAccessPermission())
 		shares.Delete("/access/:resourceId/:userId", handlers.RemoveUserAccess())
+		shares.Delete("/invitations/:invitationId", handlers.CancelInvitation())
 		// Public link access (no JWT required for link generation, but required for access)
 		shares.Get("/public/:publicLink", handlers.GetResourceByPublicLink())
 	}
 
+	// Ownership transfer routes (protected)
+	transfers := api.Group("/transfers")
+	transfers.Use(middleware.RequireAuth(cfg.JWTSecret))
+	{
+		transfers.Post("/", handlers.CreateOwnershipTransfer(cfg))
+		transfers.Get("/incoming", handlers.GetIncomingTransfers(cfg))
+		transfers.Get("/outgoing", handlers.GetOutgoingTransfers(cfg))
+		transfers.Post("/:id/accept", handlers.AcceptOwnershipTransfer(cfg))
+		transfers.Post("/:id/decline", handlers.DeclineOwnershipTransfer(cfg))
+		transfers.Delete("/:id", handlers.CancelOwnershipTransfer(cfg))
+	}
+
+	// Audit log routes (protected, non-admins only see their own resources)
+	audit := api.Group("/audit")
+	audit.Use(middleware.RequireAuth(cfg.JWTSecret))
+	{
+		audit.Get("/", handlers.GetAuditEvents(cfg))
+		audit.Get("/export", handlers.ExportAuditEvents(cfg))
+	}
+
+	// Document processing job routes (protected, non-admins only see their own files' jobs)
+	jobs := api.Group("/jobs")
+	jobs.Use(middleware.RequireAuth(cfg.JWTSecret))
+	{
+		jobs.Get("/", handlers.ListJobs(cfg))
+		jobs.Get("/:id", handlers.GetJob(cfg))
+		jobs.Post("/:id/retry", handlers.RetryJob(cfg))
+		jobs.Post("/:id/cancel", handlers.CancelJob(cfg))
+	}
+
 	// User search (protected)
 	users := api.Group("/users")
 	users.Use(middleware.RequireAuth(cfg.JWTSecret))
@@ -2,12 +2,17 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"nimbus-backend/database"
 	"nimbus-backend/helpers"
 	"nimbus-backend/models"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AccessControlService struct{}
@@ -26,17 +31,11 @@ func (acs *AccessControlService) GetAccessibleUsers(resourceType, resourceID str
 		return nil, err
 	}
 
-	var accessList []struct {
-		UserID     string `bson:"user_id"`
-		AccessType string `bson:"access_type"`
-	}
+	var accessList []models.AccessEntry
 
 	if resourceType == "file" {
 		var file struct {
-			AccessList []struct {
-				UserID     string `bson:"user_id"`
-				AccessType string `bson:"access_type"`
-			} `bson:"access_list"`
+			AccessList []models.AccessEntry `bson:"access_list"`
 		}
 
 		err = database.FileCollection.FindOne(context.Background(), bson.M{
@@ -50,10 +49,7 @@ func (acs *AccessControlService) GetAccessibleUsers(resourceType, resourceID str
 		accessList = file.AccessList
 	} else if resourceType == "folder" {
 		var folder struct {
-			AccessList []struct {
-				UserID     string `bson:"user_id"`
-				AccessType string `bson:"access_type"`
-			} `bson:"access_list"`
+			AccessList []models.AccessEntry `bson:"access_list"`
 		}
 
 		err = database.FolderCollection.FindOne(context.Background(), bson.M{
@@ -69,7 +65,7 @@ func (acs *AccessControlService) GetAccessibleUsers(resourceType, resourceID str
 
 	// Extract user IDs
 	userIDs := make([]string, 0, len(accessList))
-	for _, access := range accessList {
+	for _, access := range helpers.FilterActiveAccess(accessList) {
 		userIDs = append(userIDs, access.UserID)
 	}
 
@@ -80,8 +76,8 @@ func (acs *AccessControlService) GetAccessibleUsers(resourceType, resourceID str
 // GetAccessTypeFromList - Get access type for user from access list
 func (acs *AccessControlService) GetAccessTypeFromList(accessList []models.AccessEntry, userID string) string {
 	for _, access := range accessList {
-		if access.UserID == userID {
-			return access.AccessType
+		if access.UserID == userID && helpers.IsAccessEntryActive(access) {
+			return helpers.NormalizeRole(access.AccessType)
 		}
 	}
 	return "none"
@@ -94,9 +90,14 @@ func (acs *AccessControlService) AddUserAccess(resourceType, resourceID, userID,
 		return err
 	}
 
+	role := helpers.NormalizeRole(accessType)
+	if role == "" {
+		return fmt.Errorf("invalid role: %s", accessType)
+	}
+
 	accessEntry := models.AccessEntry{
 		UserID:     userID,
-		AccessType: accessType,
+		AccessType: role,
 	}
 
 	if resourceType == "file" {
@@ -151,3 +152,34 @@ func (acs *AccessControlService) RemoveUserAccess(resourceType, resourceID, user
 
 	return err
 }
+
+// MigrateLegacyRoles - Rewrite legacy "read"/"write" access entries to viewer/editor roles
+func (acs *AccessControlService) MigrateLegacyRoles() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	legacy := map[string]string{
+		"read":  helpers.RoleViewer,
+		"write": helpers.RoleEditor,
+	}
+
+	for _, collection := range []*mongo.Collection{database.FileCollection, database.FolderCollection} {
+		for oldRole, newRole := range legacy {
+			result, err := collection.UpdateMany(ctx,
+				bson.M{"access_list.access_type": oldRole},
+				bson.M{"$set": bson.M{"access_list.$[entry].access_type": newRole}},
+				options.Update().SetArrayFilters(options.ArrayFilters{
+					Filters: []interface{}{bson.M{"entry.access_type": oldRole}},
+				}),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to migrate %s roles in %s: %w", oldRole, collection.Name(), err)
+			}
+			if result.ModifiedCount > 0 {
+				log.Printf("Migrated %d %s documents from %q to %q", result.ModifiedCount, collection.Name(), oldRole, newRole)
+			}
+		}
+	}
+
+	return nil
+}
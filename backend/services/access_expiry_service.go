@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nimbus-backend/database"
+	"nimbus-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AccessExpiryService struct{}
+
+var AccessExpiryServiceInstance = &AccessExpiryService{}
+
+// StartSweeper - Süresi dolan erişimleri periyodik olarak temizler
+func (aes *AccessExpiryService) StartSweeper(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if removed, err := aes.SweepExpiredGrants(); err != nil {
+				log.Printf("⚠️ Erişim süresi temizleme hatası: %v", err)
+			} else if removed > 0 {
+				log.Printf("🧹 %d süresi dolmuş erişim kaldırıldı", removed)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("✅ Erişim süresi temizleyici başlatıldı (%s)", interval)
+}
+
+// SweepExpiredGrants - Süresi dolmuş access_list girişlerini kaldırır ve paylaşanı bilgilendirir.
+// Miras alınan (inherited) girişler ana girişle aynı süreye sahip olduğu için sadece
+// doğrudan verilen erişimler için bildirim gönderilir.
+func (aes *AccessExpiryService) SweepExpiredGrants() (int, error) {
+	removed := 0
+
+	for resourceType, collection := range map[string]*mongo.Collection{
+		"file":   database.FileCollection,
+		"folder": database.FolderCollection,
+	} {
+		count, err := aes.sweepCollection(resourceType, collection)
+		removed += count
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+func (aes *AccessExpiryService) sweepCollection(resourceType string, collection *mongo.Collection) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	cursor, err := collection.Find(ctx, bson.M{"access_list.expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return 0, fmt.Errorf("%s kayıtları alınamadı: %v", resourceType, err)
+	}
+	defer cursor.Close(ctx)
+
+	removed := 0
+	for cursor.Next(ctx) {
+		var resource struct {
+			ID         primitive.ObjectID   `bson:"_id"`
+			Filename   string               `bson:"filename"`
+			Name       string               `bson:"name"`
+			AccessList []models.AccessEntry `bson:"access_list"`
+		}
+		if err := cursor.Decode(&resource); err != nil {
+			continue
+		}
+
+		var expired []models.AccessEntry
+		for _, entry := range resource.AccessList {
+			if entry.ExpiresAt != nil && !entry.ExpiresAt.After(now) {
+				expired = append(expired, entry)
+			}
+		}
+		if len(expired) == 0 {
+			continue
+		}
+
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": resource.ID},
+			bson.M{
+				"$pull": bson.M{"access_list": bson.M{"expires_at": bson.M{"$lte": now}}},
+				"$set":  bson.M{"updated_at": now},
+			},
+		)
+		if err != nil {
+			log.Printf("Süresi dolmuş erişim kaldırılamadı %s: %v", resource.ID.Hex(), err)
+			continue
+		}
+		removed += len(expired)
+
+		name := resource.Filename
+		if name == "" {
+			name = resource.Name
+		}
+		for _, entry := range expired {
+			if entry.InheritedFrom == nil {
+				aes.notifyGranter(entry, name)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// notifyGranter - Erişimi veren kullanıcıya sürenin dolduğunu bildir
+func (aes *AccessExpiryService) notifyGranter(entry models.AccessEntry, resourceName string) {
+	if MailerInstance == nil || entry.GrantedBy == "" {
+		return
+	}
+
+	granter, err := UserServiceInstance.GetUserByID(entry.GrantedBy)
+	if err != nil || granter.Email == "" {
+		return
+	}
+
+	grantee := entry.UserID
+	if user := UserServiceInstance.GetUserResponse(entry.UserID); user != nil {
+		grantee = user.Name
+		if grantee == "" {
+			grantee = user.Email
+		}
+	}
+
+	err = MailerInstance.Send(MailMessage{
+		To:      granter.Email,
+		Subject: fmt.Sprintf("\"%s\" için paylaşım süresi doldu", resourceName),
+		Body: fmt.Sprintf(
+			"%s kullanıcısının \"%s\" öğesine verdiğiniz %s erişiminin süresi %s tarihinde doldu ve erişim kaldırıldı.\n",
+			grantee, resourceName, entry.AccessType, entry.ExpiresAt.Format("02.01.2006 15:04"),
+		),
+	})
+	if err != nil {
+		log.Printf("Süre dolumu bildirimi gönderilemedi (%s): %v", granter.Email, err)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nimbus-backend/database"
+	"nimbus-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditService - Append-only audit kaydı. Bilinçli olarak güncelleme/silme metodu yoktur.
+type AuditService struct{}
+
+var AuditServiceInstance = &AuditService{}
+
+// Record - Audit event'i kaydet. Hata ana işlemi engellemez, sadece loglanır.
+func (as *AuditService) Record(event models.AuditEvent) {
+	if database.AuditCollection == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event.ID = primitive.NewObjectID()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if _, err := database.AuditCollection.InsertOne(ctx, event); err != nil {
+		log.Printf("Audit kaydı yazılamadı (%s): %v", event.Action, err)
+	}
+}
+
+// Query - Filtrelenmiş ve sayfalanmış audit kayıtları (en yeni önce)
+func (as *AuditService) Query(filter models.AuditFilter, page, limit int64) ([]models.AuditEvent, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := buildAuditQuery(filter)
+
+	total, err := database.AuditCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("audit kayıtları sayılamadı: %v", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"timestamp": -1}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+
+	cursor, err := database.AuditCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("audit kayıtları alınamadı: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, fmt.Errorf("audit kayıtları decode edilemedi: %v", err)
+	}
+
+	return events, total, nil
+}
+
+// Stream - Filtreye uyan tüm kayıtları sırayla fn'e verir (export için)
+func (as *AuditService) Stream(ctx context.Context, filter models.AuditFilter, fn func(models.AuditEvent) error) error {
+	cursor, err := database.AuditCollection.Find(ctx, buildAuditQuery(filter), options.Find().SetSort(bson.M{"timestamp": 1}))
+	if err != nil {
+		return fmt.Errorf("audit kayıtları alınamadı: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var event models.AuditEvent
+		if err := cursor.Decode(&event); err != nil {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func buildAuditQuery(filter models.AuditFilter) bson.M {
+	query := bson.M{}
+	if filter.ActorID != "" {
+		query["actor_id"] = filter.ActorID
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.ResourceType != "" {
+		query["resource_type"] = filter.ResourceType
+	}
+	if filter.ResourceID != "" {
+		query["resource_id"] = filter.ResourceID
+	}
+	if filter.OwnerID != "" {
+		query["owner_id"] = filter.OwnerID
+	}
+
+	if filter.From != nil || filter.To != nil {
+		timeRange := bson.M{}
+		if filter.From != nil {
+			timeRange["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			timeRange["$lte"] = *filter.To
+		}
+		query["timestamp"] = timeRange
+	}
+
+	return query
+}
+
+// EnsureIndexes - Sık kullanılan sorgular için index oluştur
+func (as *AuditService) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := database.AuditCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "resource_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+	})
+	return err
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+
+	"nimbus-backend/models"
+)
+
+// citationMarkerPattern matches [1], [12] and the comma form [1, 3]
+var citationMarkerPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+var citationNumberPattern = regexp.MustCompile(`\d+`)
+
+// ExtractCitationNumbers returns the distinct citation numbers referenced in an answer, ascending
+func ExtractCitationNumbers(answer string) []int {
+	seen := make(map[int]bool)
+	for _, marker := range citationMarkerPattern.FindAllStringSubmatch(answer, -1) {
+		for _, raw := range citationNumberPattern.FindAllString(marker[1], -1) {
+			if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+				seen[n] = true
+			}
+		}
+	}
+
+	numbers := make([]int, 0, len(seen))
+	for n := range seen {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// BuildCitations numbers the retrieved chunks in prompt order (1-based) and marks the ones
+// the answer actually cites. Markers pointing outside the retrieved set are ignored.
+func BuildCitations(chunks []ChunkResult, answer string) []models.Citation {
+	cited := make(map[int]bool)
+	for _, n := range ExtractCitationNumbers(answer) {
+		cited[n] = true
+	}
+
+	citations := make([]models.Citation, 0, len(chunks))
+	for i, chunk := range chunks {
+		number := i + 1
+
+		preview := chunk.Text
+		if len(preview) > 200 {
+			preview = preview[:200] + "..."
+		}
+
+		fileID, _ := chunk.Metadata["file_id"].(string)
+		pageStart, pageEnd := chunk.PageRange()
+		filename, _ := chunk.Metadata["filename"].(string)
+		symbol, _ := chunk.Metadata["symbol_name"].(string)
+		lineStart, lineEnd := chunk.LineRange()
+
+		citations = append(citations, models.Citation{
+			Number:     number,
+			ChunkID:    chunk.ID,
+			FileID:     fileID,
+			Filename:   filename,
+			ChunkIndex: metadataInt(chunk.Metadata, "chunk_index"),
+			StartChar:  metadataInt(chunk.Metadata, "start_char"),
+			EndChar:    metadataInt(chunk.Metadata, "end_char"),
+			PageStart:  pageStart,
+			PageEnd:    pageEnd,
+			Symbol:     symbol,
+			LineStart:  lineStart,
+			LineEnd:    lineEnd,
+			Score:      chunk.Score,
+			Preview:    preview,
+			Cited:      cited[number],
+		})
+	}
+
+	return citations
+}
+
+// PageRange returns the pages a chunk spans, or 0, 0 for formats without pages
+func (r ChunkResult) PageRange() (int, int) {
+	return metadataInt(r.Metadata, "page_start"), metadataInt(r.Metadata, "page_end")
+}
+
+// LineRange returns the source lines of a code chunk, or 0, 0 for other documents
+func (r ChunkResult) LineRange() (int, int) {
+	return metadataInt(r.Metadata, "line_start"), metadataInt(r.Metadata, "line_end")
+}
+
+// RowRange returns the data rows of a spreadsheet row group chunk, or 0, 0 for other documents
+func (r ChunkResult) RowRange() (int, int) {
+	return metadataInt(r.Metadata, "row_start"), metadataInt(r.Metadata, "row_end")
+}
+
+// metadataInt reads a numeric metadata value; vector stores return JSON numbers as float64
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
@@ -2,8 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"nimbus-backend/database"
 	"nimbus-backend/helpers"
@@ -11,6 +15,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -18,141 +23,357 @@ type ConversationService struct{}
 
 var ConversationServiceInstance = &ConversationService{}
 
-// GetOrCreateConversation gets existing conversation for a file or creates a new one
-func (s *ConversationService) GetOrCreateConversation(userID, fileID string) (*models.Conversation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// ErrThreadNotFound is returned for unknown threads, threads of other users and threads
+// that belong to a different scope than the one asked for
+var ErrThreadNotFound = errors.New("conversation thread not found")
 
-	// Try to find existing conversation
-	var conversation models.Conversation
-	err := database.ConversationCollection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"file_id": fileID,
-	}).Decode(&conversation)
+// threadTitleMaxRunes caps generated titles; longer first questions are cut at a word boundary
+const threadTitleMaxRunes = 60
 
-	if err == nil {
-		// Found existing conversation
-		return &conversation, nil
-	}
+// threadSort lists pinned threads first, then the most recently active ones
+var threadSort = bson.D{{Key: "pinned", Value: -1}, {Key: "updated_at", Value: -1}}
+
+// CreateThread starts a new thread for a scope. An empty title is generated from the first
+// question asked in the thread.
+func (s *ConversationService) CreateThread(userID string, scope models.ConversationScope, title string) (*models.Conversation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Create new conversation
-	conversation = models.Conversation{
+	now := time.Now()
+	conversation := models.Conversation{
 		ID:        primitive.NewObjectID(),
-		FileID:    fileID,
 		UserID:    userID,
-		Messages:  []models.Message{},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Title:     strings.TrimSpace(title),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	// Single-file threads keep using file_id so they match the legacy conversations
+	if scope.Type == "" || scope.Type == models.ScopeFile {
+		conversation.FileID = scope.ID
+	} else {
+		conversation.Scope = &scope
+		conversation.ScopeKey = scope.Key()
 	}
 
-	_, err = database.ConversationCollection.InsertOne(ctx, conversation)
-	if err != nil {
+	if _, err := database.ConversationCollection.InsertOne(ctx, conversation); err != nil {
 		return nil, fmt.Errorf("failed to create conversation: %w", err)
 	}
 
 	return &conversation, nil
 }
 
-// AddMessage adds a new message to the conversation
-func (s *ConversationService) AddMessage(userID, fileID string, message models.Message) error {
+// GetThread returns a thread owned by the user
+func (s *ConversationService) GetThread(userID, threadID string) (*models.Conversation, error) {
+	objectID, err := primitive.ObjectIDFromHex(threadID)
+	if err != nil {
+		return nil, ErrThreadNotFound
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Ensure message has timestamp
-	if message.Timestamp.IsZero() {
-		message.Timestamp = time.Now()
+	var conversation models.Conversation
+	err = database.ConversationCollection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userID}).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrThreadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
 
-	// Update or insert conversation
-	filter := bson.M{
-		"user_id": userID,
-		"file_id": fileID,
+	return &conversation, nil
+}
+
+// ResolveThread returns the thread a request refers to: the given thread, which must belong
+// to scope, or without an ID the most recently active thread of the scope (nil if it has none)
+func (s *ConversationService) ResolveThread(userID string, scope models.ConversationScope, threadID string) (*models.Conversation, error) {
+	if threadID != "" {
+		conversation, err := s.GetThread(userID, threadID)
+		if err != nil {
+			return nil, err
+		}
+		if conversation.ThreadScope().Key() != scope.Key() {
+			return nil, ErrThreadNotFound
+		}
+		return conversation, nil
 	}
 
-	update := bson.M{
-		"$push": bson.M{"messages": message},
-		"$set":  bson.M{"updated_at": time.Now()},
-		"$setOnInsert": bson.M{
-			"created_at": time.Now(),
-		},
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var conversation models.Conversation
+	opts := options.FindOne().SetSort(bson.M{"updated_at": -1})
+	err := database.ConversationCollection.FindOne(ctx, conversationFilter(userID, scope), opts).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
 
-	opts := options.Update().SetUpsert(true)
-	_, err := database.ConversationCollection.UpdateOne(ctx, filter, update, opts)
+	return &conversation, nil
+}
+
+// ListThreads returns the user's threads for a scope, pinned first
+func (s *ConversationService) ListThreads(userID string, scope models.ConversationScope) ([]models.Conversation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(threadSort).SetProjection(bson.M{"messages": 0})
+	cursor, err := database.ConversationCollection.Find(ctx, conversationFilter(userID, scope), opts)
 	if err != nil {
-		return fmt.Errorf("failed to add message: %w", err)
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
 	}
+	defer cursor.Close(ctx)
 
-	return nil
+	threads := []models.Conversation{}
+	if err := cursor.All(ctx, &threads); err != nil {
+		return nil, fmt.Errorf("failed to decode conversations: %w", err)
+	}
+
+	return threads, nil
 }
 
-// GetConversation retrieves the conversation for a specific file
-func (s *ConversationService) GetConversation(userID, fileID string) (*models.Conversation, error) {
+// UpdateThread renames and/or pins a thread
+func (s *ConversationService) UpdateThread(userID, threadID string, req models.UpdateThreadRequest) (*models.Conversation, error) {
+	objectID, err := primitive.ObjectIDFromHex(threadID)
+	if err != nil {
+		return nil, ErrThreadNotFound
+	}
+
+	set := bson.M{}
+	if req.Title != nil {
+		set["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.Pinned != nil {
+		set["pinned"] = *req.Pinned
+	}
+	if len(set) == 0 {
+		return s.GetThread(userID, threadID)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	var conversation models.Conversation
-	err := database.ConversationCollection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"file_id": fileID,
-	}).Decode(&conversation)
-
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = database.ConversationCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "user_id": userID},
+		bson.M{"$set": set},
+		opts,
+	).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrThreadNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("conversation not found: %w", err)
+		return nil, fmt.Errorf("failed to update conversation: %w", err)
 	}
 
 	return &conversation, nil
 }
 
-// DeleteConversationsByFileID deletes all conversations for a specific file
-func (s *ConversationService) DeleteConversationsByFileID(fileID string) error {
+// DeleteThread deletes a thread together with its messages
+func (s *ConversationService) DeleteThread(userID, threadID string) error {
+	conversation, err := s.GetThread(userID, threadID)
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err := database.ConversationCollection.DeleteMany(ctx, bson.M{
-		"file_id": fileID,
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to delete conversations: %w", err)
+	if _, err := database.MessageCollection.DeleteMany(ctx, bson.M{"conversation_id": conversation.ID}); err != nil {
+		return fmt.Errorf("failed to delete conversation messages: %w", err)
+	}
+	if _, err := database.ConversationCollection.DeleteOne(ctx, bson.M{"_id": conversation.ID}); err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
 	}
 
 	return nil
 }
 
-// ClearConversation removes all messages from a conversation (but keeps the conversation record)
-func (s *ConversationService) ClearConversation(userID, fileID string) error {
+// ClearThread removes all messages from a thread but keeps the thread itself
+func (s *ConversationService) ClearThread(conversation *models.Conversation) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if _, err := database.MessageCollection.DeleteMany(ctx, bson.M{"conversation_id": conversation.ID}); err != nil {
+		return fmt.Errorf("failed to clear conversation: %w", err)
+	}
+
 	update := bson.M{
 		"$set": bson.M{
-			"messages":   []models.Message{},
-			"updated_at": time.Now(),
+			"message_count": 0,
+			"updated_at":    time.Now(),
 		},
 	}
+	if _, err := database.ConversationCollection.UpdateOne(ctx, bson.M{"_id": conversation.ID}, update); err != nil {
+		return fmt.Errorf("failed to clear conversation: %w", err)
+	}
 
-	_, err := database.ConversationCollection.UpdateOne(ctx, bson.M{
-		"user_id": userID,
-		"file_id": fileID,
-	}, update)
+	return nil
+}
+
+// AddMessage appends a message to a thread and sets its ID. The first user message names an
+// untitled thread.
+func (s *ConversationService) AddMessage(conversation *models.Conversation, message *models.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Ensure message has timestamp
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+	message.ID = primitive.NewObjectID()
+	message.ConversationID = conversation.ID
 
+	if _, err := database.MessageCollection.InsertOne(ctx, message); err != nil {
+		return fmt.Errorf("failed to add message: %w", err)
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"message_count": 1},
+		"$set": bson.M{"updated_at": message.Timestamp},
+	}
+	if _, err := database.ConversationCollection.UpdateOne(ctx, bson.M{"_id": conversation.ID}, update); err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+	conversation.MessageCount++
+	conversation.UpdatedAt = message.Timestamp
+
+	// Only an untitled thread is named, so a rename in the meantime wins
+	if conversation.Title == "" && message.Role == "user" {
+		title := generateThreadTitle(message.Content)
+		_, err := database.ConversationCollection.UpdateOne(ctx,
+			bson.M{"_id": conversation.ID, "title": bson.M{"$in": bson.A{"", nil}}},
+			bson.M{"$set": bson.M{"title": title}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to name conversation: %w", err)
+		}
+		conversation.Title = title
+	}
+
+	return nil
+}
+
+// GetMessages returns one page of a thread's messages. Page 1 holds the newest messages;
+// each page is ordered oldest first so it can be rendered as is.
+func (s *ConversationService) GetMessages(conversationID primitive.ObjectID, page, limit int64) ([]models.Message, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"conversation_id": conversationID}
+	total, err := database.MessageCollection.CountDocuments(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to clear conversation: %w", err)
+		return nil, 0, fmt.Errorf("failed to count conversation messages: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := database.MessageCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get conversation messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	messages := []models.Message{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode conversation messages: %w", err)
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, total, nil
+}
+
+// GetRecentMessages returns the last limit messages of a thread, oldest first
+func (s *ConversationService) GetRecentMessages(conversationID primitive.ObjectID, limit int) ([]models.Message, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	messages, _, err := s.GetMessages(conversationID, 1, int64(limit))
+	return messages, err
+}
+
+// DeleteConversationsByFileID removes a permanently deleted file from chat history.
+// Threads about the file alone are deleted, multi-file threads drop it from their scope
+// (and are deleted once no file is left) and citations of the file are removed from the
+// messages of every remaining thread, e.g. folder or "all" scoped ones.
+func (s *ConversationService) DeleteConversationsByFileID(fileID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"file_id": fileID}
+	ids, err := database.ConversationCollection.Distinct(ctx, "_id", filter)
+	if err != nil {
+		return fmt.Errorf("failed to find conversations: %w", err)
+	}
+
+	cursor, err := database.ConversationCollection.Find(ctx,
+		bson.M{"scope.type": models.ScopeFiles, "scope.file_ids": fileID},
+		options.Find().SetProjection(bson.M{"scope": 1}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to find multi-file conversations: %w", err)
+	}
+	var multiFile []models.Conversation
+	if err := cursor.All(ctx, &multiFile); err != nil {
+		return fmt.Errorf("failed to decode multi-file conversations: %w", err)
+	}
+
+	for _, conversation := range multiFile {
+		scope := conversation.ThreadScope()
+		remaining := make([]string, 0, len(scope.FileIDs))
+		for _, id := range scope.FileIDs {
+			if id != fileID {
+				remaining = append(remaining, id)
+			}
+		}
+		if len(remaining) == 0 {
+			ids = append(ids, conversation.ID)
+			continue
+		}
+
+		scope.FileIDs = remaining
+		if _, err := database.ConversationCollection.UpdateOne(ctx,
+			bson.M{"_id": conversation.ID},
+			bson.M{"$set": bson.M{"scope.file_ids": remaining, "scope_key": scope.Key()}},
+		); err != nil {
+			return fmt.Errorf("failed to update conversation scope: %w", err)
+		}
+	}
+
+	if len(ids) > 0 {
+		if _, err := database.MessageCollection.DeleteMany(ctx, bson.M{"conversation_id": bson.M{"$in": ids}}); err != nil {
+			return fmt.Errorf("failed to delete conversation messages: %w", err)
+		}
+		if _, err := database.ConversationCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+			return fmt.Errorf("failed to delete conversations: %w", err)
+		}
+	}
+
+	if _, err := database.MessageCollection.UpdateMany(ctx,
+		bson.M{"citations.file_id": fileID},
+		bson.M{"$pull": bson.M{"citations": bson.M{"file_id": fileID}}},
+	); err != nil {
+		return fmt.Errorf("failed to remove citations: %w", err)
 	}
 
 	return nil
 }
 
-// GetUserConversations retrieves all conversations for a user with file information
+// GetUserConversations retrieves all threads of a user with file information, pinned first
 func (s *ConversationService) GetUserConversations(userID string) ([]models.ConversationWithFile, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get all conversations for the user, ordered by updated_at descending
 	cursor, err := database.ConversationCollection.Find(ctx, bson.M{
 		"user_id": userID,
-	}, options.Find().SetSort(bson.M{"updated_at": -1}))
+	}, options.Find().SetSort(threadSort).SetProjection(bson.M{"messages": 0}))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversations: %w", err)
@@ -167,6 +388,29 @@ func (s *ConversationService) GetUserConversations(userID string) ([]models.Conv
 	// Get file information for each conversation
 	conversationsWithFile := make([]models.ConversationWithFile, 0, len(conversations))
 	for _, conv := range conversations {
+		item := models.ConversationWithFile{
+			ID:           conv.ID.Hex(),
+			Title:        conv.Title,
+			Pinned:       conv.Pinned,
+			MessageCount: conv.MessageCount,
+			CreatedAt:    conv.CreatedAt,
+			UpdatedAt:    conv.UpdatedAt,
+		}
+
+		// Folder / multi-file / "all" conversations have no single file to resolve
+		if conv.Scope != nil && conv.Scope.Type != models.ScopeFile {
+			if conv.Scope.Type == models.ScopeFolder {
+				hasAccess, err := helpers.CanUserAccess(userID, "folder", conv.Scope.ID, helpers.AccessLevelRead)
+				if err != nil || !hasAccess {
+					continue
+				}
+			}
+
+			item.Scope = conv.Scope
+			conversationsWithFile = append(conversationsWithFile, item)
+			continue
+		}
+
 		// Get file information
 		file, err := FileServiceInstance.GetFileByID(conv.FileID)
 		if err != nil {
@@ -181,20 +425,124 @@ func (s *ConversationService) GetUserConversations(userID string) ([]models.Conv
 			continue
 		}
 
-		conversationsWithFile = append(conversationsWithFile, models.ConversationWithFile{
-			ID:        conv.ID.Hex(),
-			FileID:    conv.FileID,
-			Messages:  conv.Messages,
-			CreatedAt: conv.CreatedAt,
-			UpdatedAt: conv.UpdatedAt,
-			File: models.FileInfo{
-				ID:          file.ID.Hex(),
-				Filename:    file.Filename,
-				ContentType: file.ContentType,
-				Size:        file.Size,
-			},
-		})
+		item.FileID = conv.FileID
+		item.File = models.FileInfo{
+			ID:          file.ID.Hex(),
+			Filename:    file.Filename,
+			ContentType: file.ContentType,
+			Size:        file.Size,
+		}
+		conversationsWithFile = append(conversationsWithFile, item)
 	}
 
 	return conversationsWithFile, nil
 }
+
+// MigrateEmbeddedMessages moves the message arrays of conversations created before threads
+// into the messages collection. Re-running it after a partial failure is safe: a
+// conversation's moved messages are replaced until its array has been removed.
+func (s *ConversationService) MigrateEmbeddedMessages() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := database.ConversationCollection.Find(ctx, bson.M{"messages.0": bson.M{"$exists": true}})
+	if err != nil {
+		return fmt.Errorf("failed to find conversations to migrate: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var conversation models.Conversation
+		if err := cursor.Decode(&conversation); err != nil {
+			return fmt.Errorf("failed to decode conversation: %w", err)
+		}
+
+		if _, err := database.MessageCollection.DeleteMany(ctx, bson.M{"conversation_id": conversation.ID}); err != nil {
+			return fmt.Errorf("failed to reset messages of %s: %w", conversation.ID.Hex(), err)
+		}
+
+		// Fresh ObjectIDs keep the original order, which is what pagination sorts by
+		docs := make([]interface{}, len(conversation.Messages))
+		title := conversation.Title
+		for i, message := range conversation.Messages {
+			message.ID = primitive.NewObjectID()
+			message.ConversationID = conversation.ID
+			docs[i] = message
+			if title == "" && message.Role == "user" {
+				title = generateThreadTitle(message.Content)
+			}
+		}
+		if _, err := database.MessageCollection.InsertMany(ctx, docs); err != nil {
+			return fmt.Errorf("failed to move messages of %s: %w", conversation.ID.Hex(), err)
+		}
+
+		update := bson.M{
+			"$set":   bson.M{"message_count": len(docs), "title": title},
+			"$unset": bson.M{"messages": ""},
+		}
+		if _, err := database.ConversationCollection.UpdateOne(ctx, bson.M{"_id": conversation.ID}, update); err != nil {
+			return fmt.Errorf("failed to update conversation %s: %w", conversation.ID.Hex(), err)
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if migrated > 0 {
+		log.Printf("Moved the messages of %d conversations into their own collection", migrated)
+	}
+	return nil
+}
+
+// EnsureIndexes creates the indexes used to list threads, page through their messages and
+// find the citations of a deleted file
+func (s *ConversationService) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := database.ConversationCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "file_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "scope_key", Value: 1}, {Key: "updated_at", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = database.MessageCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "conversation_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "citations.file_id", Value: 1}}}, // Citation cleanup on permanent file deletion
+	})
+	return err
+}
+
+// conversationFilter builds the lookup filter for a scope. Single-file conversations keep
+// using file_id so existing documents continue to match.
+func conversationFilter(userID string, scope models.ConversationScope) bson.M {
+	if scope.Type == "" || scope.Type == models.ScopeFile {
+		return bson.M{
+			"user_id": userID,
+			"file_id": scope.ID,
+		}
+	}
+	return bson.M{
+		"user_id":   userID,
+		"scope_key": scope.Key(),
+	}
+}
+
+// generateThreadTitle turns the first question of a thread into its title
+func generateThreadTitle(question string) string {
+	title := strings.Join(strings.Fields(question), " ")
+	if utf8.RuneCountInString(title) <= threadTitleMaxRunes {
+		return title
+	}
+
+	runes := []rune(title)[:threadTitleMaxRunes]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > threadTitleMaxRunes/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
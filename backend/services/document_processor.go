@@ -1,16 +1,18 @@
 package services
 
 import (
-	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/ledongthuc/pdf"
 	"github.com/minio/minio-go/v7"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,12 +20,15 @@ import (
 
 	"nimbus-backend/chunks"
 	"nimbus-backend/config"
+	"nimbus-backend/extract"
+	"nimbus-backend/llm"
+	"nimbus-backend/models"
 	"nimbus-backend/retrieval"
 )
 
 type DocumentProcessor struct {
-	ollamaService  *OllamaService
-	chromaService  *ChromaService
+	embedder       llm.Embedder
+	vectorService  *VectorService
 	minioService   *MinIOService
 	fileCollection *mongo.Collection
 	config         *config.Config
@@ -36,8 +41,8 @@ var DocumentProcessorInstance *DocumentProcessor
 
 func NewDocumentProcessor(cfg *config.Config, minioService *MinIOService, fileCollection *mongo.Collection) *DocumentProcessor {
 	return &DocumentProcessor{
-		ollamaService:  NewOllamaService(cfg),
-		chromaService:  NewChromaService(cfg),
+		embedder:       EmbedderInstance,
+		vectorService:  NewVectorService(cfg),
 		minioService:   minioService,
 		fileCollection: fileCollection,
 		config:         cfg,
@@ -51,126 +56,314 @@ func InitDocumentProcessor(cfg *config.Config, fileCollection *mongo.Collection)
 	if MinioService == nil {
 		return fmt.Errorf("MinIO service must be initialized first")
 	}
+	if EmbedderInstance == nil {
+		return fmt.Errorf("AI providers must be initialized first")
+	}
+	if VectorStoreInstance == nil {
+		return fmt.Errorf("vector store must be initialized first")
+	}
+	if cfg.ExtractMaxZipEntryMB > 0 {
+		extract.MaxZipEntrySize = int64(cfg.ExtractMaxZipEntryMB) << 20
+	}
 	DocumentProcessorInstance = NewDocumentProcessor(cfg, MinioService, fileCollection)
 	log.Println("✅ Document processor initialized")
 	return nil
 }
 
-// ProcessDocumentAsync starts document processing in a goroutine
-func (p *DocumentProcessor) ProcessDocumentAsync(fileID, minioPath, contentType string) {
-	go func() {
-		if err := p.processDocument(fileID, minioPath, contentType, nil); err != nil {
-			log.Printf("Error processing document %s: %v", fileID, err)
-			// Update file status to failed
-			p.updateFileStatus(fileID, "failed", err.Error(), 0)
+// ProcessDocumentAsync queues the file for processing. The job queue bounds concurrency,
+// retries failures with backoff and survives restarts.
+func (p *DocumentProcessor) ProcessDocumentAsync(file *models.File) (*models.ProcessingJob, error) {
+	return p.enqueue(file, false)
+}
+
+// ProcessDocumentWithDeduplication queues the file and reuses the embeddings of an
+// identical, already processed file when one exists
+func (p *DocumentProcessor) ProcessDocumentWithDeduplication(file *models.File) (*models.ProcessingJob, error) {
+	return p.enqueue(file, p.config.EnableDeduplication)
+}
+
+// ReindexAsync queues a file whose content changed. Until the job finishes the file keeps
+// answering from its previous chunks and is flagged content_stale; cached queries that
+// retrieved its chunks are dropped right away.
+func (p *DocumentProcessor) ReindexAsync(file *models.File) (*models.ProcessingJob, error) {
+	if queryCache := SharedQueryCache(p.config); queryCache != nil {
+		if removed := queryCache.InvalidateFile(file.ID.Hex()); removed > 0 {
+			log.Printf("Invalidated %d cached queries for changed file %s", removed, file.ID.Hex())
 		}
-	}()
+	}
+	return p.enqueue(file, false)
 }
 
-// ProcessDocumentWithDeduplication checks for duplicate file and processes if unique
-func (p *DocumentProcessor) ProcessDocumentWithDeduplication(fileID, minioPath, contentType string, fileBytes []byte) {
-	go func() {
-		// Step 1: Check deduplication if enabled
-		if p.config.EnableDeduplication && fileBytes != nil {
-			fileHash := chunks.ComputeFileHash(fileBytes)
+// ReindexOutdatedEmbeddings queues every indexed file whose chunks were embedded by another
+// model than the configured one (or before the model was recorded), so switching models
+// re-indexes instead of mixing vector spaces. Returns the number of queued files.
+func (p *DocumentProcessor) ReindexOutdatedEmbeddings() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-			// Store hash for this file
-			if err := p.deduplicator.StoreFileHash(fileID, fileHash); err != nil {
-				log.Printf("Warning: failed to store file hash: %v", err)
-			}
+	filter := bson.M{
+		"processing_status": bson.M{"$in": models.QueryableStatuses},
+		"embed_model":       bson.M{"$ne": p.embedder.ModelID()},
+	}
+	cursor, err := p.fileCollection.Find(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find outdated files: %w", err)
+	}
+	var files []models.File
+	if err := cursor.All(ctx, &files); err != nil {
+		return 0, fmt.Errorf("failed to decode outdated files: %w", err)
+	}
 
-			// Check for duplicates
-			duplicate, err := p.deduplicator.CheckDuplicate(fileHash)
-			if err != nil {
-				log.Printf("Warning: deduplication check failed: %v", err)
-				// Continue with normal processing
-			} else if duplicate != nil {
-				// Found duplicate - reuse embeddings
-				log.Printf("Deduplication hit: file %s is duplicate of %s", fileID, duplicate.ExistingFileID)
-				if err := p.deduplicator.LinkToExistingEmbeddings(fileID, duplicate.ExistingFileID, duplicate.ChunkCount); err != nil {
-					log.Printf("Error linking to existing embeddings: %v", err)
-					// Fall back to normal processing
-				} else {
-					log.Printf("Successfully reused embeddings for file %s", fileID)
-					return // Done - no need to process
-				}
-			}
+	queued := 0
+	for i := range files {
+		if _, err := p.ReindexAsync(&files[i]); err != nil {
+			log.Printf("Warning: failed to queue re-indexing of %s: %v", files[i].ID.Hex(), err)
+			continue
 		}
+		queued++
+	}
+	return queued, nil
+}
+
+func (p *DocumentProcessor) enqueue(file *models.File, deduplicate bool) (*models.ProcessingJob, error) {
+	if JobQueueInstance == nil {
+		return nil, fmt.Errorf("job queue is not initialized")
+	}
+	return JobQueueInstance.Enqueue(file.ID.Hex(), file.UserID, file.Filename, file.MinioPath, file.ContentType, deduplicate)
+}
+
+// runJob processes one queued job. Errors wrapped with Permanent are not retried.
+func (p *DocumentProcessor) runJob(ctx context.Context, job *models.ProcessingJob) error {
+	if !extract.Supports(job.ContentType, job.MinioPath) {
+		return Permanent(fmt.Errorf("unsupported content type: %s", job.ContentType))
+	}
 
-		// Step 2: Process normally if not duplicate or deduplication disabled
-		if err := p.processDocument(fileID, minioPath, contentType, fileBytes); err != nil {
-			log.Printf("Error processing document %s: %v", fileID, err)
-			p.updateFileStatus(fileID, "failed", err.Error(), 0)
+	fileBytes, err := p.downloadFile(ctx, job.MinioPath)
+	if err != nil {
+		return err
+	}
+
+	// Check deduplication if requested
+	if job.Deduplicate {
+		fileHash := chunks.ComputeFileHash(fileBytes)
+
+		// Store hash for this file
+		if err := p.deduplicator.StoreFileHash(job.FileID, fileHash); err != nil {
+			log.Printf("Warning: failed to store file hash: %v", err)
+		}
+
+		// Check for duplicates
+		duplicate, err := p.deduplicator.CheckDuplicate(fileHash, p.embedder.ModelID())
+		if err != nil {
+			log.Printf("Warning: deduplication check failed: %v", err)
+			// Continue with normal processing
+		} else if duplicate != nil && duplicate.ExistingFileID != job.FileID {
+			// Found duplicate - reuse embeddings
+			log.Printf("Deduplication hit: file %s is duplicate of %s", job.FileID, duplicate.ExistingFileID)
+			if err := p.deduplicator.LinkToExistingEmbeddings(job.FileID, duplicate.ExistingFileID, duplicate.ChunkCount, p.embedder.ModelID()); err != nil {
+				log.Printf("Error linking to existing embeddings: %v", err)
+				// Fall back to normal processing
+			} else {
+				log.Printf("Successfully reused embeddings for file %s", job.FileID)
+				return nil // Done - no need to process
+			}
 		}
-	}()
+	}
+
+	return p.processDocument(ctx, newProgressReporter(ProgressTrackerInstance, job), job.FileID, job.MinioPath, job.ContentType, fileBytes)
 }
 
 // processDocument is the main processing pipeline
-func (p *DocumentProcessor) processDocument(fileID, minioPath, contentType string, fileBytes []byte) error {
+func (p *DocumentProcessor) processDocument(ctx context.Context, progress *progressReporter, fileID, minioPath, contentType string, fileBytes []byte) error {
 	startTime := time.Now()
 	log.Printf("Starting document processing for file %s", fileID)
 
-	// Update status to processing
-	if err := p.updateFileStatus(fileID, "processing", "", 0); err != nil {
+	// Update status to processing (re-indexed files stay queryable, see markInProgress)
+	if err := p.markInProgress(fileID, "processing", ""); err != nil {
 		return fmt.Errorf("failed to update status to processing: %w", err)
 	}
 
 	// Step 1: Extract text from document
-	var text string
+	progress.Stage(models.ProcessingStageExtracting)
+	var doc *extract.Document
 	var err error
 	if fileBytes != nil {
-		// Use provided bytes (for deduplication flow)
-		text, err = p.extractTextFromBytes(fileBytes, contentType)
+		// Use provided bytes (downloaded by the job runner)
+		doc, err = p.extractTextFromBytes(fileBytes, contentType, minioPath)
 	} else {
 		// Download from MinIO
-		text, err = p.extractText(minioPath, contentType)
+		doc, err = p.extractText(minioPath, contentType)
 	}
 	if err != nil {
+		if fileBytes != nil || errors.Is(err, extract.ErrEntryTooLarge) {
+			// Parsing the same bytes again fails the same way
+			return Permanent(fmt.Errorf("failed to extract text: %w", err))
+		}
 		return fmt.Errorf("failed to extract text: %w", err)
 	}
 
-	if len(strings.TrimSpace(text)) == 0 {
-		return fmt.Errorf("extracted text is empty")
+	if len(strings.TrimSpace(doc.Text)) == 0 {
+		return Permanent(fmt.Errorf("extracted text is empty"))
 	}
 
-	log.Printf("Extracted %d characters from document %s", len(text), fileID)
+	log.Printf("Extracted %d characters (%d pages) from document %s", len(doc.Text), len(doc.Pages), fileID)
+
+	semanticChunks := splitDocument(progress, doc, minioPath)
+	log.Printf("Generated %d chunks for %s", len(semanticChunks), fileID)
+
+	if len(semanticChunks) == 0 {
+		return Permanent(fmt.Errorf("no chunks created from text"))
+	}
+
+	// Step 5: Generate embeddings and store in the vector store
+	progress.Stage(models.ProcessingStageEmbedding)
+	// Build the stored form of every chunk first; its hashes decide what has to be embedded
+	embedModel := p.embedder.ModelID()
+	candidates, embeddingTexts := BuildChunkRecords(fileID, embedModel, semanticChunks)
+
+	// Diff against the chunks already indexed for this file, so re-indexing an edited file
+	// only embeds changed text and deletes chunks that no longer exist
+	stored, err := p.vectorService.GetFileChunks(fileID)
+	if err != nil {
+		return fmt.Errorf("failed to load indexed chunks: %w", err)
+	}
+	plan := planChunkSync(candidates, stored, embedModel)
+
+	var toEmbed []int
+	var texts []string
+	for i := range candidates {
+		if plan.embeddings[i] == nil {
+			toEmbed = append(toEmbed, i)
+			texts = append(texts, embeddingTexts[i])
+		}
+	}
+	progress.Chunks(len(texts))
+	log.Printf("Chunk diff for %s: %d unchanged, %d reused, %d to embed, %d removed",
+		fileID, plan.unchangedCount(), len(candidates)-plan.unchangedCount()-len(texts), len(texts), len(plan.deleteIDs))
 
-	// Step 2: Normalize Text
-	log.Printf("Normalizing text for %s...", fileID)
+	// Batched embedding calls on a bounded worker pool. Returns early when the job was
+	// cancelled or its lease was lost.
+	embeddings, failures, err := embedBatched(ctx, p.embedder, texts,
+		p.config.EmbedBatchSize, p.config.EmbedConcurrency,
+		func(done int) { progress.Embedded(done, len(texts)) })
+	if err != nil {
+		return err
+	}
+	for i, embedding := range embeddings {
+		plan.embeddings[toEmbed[i]] = embedding
+	}
+	for i := range failures {
+		failures[i].Index = toEmbed[failures[i].Index]
+		log.Printf("Warning: failed to generate embedding for chunk %d of %s: %v",
+			semanticChunks[failures[i].Index].Index, fileID, failures[i].Err)
+	}
+	progress.Failed(len(failures))
+
+	// Unchanged chunks stay as they are; failed ones are dropped (their stored text is stale)
+	var indexed, upserts []ChunkData
+	deleteIDs := plan.deleteIDs
+	for i, candidate := range candidates {
+		if plan.embeddings[i] == nil {
+			if plan.existed[i] {
+				deleteIDs = append(deleteIDs, candidate.ID)
+			}
+			continue
+		}
+		candidate.Embedding = plan.embeddings[i]
+		indexed = append(indexed, candidate)
+		if !plan.unchanged[i] {
+			upserts = append(upserts, candidate)
+		}
+	}
+
+	if len(indexed) == 0 {
+		return fmt.Errorf("failed to generate any embeddings: %w", failures[0].Err)
+	}
+
+	// Step 5: Write changes to the vector store
+	progress.Stage(models.ProcessingStageIndexing)
+	if err := p.vectorService.ApplyFileChunks(fileID, indexed, upserts, deleteIDs); err != nil {
+		return fmt.Errorf("failed to write chunks to the vector store: %w", err)
+	}
+
+	log.Printf("Indexed %d chunks for document %s (%d written, %d deleted)", len(indexed), fileID, len(upserts), len(deleteIDs))
+
+	// Step 6: Update file status to completed, or partial when chunks were skipped
+	if err := p.updateFileResult(fileID, embedModel, len(indexed), failures); err != nil {
+		return fmt.Errorf("failed to update status to completed: %w", err)
+	}
+
+	processingTime := time.Since(startTime)
+	log.Printf("Document processing completed for file %s in %v", fileID, processingTime)
+	return nil
+}
+
+// SplitDocument chunks an extracted document the way uploads are indexed. The filename
+// picks the code splitter for source files.
+func SplitDocument(doc *extract.Document, filename string) []chunks.Chunk {
+	return splitDocument(nil, doc, filename)
+}
+
+// splitDocument runs steps 2-4 of the pipeline: normalization, table detection and splitting
+func splitDocument(progress *progressReporter, doc *extract.Document, filename string) []chunks.Chunk {
+	if len(doc.Tables) > 0 {
+		// Steps 2-4 for spreadsheets: keep rows whole and repeat the headers in every chunk
+		progress.Stage(models.ProcessingStageChunking)
+		log.Printf("Splitting %d sheets into row groups for %s...", len(doc.Tables), filename)
+		rowSplitter := chunks.NewRowGroupSplitter(chunks.DefaultChunkerConfig())
+		return rowSplitter.Split(doc.Tables)
+	}
+
+	if language := chunks.DetectLanguage(filename); language != "" {
+		// Steps 2-4 for source code: split on declarations. Normalization would
+		// collapse indentation and the table detector misreads code as tables.
+		progress.Stage(models.ProcessingStageChunking)
+		log.Printf("Splitting %s source on declarations for %s...", language, filename)
+		codeSplitter := chunks.NewCodeSplitter(chunks.DefaultChunkerConfig())
+		return codeSplitter.Split(doc.Text, language)
+	}
+
+	// Step 2: Normalize Text (page by page when the format has pages)
+	progress.Stage(models.ProcessingStageNormalizing)
+	log.Printf("Normalizing text for %s...", filename)
 	normalizer := chunks.NewTextNormalizer(chunks.DefaultNormalizerConfig())
-	normalizedText := normalizer.Normalize(text)
+	var normalizedText string
+	var pageMap chunks.PageMap
+	if len(doc.Pages) > 0 {
+		normalizedText, pageMap = normalizer.NormalizePages(doc.Pages)
+	} else {
+		normalizedText = normalizer.Normalize(doc.Text)
+	}
 
 	// Step 3: Process Tables
-	log.Printf("Processing tables for %s...", fileID)
-	tableProcessor := chunks.NewTableProcessor()
+	log.Printf("Processing tables for %s...", filename)
+	tableProcessor := chunks.NewTableProcessor().WithPages(pageMap)
 	segments := tableProcessor.Process(normalizedText)
 
 	// Step 4: Split into Chunks
-	log.Printf("Splitting text into chunks for %s...", fileID)
+	progress.Stage(models.ProcessingStageChunking)
+	log.Printf("Splitting text into chunks for %s...", filename)
 	splitter := chunks.NewSemanticTextSplitter(chunks.DefaultChunkerConfig())
-	semanticChunks := splitter.SplitSegments(segments)
-
-	log.Printf("Generated %d chunks for %s", len(semanticChunks), fileID)
-
-	if len(semanticChunks) == 0 {
-		return fmt.Errorf("no chunks created from text")
-	}
+	return splitter.SplitSegments(segments)
+}
 
-	// Step 5: Generate embeddings and store in Chroma
+// BuildChunkRecords turns the chunks of a file into vector store records with the
+// cross-referencing metadata used at query time. It also returns the text to embed for
+// each record (lighter normalization, declaration names up front for code).
+func BuildChunkRecords(fileID, embedModel string, semanticChunks []chunks.Chunk) ([]ChunkData, []string) {
 	// Extract key terms for cross-referencing (optional optimization)
 	termExtractor := retrieval.NewKeyTermExtractor()
 
-	var chromaChunks []ChunkData
-	for _, chunk := range semanticChunks {
+	candidates := make([]ChunkData, len(semanticChunks))
+	embeddingTexts := make([]string, len(semanticChunks))
+	for i, chunk := range semanticChunks {
 		// Normalize for embedding (lighter normalization)
 		embeddingText := chunks.NormalizeForEmbedding(chunk.Text)
-
-		// Generate embedding for this chunk
-		embedding, err := p.ollamaService.GenerateEmbedding(embeddingText)
-		if err != nil {
-			log.Printf("Warning: failed to generate embedding for chunk %d: %v", chunk.Index, err)
-			continue // Skip this chunk but continue with others
+		if symbol, ok := chunk.Metadata["symbol_name"].(string); ok {
+			// Put the declaration name up front so "what does X do" questions match it
+			embeddingText = fmt.Sprintf("%v %s\n%s", chunk.Metadata["symbol_kind"], symbol, embeddingText)
 		}
+		embeddingTexts[i] = embeddingText
 
 		// Extract key terms from chunk for cross-referencing
 		keyTerms := termExtractor.Extract(chunk.Text)
@@ -191,14 +384,19 @@ func (p *DocumentProcessor) processDocument(fileID, minioPath, contentType strin
 		metadata := map[string]interface{}{
 			"file_id":     fileID,
 			"chunk_index": chunk.Index,
-			"timestamp":   time.Now().Unix(),
 			"start_char":  chunk.StartChar,
 			"end_char":    chunk.EndChar,
 		}
 
+		// Page range for paginated formats (PDF)
+		if chunk.PageStart > 0 {
+			metadata["page_start"] = chunk.PageStart
+			metadata["page_end"] = chunk.PageEnd
+		}
+
 		// Add cross-referencing metadata if we found key terms
 		if len(keyTerms) > 0 {
-			// Chroma only accepts scalar values in metadata (string, number, bool)
+			// Vector stores only accept scalar values in metadata (string, number, bool)
 			// Convert array to comma-separated string
 			metadata["key_terms"] = strings.Join(keyTerms, ",")
 			metadata["term_count"] = len(keyTerms)
@@ -212,152 +410,105 @@ func (p *DocumentProcessor) processDocument(fileID, minioPath, contentType strin
 			metadata[k] = v
 		}
 
-		chromaChunk := ChunkData{
-			ID:        fmt.Sprintf("%s_%d", fileID, chunk.Index),
-			Embedding: embedding,
-			Text:      chunk.Text,
-			Metadata:  metadata,
+		// embed_model names the vector space; content_hash identifies the embedded text,
+		// record_hash everything that is stored
+		metadata["embed_model"] = embedModel
+		metadata["content_hash"] = chunkHash(embeddingText)
+		metadata["record_hash"] = chunkRecordHash(chunk.Text, metadata)
+		metadata["timestamp"] = time.Now().Unix()
+
+		candidates[i] = ChunkData{
+			ID:       fmt.Sprintf("%s_%d", fileID, chunk.Index),
+			Text:     chunk.Text,
+			Metadata: metadata,
 		}
-		chromaChunks = append(chromaChunks, chromaChunk)
-
-		log.Printf("Generated embedding for chunk %d/%d (type: %s, terms: %d)",
-			chunk.Index+1, len(semanticChunks), chunkType, len(keyTerms))
-	}
-
-	if len(chromaChunks) == 0 {
-		return fmt.Errorf("failed to generate any embeddings")
-	}
-
-	// Step 5: Add to Chroma
-	if err := p.chromaService.AddDocuments(chromaChunks); err != nil {
-		return fmt.Errorf("failed to add documents to Chroma: %w", err)
 	}
 
-	log.Printf("Successfully added %d chunks to Chroma for document %s", len(chromaChunks), fileID)
+	return candidates, embeddingTexts
+}
 
-	// Step 6: Update file status to completed
-	if err := p.updateFileStatus(fileID, "completed", "", len(chromaChunks)); err != nil {
-		return fmt.Errorf("failed to update status to completed: %w", err)
+// extractText downloads the file from MinIO and extracts its text
+func (p *DocumentProcessor) extractText(minioPath string, contentType string) (*extract.Document, error) {
+	fileBytes, err := p.downloadFile(context.Background(), minioPath)
+	if err != nil {
+		return nil, err
 	}
 
-	processingTime := time.Since(startTime)
-	log.Printf("Document processing completed for file %s in %v", fileID, processingTime)
-	return nil
+	return p.extractTextFromBytes(fileBytes, contentType, minioPath)
 }
 
-// extractText extracts text from PDF or DOCX files
-func (p *DocumentProcessor) extractText(minioPath string, contentType string) (string, error) {
-	// Download file from MinIO
-	ctx := context.Background()
+// downloadFile reads the whole object into memory (zip based formats need random access)
+func (p *DocumentProcessor) downloadFile(ctx context.Context, minioPath string) ([]byte, error) {
 	reader, err := p.minioService.Client.GetObject(ctx, "user-files", minioPath, minio.GetObjectOptions{})
 	if err != nil {
-		return "", fmt.Errorf("failed to get file from MinIO: %w", err)
+		return nil, fmt.Errorf("failed to get file from MinIO: %w", err)
 	}
 	defer reader.Close()
 
-	// Read entire file into memory (for PDF library)
 	fileBytes, err := io.ReadAll(reader)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
-
-	return p.extractTextFromBytes(fileBytes, contentType)
+	return fileBytes, nil
 }
 
-// extractTextFromBytes extracts text from file bytes
-func (p *DocumentProcessor) extractTextFromBytes(fileBytes []byte, contentType string) (string, error) {
-	contentTypeLower := strings.ToLower(contentType)
-
-	// Extract based on content type
-	if strings.Contains(contentTypeLower, "pdf") {
-		return p.extractTextFromPDF(fileBytes)
-	} else if strings.Contains(contentTypeLower, "wordprocessingml") || strings.Contains(contentTypeLower, "msword") {
-		return p.extractTextFromDOCX(fileBytes)
-	}
-
-	return "", fmt.Errorf("unsupported content type: %s", contentType)
+// extractTextFromBytes picks the extractor by file extension, falling back to the content type
+func (p *DocumentProcessor) extractTextFromBytes(fileBytes []byte, contentType, filename string) (*extract.Document, error) {
+	return extract.Default.Extract(fileBytes, contentType, filename)
 }
 
-// extractTextFromPDF extracts text from PDF bytes
-func (p *DocumentProcessor) extractTextFromPDF(fileBytes []byte) (string, error) {
-	reader, err := pdf.NewReader(strings.NewReader(string(fileBytes)), int64(len(fileBytes)))
+// markInProgress sets a pending/processing status. Files that are already queryable keep
+// their status while being re-indexed, so questions keep working on the previous index;
+// they are flagged content_stale instead.
+func (p *DocumentProcessor) markInProgress(fileID, status, errorMsg string) error {
+	objID, err := primitive.ObjectIDFromHex(fileID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create PDF reader: %w", err)
+		return fmt.Errorf("invalid file ID: %w", err)
 	}
 
-	var textBuilder strings.Builder
-	numPages := reader.NumPage()
-
-	for i := 1; i <= numPages; i++ {
-		page := reader.Page(i)
-		if page.V.IsNull() {
-			continue
-		}
-
-		text, err := page.GetPlainText(nil)
-		if err != nil {
-			log.Printf("Warning: failed to extract text from page %d: %v", i, err)
-			continue
-		}
-
-		textBuilder.WriteString(text)
-		textBuilder.WriteString("\n\n")
+	set := bson.M{"content_stale": true}
+	if errorMsg != "" {
+		set["processing_error"] = errorMsg
 	}
 
-	return textBuilder.String(), nil
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-// extractTextFromDOCX extracts text from DOCX bytes
-func (p *DocumentProcessor) extractTextFromDOCX(fileBytes []byte) (string, error) {
-	reader := strings.NewReader(string(fileBytes))
-	zipReader, err := zip.NewReader(reader, int64(len(fileBytes)))
+	result, err := p.fileCollection.UpdateOne(ctx,
+		bson.M{"_id": objID, "processing_status": bson.M{"$in": models.QueryableStatuses}},
+		bson.M{"$set": set},
+	)
 	if err != nil {
-		return "", fmt.Errorf("failed to open DOCX as ZIP: %w", err)
-	}
-
-	var documentXML string
-	for _, file := range zipReader.File {
-		if file.Name == "word/document.xml" {
-			rc, err := file.Open()
-			if err != nil {
-				return "", fmt.Errorf("failed to open document.xml: %w", err)
-			}
-			defer rc.Close()
-
-			xmlBytes, err := io.ReadAll(rc)
-			if err != nil {
-				return "", fmt.Errorf("failed to read document.xml: %w", err)
-			}
-			documentXML = string(xmlBytes)
-			break
-		}
+		return fmt.Errorf("failed to update file status: %w", err)
 	}
-
-	if documentXML == "" {
-		return "", fmt.Errorf("document.xml not found in DOCX")
+	if result.MatchedCount > 0 {
+		return nil
 	}
-
-	text := p.extractTextFromXML(documentXML)
-	return text, nil
+	return p.updateFileStatus(fileID, status, errorMsg, 0)
 }
 
-// extractTextFromXML extracts text content from XML by removing tags
-func (p *DocumentProcessor) extractTextFromXML(xml string) string {
-	textRegex := regexp.MustCompile(`<w:t[^>]*>([^<]+)</w:t>`)
-	matches := textRegex.FindAllStringSubmatch(xml, -1)
-
-	var textBuilder strings.Builder
-	for _, match := range matches {
-		if len(match) > 1 {
-			textBuilder.WriteString(match[1])
-			textBuilder.WriteString(" ")
-		}
+// markCancelled records a cancelled job on the file. A file that already has an index keeps
+// its status and drops the content_stale flag; only first-time processing is marked failed.
+func (p *DocumentProcessor) markCancelled(fileID, errorMsg string) error {
+	objID, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return fmt.Errorf("invalid file ID: %w", err)
 	}
 
-	paragraphRegex := regexp.MustCompile(`</w:p>`)
-	text := paragraphRegex.ReplaceAllString(textBuilder.String(), "\n\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	return text
+	result, err := p.fileCollection.UpdateOne(ctx,
+		bson.M{"_id": objID, "processing_status": bson.M{"$in": models.QueryableStatuses}},
+		bson.M{"$unset": bson.M{"content_stale": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update file status: %w", err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	return p.updateFileStatus(fileID, "failed", errorMsg, 0)
 }
 
 // updateFileStatus updates the processing status of a file
@@ -394,6 +545,140 @@ func (p *DocumentProcessor) updateFileStatus(fileID, status, errorMsg string, ch
 	return nil
 }
 
+// chunkSyncPlan is the diff between a new chunk set and the chunks stored for the file
+type chunkSyncPlan struct {
+	embeddings [][]float64 // Known embedding per candidate; nil means it must be embedded
+	unchanged  []bool      // Stored chunk with the same ID is identical, nothing to write
+	existed    []bool      // A stored chunk with the same ID exists
+	deleteIDs  []string    // Stored chunks whose IDs are not in the new set
+}
+
+func (p chunkSyncPlan) unchangedCount() int {
+	count := 0
+	for _, u := range p.unchanged {
+		if u {
+			count++
+		}
+	}
+	return count
+}
+
+// planChunkSync matches candidates to stored chunks: same ID and record hash means unchanged,
+// same content hash anywhere in the file means the embedding can be reused. Embeddings of
+// another model are never reused.
+func planChunkSync(candidates []ChunkData, stored []StoredChunk, embedModel string) chunkSyncPlan {
+	plan := chunkSyncPlan{
+		embeddings: make([][]float64, len(candidates)),
+		unchanged:  make([]bool, len(candidates)),
+		existed:    make([]bool, len(candidates)),
+	}
+
+	byID := make(map[string]StoredChunk, len(stored))
+	byContent := make(map[string][]float64, len(stored))
+	existing := make(map[string]bool, len(stored))
+	for _, chunk := range stored {
+		existing[chunk.ID] = true
+		if len(chunk.Embedding) == 0 || chunk.Metadata["embed_model"] != embedModel {
+			continue
+		}
+		byID[chunk.ID] = chunk
+		if hash, ok := chunk.Metadata["content_hash"].(string); ok {
+			byContent[hash] = chunk.Embedding
+		}
+	}
+
+	keep := make(map[string]bool, len(candidates))
+	for i, candidate := range candidates {
+		keep[candidate.ID] = true
+		plan.existed[i] = existing[candidate.ID]
+		if old, ok := byID[candidate.ID]; ok {
+			if old.Metadata["record_hash"] == candidate.Metadata["record_hash"] {
+				plan.unchanged[i] = true
+				plan.embeddings[i] = old.Embedding
+				continue
+			}
+		}
+		if embedding, ok := byContent[candidate.Metadata["content_hash"].(string)]; ok {
+			plan.embeddings[i] = embedding
+		}
+	}
+
+	for _, chunk := range stored {
+		if !keep[chunk.ID] {
+			plan.deleteIDs = append(plan.deleteIDs, chunk.ID)
+		}
+	}
+	return plan
+}
+
+// chunkHash is a short content hash stored in chunk metadata
+func chunkHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// chunkRecordHash hashes the chunk text and metadata (in key order), so offsets, pages or
+// key terms changing also count as a change
+func chunkRecordHash(text string, metadata map[string]interface{}) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(text)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "\x00%s=%v", k, metadata[k])
+	}
+	return chunkHash(sb.String())
+}
+
+// updateFileResult stores the outcome of a successful run. Files whose chunks were partly
+// skipped are marked "partial" with the failure count, so they stay queryable but visible.
+func (p *DocumentProcessor) updateFileResult(fileID, embedModel string, chunkCount int, failures []EmbeddingFailure) error {
+	objID, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return fmt.Errorf("invalid file ID: %w", err)
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"processing_status": "completed",
+		"chunk_count":       chunkCount,
+		"processed_at":      &now,
+		"embed_model":       embedModel,
+	}
+	unset := bson.M{}
+
+	if len(failures) > 0 {
+		set["processing_status"] = "partial"
+		set["failed_chunk_count"] = len(failures)
+		set["processing_error"] = fmt.Sprintf("%d of %d chunks could not be embedded: %v",
+			len(failures), chunkCount+len(failures), failures[0].Err)
+	} else {
+		unset["failed_chunk_count"] = ""
+		unset["processing_error"] = ""
+	}
+	// Content saved during this run is indexed by the re-run; until then the file stays stale
+	if !rerunRequested(fileID) {
+		unset["content_stale"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := p.fileCollection.UpdateOne(ctx, bson.M{"_id": objID}, update); err != nil {
+		return fmt.Errorf("failed to update file status: %w", err)
+	}
+	return nil
+}
+
 // detectChunkType identifies the type of chunk based on its content and key terms
 func detectChunkType(text string, keyTerms []string) string {
 	lowerText := strings.ToLower(text)
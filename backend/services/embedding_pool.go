@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"nimbus-backend/llm"
+)
+
+// EmbeddingFailure records a chunk that could not be embedded
+type EmbeddingFailure struct {
+	Index int
+	Err   error
+}
+
+// embedBatched embeds texts in batches of batchSize with at most concurrency requests in
+// flight. A failed batch is retried one text at a time, so a single bad chunk only loses
+// itself. onProgress is called with the number of texts finished so far (from any worker).
+// Failures are returned in index order; a cancelled ctx returns ctx.Err().
+func embedBatched(ctx context.Context, embedder llm.Embedder, texts []string, batchSize, concurrency int, onProgress func(done int)) ([][]float64, []EmbeddingFailure, error) {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	embeddings := make([][]float64, len(texts))
+	errs := make([]error, len(texts))
+
+	batches := make(chan [2]int)
+	go func() {
+		defer close(batches)
+		for start := 0; start < len(texts); start += batchSize {
+			end := start + batchSize
+			if end > len(texts) {
+				end = len(texts)
+			}
+			select {
+			case batches <- [2]int{start, end}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	done := 0
+	finished := func(n int) {
+		mu.Lock()
+		done += n
+		current := done
+		mu.Unlock()
+		if onProgress != nil {
+			onProgress(current)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				start, end := batch[0], batch[1]
+
+				vectors, err := embedder.Embed(ctx, texts[start:end])
+				if err == nil {
+					copy(embeddings[start:end], vectors)
+					finished(end - start)
+					continue
+				}
+
+				// Isolate the failing chunk(s) by embedding the batch one by one
+				for i := start; i < end; i++ {
+					if ctx.Err() != nil {
+						errs[i] = ctx.Err()
+						continue
+					}
+					vectors, err := embedder.Embed(ctx, texts[i:i+1])
+					if err != nil {
+						errs[i] = err
+					} else {
+						embeddings[i] = vectors[0]
+					}
+				}
+				finished(end - start)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var failures []EmbeddingFailure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, EmbeddingFailure{Index: i, Err: err})
+		}
+	}
+	return embeddings, failures, nil
+}
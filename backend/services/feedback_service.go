@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"nimbus-backend/database"
+	"nimbus-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FeedbackService stores user ratings of assistant answers next to the messages and
+// aggregates them by how the answers were retrieved
+type FeedbackService struct{}
+
+var FeedbackServiceInstance = &FeedbackService{}
+
+// ErrMessageNotFound is returned when the message does not exist in the thread or is not
+// an assistant answer
+var ErrMessageNotFound = errors.New("message not found")
+
+// SetFeedback records (or replaces) the rating of an assistant message in one of the user's threads
+func (s *FeedbackService) SetFeedback(userID, threadID, messageID string, feedback models.MessageFeedback) (*models.Message, error) {
+	filter, err := s.messageFilter(userID, threadID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	feedback.UpdatedAt = time.Now()
+	var message models.Message
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = database.MessageCollection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"feedback": feedback}}, opts).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save feedback: %w", err)
+	}
+
+	return &message, nil
+}
+
+// ClearFeedback removes the rating of an assistant message
+func (s *FeedbackService) ClearFeedback(userID, threadID, messageID string) error {
+	filter, err := s.messageFilter(userID, threadID, messageID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := database.MessageCollection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"feedback": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to clear feedback: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// messageFilter matches an assistant message of a thread owned by the user
+func (s *FeedbackService) messageFilter(userID, threadID, messageID string) (bson.M, error) {
+	thread, err := ConversationServiceInstance.GetThread(userID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	objectID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	return bson.M{
+		"_id":             objectID,
+		"conversation_id": thread.ID,
+		"role":            "assistant",
+	}, nil
+}
+
+// Stats counts answers and ratings per intent, retrieval path, reranker and source file
+// type, optionally limited to answers given within [from, to]
+func (s *FeedbackService) Stats(from, to *time.Time) (*models.FeedbackStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	match := bson.M{
+		"role":      "assistant",
+		"retrieval": bson.M{"$exists": true},
+	}
+	if from != nil || to != nil {
+		timeRange := bson.M{}
+		if from != nil {
+			timeRange["$gte"] = *from
+		}
+		if to != nil {
+			timeRange["$lte"] = *to
+		}
+		match["timestamp"] = timeRange
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"total":        feedbackGroup(nil),
+			"by_intent":    feedbackGroup("$retrieval.intent"),
+			"by_path":      feedbackGroup("$retrieval.path"),
+			"by_reranker":  feedbackGroup("$retrieval.reranker"),
+			"by_file_type": append(bson.A{bson.M{"$unwind": "$retrieval.file_types"}}, feedbackGroup("$retrieval.file_types")...),
+			"reasons": bson.A{
+				bson.M{"$match": bson.M{"feedback.rating": models.FeedbackDown, "feedback.reason": bson.M{"$nin": bson.A{"", nil}}}},
+				bson.M{"$group": bson.M{"_id": "$feedback.reason", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			},
+		}}},
+	}
+
+	cursor, err := database.MessageCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate feedback: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Total      []models.FeedbackBucket      `bson:"total"`
+		ByIntent   []models.FeedbackBucket      `bson:"by_intent"`
+		ByPath     []models.FeedbackBucket      `bson:"by_path"`
+		ByFileType []models.FeedbackBucket      `bson:"by_file_type"`
+		ByReranker []models.FeedbackBucket      `bson:"by_reranker"`
+		Reasons    []models.FeedbackReasonCount `bson:"reasons"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode feedback stats: %w", err)
+	}
+
+	stats := &models.FeedbackStats{
+		ByIntent:   []models.FeedbackBucket{},
+		ByPath:     []models.FeedbackBucket{},
+		ByFileType: []models.FeedbackBucket{},
+		ByReranker: []models.FeedbackBucket{},
+		Reasons:    []models.FeedbackReasonCount{},
+	}
+	if len(results) == 0 {
+		return stats, nil
+	}
+
+	result := results[0]
+	if len(result.Total) > 0 {
+		stats.Total = withSatisfactionRates(result.Total)[0]
+	}
+	stats.ByIntent = withSatisfactionRates(result.ByIntent)
+	stats.ByPath = withSatisfactionRates(result.ByPath)
+	stats.ByFileType = withSatisfactionRates(result.ByFileType)
+	stats.ByReranker = withSatisfactionRates(result.ByReranker)
+	if result.Reasons != nil {
+		stats.Reasons = result.Reasons
+	}
+
+	return stats, nil
+}
+
+// feedbackGroup returns the stages counting answers and ratings per key, most answers first
+func feedbackGroup(key interface{}) bson.A {
+	countIf := func(condition interface{}) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{condition, 1, 0}}}
+	}
+
+	return bson.A{
+		bson.M{"$group": bson.M{
+			"_id":       key,
+			"answers":   bson.M{"$sum": 1},
+			"rated":     countIf(bson.M{"$in": bson.A{"$feedback.rating", bson.A{models.FeedbackUp, models.FeedbackDown}}}),
+			"up":        countIf(bson.M{"$eq": bson.A{"$feedback.rating", models.FeedbackUp}}),
+			"down":      countIf(bson.M{"$eq": bson.A{"$feedback.rating", models.FeedbackDown}}),
+			"corrected": countIf(bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$feedback.corrected_answer", ""}}, ""}}),
+		}},
+		bson.M{"$sort": bson.D{{Key: "answers", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+}
+
+// withSatisfactionRates fills in the share of positive ratings of each bucket
+func withSatisfactionRates(buckets []models.FeedbackBucket) []models.FeedbackBucket {
+	if buckets == nil {
+		return []models.FeedbackBucket{}
+	}
+	for i := range buckets {
+		if buckets[i].Rated > 0 {
+			buckets[i].SatisfactionRate = float64(buckets[i].Up) / float64(buckets[i].Rated)
+		}
+	}
+	return buckets
+}
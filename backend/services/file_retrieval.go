@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"nimbus-backend/models"
+	"nimbus-backend/retrieval"
+)
+
+// Errors returned by RetrieveFileChunks
+var (
+	ErrQuestionEmbedding = errors.New("failed to embed the question")
+	ErrNoRelevantChunks  = errors.New("no relevant chunks found")
+)
+
+// FileRetrieval is what RetrieveFileChunks found for a question
+type FileRetrieval struct {
+	Chunks   []ChunkResult // Reranked, best first
+	Intent   retrieval.IntentMetadata
+	KeyTerms []string
+	Path     string // models.RetrievalPath*
+}
+
+// RetrieveFileChunks runs intent analysis, retrieval and reranking for a question against
+// a single processed file. Comparison and definition questions use hybrid search, the rest
+// semantic search with the top-k recommended for their intent.
+func RetrieveFileChunks(ctx context.Context, llmService *LLMService, vectorService *VectorService, question, fileID string) (*FileRetrieval, error) {
+	// Initialize retrieval components
+	intentClassifier := retrieval.NewIntentClassifier()
+	termExtractor := retrieval.NewKeyTermExtractor()
+
+	// Step 1: Analyze query intent
+	intentMetadata := intentClassifier.AnalyzeQuery(question)
+	log.Printf("Query intent: %s (confidence: %.2f) - %s",
+		intentMetadata.Intent, intentMetadata.Confidence, intentMetadata.Explanation)
+
+	// Step 2: Extract key terms from query
+	keyTerms := termExtractor.ExtractNamedTerms(question)
+	log.Printf("Extracted key terms: %v", keyTerms)
+
+	result := &FileRetrieval{Intent: intentMetadata, KeyTerms: keyTerms, Path: models.RetrievalPathSemantic}
+	if vectorService.RoutesFile(fileID) {
+		result.Path = models.RetrievalPathFileRouter
+	}
+
+	// Step 3: Determine retrieval strategy based on intent
+	var chunks []ChunkResult
+	var retrievalErr error
+
+	// Use hybrid search for comparison queries (keyword + semantic) to ensure we find comparison tables
+	if intentMetadata.Intent == retrieval.IntentComparison && len(keyTerms) >= 2 {
+		log.Printf("Using hybrid search for comparison query with %d terms", len(keyTerms))
+		result.Path = models.RetrievalPathHybrid
+		chunks, retrievalErr = performHybridRetrieval(
+			llmService, vectorService,
+			question, keyTerms, fileID, intentMetadata.RecommendedTopK)
+	} else if intentMetadata.Intent == retrieval.IntentDefinition && len(keyTerms) > 0 {
+		// For definition queries, use hybrid search (keyword + semantic)
+		log.Printf("Using hybrid search for definition query")
+		result.Path = models.RetrievalPathHybrid
+		chunks, retrievalErr = performHybridRetrieval(
+			llmService, vectorService,
+			question, keyTerms, fileID, intentMetadata.RecommendedTopK)
+	} else {
+		topK := intentMetadata.RecommendedTopK
+		if intentMetadata.Intent == retrieval.IntentSummary && topK < 10 {
+			// For summary queries, retrieve more chunks for comprehensive overview
+			topK = 10 // Minimum 10 chunks for summary
+		}
+		// Standard semantic search with dynamic top-k based on intent
+		log.Printf("Using standard semantic search with top-k=%d", topK)
+		questionEmbedding, embErr := llmService.GenerateEmbedding(question)
+		if embErr != nil {
+			log.Printf("Failed to generate embedding for question: %v", embErr)
+			return result, fmt.Errorf("%w: %v", ErrQuestionEmbedding, embErr)
+		}
+
+		chunks, retrievalErr = vectorService.QuerySimilar(questionEmbedding, fileID, topK)
+	}
+
+	if retrievalErr != nil {
+		log.Printf("Failed to retrieve chunks: %v", retrievalErr)
+		return result, fmt.Errorf("failed to retrieve chunks: %w", retrievalErr)
+	}
+
+	if len(chunks) == 0 {
+		return result, ErrNoRelevantChunks
+	}
+
+	// Debug: Log which chunks were retrieved
+	chunkIDs := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = chunk.ID
+	}
+	log.Printf("Retrieved %d relevant chunks for query: %v", len(chunks), chunkIDs)
+
+	// Step 4: Rerank (intent heuristics or LLM scoring, see RERANKER)
+	result.Chunks = RerankChunks(ctx, retrieval.RerankQuery{
+		Question: question,
+		Intent:   intentMetadata.Intent,
+		KeyTerms: keyTerms,
+	}, chunks)
+
+	return result, nil
+}
+
+// performHybridRetrieval combines semantic and keyword search
+func performHybridRetrieval(
+	llmService *LLMService,
+	vectorService *VectorService,
+	query string,
+	keywords []string,
+	fileID string,
+	topK int,
+) ([]ChunkResult, error) {
+	// Generate query embedding
+	queryEmbedding, err := llmService.GenerateEmbedding(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	// Perform hybrid search (semantic + keyword)
+	chunks, err := vectorService.HybridSearch(queryEmbedding, keywords, fileID, topK)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid search failed: %w", err)
+	}
+
+	log.Printf("Hybrid retrieval returned %d chunks", len(chunks))
+	return chunks, nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -286,53 +287,31 @@ func (fs *FolderService) getFolderSizeRecursive(ctx context.Context, folderID st
 	return totalSize, nil
 }
 
-// GetUserStorageUsage - Kullanıcının toplam depolama kullanımını hesapla
+// GetUserStorageUsage - Kullanıcının toplam depolama kullanımını hesapla. Sadece sahibi olduğu
+// dosyalar sayılır; paylaşılan dosyalar sahibinin kotasına aittir (sahiplik devrinde kota yeni sahibe geçer).
 func (fs *FolderService) GetUserStorageUsage(userID string) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var totalSize int64 = 0
-
-	// Kullanıcının sahibi olduğu dosyaları al
-	ownedFiles, err := database.FileCollection.Find(ctx, bson.M{"user_id": userID})
+	cursor, err := database.FileCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": "$size"}}}},
+	})
 	if err != nil {
 		return 0, fmt.Errorf("owned files alınamadı: %v", err)
 	}
-	defer ownedFiles.Close(ctx)
+	defer cursor.Close(ctx)
 
-	for ownedFiles.Next(ctx) {
-		var file models.File
-		if err := ownedFiles.Decode(&file); err != nil {
-			continue
-		}
-		totalSize += file.Size
+	var results []struct {
+		Total int64 `bson:"total"`
 	}
-
-	// Kullanıcının access list'te bulunduğu dosyaları al
-	accessFiles, err := database.FileCollection.Find(ctx, bson.M{
-		"access_list.user_id": userID,
-	})
-	if err != nil {
-		return 0, fmt.Errorf("access files alınamadı: %v", err)
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, fmt.Errorf("depolama kullanımı decode edilemedi: %v", err)
 	}
-	defer accessFiles.Close(ctx)
-
-	for accessFiles.Next(ctx) {
-		var file models.File
-		if err := accessFiles.Decode(&file); err != nil {
-			continue
-		}
-
-		// Kullanıcının bu dosyaya erişimi var mı kontrol et
-		for _, access := range file.AccessList {
-			if access.UserID == userID {
-				totalSize += file.Size
-				break
-			}
-		}
+	if len(results) == 0 {
+		return 0, nil
 	}
-
-	return totalSize, nil
+	return results[0].Total, nil
 }
 
 // UpdateFolder - Klasör güncelle
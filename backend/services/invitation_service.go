@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"nimbus-backend/database"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InvitationService struct {
+	FrontendURL string // Davet e-postasındaki bağlantı için
+}
+
+var InvitationServiceInstance = &InvitationService{}
+
+// CreateInvitation - Hesabı olmayan e-posta için bekleyen davet oluştur ve bildirim gönder.
+// Aynı e-posta + kaynak için bekleyen davet varsa erişim tipi ve bitiş zamanı güncellenir.
+// expiresAt nil ise erişim süresizdir.
+func (is *InvitationService) CreateInvitation(resourceID primitive.ObjectID, resourceType, email, accessType, invitedBy string, expiresAt *time.Time) (*models.ShareInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Sadece çıplak adres kabul edilir ("Ad <adres>" veya satır sonu içeren değerler e-posta başlığına giremez)
+	parsed, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || parsed.Name != "" || strings.ContainsAny(parsed.Address, "\r\n") {
+		return nil, fmt.Errorf("geçersiz e-posta adresi")
+	}
+	email = strings.ToLower(parsed.Address)
+
+	resourceName := ""
+	if resourceType == "file" {
+		if file, err := FileServiceInstance.GetFileByID(resourceID.Hex()); err == nil {
+			resourceName = file.Filename
+		}
+	} else if folder, err := FolderServiceInstance.GetFolderByID(resourceID.Hex()); err == nil {
+		resourceName = folder.Name
+	}
+
+	set := bson.M{
+		"access_type": accessType,
+		"invited_by":  invitedBy,
+	}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"resource_type": resourceType,
+			"resource_name": resourceName,
+			"created_at":    time.Now(),
+		},
+	}
+	// Süresiz yeniden davet önceki bitiş zamanını kaldırır
+	if expiresAt != nil {
+		set["expires_at"] = expiresAt
+	} else {
+		update["$unset"] = bson.M{"expires_at": ""}
+	}
+
+	var invitation models.ShareInvitation
+	err = database.InvitationCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"email":       email,
+			"resource_id": resourceID,
+			"status":      models.InvitationStatusPending,
+		},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&invitation)
+	if err != nil {
+		return nil, fmt.Errorf("davet oluşturulamadı: %v", err)
+	}
+
+	is.notify(&invitation)
+
+	return &invitation, nil
+}
+
+// GetPendingInvitations - Bir kaynak için bekleyen davetler
+func (is *InvitationService) GetPendingInvitations(resourceID primitive.ObjectID) ([]models.ShareInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := database.InvitationCollection.Find(ctx, bson.M{
+		"resource_id": resourceID,
+		"status":      models.InvitationStatusPending,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("davetler alınamadı: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []models.ShareInvitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, fmt.Errorf("davetler decode edilemedi: %v", err)
+	}
+
+	return invitations, nil
+}
+
+// GetInvitationByID - ID'ye göre davet getir
+func (is *InvitationService) GetInvitationByID(invitationID string) (*models.ShareInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(invitationID)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz davet ID'si: %v", err)
+	}
+
+	var invitation models.ShareInvitation
+	if err := database.InvitationCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&invitation); err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// DeleteInvitation - Bekleyen daveti sil
+func (is *InvitationService) DeleteInvitation(invitationID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := database.InvitationCollection.DeleteOne(ctx, bson.M{
+		"_id":    invitationID,
+		"status": models.InvitationStatusPending,
+	})
+	if err != nil {
+		return fmt.Errorf("davet silinemedi: %v", err)
+	}
+	return nil
+}
+
+// MaterializeInvitations - Kullanıcı ilk kez giriş yaptığında e-postasına ait bekleyen
+// davetleri gerçek AccessEntry'lere dönüştür
+func (is *InvitationService) MaterializeInvitations(user *models.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := database.InvitationCollection.Find(ctx, bson.M{
+		"email":  strings.ToLower(strings.TrimSpace(user.Email)),
+		"status": models.InvitationStatusPending,
+	})
+	if err != nil {
+		return fmt.Errorf("bekleyen davetler alınamadı: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var invitations []models.ShareInvitation
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return fmt.Errorf("bekleyen davetler decode edilemedi: %v", err)
+	}
+
+	userID := user.ID.Hex()
+	for _, invitation := range invitations {
+		// Süresi dolmuş davet erişime dönüşmez
+		if invitation.ExpiresAt != nil && !invitation.ExpiresAt.After(time.Now()) {
+			database.InvitationCollection.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{"$set": bson.M{
+				"status": models.InvitationStatusExpired,
+			}})
+			continue
+		}
+
+		collection := database.FileCollection
+		if invitation.ResourceType == "folder" {
+			collection = database.FolderCollection
+		}
+
+		entry := models.AccessEntry{
+			UserID:     userID,
+			AccessType: invitation.AccessType,
+			GrantedAt:  time.Now(),
+			GrantedBy:  invitation.InvitedBy,
+			ExpiresAt:  invitation.ExpiresAt,
+		}
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": invitation.ResourceID, "access_list.user_id": bson.M{"$ne": userID}},
+			bson.M{
+				"$push": bson.M{"access_list": entry},
+				"$set":  bson.M{"updated_at": time.Now()},
+			},
+		)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Printf("Davet erişime dönüştürülemedi %s: %v", invitation.ID.Hex(), err)
+			continue
+		}
+
+		if invitation.ResourceType == "folder" {
+			if err := helpers.PropagateAccessToChildrenWithExpiry(invitation.ResourceID, userID, invitation.AccessType, invitation.InvitedBy, invitation.ExpiresAt); err != nil {
+				log.Printf("Davet alt öğelere yayılamadı %s: %v", invitation.ID.Hex(), err)
+			}
+		}
+
+		now := time.Now()
+		database.InvitationCollection.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{"$set": bson.M{
+			"status":           models.InvitationStatusAccepted,
+			"accepted_user_id": userID,
+			"accepted_at":      now,
+		}})
+	}
+
+	return nil
+}
+
+// notify - Davet e-postasını gönder (hata paylaşımı engellemez)
+func (is *InvitationService) notify(invitation *models.ShareInvitation) {
+	if MailerInstance == nil {
+		return
+	}
+
+	inviter := "Bir kullanıcı"
+	if owner := UserServiceInstance.GetUserResponse(invitation.InvitedBy); owner != nil {
+		inviter = owner.Name
+	}
+
+	body := fmt.Sprintf(
+		"%s sizinle \"%s\" öğesini paylaştı (%s erişimi).\n\nGörüntülemek için Google hesabınızla giriş yapın:\n%s\n",
+		inviter, invitation.ResourceName, invitation.AccessType, is.FrontendURL,
+	)
+	if invitation.ExpiresAt != nil {
+		body += fmt.Sprintf("\nErişim %s tarihine kadar geçerlidir.\n", invitation.ExpiresAt.Format("02.01.2006 15:04"))
+	}
+
+	err := MailerInstance.Send(MailMessage{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("%s sizinle bir öğe paylaştı", inviter),
+		Body:    body,
+	})
+	if err != nil {
+		log.Printf("Davet e-postası gönderilemedi (%s): %v", invitation.Email, err)
+	}
+}
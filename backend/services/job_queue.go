@@ -0,0 +1,648 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"nimbus-backend/config"
+	"nimbus-backend/database"
+	"nimbus-backend/models"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxRetryDelay - Exponential backoff üst sınırı
+const maxRetryDelay = time.Hour
+
+// JobQueue - Mongo tabanlı doküman işleme kuyruğu. İşler lease ile alınır, sınırlı sayıda
+// worker çalışır, hatalar exponential backoff ile tekrar denenir ve deneme hakkı bitince
+// dead-letter olarak işaretlenir. Sunucu yeniden başlasa bile iş kaybolmaz.
+type JobQueue struct {
+	processor     *DocumentProcessor
+	instanceID    string // Worker ID'lerinin ön eki (hostname + rastgele ek)
+	concurrency   int
+	maxAttempts   int
+	leaseDuration time.Duration
+	retryBase     time.Duration
+	pollInterval  time.Duration
+	wake          chan struct{}
+}
+
+var JobQueueInstance *JobQueue
+
+// ErrJobNotFound - İş bulunamadı
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobState - İş mevcut durumunda bu işleme izin vermiyor
+var ErrJobState = errors.New("job state does not allow this operation")
+
+// permanentError - Tekrar denemenin anlamsız olduğu hatalar (desteklenmeyen format, boş metin)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent - Hatayı retry edilmeyecek şekilde işaretle
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// InitJobQueue - Kuyruğu config ile oluştur (Start çağrılana kadar worker çalışmaz)
+func InitJobQueue(cfg *config.Config, processor *DocumentProcessor) error {
+	if processor == nil {
+		return fmt.Errorf("document processor must be initialized first")
+	}
+
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	concurrency := cfg.JobWorkers
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	JobQueueInstance = &JobQueue{
+		processor:     processor,
+		instanceID:    fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(suffix)),
+		concurrency:   concurrency,
+		maxAttempts:   cfg.JobMaxAttempts,
+		leaseDuration: time.Duration(cfg.JobLeaseSeconds) * time.Second,
+		retryBase:     time.Duration(cfg.JobRetryBaseSeconds) * time.Second,
+		pollInterval:  2 * time.Second,
+		wake:          make(chan struct{}, concurrency),
+	}
+	return nil
+}
+
+// EnsureIndexes - Kuyruk sorguları için index oluştur
+func (q *JobQueue) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := database.JobCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lease_until", Value: 1}}},
+		{Keys: bson.D{{Key: "file_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bir dosyanın aynı anda tek aktif işi olabilir; eşzamanlı Enqueue çağrıları bu index'e takılır.
+	// $in içeren partial index MongoDB 6.0 gerektirir.
+	_, err = database.JobCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "file_id", Value: 1}},
+		Options: options.Index().
+			SetName("file_id_active_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{
+				"status": bson.M{"$in": []string{models.JobStatusQueued, models.JobStatusRunning}},
+			}),
+	})
+	if err != nil {
+		return fmt.Errorf("aktif iş index'i oluşturulamadı: %w", err)
+	}
+	return nil
+}
+
+// Start - Takılı kalmış kayıtları kurtarır ve worker'ları başlatır
+func (q *JobQueue) Start() {
+	if recovered, err := q.RecoverStale(); err != nil {
+		log.Printf("⚠️ Takılı işleme kayıtları kurtarılamadı: %v", err)
+	} else if recovered > 0 {
+		log.Printf("♻️ %d takılı dosya işleme kuyruğuna geri alındı", recovered)
+	}
+
+	// Her worker kendi ID'siyle lease alır; aynı süreçteki worker'lar birbirinin lease'ini yenileyemez
+	for i := 0; i < q.concurrency; i++ {
+		go q.worker(fmt.Sprintf("%s-%d", q.instanceID, i+1))
+	}
+
+	log.Printf("✅ İşleme kuyruğu başlatıldı (%d worker, id %s)", q.concurrency, q.instanceID)
+}
+
+// Enqueue - Dosya için iş oluştur. Dosyanın bekleyen bir işi varsa o döner; çalışan iş eski
+// içeriği indirmiş olabileceğinden bitince tekrar çalışması için işaretlenir.
+func (q *JobQueue) Enqueue(fileID, userID, filename, minioPath, contentType string, deduplicate bool) (*models.ProcessingJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	existing, err := q.activeJob(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.Status == models.JobStatusQueued {
+			return existing, nil
+		}
+		requested, err := q.requestRerun(ctx, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if requested {
+			return existing, nil
+		}
+		// İş bu arada bitti, yeni iş oluştur
+	}
+
+	now := time.Now()
+	job := models.ProcessingJob{
+		ID:          primitive.NewObjectID(),
+		FileID:      fileID,
+		UserID:      userID,
+		Filename:    filename,
+		MinioPath:   minioPath,
+		ContentType: contentType,
+		Deduplicate: deduplicate,
+		Status:      models.JobStatusQueued,
+		MaxAttempts: q.maxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if _, err := database.JobCollection.InsertOne(ctx, job); err != nil {
+		// Kontrol ile ekleme arasında başka bir istek aynı dosya için iş oluşturdu
+		if mongo.IsDuplicateKeyError(err) {
+			if existing, findErr := q.activeJob(ctx, fileID); findErr == nil && existing != nil {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("iş kuyruğa eklenemedi: %v", err)
+	}
+
+	q.processor.markInProgress(fileID, "pending", "")
+	q.notify()
+	return &job, nil
+}
+
+// activeJob - Dosyanın bekleyen veya çalışan işi (yoksa nil)
+func (q *JobQueue) activeJob(ctx context.Context, fileID string) (*models.ProcessingJob, error) {
+	var job models.ProcessingJob
+	err := database.JobCollection.FindOne(ctx, bson.M{
+		"file_id": fileID,
+		"status":  bson.M{"$in": []string{models.JobStatusQueued, models.JobStatusRunning}},
+	}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("aktif iş kontrol edilemedi: %v", err)
+	}
+	return &job, nil
+}
+
+// requestRerun - Çalışan işi bitince tekrar kuyruğa alınmak üzere işaretle. İş artık
+// çalışmıyorsa false döner.
+func (q *JobQueue) requestRerun(ctx context.Context, jobID primitive.ObjectID) (bool, error) {
+	result, err := database.JobCollection.UpdateOne(ctx,
+		bson.M{"_id": jobID, "status": models.JobStatusRunning},
+		bson.M{"$set": bson.M{"rerun_requested": true, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("iş tekrar çalıştırma için işaretlenemedi: %v", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// rerunRequested - Dosyanın çalışan işi içerik değiştiği için tekrar çalışacak mı
+func rerunRequested(fileID string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := database.JobCollection.CountDocuments(ctx, bson.M{
+		"file_id":         fileID,
+		"status":          models.JobStatusRunning,
+		"rerun_requested": true,
+	})
+	return err == nil && count > 0
+}
+
+// notify - Boşta bekleyen bir worker'ı uyandır
+func (q *JobQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *JobQueue) worker(workerID string) {
+	for {
+		job, err := q.claim(workerID)
+		if err != nil {
+			log.Printf("İş alınamadı: %v", err)
+			time.Sleep(q.pollInterval)
+			continue
+		}
+		if job == nil {
+			select {
+			case <-q.wake:
+			case <-time.After(q.pollInterval):
+			}
+			continue
+		}
+		q.run(job)
+	}
+}
+
+// claim - Zamanı gelmiş bir işi ya da lease'i dolmuş (worker'ı çökmüş) bir işi atomik olarak al
+func (q *JobQueue) claim(workerID string) (*models.ProcessingJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	leaseUntil := now.Add(q.leaseDuration)
+
+	filter := bson.M{"$or": []bson.M{
+		{"status": models.JobStatusQueued, "run_at": bson.M{"$lte": now}},
+		{"status": models.JobStatusRunning, "lease_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.JobStatusRunning,
+			"lease_owner": workerID,
+			"lease_until": leaseUntil,
+			"started_at":  now,
+			"updated_at":  now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.ProcessingJob
+	if err := database.JobCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// run - İşi çalıştırır; lease'i düzenli yeniler, iş iptal edilirse context'i keser.
+// Lease sahibi claim'in yazdığı job.LeaseOwner'dır.
+func (q *JobQueue) run(job *models.ProcessingJob) {
+	// Worker'ı tekrar tekrar çökerten bir iş lease süresi dolarak geri gelir; hakkı bittiyse çalıştırma
+	if job.MaxAttempts > 0 && job.Attempts > job.MaxAttempts {
+		q.finish(job, fmt.Errorf("deneme hakkı doldu: %s", job.LastError))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer ProgressTrackerInstance.Clear(job.FileID)
+
+	go q.heartbeat(ctx, cancel, job)
+
+	log.Printf("İş %s başladı (dosya %s, deneme %d/%d)", job.ID.Hex(), job.FileID, job.Attempts, job.MaxAttempts)
+	err := q.safeRun(ctx, job)
+	if err != nil && ctx.Err() != nil {
+		log.Printf("İş %s iptal edildi veya lease kaybedildi", job.ID.Hex())
+		return
+	}
+	q.finish(job, err)
+}
+
+// safeRun - İşleme sırasında oluşan panic worker'ı öldürmesin
+func (q *JobQueue) safeRun(ctx context.Context, job *models.ProcessingJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return q.processor.runJob(ctx, job)
+}
+
+// heartbeat - Lease'i yeniler. İş iptal edildiyse ya da başka worker aldıysa çalışmayı durdurur.
+func (q *JobQueue) heartbeat(ctx context.Context, cancel context.CancelFunc, job *models.ProcessingJob) {
+	interval := q.leaseDuration / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			updateCtx, updateCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			result, err := database.JobCollection.UpdateOne(updateCtx,
+				bson.M{"_id": job.ID, "status": models.JobStatusRunning, "lease_owner": job.LeaseOwner},
+				bson.M{"$set": bson.M{"lease_until": time.Now().Add(q.leaseDuration), "updated_at": time.Now()}},
+			)
+			updateCancel()
+			if err != nil {
+				log.Printf("İş %s lease yenilenemedi: %v", job.ID.Hex(), err)
+				continue
+			}
+			if result.MatchedCount == 0 {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// finish - Sonucu yaz: başarı, backoff ile tekrar deneme veya dead-letter
+func (q *JobQueue) finish(job *models.ProcessingJob, runErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{"updated_at": now}
+	unset := bson.M{"lease_owner": "", "lease_until": "", "progress.eta_seconds": "", "rerun_requested": ""}
+
+	var permanent *permanentError
+	switch {
+	case runErr == nil:
+		set["status"] = models.JobStatusSucceeded
+		set["finished_at"] = now
+		set["progress.stage"] = models.ProcessingStageCompleted
+		set["progress.percent"] = 100
+	case errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts:
+		set["status"] = models.JobStatusDead
+		set["last_error"] = runErr.Error()
+		set["finished_at"] = now
+		set["progress.stage"] = models.ProcessingStageFailed
+	default:
+		delay := retryDelay(q.retryBase, job.Attempts)
+		set["status"] = models.JobStatusQueued
+		set["last_error"] = runErr.Error()
+		set["run_at"] = now.Add(delay)
+		set["progress.stage"] = models.ProcessingStageQueued
+		set["progress.percent"] = 0
+		log.Printf("İş %s başarısız, %s sonra tekrar denenecek: %v", job.ID.Hex(), delay, runErr)
+	}
+
+	// Sadece lease hâlâ bizdeyse yaz; iptal edilen iş tekrar canlanmasın. Önceki hali
+	// çalışma sırasında gelen tekrar çalıştırma isteğini gösterir.
+	var before models.ProcessingJob
+	err := database.JobCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": job.ID, "status": models.JobStatusRunning, "lease_owner": job.LeaseOwner},
+		bson.M{"$set": set, "$unset": unset},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return
+	}
+	if err != nil {
+		log.Printf("İş %s sonucu yazılamadı: %v", job.ID.Hex(), err)
+		return
+	}
+
+	switch set["status"] {
+	case models.JobStatusDead:
+		log.Printf("İş %s dead-letter olarak işaretlendi: %v", job.ID.Hex(), runErr)
+		q.processor.updateFileStatus(job.FileID, "failed", runErr.Error(), 0)
+	case models.JobStatusQueued:
+		// Tekrar deneme yeni içeriği zaten indirir
+		q.processor.markInProgress(job.FileID, "pending", runErr.Error())
+		return
+	}
+
+	if before.RerunRequested {
+		log.Printf("Dosya %s iş %s çalışırken değişti, tekrar kuyruğa alınıyor", job.FileID, job.ID.Hex())
+		if _, err := q.Enqueue(job.FileID, job.UserID, job.Filename, job.MinioPath, job.ContentType, false); err != nil {
+			log.Printf("Dosya %s tekrar kuyruğa alınamadı: %v", job.FileID, err)
+		}
+	}
+}
+
+// retryDelay - base * 2^(deneme-1), maxRetryDelay ile sınırlı
+func retryDelay(base time.Duration, attempts int) time.Duration {
+	if base <= 0 {
+		base = 30 * time.Second
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Duration(float64(base) * math.Pow(2, float64(attempts-1)))
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// RecoverStale - "processing"/"pending" durumda kalmış veya yeniden indekslenmeyi bekleyen
+// (content_stale) ama aktif işi olmayan dosyaları
+// (ör. kuyruk öncesi goroutine'ler veya sunucu çökmesi) tekrar kuyruğa al
+func (q *JobQueue) RecoverStale() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	cursor, err := database.FileCollection.Find(ctx, bson.M{
+		"$or": []bson.M{
+			{"processing_status": bson.M{"$in": []string{"processing", "pending"}}},
+			{"content_stale": true, "processing_status": bson.M{"$in": models.QueryableStatuses}},
+		},
+		"deleted_at": nil,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("takılı dosyalar alınamadı: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var files []models.File
+	if err := cursor.All(ctx, &files); err != nil {
+		return 0, fmt.Errorf("takılı dosyalar decode edilemedi: %v", err)
+	}
+
+	recovered := 0
+	for _, file := range files {
+		active, err := database.JobCollection.CountDocuments(ctx, bson.M{
+			"file_id": file.ID.Hex(),
+			"status":  bson.M{"$in": []string{models.JobStatusQueued, models.JobStatusRunning}},
+		})
+		if err != nil || active > 0 {
+			// Çalışan işlerin lease'i dolunca claim onları zaten geri alır
+			continue
+		}
+		if _, err := q.Enqueue(file.ID.Hex(), file.UserID, file.Filename, file.MinioPath, file.ContentType, false); err != nil {
+			log.Printf("Dosya %s tekrar kuyruğa alınamadı: %v", file.ID.Hex(), err)
+			continue
+		}
+		recovered++
+	}
+
+	return recovered, nil
+}
+
+// List - Filtrelenmiş ve sayfalanmış işler (en yeni önce)
+func (q *JobQueue) List(filter models.JobFilter, page, limit int64) ([]models.ProcessingJob, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.FileID != "" {
+		query["file_id"] = filter.FileID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	total, err := database.JobCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("işler sayılamadı: %v", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+
+	cursor, err := database.JobCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("işler alınamadı: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []models.ProcessingJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, 0, fmt.Errorf("işler decode edilemedi: %v", err)
+	}
+
+	return jobs, total, nil
+}
+
+// GetJob - ID ile iş getir
+func (q *JobQueue) GetJob(jobID string) (*models.ProcessingJob, error) {
+	objectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.ProcessingJob
+	if err := database.JobCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// LatestForFile - Dosyanın en son oluşturulan işi (yoksa nil)
+func (q *JobQueue) LatestForFile(fileID string) (*models.ProcessingJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.ProcessingJob
+	err := database.JobCollection.FindOne(ctx,
+		bson.M{"file_id": fileID},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retry - Dead veya iptal edilmiş işi deneme sayacını sıfırlayarak tekrar kuyruğa al
+func (q *JobQueue) Retry(jobID string) (*models.ProcessingJob, error) {
+	// Aynı dosya için yeni bir iş zaten kuyruktaysa ikinci kopya oluşturma
+	if job, err := q.GetJob(jobID); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		active, countErr := database.JobCollection.CountDocuments(ctx, bson.M{
+			"file_id": job.FileID,
+			"status":  bson.M{"$in": []string{models.JobStatusQueued, models.JobStatusRunning}},
+		})
+		cancel()
+		if countErr == nil && active > 0 {
+			return nil, ErrJobState
+		}
+	}
+
+	job, err := q.transition(jobID,
+		[]string{models.JobStatusDead, models.JobStatusCancelled},
+		bson.M{"status": models.JobStatusQueued, "attempts": 0, "run_at": time.Now()},
+		bson.M{"finished_at": ""},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	q.processor.markInProgress(job.FileID, "pending", "")
+	q.notify()
+	return job, nil
+}
+
+// Cancel - Bekleyen veya çalışan işi iptal et. Çalışan iş bir sonraki lease yenilemesinde durur.
+func (q *JobQueue) Cancel(jobID string) (*models.ProcessingJob, error) {
+	job, err := q.transition(jobID,
+		[]string{models.JobStatusQueued, models.JobStatusRunning},
+		bson.M{"status": models.JobStatusCancelled, "finished_at": time.Now(), "last_error": "cancelled", "progress.stage": models.ProcessingStageFailed},
+		bson.M{"lease_owner": "", "lease_until": ""},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// İndeksi olan dosya önceki indeksle sorgulanmaya devam eder; sadece ilk işleme başarısız sayılır
+	if err := q.processor.markCancelled(job.FileID, "İşlem iptal edildi"); err != nil {
+		log.Printf("İptal edilen iş %s için dosya durumu güncellenemedi: %v", jobID, err)
+	}
+	return job, nil
+}
+
+// transition - İşi sadece izin verilen durumlardan birindeyse atomik olarak güncelle
+func (q *JobQueue) transition(jobID string, from []string, set, unset bson.M) (*models.ProcessingJob, error) {
+	objectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set["updated_at"] = time.Now()
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var job models.ProcessingJob
+	err = database.JobCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "status": bson.M{"$in": from}},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		if _, getErr := q.GetJob(jobID); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrJobState
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
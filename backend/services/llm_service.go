@@ -0,0 +1,411 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"nimbus-backend/cache"
+	"nimbus-backend/config"
+	"nimbus-backend/llm"
+	"nimbus-backend/models"
+	"nimbus-backend/retrieval"
+)
+
+// LLMService answers questions through the configured embedding and chat providers. It adds
+// the query embedding cache and builds the grounded RAG prompt.
+type LLMService struct {
+	embedder   llm.Embedder
+	chat       llm.ChatModel
+	queryCache cache.QueryCache
+	config     *config.Config
+}
+
+// Providers selected by EMBED_PROVIDER and CHAT_PROVIDER (see InitAIProviders)
+var (
+	EmbedderInstance  llm.Embedder
+	ChatModelInstance llm.ChatModel
+)
+
+// InitAIProviders creates the embedding and chat providers and the reranker configured
+// for this deployment
+func InitAIProviders(cfg *config.Config) error {
+	embedder, err := llm.NewEmbedder(cfg)
+	if err != nil {
+		return err
+	}
+	chat, err := llm.NewChatModel(cfg)
+	if err != nil {
+		return err
+	}
+	reranker, err := retrieval.NewReranker(cfg.Reranker, chat, cfg.RerankTopN)
+	if err != nil {
+		return err
+	}
+
+	EmbedderInstance = embedder
+	ChatModelInstance = chat
+	RerankerInstance = reranker
+	log.Printf("✅ AI providers initialized (embedder: %s, chat: %s, reranker: %s)", embedder.ModelID(), cfg.ChatProvider, reranker.Name())
+	return nil
+}
+
+var (
+	sharedQueryCache     cache.QueryCache
+	sharedQueryCacheOnce sync.Once
+)
+
+// SharedQueryCache returns the process-wide query cache (nil when disabled). It is shared so
+// content changes can invalidate entries no matter which service instance cached them.
+func SharedQueryCache(cfg *config.Config) cache.QueryCache {
+	sharedQueryCacheOnce.Do(func() {
+		// Initialize query cache if enabled
+		if cfg.EnableQueryCache {
+			ttl := time.Duration(cfg.QueryCacheTTL) * time.Minute
+			sharedQueryCache = cache.NewInMemoryQueryCache(ttl)
+			log.Printf("Query cache enabled with TTL: %v", ttl)
+		}
+	})
+	return sharedQueryCache
+}
+
+func NewLLMService(cfg *config.Config) *LLMService {
+	return &LLMService{
+		embedder:   EmbedderInstance,
+		chat:       ChatModelInstance,
+		queryCache: SharedQueryCache(cfg),
+		config:     cfg,
+	}
+}
+
+// EmbedModelID identifies the vector space of the configured embedder ("" before init)
+func EmbedModelID() string {
+	if EmbedderInstance == nil {
+		return ""
+	}
+	return EmbedderInstance.ModelID()
+}
+
+// GenerateEmbedding generates an embedding vector for the given text
+// Includes caching support if query cache is enabled
+func (s *LLMService) GenerateEmbedding(text string) ([]float64, error) {
+	// Check cache if enabled
+	if s.queryCache != nil && s.config.EnableQueryCache {
+		queryKey := cache.GenerateQueryKey(text)
+
+		// Try exact cache hit
+		if cachedQuery, found := s.queryCache.Get(queryKey); found {
+			log.Printf("Cache hit for embedding (exact match)")
+			return cachedQuery.Embedding, nil
+		}
+	}
+
+	embeddings, err := s.embedder.Embed(context.Background(), []string{text})
+	if err != nil {
+		return nil, err
+	}
+	embedding := embeddings[0]
+
+	// Cache the result if caching is enabled
+	if s.queryCache != nil && s.config.EnableQueryCache {
+		queryKey := cache.GenerateQueryKey(text)
+		cachedQuery := &cache.CachedQuery{
+			Embedding: embedding,
+			Timestamp: time.Now(),
+		}
+
+		ttl := time.Duration(s.config.QueryCacheTTL) * time.Minute
+		if err := s.queryCache.Set(queryKey, cachedQuery, ttl); err != nil {
+			log.Printf("Warning: failed to cache embedding: %v", err)
+		}
+	}
+
+	return embedding, nil
+}
+
+// GetCacheStats returns query cache statistics if cache is enabled
+func (s *LLMService) GetCacheStats() *cache.CacheStats {
+	if s.queryCache != nil {
+		stats := s.queryCache.Stats()
+		return &stats
+	}
+	return nil
+}
+
+// GenerateResponse generates a text response using the chat model
+func (s *LLMService) GenerateResponse(prompt string) (string, error) {
+	return s.chat.Generate(context.Background(), prompt)
+}
+
+// GenerateResponseStream generates a response and calls onToken for every delta as it
+// arrives. Cancelling ctx (e.g. when the client disconnects) aborts the upstream request.
+func (s *LLMService) GenerateResponseStream(ctx context.Context, prompt string, onToken func(string) error) (string, error) {
+	return s.chat.GenerateStream(ctx, prompt, onToken)
+}
+
+// GenerateRAGResponse generates a response with context chunks (for RAG). history holds
+// the previous messages of the conversation, oldest first.
+func (s *LLMService) GenerateRAGResponse(question string, contextChunks []string, history []models.Message) (string, error) {
+	return s.GenerateResponse(s.BuildRAGPrompt(question, contextChunks, history))
+}
+
+// GenerateRAGResponseStream is the streaming counterpart of GenerateRAGResponse
+func (s *LLMService) GenerateRAGResponseStream(ctx context.Context, question string, contextChunks []string, history []models.Message, onToken func(string) error) (string, error) {
+	return s.GenerateResponseStream(ctx, s.BuildRAGPrompt(question, contextChunks, history), onToken)
+}
+
+const condensePrompt = `Rewrite the follow-up question so it can be understood without the conversation. Replace pronouns and references such as "it", "the second one", "bu", "o" or "ikincisi" with what they refer to in the conversation. Keep the language of the follow-up question. If it is already self-contained, return it unchanged. Reply with the rewritten question only.
+
+CONVERSATION:
+%s
+FOLLOW-UP QUESTION: %s
+
+STANDALONE QUESTION:`
+
+// condenseMessageTokens caps each previous message in the rewrite prompt; long answers
+// matter there only for the entities they mention first
+const condenseMessageTokens = 150
+
+// CondenseQuestion rewrites a follow-up question into a standalone one using the previous
+// messages (oldest first), so it can be embedded and searched on its own. Without history
+// the question is returned as is; an unusable rewrite falls back to the question.
+func (s *LLMService) CondenseQuestion(ctx context.Context, history []models.Message, question string) (string, error) {
+	if len(history) == 0 {
+		return question, nil
+	}
+
+	var conversation strings.Builder
+	for _, message := range history {
+		conversation.WriteString(fmt.Sprintf("%s: %s\n", messageSpeaker(message), truncateTokens(message.Content, condenseMessageTokens)))
+	}
+
+	rewritten, err := s.chat.Generate(ctx, fmt.Sprintf(condensePrompt, conversation.String(), question))
+	if err != nil {
+		return question, err
+	}
+
+	// Keep the first line, without quotes or a repeated label
+	rewritten = strings.TrimSpace(rewritten)
+	if i := strings.IndexByte(rewritten, '\n'); i >= 0 {
+		rewritten = rewritten[:i]
+	}
+	rewritten = strings.TrimPrefix(rewritten, "STANDALONE QUESTION:")
+	rewritten = strings.Trim(strings.TrimSpace(rewritten), "\"'“”")
+	if rewritten == "" || len(rewritten) > 4*len(question)+400 {
+		return question, nil
+	}
+	return rewritten, nil
+}
+
+// fitHistory keeps the newest messages that fit into budget tokens, oldest first. The
+// oldest kept message is shortened when only part of it fits.
+func (s *LLMService) fitHistory(history []models.Message, budget int) []models.Message {
+	const minPartialTokens = 50
+
+	var fitted []models.Message
+	used := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		message := history[i]
+		tokens := s.CountTokens(message.Content)
+		if used+tokens > budget {
+			if remaining := budget - used; remaining >= minPartialTokens {
+				message.Content = truncateTokens(message.Content, remaining)
+				fitted = append(fitted, message)
+			}
+			break
+		}
+		used += tokens
+		fitted = append(fitted, message)
+	}
+
+	for i, j := 0, len(fitted)-1; i < j; i, j = i+1, j-1 {
+		fitted[i], fitted[j] = fitted[j], fitted[i]
+	}
+	return fitted
+}
+
+func messageSpeaker(message models.Message) string {
+	if message.Role == "assistant" {
+		return "Assistant"
+	}
+	return "User"
+}
+
+// truncateTokens cuts text to roughly maxTokens (see CountTokens) on a rune boundary
+func truncateTokens(text string, maxTokens int) string {
+	maxBytes := maxTokens * 4
+	if len(text) <= maxBytes {
+		return text
+	}
+	for maxBytes > 0 && !utf8.RuneStart(text[maxBytes]) {
+		maxBytes--
+	}
+	return text[:maxBytes] + "..."
+}
+
+// BuildRAGPrompt builds the grounded prompt sent to the LLM for a question, its context
+// chunks and the token-budgeted tail of the conversation history
+func (s *LLMService) BuildRAGPrompt(question string, contextChunks []string, history []models.Message) string {
+	var sb strings.Builder
+	qLower := strings.ToLower(question)
+
+	// Detect intent categories
+	isComparison := strings.Contains(qLower, "compare") ||
+		strings.Contains(qLower, "comparison") ||
+		strings.Contains(qLower, "vs") ||
+		strings.Contains(qLower, "versus")
+
+	isDefinition := strings.HasPrefix(qLower, "what is ") ||
+		strings.HasPrefix(qLower, "what's ") ||
+		strings.HasPrefix(qLower, "whats ") ||
+		strings.HasPrefix(qLower, "define ") ||
+		strings.Contains(qLower, "definition of") ||
+		strings.Contains(qLower, "meaning of")
+
+	isSummary := strings.Contains(qLower, "summarize") ||
+		strings.Contains(qLower, "summary") ||
+		strings.Contains(qLower, "overview") ||
+		strings.Contains(qLower, "brief") ||
+		strings.Contains(qLower, "outline")
+
+	// Extract definition term once (optimized)
+	defTerm := ""
+	if isDefinition {
+		defTerm = strings.TrimPrefix(qLower, "what is ")
+		defTerm = strings.TrimPrefix(defTerm, "what's ")
+		defTerm = strings.TrimPrefix(defTerm, "whats ")
+		defTerm = strings.TrimPrefix(defTerm, "define ")
+		defTerm = strings.TrimPrefix(defTerm, "the ")
+		defTerm = strings.TrimSpace(defTerm)
+	}
+
+	// === CORE SYSTEM RULES ===
+	sb.WriteString("You are a document analysis assistant. You ONLY answer questions using the document context below.\n\n")
+
+	sb.WriteString("CRITICAL RULES:\n")
+	sb.WriteString("1. Only answer using the provided context.\n")
+	sb.WriteString("2. If the answer is NOT in the context, reply EXACTLY:\n")
+	sb.WriteString("   \"Sorry, this question is not related to the document content. Please ask a question that is related to the document.\"\n")
+	sb.WriteString("3. NEVER generate or assist with:\n")
+	sb.WriteString("   - database/system/shell commands\n")
+	sb.WriteString("   - harmful or unethical actions\n")
+	sb.WriteString("   - code execution or scripts\n")
+	sb.WriteString("4. Your ONLY job: summarize, analyze, explain, compare **based on the context**.\n\n")
+
+	// ==== INTENT-SPECIFIC RULES ====
+	if isComparison {
+		sb.WriteString("SPECIAL MODE: COMPARISON QUERY\n")
+		sb.WriteString("- Extract ALL features and ALL item values from any comparison-like text.\n")
+		sb.WriteString("- Comparison tables may appear as bullet lists or inline patterns.\n")
+		sb.WriteString("- Do NOT omit any features.\n")
+		sb.WriteString("- Present the final result in a clean Markdown table.\n\n")
+	}
+
+	if isDefinition {
+		sb.WriteString("SPECIAL MODE: DEFINITION QUERY\n")
+		sb.WriteString(fmt.Sprintf("- The term you must define is: **%s**\n", defTerm))
+		sb.WriteString("- Look for chunks where this term appears prominently.\n")
+		sb.WriteString("- Extract the complete meaning, purpose, components, and key characteristics.\n\n")
+	}
+
+	if isSummary {
+		sb.WriteString("SPECIAL MODE: SUMMARY QUERY\n")
+		sb.WriteString("- Produce a structured, topic-based summary.\n")
+		sb.WriteString("- Cover all major themes, lists, and important details.\n")
+		sb.WriteString("- Use headers and bullet points.\n\n")
+	}
+
+	// Formatting rules
+	sb.WriteString("FORMATTING RULES:\n")
+	sb.WriteString("- Use Markdown.\n")
+	sb.WriteString("- Use headings, lists, bold text.\n")
+	sb.WriteString("- Keep paragraphs clean and well-structured.\n")
+	sb.WriteString("- Final answer MUST be clean and professional.\n")
+	sb.WriteString("- Every factual sentence should end with a citation marker like [1].\n")
+	sb.WriteString("- When a source line gives a page number, you may mention it (e.g. \"see page 12\").\n\n")
+
+	// ==== DOCUMENT CONTEXT ====
+	sb.WriteString("DOCUMENT CONTEXT:\n")
+	sb.WriteString("====================================================\n\n")
+
+	// Calculate available tokens for context
+	// Reserve 1000 tokens for system prompt and answer, plus what the history takes
+	history = s.fitHistory(history, s.config.HistoryTokenBudget)
+	historyTokens := 0
+	for _, message := range history {
+		historyTokens += s.CountTokens(message.Content)
+	}
+	maxContextTokens := s.config.ContextWindowSize - 1000 - historyTokens
+	currentTokens := 0
+
+	for i, chunk := range contextChunks {
+		chunkTokens := s.CountTokens(chunk)
+
+		// Check if adding this chunk exceeds the limit
+		if currentTokens+chunkTokens > maxContextTokens {
+			log.Printf("Context limit reached (%d/%d tokens). Truncating remaining %d chunks.",
+				currentTokens, maxContextTokens, len(contextChunks)-i)
+			break
+		}
+
+		chLower := strings.ToLower(chunk)
+		header := fmt.Sprintf("--- Source [%d] ---", i+1)
+
+		// Highlight comparison chunks
+		if isComparison && (strings.Contains(chLower, "vs") ||
+			strings.Contains(chLower, "versus") ||
+			strings.Contains(chLower, "comparison")) {
+			header = fmt.Sprintf("--- Source [%d] (comparison data) ---", i+1)
+		}
+
+		// Highlight definition chunks
+		if isDefinition && defTerm != "" {
+			if strings.HasPrefix(chLower, defTerm) ||
+				strings.Contains(chLower, defTerm+" ") ||
+				strings.Contains(chLower, defTerm+"\n") {
+				header = fmt.Sprintf("--- Source [%d] (definition of %s) ---", i+1, defTerm)
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("%s\n%s\n\n", header, chunk))
+		currentTokens += chunkTokens
+	}
+
+	sb.WriteString("====================================================\n\n")
+
+	// ==== CONVERSATION HISTORY ====
+	// Earlier turns only explain what the question refers to; facts still come from the context
+	if len(history) > 0 {
+		sb.WriteString("CONVERSATION HISTORY (for resolving references only, not a source of facts):\n")
+		for _, message := range history {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", messageSpeaker(message), message.Content))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("USER QUESTION: %s\n\n", question))
+
+	// Chain of Thought Prompting (Internal only - do not output reasoning)
+	sb.WriteString("INSTRUCTIONS:\n")
+	sb.WriteString("1. Analyze the user's question and the provided context.\n")
+	sb.WriteString("2. Synthesize the information into a cohesive, natural answer.\n")
+	sb.WriteString("3. Cite the sources you used with their bracketed number right after the claim, e.g. [1] or [2][3].\n")
+	sb.WriteString("   Only cite numbers that appear in the context. Do NOT write the word 'Source' or 'Chunk'.\n")
+	sb.WriteString("4. Do NOT output your internal reasoning or analysis steps.\n")
+	sb.WriteString("5. If the context has conflicting info, mention the conflict naturally.\n")
+	sb.WriteString("6. Provide a direct, professional response in Markdown.\n\n")
+
+	sb.WriteString("YOUR ANSWER (Markdown only):\n")
+
+	return sb.String()
+}
+
+// CountTokens estimates the number of tokens in a string
+// Uses a simple heuristic (1 token ~= 4 chars) as we don't have a tokenizer
+func (s *LLMService) CountTokens(text string) int {
+	return len(text) / 4
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"nimbus-backend/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MailMessage - Gönderilecek e-posta
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer - Bildirim e-postaları için takılabilir gönderici arayüzü
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+var MailerInstance Mailer
+
+// InitMailer - Config'e göre mailer seç (smtp veya file)
+func InitMailer(cfg *config.Config) error {
+	switch cfg.MailerDriver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return fmt.Errorf("SMTP_HOST gerekli")
+		}
+		MailerInstance = &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}
+	case "file", "":
+		if err := os.MkdirAll(cfg.MailerOutputDir, 0755); err != nil {
+			return fmt.Errorf("mail dizini oluşturulamadı: %v", err)
+		}
+		MailerInstance = &FileMailer{Dir: cfg.MailerOutputDir, From: cfg.SMTPFrom}
+	default:
+		return fmt.Errorf("bilinmeyen mailer: %s", cfg.MailerDriver)
+	}
+
+	log.Printf("✅ Mailer başlatıldı (%s)", cfg.MailerDriver)
+	return nil
+}
+
+// SMTPMailer - net/smtp üzerinden gönderim
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg MailMessage) error {
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	body, err := buildMailBody(m.From, msg)
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(addr, auth, envelopeAddress(m.From), []string{msg.To}, body); err != nil {
+		return fmt.Errorf("e-posta gönderilemedi: %v", err)
+	}
+	return nil
+}
+
+// FileMailer - Development için e-postaları .eml dosyası olarak diske yazar
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(msg MailMessage) error {
+	safeTo := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), safeTo)
+
+	body, err := buildMailBody(m.From, msg)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(m.Dir, name), body, 0644); err != nil {
+		return fmt.Errorf("e-posta dosyası yazılamadı: %v", err)
+	}
+	return nil
+}
+
+// buildMailBody - Başlıklara satır sonu girilerek yeni başlık/alıcı eklenmesini engeller;
+// konu MIME Q-encoding ile kodlanır
+func buildMailBody(from string, msg MailMessage) ([]byte, error) {
+	for name, value := range map[string]string{"From": from, "To": msg.To, "Subject": msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("e-posta başlığı geçersiz karakter içeriyor: %s", name)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String()), nil
+}
+
+// envelopeAddress - "Ad <adres>" formatından sadece adresi çıkar
+func envelopeAddress(from string) string {
+	if start := strings.Index(from, "<"); start >= 0 {
+		if end := strings.Index(from[start:], ">"); end > 0 {
+			return from[start+1 : start+end]
+		}
+	}
+	return from
+}
@@ -352,3 +352,42 @@ func (m *MinIOService) DeleteFile(objectName string) error {
 
 	return nil
 }
+
+// GenerateObjectPresignedURL - Kayıtlı MinIO path'i için presigned GET URL oluştur
+func (m *MinIOService) GenerateObjectPresignedURL(objectName string, expiry time.Duration) (string, error) {
+	ctx := context.Background()
+
+	// Dosya varlığını kontrol et
+	_, err := m.Client.StatObject(ctx, "user-files", objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("dosya bulunamadı: %v", err)
+	}
+
+	presignedURL, err := m.Client.PresignedGetObject(ctx, "user-files", objectName, expiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("presigned URL oluşturma hatası: %v", err)
+	}
+
+	return presignedURL.String(), nil
+}
+
+// CopyObject - MinIO içinde objeyi yeni path'e kopyala (kaynak silinmez)
+func (m *MinIOService) CopyObject(srcObject, dstObject string) error {
+	ctx := context.Background()
+
+	_, err := m.Client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: "user-files", Object: dstObject},
+		minio.CopySrcOptions{Bucket: "user-files", Object: srcObject},
+	)
+	if err != nil {
+		return fmt.Errorf("dosya kopyalanamadı: %v", err)
+	}
+
+	return nil
+}
+
+// ObjectExists - Obje MinIO'da var mı
+func (m *MinIOService) ObjectExists(objectName string) bool {
+	_, err := m.Client.StatObject(context.Background(), "user-files", objectName, minio.StatObjectOptions{})
+	return err == nil
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nimbus-backend/database"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OwnershipTransferService struct{}
+
+var OwnershipTransferServiceInstance = &OwnershipTransferService{}
+
+// CreateTransfer - Sahiplik devri daveti oluştur (sadece mevcut sahip başlatabilir)
+func (ots *OwnershipTransferService) CreateTransfer(fromUserID string, req models.CreateTransferRequest) (*models.OwnershipTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if req.ToUserID == fromUserID {
+		return nil, fmt.Errorf("sahiplik kendinize devredilemez")
+	}
+
+	resourceOID, err := primitive.ObjectIDFromHex(req.ResourceID)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz kaynak ID'si: %v", err)
+	}
+
+	var ownerID, name string
+	switch req.ResourceType {
+	case "file":
+		file, err := FileServiceInstance.GetFileByID(req.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		ownerID, name = file.UserID, file.Filename
+	case "folder":
+		folder, err := FolderServiceInstance.GetFolderByID(req.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		ownerID, name = folder.UserID, folder.Name
+	default:
+		return nil, fmt.Errorf("geçersiz kaynak tipi: %s", req.ResourceType)
+	}
+
+	if ownerID != fromUserID {
+		return nil, fmt.Errorf("sadece sahip sahipliği devredebilir")
+	}
+
+	if _, err := UserServiceInstance.GetUserByID(req.ToUserID); err != nil {
+		return nil, fmt.Errorf("hedef kullanıcı bulunamadı")
+	}
+
+	// Aynı kaynak için bekleyen bir davet varsa yenisini oluşturma
+	count, err := database.TransferCollection.CountDocuments(ctx, bson.M{
+		"resource_id": resourceOID,
+		"status":      bson.M{"$in": bson.A{models.TransferStatusPending, models.TransferStatusAccepting}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bekleyen davetler kontrol edilemedi: %v", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("bu kaynak için zaten bekleyen bir devir daveti var")
+	}
+
+	transfer := &models.OwnershipTransfer{
+		ID:           primitive.NewObjectID(),
+		ResourceID:   resourceOID,
+		ResourceType: req.ResourceType,
+		ResourceName: name,
+		FromUserID:   fromUserID,
+		ToUserID:     req.ToUserID,
+		MoveObjects:  req.MoveObjects,
+		Status:       models.TransferStatusPending,
+		CreatedAt:    time.Now(),
+	}
+
+	if _, err := database.TransferCollection.InsertOne(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("devir daveti oluşturulamadı: %v", err)
+	}
+
+	return transfer, nil
+}
+
+// GetTransferByID - ID'ye göre devir davetini getir
+func (ots *OwnershipTransferService) GetTransferByID(transferID string) (*models.OwnershipTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(transferID)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz davet ID'si: %v", err)
+	}
+
+	var transfer models.OwnershipTransfer
+	if err := database.TransferCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&transfer); err != nil {
+		return nil, fmt.Errorf("devir daveti bulunamadı: %v", err)
+	}
+
+	return &transfer, nil
+}
+
+// GetIncomingTransfers - Kullanıcıya gelen bekleyen devir davetleri
+func (ots *OwnershipTransferService) GetIncomingTransfers(userID string) ([]models.OwnershipTransfer, error) {
+	return ots.findTransfers(bson.M{"to_user_id": userID, "status": models.TransferStatusPending})
+}
+
+// GetOutgoingTransfers - Kullanıcının başlattığı bekleyen devir davetleri
+func (ots *OwnershipTransferService) GetOutgoingTransfers(userID string) ([]models.OwnershipTransfer, error) {
+	return ots.findTransfers(bson.M{"from_user_id": userID, "status": models.TransferStatusPending})
+}
+
+func (ots *OwnershipTransferService) findTransfers(filter bson.M) ([]models.OwnershipTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := database.TransferCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("devir davetleri alınamadı: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	transfers := []models.OwnershipTransfer{}
+	if err := cursor.All(ctx, &transfers); err != nil {
+		return nil, fmt.Errorf("devir davetleri decode edilemedi: %v", err)
+	}
+
+	return transfers, nil
+}
+
+// AcceptTransfer - Alıcı daveti kabul eder, sahiplik (ve klasörse tüm alt ağaç) devredilir.
+// Davet önce "accepting" durumuna alınır; aynı anda gelen ikinci kabul isteği devri tekrar uygulayamaz.
+// Devir tamamlanamazsa davet beklemeye döner, tekrar kabul kalan öğeleri devreder.
+func (ots *OwnershipTransferService) AcceptTransfer(transferID, userID string) (*models.OwnershipTransfer, error) {
+	transfer, err := ots.GetTransferByID(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != userID {
+		return nil, fmt.Errorf("bu davet size ait değil")
+	}
+	if err := ots.changeStatus(transfer, models.TransferStatusPending, models.TransferStatusAccepting); err != nil {
+		return nil, err
+	}
+
+	if err := ots.applyTransfer(transfer); err != nil {
+		if revertErr := ots.changeStatus(transfer, models.TransferStatusAccepting, models.TransferStatusPending); revertErr != nil {
+			log.Printf("Devir daveti beklemeye döndürülemedi %s: %v", transfer.ID.Hex(), revertErr)
+		}
+		return nil, err
+	}
+
+	if err := ots.changeStatus(transfer, models.TransferStatusAccepting, models.TransferStatusAccepted); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// DeclineTransfer - Alıcı daveti reddeder
+func (ots *OwnershipTransferService) DeclineTransfer(transferID, userID string) (*models.OwnershipTransfer, error) {
+	transfer, err := ots.GetTransferByID(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != userID {
+		return nil, fmt.Errorf("bu davet size ait değil")
+	}
+	if err := ots.changeStatus(transfer, models.TransferStatusPending, models.TransferStatusDeclined); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// CancelTransfer - Gönderen bekleyen daveti iptal eder
+func (ots *OwnershipTransferService) CancelTransfer(transferID, userID string) (*models.OwnershipTransfer, error) {
+	transfer, err := ots.GetTransferByID(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.FromUserID != userID {
+		return nil, fmt.Errorf("sadece daveti gönderen iptal edebilir")
+	}
+	if err := ots.changeStatus(transfer, models.TransferStatusPending, models.TransferStatusCancelled); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// errTransferNotPending - Davet başka bir istek tarafından yanıtlanmış
+var errTransferNotPending = errors.New("davet artık beklemede değil")
+
+// changeStatus - Daveti sadece hâlâ "from" durumundaysa "to" durumuna geçirir (eşzamanlı yanıtlara karşı)
+func (ots *OwnershipTransferService) changeStatus(transfer *models.OwnershipTransfer, from, to string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": to, "responded_at": now}}
+	if to == models.TransferStatusPending {
+		update = bson.M{"$set": bson.M{"status": to}, "$unset": bson.M{"responded_at": ""}}
+	}
+	result, err := database.TransferCollection.UpdateOne(ctx, bson.M{"_id": transfer.ID, "status": from}, update)
+	if err != nil {
+		return fmt.Errorf("davet durumu güncellenemedi: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return errTransferNotPending
+	}
+
+	transfer.Status = to
+	transfer.RespondedAt = &now
+	if to == models.TransferStatusPending {
+		transfer.RespondedAt = nil
+	}
+	return nil
+}
+
+// applyTransfer - Sahipliği devreder. Depolama kullanımı sadece user_id üzerinden hesaplanır
+// (bkz. GetUserStorageUsage); eski sahip editör olarak kalsa da kotası düşer, yeni sahibinki artar.
+// Daha önce yarım kalan bir devirde kök zaten alıcıya geçmiş olabilir; o durumda sadece eski sahipte
+// kalan alt öğeler devredilir. Devredilemeyen alt öğeler hata olarak döner.
+func (ots *OwnershipTransferService) applyTransfer(transfer *models.OwnershipTransfer) error {
+	if transfer.ResourceType == "file" {
+		file, err := FileServiceInstance.GetFileByID(transfer.ResourceID.Hex())
+		if err != nil {
+			return err
+		}
+		if file.UserID != transfer.FromUserID {
+			return fmt.Errorf("kaynağın sahibi değişmiş, davet geçersiz")
+		}
+		return ots.transferFile(file, transfer, nil)
+	}
+
+	folder, err := FolderServiceInstance.GetFolderByID(transfer.ResourceID.Hex())
+	if err != nil {
+		return err
+	}
+	switch folder.UserID {
+	case transfer.FromUserID:
+		if err := ots.transferFolder(folder, transfer, nil); err != nil {
+			return err
+		}
+	case transfer.ToUserID:
+		// Önceki denemede kök devredildi, alt öğelerle devam et
+	default:
+		return fmt.Errorf("kaynağın sahibi değişmiş, davet geçersiz")
+	}
+
+	// Alt ağaç: sadece eski sahibe ait öğeler devredilir, başkalarının dosyaları olduğu gibi kalır
+	childFolders, childFiles, err := helpers.GetAllChildrenRecursive(folder.ID)
+	if err != nil {
+		return fmt.Errorf("alt öğeler alınamadı: %v", err)
+	}
+
+	var failures []error
+	seen := make(map[primitive.ObjectID]bool)
+	for i := range childFolders {
+		child := &childFolders[i]
+		if seen[child.ID] || child.UserID != transfer.FromUserID {
+			continue
+		}
+		seen[child.ID] = true
+		if err := ots.transferFolder(child, transfer, &folder.ID); err != nil {
+			log.Printf("Alt klasör devredilemedi %s: %v", child.ID.Hex(), err)
+			failures = append(failures, fmt.Errorf("klasör %s: %v", child.Name, err))
+		}
+	}
+	for i := range childFiles {
+		child := &childFiles[i]
+		if seen[child.ID] || child.UserID != transfer.FromUserID {
+			continue
+		}
+		seen[child.ID] = true
+		if err := ots.transferFile(child, transfer, &folder.ID); err != nil {
+			log.Printf("Alt dosya devredilemedi %s: %v", child.ID.Hex(), err)
+			failures = append(failures, fmt.Errorf("dosya %s: %v", child.Filename, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d alt öğe devredilemedi, daveti tekrar kabul ederek kalanları devralabilirsiniz: %w",
+			len(failures), errors.Join(failures...))
+	}
+	return nil
+}
+
+func (ots *OwnershipTransferService) transferFile(file *models.File, transfer *models.OwnershipTransfer, inheritedFrom *primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	updates := bson.M{
+		"user_id":     transfer.ToUserID,
+		"access_list": ownershipAccessList(file.AccessList, transfer, inheritedFrom),
+		"updated_at":  time.Now(),
+	}
+	updateFile := func() error {
+		if _, err := database.FileCollection.UpdateOne(ctx, bson.M{"_id": file.ID}, bson.M{"$set": updates}); err != nil {
+			return fmt.Errorf("dosya sahipliği güncellenemedi: %v", err)
+		}
+		return nil
+	}
+
+	if !transfer.MoveObjects || MinioService == nil || file.MinioPath == "" {
+		return updateFile()
+	}
+
+	newPath := MinioService.GetUserFilePath(transfer.ToUserID, file.Filename)
+	if newPath == file.MinioPath {
+		return updateFile()
+	}
+	// Yeni sahipte aynı isimli obje varsa üzerine yazma
+	if MinioService.ObjectExists(newPath) {
+		newPath = MinioService.GetUserFilePath(transfer.ToUserID, file.ID.Hex()+"_"+file.Filename)
+	}
+	updates["minio_path"] = newPath
+
+	if err := relocateObject(MinioService, file.MinioPath, newPath, updateFile); err != nil {
+		return err
+	}
+
+	// Bekleyen/çalışan işler yeni path'ten indirsin
+	if _, err := database.JobCollection.UpdateMany(ctx,
+		bson.M{"file_id": file.ID.Hex(), "status": bson.M{"$in": []string{models.JobStatusQueued, models.JobStatusRunning}}},
+		bson.M{"$set": bson.M{"minio_path": newPath, "updated_at": time.Now()}},
+	); err != nil {
+		log.Printf("Dosya %s işlerinin MinIO path'i güncellenemedi: %v", file.ID.Hex(), err)
+	}
+	return nil
+}
+
+// objectCopier - Devirde kullanılan obje işlemleri (MinIOService; testlerde bellek içi depo)
+type objectCopier interface {
+	CopyObject(srcObject, dstObject string) error
+	DeleteFile(objectName string) error
+}
+
+// relocateObject - Objeyi yeni path'e kopyalar, kaydı commit ile günceller ve eski objeyi
+// ancak commit başarılı olursa siler. Commit başarısız olursa kopya silinir; kayıt hâlâ
+// var olan eski objeyi gösterir.
+func relocateObject(store objectCopier, srcObject, dstObject string, commit func() error) error {
+	if err := store.CopyObject(srcObject, dstObject); err != nil {
+		return err
+	}
+
+	if err := commit(); err != nil {
+		if cleanupErr := store.DeleteFile(dstObject); cleanupErr != nil {
+			log.Printf("Kopyalanan obje %s silinemedi: %v", dstObject, cleanupErr)
+		}
+		return err
+	}
+
+	// Kayıt yeni objeyi gösteriyor; eski objenin kalması veri kaybı değil, sadece çöp
+	if err := store.DeleteFile(srcObject); err != nil {
+		log.Printf("Eski obje %s silinemedi: %v", srcObject, err)
+	}
+	return nil
+}
+
+func (ots *OwnershipTransferService) transferFolder(folder *models.Folder, transfer *models.OwnershipTransfer, inheritedFrom *primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := database.FolderCollection.UpdateOne(ctx, bson.M{"_id": folder.ID}, bson.M{"$set": bson.M{
+		"user_id":     transfer.ToUserID,
+		"access_list": ownershipAccessList(folder.AccessList, transfer, inheritedFrom),
+		"updated_at":  time.Now(),
+	}})
+	if err != nil {
+		return fmt.Errorf("klasör sahipliği güncellenemedi: %v", err)
+	}
+	return nil
+}
+
+// ownershipAccessList - Yeni sahibin eski girişlerini kaldırır, eski sahibi editör olarak ekler
+func ownershipAccessList(accessList []models.AccessEntry, transfer *models.OwnershipTransfer, inheritedFrom *primitive.ObjectID) []models.AccessEntry {
+	result := make([]models.AccessEntry, 0, len(accessList)+1)
+	for _, entry := range accessList {
+		if entry.UserID == transfer.ToUserID || entry.UserID == transfer.FromUserID {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	return append(result, models.AccessEntry{
+		UserID:        transfer.FromUserID,
+		AccessType:    helpers.RoleEditor,
+		GrantedAt:     time.Now(),
+		GrantedBy:     transfer.ToUserID,
+		InheritedFrom: inheritedFrom,
+	})
+}
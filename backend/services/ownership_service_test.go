@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+// memoryObjects is an in-memory object store for relocateObject
+type memoryObjects map[string]string
+
+func (m memoryObjects) CopyObject(srcObject, dstObject string) error {
+	data, ok := m[srcObject]
+	if !ok {
+		return errors.New("no such object")
+	}
+	m[dstObject] = data
+	return nil
+}
+
+func (m memoryObjects) DeleteFile(objectName string) error {
+	delete(m, objectName)
+	return nil
+}
+
+func TestRelocateObjectKeepsSourceWhenUpdateFails(t *testing.T) {
+	store := memoryObjects{"alice/report.pdf": "content"}
+
+	updateErr := errors.New("write timed out")
+	err := relocateObject(store, "alice/report.pdf", "bob/report.pdf", func() error { return updateErr })
+	if !errors.Is(err, updateErr) {
+		t.Fatalf("expected the update error, got %v", err)
+	}
+	if store["alice/report.pdf"] != "content" {
+		t.Error("the original object must survive a failed update")
+	}
+	if _, ok := store["bob/report.pdf"]; ok {
+		t.Error("the copy must be removed after a failed update")
+	}
+}
+
+func TestRelocateObjectRemovesSourceAfterUpdate(t *testing.T) {
+	store := memoryObjects{"alice/report.pdf": "content"}
+
+	committed := false
+	err := relocateObject(store, "alice/report.pdf", "bob/report.pdf", func() error {
+		if _, ok := store["bob/report.pdf"]; !ok {
+			t.Error("the copy must exist before the record points at it")
+		}
+		committed = true
+		return nil
+	})
+	if err != nil || !committed {
+		t.Fatalf("unexpected result: err=%v committed=%v", err, committed)
+	}
+	if _, ok := store["alice/report.pdf"]; ok {
+		t.Error("the original object should be removed after the update")
+	}
+	if store["bob/report.pdf"] != "content" {
+		t.Error("the copy is missing")
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"nimbus-backend/database"
+	"nimbus-backend/models"
+)
+
+// progressPersistInterval throttles how often embedding progress is written to the job
+// document; stage changes are always written
+const progressPersistInterval = 2 * time.Second
+
+// stageWeights maps each stage to the share of the overall percentage it starts at.
+// Embedding dominates the runtime, so it covers most of the bar.
+var stageWeights = map[string]float64{
+	models.ProcessingStageQueued:      0,
+	models.ProcessingStageExtracting:  2,
+	models.ProcessingStageNormalizing: 6,
+	models.ProcessingStageChunking:    8,
+	models.ProcessingStageEmbedding:   10,
+	models.ProcessingStageIndexing:    95,
+	models.ProcessingStageCompleted:   100,
+}
+
+// ProgressTracker keeps the live progress of jobs running in this process and mirrors it
+// to the job document so other instances (and restarts) can report it too
+type ProgressTracker struct {
+	mu          sync.RWMutex
+	entries     map[string]*models.ProcessingProgress // by file ID
+	lastPersist map[string]time.Time
+}
+
+var ProgressTrackerInstance = NewProgressTracker()
+
+// NewProgressTracker creates an empty tracker
+func NewProgressTracker() *ProgressTracker {
+	return &ProgressTracker{
+		entries:     make(map[string]*models.ProcessingProgress),
+		lastPersist: make(map[string]time.Time),
+	}
+}
+
+// Get returns a copy of the live progress of a file processed by this instance
+func (t *ProgressTracker) Get(fileID string) (*models.ProcessingProgress, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	progress, ok := t.entries[fileID]
+	if !ok {
+		return nil, false
+	}
+	copied := *progress
+	return &copied, true
+}
+
+// Clear forgets a file once its job has finished
+func (t *ProgressTracker) Clear(fileID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, fileID)
+	delete(t.lastPersist, fileID)
+}
+
+// update applies fn to the file's progress and persists the result when due
+func (t *ProgressTracker) update(jobID primitive.ObjectID, fileID string, force bool, fn func(p *models.ProcessingProgress)) {
+	now := time.Now()
+
+	t.mu.Lock()
+	progress, ok := t.entries[fileID]
+	if !ok {
+		progress = &models.ProcessingProgress{StartedAt: now, StageStartedAt: now}
+		t.entries[fileID] = progress
+	}
+	fn(progress)
+	progress.UpdatedAt = now
+	snapshot := *progress
+
+	persist := force || now.Sub(t.lastPersist[fileID]) >= progressPersistInterval
+	if persist {
+		t.lastPersist[fileID] = now
+	}
+	t.mu.Unlock()
+
+	if persist && !jobID.IsZero() && database.JobCollection != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := database.JobCollection.UpdateOne(ctx, bson.M{"_id": jobID}, bson.M{"$set": bson.M{"progress": snapshot}}); err != nil {
+			log.Printf("Warning: failed to persist progress of job %s: %v", jobID.Hex(), err)
+		}
+	}
+}
+
+// progressReporter reports the stages of one processDocument run. A nil reporter is a no-op.
+type progressReporter struct {
+	tracker *ProgressTracker
+	jobID   primitive.ObjectID
+	fileID  string
+}
+
+func newProgressReporter(tracker *ProgressTracker, job *models.ProcessingJob) *progressReporter {
+	return &progressReporter{tracker: tracker, jobID: job.ID, fileID: job.FileID}
+}
+
+// Stage moves the file to a new pipeline stage
+func (r *progressReporter) Stage(stage string) {
+	if r == nil {
+		return
+	}
+	r.tracker.update(r.jobID, r.fileID, true, func(p *models.ProcessingProgress) {
+		p.Stage = stage
+		p.StageStartedAt = time.Now()
+		p.Percent = stageWeights[stage]
+		p.ETASeconds = nil
+		if stage != models.ProcessingStageEmbedding {
+			p.Current = 0
+		}
+	})
+}
+
+// Chunks records how many chunks will be embedded
+func (r *progressReporter) Chunks(total int) {
+	if r == nil {
+		return
+	}
+	r.tracker.update(r.jobID, r.fileID, true, func(p *models.ProcessingProgress) {
+		p.Total = total
+	})
+}
+
+// Failed records how many chunks could not be embedded
+func (r *progressReporter) Failed(count int) {
+	if r == nil {
+		return
+	}
+	r.tracker.update(r.jobID, r.fileID, true, func(p *models.ProcessingProgress) {
+		p.Failed = count
+	})
+}
+
+// Embedded records that current of total chunks have been embedded and estimates the
+// remaining time from the embedding rate so far
+func (r *progressReporter) Embedded(current, total int) {
+	if r == nil {
+		return
+	}
+	r.tracker.update(r.jobID, r.fileID, current == total, func(p *models.ProcessingProgress) {
+		p.Current = current
+		p.Total = total
+		if total <= 0 {
+			return
+		}
+
+		start := stageWeights[models.ProcessingStageEmbedding]
+		span := stageWeights[models.ProcessingStageIndexing] - start
+		p.Percent = math.Round((start+span*float64(current)/float64(total))*10) / 10
+
+		if current > 0 {
+			perChunk := time.Since(p.StageStartedAt) / time.Duration(current)
+			eta := int((perChunk * time.Duration(total-current)).Seconds())
+			p.ETASeconds = &eta
+		}
+	})
+}
+
+// ProcessingState combines the file's processing status with its latest job and the most
+// recent progress (live when this instance runs the job, otherwise the persisted copy)
+func ProcessingState(file *models.File) (*models.ProcessingState, error) {
+	fileID := file.ID.Hex()
+	state := &models.ProcessingState{
+		FileID:           fileID,
+		Status:           file.ProcessingStatus,
+		Error:            file.ProcessingError,
+		ChunkCount:       file.ChunkCount,
+		FailedChunkCount: file.FailedChunkCount,
+		Stale:            file.ContentStale,
+	}
+
+	if JobQueueInstance != nil {
+		job, err := JobQueueInstance.LatestForFile(fileID)
+		if err != nil {
+			return nil, err
+		}
+		if job != nil {
+			state.Progress = job.Progress
+			job.Progress = nil
+			state.Job = job
+		}
+	}
+
+	if live, ok := ProgressTrackerInstance.Get(fileID); ok {
+		state.Progress = live
+	}
+	if state.Progress == nil && state.Job != nil && state.Job.Status == models.JobStatusQueued {
+		state.Progress = &models.ProcessingProgress{Stage: models.ProcessingStageQueued}
+	}
+	return state, nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nimbus-backend/database"
+	"nimbus-backend/helpers"
+	"nimbus-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type QueryScopeService struct{}
+
+var QueryScopeServiceInstance = &QueryScopeService{}
+
+// ResolveFiles returns the processed files in the scope that the user can read, most
+// recently updated first and capped at limit (0 = no cap). total counts every readable
+// file, so callers can tell the user when the scope was cut.
+func (s *QueryScopeService) ResolveFiles(userID string, scope models.ConversationScope, limit int) (files []models.File, total int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"deleted_at":        nil,
+		"processing_status": bson.M{"$in": models.QueryableStatuses},
+	}
+
+	switch scope.Type {
+	case models.ScopeFile:
+		objectID, err := primitive.ObjectIDFromHex(scope.ID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid file ID: %w", err)
+		}
+		filter["_id"] = objectID
+	case models.ScopeFiles:
+		objectIDs := make([]primitive.ObjectID, 0, len(scope.FileIDs))
+		for _, id := range scope.FileIDs {
+			objectID, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid file ID %s: %w", id, err)
+			}
+			objectIDs = append(objectIDs, objectID)
+		}
+		filter["_id"] = bson.M{"$in": objectIDs}
+	case models.ScopeFolder:
+		folderID, err := primitive.ObjectIDFromHex(scope.ID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid folder ID: %w", err)
+		}
+		filter["$or"] = []bson.M{
+			{"folder_id": scope.ID},
+			{"ancestors": folderID},
+		}
+	case models.ScopeAll:
+		filter["$or"] = []bson.M{
+			{"user_id": userID},
+			helpers.ActiveAccessFilter(userID),
+		}
+	default:
+		return nil, 0, fmt.Errorf("unknown query scope: %s", scope.Type)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	cursor, err := database.FileCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list scope files: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []models.File
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode scope files: %w", err)
+	}
+
+	// Every file is checked individually so inherited, expired and revoked grants are honoured
+	files = make([]models.File, 0, len(candidates))
+	for _, file := range candidates {
+		hasAccess, err := helpers.CanUserAccess(userID, "file", file.ID.Hex(), helpers.AccessLevelRead)
+		if err != nil || !hasAccess {
+			continue
+		}
+		total++
+		if limit <= 0 || len(files) < limit {
+			files = append(files, file)
+		}
+	}
+
+	return files, total, nil
+}
+
+// Authorize checks that the user may open a conversation for the scope itself
+// (the folder for folder scope, every selected file for multi-file scope).
+func (s *QueryScopeService) Authorize(userID string, scope models.ConversationScope) (bool, error) {
+	switch scope.Type {
+	case models.ScopeFile:
+		return helpers.CanUserAccess(userID, "file", scope.ID, helpers.AccessLevelRead)
+	case models.ScopeFolder:
+		return helpers.CanUserAccess(userID, "folder", scope.ID, helpers.AccessLevelRead)
+	case models.ScopeFiles:
+		for _, fileID := range scope.FileIDs {
+			hasAccess, err := helpers.CanUserAccess(userID, "file", fileID, helpers.AccessLevelRead)
+			if err != nil || !hasAccess {
+				return false, err
+			}
+		}
+		return true, nil
+	case models.ScopeAll:
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown query scope: %s", scope.Type)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"nimbus-backend/retrieval"
+)
+
+// RerankerInstance is the reranker selected by RERANKER (see InitAIProviders)
+var RerankerInstance retrieval.Reranker
+
+// rerankTimeout bounds LLM reranking; on timeout the retrieval order is used
+const rerankTimeout = 60 * time.Second
+
+// RerankerName names the active reranker, "none" before InitAIProviders
+func RerankerName() string {
+	if RerankerInstance == nil {
+		return retrieval.RerankerNone
+	}
+	return RerankerInstance.Name()
+}
+
+// RerankChunks reorders retrieved chunks with RerankerInstance within rerankTimeout of ctx.
+// Reranking is an improvement, not a requirement: on failure or cancellation the chunks are
+// returned as retrieved.
+func RerankChunks(ctx context.Context, query retrieval.RerankQuery, chunks []ChunkResult) []ChunkResult {
+	if RerankerInstance == nil || len(chunks) < 2 {
+		return chunks
+	}
+
+	candidates := make([]retrieval.ChunkResult, len(chunks))
+	for i, chunk := range chunks {
+		candidates[i] = retrieval.ChunkResult{ID: chunk.ID, Text: chunk.Text, Metadata: chunk.Metadata, Distance: chunk.Distance, Score: chunk.Score}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rerankTimeout)
+	defer cancel()
+	started := time.Now()
+	reranked, err := RerankerInstance.Rerank(ctx, query, candidates)
+	if err != nil {
+		log.Printf("Warning: %s reranking failed, keeping retrieval order: %v", RerankerInstance.Name(), err)
+		return chunks
+	}
+
+	results := make([]ChunkResult, len(reranked))
+	for i, chunk := range reranked {
+		// Rerankers that score chunks (LLM) overwrite Score; others pass it through
+		results[i] = ChunkResult{ID: chunk.ID, Text: chunk.Text, Metadata: chunk.Metadata, Distance: chunk.Distance, Score: chunk.Score}
+	}
+	log.Printf("Reranked %d chunks with %s reranker in %v", len(chunks), RerankerInstance.Name(), time.Since(started))
+	return results
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"nimbus-backend/extract"
+	"nimbus-backend/models"
+	"nimbus-backend/tables"
+)
+
+// maxTableQueryFiles limits how many spreadsheets one structured query loads
+const maxTableQueryFiles = 5
+
+// maxCachedTableFiles bounds the parsed spreadsheet cache
+const maxCachedTableFiles = 20
+
+type cachedTables struct {
+	updatedAt time.Time
+	tables    []tables.Table
+}
+
+// TableQueryService answers aggregate questions over spreadsheets by letting the LLM write a
+// constrained filter/aggregate expression and evaluating it in Go
+type TableQueryService struct {
+	mu    sync.Mutex
+	cache map[string]cachedTables
+}
+
+var TableQueryServiceInstance = &TableQueryService{cache: make(map[string]cachedTables)}
+
+// Answer evaluates the question against the spreadsheets among files and returns the
+// result as a synthetic chunk for the RAG prompt. Returns nil when no spreadsheet is in
+// scope or the question is not an aggregate the expression language can express.
+func (s *TableQueryService) Answer(llmService *LLMService, question string, files []models.File) (*ChunkResult, error) {
+	var sheets []tables.Table
+	var sheetSources []models.File // File each sheet came from, by index
+	var sources []models.File
+	for _, file := range files {
+		if !extract.IsSpreadsheet(file.ContentType, file.Filename) {
+			continue
+		}
+		fileTables, err := s.LoadTables(file)
+		if err != nil {
+			log.Printf("Warning: failed to load tables of %s: %v", file.ID.Hex(), err)
+			continue
+		}
+
+		// Sheet names are qualified with the filename when several spreadsheets are queried.
+		// CSV and TSV tables have no sheet name, the filename alone names them.
+		for _, table := range fileTables {
+			if len(files) > 1 {
+				if table.Name == "" {
+					table.Name = file.Filename
+				} else {
+					table.Name = file.Filename + " / " + table.Name
+				}
+			}
+			sheets = append(sheets, table)
+			sheetSources = append(sheetSources, file)
+		}
+		sources = append(sources, file)
+		if len(sources) >= maxTableQueryFiles {
+			break
+		}
+	}
+	if len(sheets) == 0 {
+		return nil, nil
+	}
+
+	query, err := s.PlanQuery(llmService, question, sheets)
+	if err != nil || query == nil {
+		return nil, err
+	}
+
+	result, err := tables.Evaluate(sheets, *query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", query.String(), err)
+	}
+	log.Printf("Structured query %s matched %d rows", query.String(), result.Count)
+
+	// Attribute the result to the spreadsheet the evaluated sheet came from
+	source := sources[0]
+	for i, sheet := range sheets {
+		if sheet.Name == result.Table {
+			source = sheetSources[i]
+			break
+		}
+	}
+
+	return &ChunkResult{
+		ID:   source.ID.Hex() + "_structured",
+		Text: result.Describe(),
+		Metadata: map[string]interface{}{
+			"file_id":    source.ID.Hex(),
+			"filename":   source.Filename,
+			"chunk_type": "structured_query",
+			"expression": query.String(),
+			"sheet":      result.Table,
+		},
+		Score: 1, // Computed from the table itself, not retrieved
+	}, nil
+}
+
+// LoadTables downloads and parses a spreadsheet, reusing the parsed copy until the file changes
+func (s *TableQueryService) LoadTables(file models.File) ([]tables.Table, error) {
+	key := file.ID.Hex()
+
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && cached.updatedAt.Equal(file.UpdatedAt) {
+		return cached.tables, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	reader, err := MinioService.Client.GetObject(ctx, "user-files", file.MinioPath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file from MinIO: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	doc, err := extract.Default.Extract(data, file.ContentType, file.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= maxCachedTableFiles {
+		// Drop an arbitrary entry; spreadsheets are cheap to parse again
+		for k := range s.cache {
+			delete(s.cache, k)
+			break
+		}
+	}
+	s.cache[key] = cachedTables{updatedAt: file.UpdatedAt, tables: doc.Tables}
+	s.mu.Unlock()
+
+	return doc.Tables, nil
+}
+
+// PlanQuery asks the LLM to translate the question into a tables.Query. Returns nil when
+// the model answers that the question is not an aggregate.
+func (s *TableQueryService) PlanQuery(llmService *LLMService, question string, sheets []tables.Table) (*tables.Query, error) {
+	response, err := llmService.GenerateResponse(buildTableQueryPrompt(question, sheets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan table query: %w", err)
+	}
+
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("table query plan is not JSON: %q", response)
+	}
+
+	var query tables.Query
+	if err := json.Unmarshal([]byte(response[start:end+1]), &query); err != nil {
+		return nil, fmt.Errorf("failed to parse table query plan: %w", err)
+	}
+
+	query.Aggregate = strings.ToLower(strings.TrimSpace(query.Aggregate))
+	if query.Aggregate == "" || query.Aggregate == tables.AggregateNone {
+		return nil, nil
+	}
+	return &query, nil
+}
+
+// buildTableQueryPrompt describes the sheets (columns, types, a few sample rows) and the
+// expression format the model must answer with
+func buildTableQueryPrompt(question string, sheets []tables.Table) string {
+	var sb strings.Builder
+
+	sb.WriteString("You translate questions about spreadsheets into a JSON query. Do not answer the question.\n\n")
+	for _, table := range sheets {
+		name := table.Name
+		if name == "" {
+			name = "(default)"
+		}
+		fmt.Fprintf(&sb, "Sheet %q (%d rows)\nColumns:\n", name, len(table.Rows))
+		for i, header := range table.Headers {
+			fmt.Fprintf(&sb, "- %s [%s] %s\n", tables.ColumnLetter(i), table.Types[i], header)
+		}
+		for i, row := range table.Rows {
+			if i >= 3 {
+				break
+			}
+			fmt.Fprintf(&sb, "Sample row: %s\n", strings.Join(row, " | "))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(`Answer with ONLY a JSON object of this form:
+{"sheet": "<sheet name, omit if there is one sheet>",
+ "aggregate": "sum" | "avg" | "min" | "max" | "count",
+ "column": "<column name>",
+ "filters": [{"column": "<column name>", "op": "=" | "!=" | ">" | ">=" | "<" | "<=" | "contains", "value": <string or number>}],
+ "group_by": "<column name, optional>"}
+
+Rules:
+- Use column names exactly as listed; a letter such as "B" is also accepted.
+- sum, avg, min and max need a number column. count may omit "column".
+- If the question cannot be answered with one such aggregate, answer {"aggregate": "none"}.
+
+Question: `)
+	sb.WriteString(question)
+	sb.WriteString("\nJSON:")
+
+	return sb.String()
+}
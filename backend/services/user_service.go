@@ -4,6 +4,8 @@ import (
 	"context"
 	"nimbus-backend/database"
 	"nimbus-backend/models"
+	"regexp"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -82,6 +84,20 @@ func (us *UserService) GetUserByGoogleID(googleID string) (*models.User, error)
 	return &user, nil
 }
 
+// GetUserByEmail - Get user by email (case-insensitive)
+func (us *UserService) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := database.UserCollection.FindOne(context.Background(), bson.M{
+		"email": bson.M{"$regex": "^" + regexp.QuoteMeta(strings.TrimSpace(email)) + "$", "$options": "i"},
+	}).Decode(&user)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 // CreateUser - Create new user
 func (us *UserService) CreateUser(user *models.User) error {
 	_, err := database.UserCollection.InsertOne(context.Background(), user)
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nimbus-backend/cache"
+	"nimbus-backend/config"
+	"nimbus-backend/retrieval"
+	"nimbus-backend/vectorstore"
+)
+
+// VectorService stores and searches chunk vectors through the configured vector store,
+// adding the chunk cache and the in-memory file router on top
+type VectorService struct {
+	store      vectorstore.VectorStore
+	keywords   *retrieval.BM25Index
+	chunkCache cache.ChunkCache
+	fileRouter *retrieval.FileRouter
+	config     *config.Config
+}
+
+type ChunkData struct {
+	ID        string                 `json:"id"`
+	Embedding []float64              `json:"embedding"`
+	Text      string                 `json:"text"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+type ChunkResult struct {
+	ID       string                 `json:"id"`
+	Text     string                 `json:"document"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Distance float64                `json:"distance"`
+	// Score is the chunk's vector similarity (higher is better), or a reranker score once
+	// reranked. Keyword-only hits leave it at 0, BM25 is on another scale. Unlike Distance
+	// it is kept when result sets are fused, where Distance turns into the inverted RRF score.
+	Score float64 `json:"score"`
+}
+
+// VectorStoreInstance is the store selected by VECTOR_STORE (see InitVectorStore)
+var VectorStoreInstance vectorstore.VectorStore
+
+// KeywordIndexInstance is the BM25 index kept next to the vector store
+var KeywordIndexInstance *retrieval.BM25Index
+
+// InitVectorStore opens the configured vector store and the keyword index
+func InitVectorStore(cfg *config.Config) error {
+	store, err := vectorstore.New(cfg)
+	if err != nil {
+		return err
+	}
+	keywords, err := retrieval.OpenBM25Index(cfg.KeywordIndexPath)
+	if err != nil {
+		store.Close()
+		return err
+	}
+	VectorStoreInstance = store
+	KeywordIndexInstance = keywords
+	log.Printf("✅ Vector store initialized (%s), keyword index: %v", cfg.VectorStore, keywords.Stats()["indexed_files"])
+	return nil
+}
+
+// The chunk cache and file router are shared by every VectorService so that re-indexing
+// a file (see ApplyFileChunks) invalidates what request handlers read
+var (
+	sharedChunkCache cache.ChunkCache
+	sharedFileRouter *retrieval.FileRouter
+	sharedCachesOnce sync.Once
+)
+
+func NewVectorService(cfg *config.Config) *VectorService {
+	sharedCachesOnce.Do(func() {
+		// Initialize chunk cache if enabled
+		if cfg.EnableChunkCache {
+			sharedChunkCache = cache.NewLRUChunkCache(cfg.ChunkCacheSize)
+			log.Printf("Chunk cache enabled with size: %d", cfg.ChunkCacheSize)
+		}
+
+		// Initialize file router if enabled
+		if cfg.EnableFileRouting {
+			annConfig := retrieval.DefaultHNSWConfig()
+			annConfig.M = cfg.HNSWM
+			annConfig.EfConstruction = cfg.HNSWEfConstruction
+			annConfig.EfSearch = cfg.HNSWEfSearch
+			annConfig.Encoding = cfg.VectorEncoding
+			sharedFileRouter = retrieval.NewFileRouter(24*time.Hour, annConfig) // 24 hour TTL
+			log.Printf("File-level routing enabled (HNSW M=%d ef=%d, %s vectors)", annConfig.M, annConfig.EfSearch, annConfig.Encoding)
+		}
+	})
+	chunkCache := sharedChunkCache
+	fileRouter := sharedFileRouter
+
+	return &VectorService{
+		store:      VectorStoreInstance,
+		keywords:   KeywordIndexInstance,
+		chunkCache: chunkCache,
+		fileRouter: fileRouter,
+		config:     cfg,
+	}
+}
+
+// upsert writes chunks to the store and warms the chunk cache
+func (s *VectorService) upsert(chunks []ChunkData) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	records := make([]vectorstore.Record, len(chunks))
+	for i, chunk := range chunks {
+		records[i] = vectorstore.Record{
+			ID:        chunk.ID,
+			Embedding: chunk.Embedding,
+			Text:      chunk.Text,
+			Metadata:  chunk.Metadata,
+		}
+
+		// Warm chunk cache if enabled
+		if s.chunkCache != nil && s.config.EnableChunkCache {
+			s.chunkCache.Set(chunk.ID, chunk.Embedding)
+		}
+	}
+
+	return s.store.Upsert(context.Background(), records)
+}
+
+// StoredChunk is a chunk as currently stored in the vector store
+type StoredChunk struct {
+	ID        string
+	Embedding []float64
+	Metadata  map[string]interface{}
+}
+
+// GetFileChunks returns every stored chunk of a file with its embedding and metadata
+func (s *VectorService) GetFileChunks(fileID string) ([]StoredChunk, error) {
+	records, err := s.store.Get(context.Background(), vectorstore.Filter{"file_id": fileID}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make([]StoredChunk, len(records))
+	for i, record := range records {
+		stored[i] = StoredChunk{ID: record.ID, Embedding: record.Embedding, Metadata: record.Metadata}
+	}
+	return stored, nil
+}
+
+// DeleteChunks removes chunks by ID
+func (s *VectorService) DeleteChunks(ids []string) error {
+	return s.store.DeleteIDs(context.Background(), ids)
+}
+
+// ApplyFileChunks brings a file's stored chunks in line with a new chunk set: it upserts
+// the changed chunks, deletes removed IDs, evicts both from the chunk cache and query
+// cache, and re-syncs the file router with the complete set (all must carry embeddings).
+func (s *VectorService) ApplyFileChunks(fileID string, all, upserts []ChunkData, deleteIDs []string) error {
+	if err := s.upsert(upserts); err != nil {
+		return err
+	}
+	if err := s.DeleteChunks(deleteIDs); err != nil {
+		return err
+	}
+
+	changedIDs := append([]string{}, deleteIDs...)
+	for _, chunk := range upserts {
+		changedIDs = append(changedIDs, chunk.ID)
+	}
+
+	if s.chunkCache != nil {
+		for _, id := range deleteIDs {
+			s.chunkCache.Delete(id)
+		}
+	}
+	if len(changedIDs) > 0 {
+		if queryCache := SharedQueryCache(s.config); queryCache != nil {
+			if removed := queryCache.InvalidateChunks(changedIDs); removed > 0 {
+				log.Printf("Invalidated %d cached queries for file %s", removed, fileID)
+			}
+		}
+	}
+
+	if s.fileRouter != nil && s.config.EnableFileRouting && len(changedIDs) > 0 {
+		s.syncFileRouter(fileID, all)
+	}
+	if s.keywords != nil && len(changedIDs) > 0 {
+		s.syncKeywordIndex(fileID, all, upserts, deleteIDs)
+	}
+	return nil
+}
+
+// syncKeywordIndex applies the chunk changes to the BM25 index. A file the index has not
+// seen yet gets its complete chunk set. Failures only affect keyword ranking, so they are
+// logged rather than failing the processing job.
+func (s *VectorService) syncKeywordIndex(fileID string, all, upserts []ChunkData, deleteIDs []string) {
+	var err error
+	if s.keywords.HasFile(fileID) {
+		err = s.keywords.UpdateFile(fileID, keywordDocs(upserts), deleteIDs)
+	} else {
+		err = s.keywords.IndexFile(fileID, keywordDocs(all))
+	}
+	if err != nil {
+		log.Printf("Warning: failed to update keyword index for file %s: %v", fileID, err)
+	}
+}
+
+func keywordDocs(chunks []ChunkData) []retrieval.KeywordDoc {
+	docs := make([]retrieval.KeywordDoc, len(chunks))
+	for i, chunk := range chunks {
+		docs[i] = retrieval.KeywordDoc{ID: chunk.ID, Text: chunk.Text, Metadata: chunk.Metadata}
+	}
+	return docs
+}
+
+// syncFileRouter syncs chunk embeddings with the file router
+func (s *VectorService) syncFileRouter(fileID string, chunks []ChunkData) {
+	routerChunks := make([]retrieval.ChunkEmbedding, len(chunks))
+	for i, chunk := range chunks {
+		routerChunks[i] = retrieval.ChunkEmbedding{
+			ChunkID:   chunk.ID,
+			Embedding: chunk.Embedding,
+			Text:      chunk.Text,
+			Metadata:  chunk.Metadata,
+		}
+	}
+
+	s.fileRouter.SyncWithChroma(fileID, routerChunks)
+}
+
+// RoutesFile reports whether QuerySimilar answers queries for the file from the in-memory
+// file router instead of the vector store
+func (s *VectorService) RoutesFile(fileID string) bool {
+	return s.fileRouter != nil && s.config.EnableFileRouting && s.fileRouter.HasIndex(fileID)
+}
+
+func (s *VectorService) QuerySimilar(queryEmbedding []float64, fileID string, topK int) ([]ChunkResult, error) {
+	// Try file router first if enabled
+	if s.fileRouter != nil && s.config.EnableFileRouting {
+		if s.fileRouter.HasIndex(fileID) {
+			log.Printf("Using file router for fast in-memory search (file: %s)", fileID)
+
+			results := s.fileRouter.SearchInFile(fileID, queryEmbedding, topK)
+			routerResults := make([]ChunkResult, len(results))
+			for i, result := range results {
+				routerResults[i] = ChunkResult{
+					ID:       result.ChunkID,
+					Text:     result.Text,
+					Metadata: result.Metadata,
+					Distance: result.Distance,
+					Score:    similarityFromDistance(result.Distance),
+				}
+
+				// Record chunk access for popularity tracking
+				if s.chunkCache != nil {
+					s.chunkCache.RecordAccess(result.ChunkID)
+				}
+			}
+
+			return routerResults, nil
+		}
+	}
+
+	// Fall back to the vector store
+	return s.queryStore(queryEmbedding, fileID, topK)
+}
+
+// queryStore runs a similarity query against the vector store
+func (s *VectorService) queryStore(queryEmbedding []float64, fileID string, topK int) ([]ChunkResult, error) {
+	filter := vectorstore.Filter{"file_id": fileID}
+	// Only compare against vectors of the configured embedding model; files indexed with
+	// another one are being re-indexed (see ReindexOutdatedEmbeddings)
+	if embedModel := EmbedModelID(); embedModel != "" {
+		filter["embed_model"] = embedModel
+	}
+
+	matches, err := s.store.Query(context.Background(), queryEmbedding, filter, topK)
+	if err != nil {
+		return nil, err
+	}
+
+	results := matchesToResults(matches)
+	for i := range results {
+		results[i].Score = similarityFromDistance(results[i].Distance)
+	}
+	// Record chunk access for popularity tracking
+	if s.chunkCache != nil {
+		for _, result := range results {
+			s.chunkCache.RecordAccess(result.ID)
+		}
+	}
+	return results, nil
+}
+
+func (s *VectorService) DeleteDocumentChunks(fileID string) error {
+	if err := s.store.Delete(context.Background(), vectorstore.Filter{"file_id": fileID}); err != nil {
+		return err
+	}
+
+	// Remove from file router if enabled
+	if s.fileRouter != nil && s.config.EnableFileRouting {
+		s.fileRouter.RemoveFileIndex(fileID)
+	}
+	if s.keywords != nil {
+		if err := s.keywords.RemoveFile(fileID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// GetCacheStats returns chunk cache statistics if cache is enabled
+func (s *VectorService) GetCacheStats() *cache.CacheStats {
+	if s.chunkCache != nil {
+		stats := s.chunkCache.Stats()
+		return &stats
+	}
+	return nil
+}
+
+// GetRouterStats returns file router statistics if routing is enabled
+func (s *VectorService) GetRouterStats() map[string]interface{} {
+	if s.fileRouter != nil {
+		return s.fileRouter.GetStats()
+	}
+	return nil
+}
+
+// KeywordSearch ranks a file's chunks against the keywords with BM25. Files indexed before
+// the keyword index existed are added to it from the vector store on first use.
+func (s *VectorService) KeywordSearch(keywords []string, fileID string, topK int) ([]ChunkResult, error) {
+	if s.keywords == nil {
+		matches, err := s.store.KeywordSearch(context.Background(), keywords, vectorstore.Filter{"file_id": fileID}, topK)
+		if err != nil {
+			return nil, err
+		}
+		return matchesToResults(matches), nil
+	}
+
+	if !s.keywords.HasFile(fileID) {
+		if err := s.buildKeywordIndex(fileID); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []ChunkResult
+	for _, hit := range s.keywords.Search(fileID, strings.Join(keywords, " "), topK) {
+		results = append(results, ChunkResult{
+			ID:       hit.ID,
+			Text:     hit.Text,
+			Metadata: hit.Metadata,
+			Distance: 1 / (1 + hit.Score), // Lower is better, like vector distances
+		})
+	}
+	return results, nil
+}
+
+// buildKeywordIndex indexes a file's stored chunks. Files without chunks (not processed
+// yet) are left out, so they are indexed in full once processing stores them.
+func (s *VectorService) buildKeywordIndex(fileID string) error {
+	records, err := s.store.Get(context.Background(), vectorstore.Filter{"file_id": fileID}, false)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	docs := make([]retrieval.KeywordDoc, len(records))
+	for i, record := range records {
+		docs[i] = retrieval.KeywordDoc{ID: record.ID, Text: record.Text, Metadata: record.Metadata}
+	}
+	log.Printf("Keyword index: indexing %d stored chunks of file %s", len(docs), fileID)
+	return s.keywords.IndexFile(fileID, docs)
+}
+
+func matchesToResults(matches []vectorstore.Match) []ChunkResult {
+	var results []ChunkResult
+	for _, match := range matches {
+		results = append(results, ChunkResult{
+			ID:       match.ID,
+			Text:     match.Text,
+			Metadata: match.Metadata,
+			Distance: match.Distance,
+		})
+	}
+	return results
+}
+
+// HybridSearch performs both semantic and keyword search, then merges results
+func (s *VectorService) HybridSearch(queryEmbedding []float64, keywords []string, fileID string, topK int) ([]ChunkResult, error) {
+	// Perform semantic search
+	semanticResults, err := s.QuerySimilar(queryEmbedding, fileID, topK)
+	if err != nil {
+		return nil, fmt.Errorf("semantic search failed: %w", err)
+	}
+
+	// Perform keyword search
+	keywordResults, err := s.KeywordSearch(keywords, fileID, topK/2)
+	if err != nil {
+		log.Printf("Warning: keyword search failed: %v", err)
+		// Continue with just semantic results
+		return semanticResults, nil
+	}
+
+	// Merge results using Reciprocal Rank Fusion (RRF)
+	return s.ReciprocalRankFusion(semanticResults, keywordResults, topK)
+}
+
+// ReciprocalRankFusion merges two result sets using RRF algorithm
+// score = 1 / (k + rank)
+func (s *VectorService) ReciprocalRankFusion(semantic, keyword []ChunkResult, topK int) ([]ChunkResult, error) {
+	return s.ReciprocalRankFusionMulti([][]ChunkResult{semantic, keyword}, topK)
+}
+
+// ReciprocalRankFusionMulti merges any number of ranked result sets (e.g. one per file) with RRF.
+// Earlier sets win ties on which copy of a duplicated chunk is kept; the kept copy carries the
+// best Score any set gave the chunk.
+func (s *VectorService) ReciprocalRankFusionMulti(resultSets [][]ChunkResult, topK int) ([]ChunkResult, error) {
+	const k = 60.0 // Standard RRF constant
+	scores := make(map[string]float64)
+	chunkMap := make(map[string]ChunkResult)
+
+	for _, results := range resultSets {
+		for rank, result := range results {
+			scores[result.ID] += 1.0 / (k + float64(rank+1))
+			existing, exists := chunkMap[result.ID]
+			if !exists {
+				chunkMap[result.ID] = result
+			} else if result.Score > existing.Score {
+				existing.Score = result.Score
+				chunkMap[result.ID] = existing
+			}
+		}
+	}
+
+	merged := make([]ChunkResult, 0, len(scores))
+	for id, score := range scores {
+		result := chunkMap[id]
+		// Callers expect lower distances to be better, so the RRF score is stored inverted
+		result.Distance = 1.0 / score
+		merged = append(merged, result)
+	}
+
+	sortResultsByDistance(merged)
+
+	// Limit
+	if len(merged) > topK {
+		merged = merged[:topK]
+	}
+
+	return merged, nil
+}
+
+// sortResultsByDistance sorts chunk results by distance (ascending). Equal distances are
+// common after RRF (the same rank in different files), so they fall back to the higher
+// Score and then the ID, keeping the order independent of how the result sets were built.
+func sortResultsByDistance(results []ChunkResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+}
+
+// similarityFromDistance maps a distance (lower is better) to a score in (0, 1]
+func similarityFromDistance(distance float64) float64 {
+	return 1.0 / (1.0 + distance)
+}
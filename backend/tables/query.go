@@ -0,0 +1,395 @@
+package tables
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported aggregates; AggregateNone means the question is not an aggregate over rows
+const (
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateCount = "count"
+	AggregateNone  = "none"
+)
+
+// Filter keeps rows whose column compares to value with op (=, !=, >, >=, <, <=, contains)
+type Filter struct {
+	Column string      `json:"column"`
+	Op     string      `json:"op"`
+	Value  interface{} `json:"value"`
+}
+
+// Query is the constrained expression the LLM produces for aggregate questions.
+// It is evaluated in Go so totals and extremes are exact instead of guessed from text.
+type Query struct {
+	Sheet     string   `json:"sheet,omitempty"`
+	Aggregate string   `json:"aggregate"`
+	Column    string   `json:"column,omitempty"` // Not needed for count
+	Filters   []Filter `json:"filters,omitempty"`
+	GroupBy   string   `json:"group_by,omitempty"`
+}
+
+// Group is one bucket of a grouped aggregate
+type Group struct {
+	Key   string
+	Value float64
+	Count int
+}
+
+// Result is the outcome of evaluating a Query
+type Result struct {
+	Query  Query
+	Table  string
+	Value  float64
+	Count  int      // Rows that passed the filters and had a usable value
+	Row    []string // For min/max: the row holding the extreme value
+	Header []string
+	Groups []Group // Set when GroupBy is used, largest value first
+}
+
+// maxGroups caps how many groups a result reports
+const maxGroups = 50
+
+// Evaluate runs the query over the matching table
+func Evaluate(tables []Table, q Query) (*Result, error) {
+	q.Aggregate = strings.ToLower(strings.TrimSpace(q.Aggregate))
+	switch q.Aggregate {
+	case AggregateSum, AggregateAvg, AggregateMin, AggregateMax, AggregateCount:
+	default:
+		return nil, fmt.Errorf("unsupported aggregate: %q", q.Aggregate)
+	}
+
+	table, err := selectTable(tables, q)
+	if err != nil {
+		return nil, err
+	}
+
+	valueColumn := -1
+	if q.Aggregate != AggregateCount || q.Column != "" {
+		if valueColumn = table.ColumnIndex(q.Column); valueColumn < 0 {
+			return nil, fmt.Errorf("unknown column: %q", q.Column)
+		}
+		if q.Aggregate != AggregateCount && table.Types[valueColumn] != ColumnNumber {
+			return nil, fmt.Errorf("column %q is not numeric", table.Headers[valueColumn])
+		}
+	}
+
+	groupColumn := -1
+	if q.GroupBy != "" {
+		if groupColumn = table.ColumnIndex(q.GroupBy); groupColumn < 0 {
+			return nil, fmt.Errorf("unknown group_by column: %q", q.GroupBy)
+		}
+	}
+
+	filters := make([]compiledFilter, 0, len(q.Filters))
+	for _, f := range q.Filters {
+		compiled, err := compileFilter(table, f)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, compiled)
+	}
+
+	total := newAccumulator()
+	groups := make(map[string]*accumulator)
+	var groupOrder []string
+
+	for _, row := range table.Rows {
+		if !matchesAll(row, filters) {
+			continue
+		}
+
+		value := 0.0
+		if valueColumn >= 0 {
+			if q.Aggregate == AggregateCount {
+				if row[valueColumn] == "" {
+					continue
+				}
+			} else {
+				n, ok := ParseNumber(row[valueColumn])
+				if !ok {
+					continue
+				}
+				value = n
+			}
+		}
+
+		total.add(value, row)
+		if groupColumn >= 0 {
+			key := row[groupColumn]
+			acc, ok := groups[key]
+			if !ok {
+				acc = newAccumulator()
+				groups[key] = acc
+				groupOrder = append(groupOrder, key)
+			}
+			acc.add(value, row)
+		}
+	}
+
+	result := &Result{
+		Query:  q,
+		Table:  table.Name,
+		Value:  total.result(q.Aggregate),
+		Count:  total.count,
+		Header: table.Headers,
+	}
+	if q.Aggregate == AggregateMin {
+		result.Row = total.minRow
+	} else if q.Aggregate == AggregateMax {
+		result.Row = total.maxRow
+	}
+
+	for _, key := range groupOrder {
+		acc := groups[key]
+		result.Groups = append(result.Groups, Group{Key: key, Value: acc.result(q.Aggregate), Count: acc.count})
+	}
+	sort.SliceStable(result.Groups, func(i, j int) bool { return result.Groups[i].Value > result.Groups[j].Value })
+	if len(result.Groups) > maxGroups {
+		result.Groups = result.Groups[:maxGroups]
+	}
+
+	return result, nil
+}
+
+// selectTable picks the sheet named in the query, or the first sheet with the referenced column
+func selectTable(tables []Table, q Query) (Table, error) {
+	if len(tables) == 0 {
+		return Table{}, fmt.Errorf("no tables to query")
+	}
+	if q.Sheet != "" {
+		for _, t := range tables {
+			if strings.EqualFold(t.Name, strings.TrimSpace(q.Sheet)) {
+				return t, nil
+			}
+		}
+		return Table{}, fmt.Errorf("unknown sheet: %q", q.Sheet)
+	}
+	if q.Column != "" {
+		for _, t := range tables {
+			if t.ColumnIndex(q.Column) >= 0 {
+				return t, nil
+			}
+		}
+	}
+	return tables[0], nil
+}
+
+type accumulator struct {
+	count  int
+	sum    float64
+	min    float64
+	max    float64
+	minRow []string
+	maxRow []string
+}
+
+func newAccumulator() *accumulator {
+	return &accumulator{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (a *accumulator) add(value float64, row []string) {
+	a.count++
+	a.sum += value
+	if value < a.min {
+		a.min, a.minRow = value, row
+	}
+	if value > a.max {
+		a.max, a.maxRow = value, row
+	}
+}
+
+func (a *accumulator) result(aggregate string) float64 {
+	switch aggregate {
+	case AggregateSum:
+		return a.sum
+	case AggregateAvg:
+		if a.count == 0 {
+			return 0
+		}
+		return a.sum / float64(a.count)
+	case AggregateMin:
+		if a.count == 0 {
+			return 0
+		}
+		return a.min
+	case AggregateMax:
+		if a.count == 0 {
+			return 0
+		}
+		return a.max
+	}
+	return float64(a.count)
+}
+
+type compiledFilter struct {
+	column int
+	op     string
+	kind   ColumnType
+	text   string
+	number float64
+}
+
+func compileFilter(table Table, f Filter) (compiledFilter, error) {
+	column := table.ColumnIndex(f.Column)
+	if column < 0 {
+		return compiledFilter{}, fmt.Errorf("unknown filter column: %q", f.Column)
+	}
+
+	op := strings.ToLower(strings.TrimSpace(f.Op))
+	switch op {
+	case "==":
+		op = "="
+	case "<>":
+		op = "!="
+	case "=", "!=", ">", ">=", "<", "<=", "contains":
+	default:
+		return compiledFilter{}, fmt.Errorf("unsupported filter operator: %q", f.Op)
+	}
+
+	compiled := compiledFilter{column: column, op: op, kind: table.Types[column], text: fmt.Sprint(f.Value)}
+	if v, ok := f.Value.(float64); ok {
+		compiled.text = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	if compiled.kind == ColumnNumber && op != "contains" {
+		n, ok := ParseNumber(compiled.text)
+		if !ok {
+			return compiledFilter{}, fmt.Errorf("filter value %q is not a number", compiled.text)
+		}
+		compiled.number = n
+	}
+	return compiled, nil
+}
+
+func matchesAll(row []string, filters []compiledFilter) bool {
+	for _, f := range filters {
+		if !f.matches(row[f.column]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f compiledFilter) matches(cell string) bool {
+	if f.op == "contains" {
+		return strings.Contains(strings.ToLower(cell), strings.ToLower(f.text))
+	}
+
+	var cmp int
+	switch f.kind {
+	case ColumnNumber:
+		n, ok := ParseNumber(cell)
+		if !ok {
+			return false
+		}
+		cmp = compareFloat(n, f.number)
+	case ColumnDate:
+		cellDate, ok1 := ParseDate(cell)
+		filterDate, ok2 := ParseDate(f.text)
+		if !ok1 || !ok2 {
+			return false
+		}
+		cmp = cellDate.Compare(filterDate)
+	default:
+		cmp = strings.Compare(strings.ToLower(cell), strings.ToLower(f.text))
+	}
+
+	switch f.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// String renders the query in SQL-like form for logs and source labels
+func (q Query) String() string {
+	column := q.Column
+	if column == "" {
+		column = "*"
+	}
+	s := fmt.Sprintf("%s(%s)", strings.ToUpper(q.Aggregate), column)
+	if len(q.Filters) > 0 {
+		conditions := make([]string, len(q.Filters))
+		for i, f := range q.Filters {
+			conditions[i] = fmt.Sprintf("%s %s %v", f.Column, f.Op, f.Value)
+		}
+		s += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if q.GroupBy != "" {
+		s += " GROUP BY " + q.GroupBy
+	}
+	return s
+}
+
+// Describe renders the result as prompt context for the answer generator
+func (r *Result) Describe() string {
+	var sb strings.Builder
+	location := "the spreadsheet"
+	if r.Table != "" {
+		location = "sheet " + r.Table
+	}
+	fmt.Fprintf(&sb, "Exact result computed over all rows of %s: %s", location, r.Query.String())
+
+	if len(r.Groups) > 0 {
+		sb.WriteString("\n")
+		for _, g := range r.Groups {
+			fmt.Fprintf(&sb, "- %s: %s (%d rows)\n", g.Key, formatNumber(g.Value), g.Count)
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	}
+
+	fmt.Fprintf(&sb, " = %s (%d matching rows)", formatNumber(r.Value), r.Count)
+	if len(r.Row) > 0 {
+		cells := make([]string, 0, len(r.Row))
+		for i, value := range r.Row {
+			if value != "" && i < len(r.Header) {
+				cells = append(cells, r.Header[i]+": "+value)
+			}
+		}
+		sb.WriteString("\nRow: " + strings.Join(cells, " | "))
+	}
+	return sb.String()
+}
+
+func formatNumber(n float64) string {
+	if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'f', 2, 64)
+}
+
+// aggregateQuestionPattern spots questions worth sending to the structured path (EN/TR)
+var aggregateQuestionPattern = regexp.MustCompile(`(?i)\b(total|sum|average|mean|avg|max(imum)?|min(imum)?|highest|lowest|largest|smallest|how many|count|number of)\b|toplam|ortalama|en (yüksek|düşük|fazla|az|büyük|küçük)|kaç|sayısı|maksimum|minimum`)
+
+// LooksAggregate reports whether a question probably asks for a computation over rows
+func LooksAggregate(question string) bool {
+	return aggregateQuestionPattern.MatchString(question)
+}
@@ -0,0 +1,98 @@
+package tables
+
+import (
+	"strings"
+	"testing"
+)
+
+func salesTable() Table {
+	return FromRows("Sales", [][]string{
+		{"Quarter", "Region", "Revenue", ""},
+		{"Q3", "EU", "1.500,50", "x"},
+		{},
+		{"Q3", "US", "$2,000", ""},
+		{"Q2", "EU", "700", ""},
+		{"Q3", "EU", "(100)", ""},
+	})
+}
+
+func TestFromRowsInfersTypes(t *testing.T) {
+	table := salesTable()
+	if len(table.Rows) != 4 {
+		t.Fatalf("expected empty row to be dropped, got %d rows", len(table.Rows))
+	}
+	if table.Types[0] != ColumnText || table.Types[2] != ColumnNumber {
+		t.Errorf("unexpected types %v", table.Types)
+	}
+	if table.Headers[3] != "Column D" || table.ColumnIndex("column c") != 2 {
+		t.Errorf("column references not resolved: %v", table.Headers)
+	}
+}
+
+func TestEvaluateFilteredSum(t *testing.T) {
+	result, err := Evaluate([]Table{salesTable()}, Query{
+		Aggregate: "sum",
+		Column:    "revenue",
+		Filters:   []Filter{{Column: "Quarter", Op: "=", Value: "q3"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Value != 3400.5 || result.Count != 3 {
+		t.Errorf("got %v over %d rows", result.Value, result.Count)
+	}
+}
+
+func TestEvaluateMaxReturnsRow(t *testing.T) {
+	result, err := Evaluate([]Table{salesTable()}, Query{Aggregate: "max", Column: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Value != 2000 || result.Row[1] != "US" {
+		t.Errorf("got %v in row %v", result.Value, result.Row)
+	}
+	if !strings.Contains(result.Describe(), "Region: US") {
+		t.Errorf("description should include the row: %s", result.Describe())
+	}
+}
+
+func TestEvaluateGroupBy(t *testing.T) {
+	result, err := Evaluate([]Table{salesTable()}, Query{
+		Aggregate: "sum",
+		Column:    "Revenue",
+		Filters:   []Filter{{Column: "Revenue", Op: ">", Value: 0.0}},
+		GroupBy:   "Region",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Groups) != 2 || result.Groups[0].Key != "EU" || result.Groups[0].Value != 2200.5 {
+		t.Errorf("unexpected groups %+v", result.Groups)
+	}
+}
+
+func TestEvaluateRejectsInvalidQueries(t *testing.T) {
+	tables := []Table{salesTable()}
+	for _, q := range []Query{
+		{Aggregate: "median", Column: "Revenue"},
+		{Aggregate: "sum", Column: "Region"},
+		{Aggregate: "sum", Column: "Profit"},
+		{Aggregate: "count", Filters: []Filter{{Column: "Revenue", Op: "~", Value: 1}}},
+	} {
+		if _, err := Evaluate(tables, q); err == nil {
+			t.Errorf("expected error for %s", q.String())
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	cases := map[string]float64{"1,234.5": 1234.5, "1.234.567": 1234567, "3,5": 3.5, "12%": 12, "₺ 99": 99, "(7)": -7}
+	for input, want := range cases {
+		if got, ok := ParseNumber(input); !ok || got != want {
+			t.Errorf("ParseNumber(%q) = %v, %v; want %v", input, got, ok, want)
+		}
+	}
+	if _, ok := ParseNumber("Q3"); ok {
+		t.Error("Q3 is not a number")
+	}
+}
@@ -0,0 +1,222 @@
+package tables
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType is the inferred type of a spreadsheet column
+type ColumnType string
+
+const (
+	ColumnText   ColumnType = "text"
+	ColumnNumber ColumnType = "number"
+	ColumnBool   ColumnType = "bool"
+	ColumnDate   ColumnType = "date"
+)
+
+// Table is a sheet (or CSV file) with a header row and typed columns
+type Table struct {
+	Name    string       // Sheet name; empty for CSV files
+	Headers []string     // Column names; empty header cells become "Column B" etc.
+	Types   []ColumnType // Inferred type of each column
+	Rows    [][]string   // Data rows (header excluded), padded to len(Headers)
+}
+
+// FromRows builds a typed table from raw cell values. The first non-empty row is the header;
+// empty rows are dropped.
+func FromRows(name string, rows [][]string) Table {
+	table := Table{Name: name}
+
+	width := 0
+	var nonEmpty [][]string
+	for _, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		nonEmpty = append(nonEmpty, row)
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return table
+	}
+
+	table.Headers = make([]string, width)
+	for i := 0; i < width; i++ {
+		header := ""
+		if i < len(nonEmpty[0]) {
+			header = strings.TrimSpace(nonEmpty[0][i])
+		}
+		if header == "" {
+			header = "Column " + ColumnLetter(i)
+		}
+		table.Headers[i] = header
+	}
+
+	for _, row := range nonEmpty[1:] {
+		padded := make([]string, width)
+		for i := 0; i < width && i < len(row); i++ {
+			padded[i] = strings.TrimSpace(row[i])
+		}
+		table.Rows = append(table.Rows, padded)
+	}
+
+	table.Types = make([]ColumnType, width)
+	for i := range table.Types {
+		table.Types[i] = inferColumnType(table.Rows, i)
+	}
+
+	return table
+}
+
+// ColumnIndex resolves a column reference: a header name (case-insensitive) or a
+// spreadsheet letter such as "B" or "column B". Returns -1 when nothing matches.
+func (t Table) ColumnIndex(ref string) int {
+	ref = strings.TrimSpace(ref)
+	for i, header := range t.Headers {
+		if strings.EqualFold(header, ref) {
+			return i
+		}
+	}
+
+	if m := columnLetterPattern.FindStringSubmatch(ref); m != nil {
+		if index := letterIndex(strings.ToUpper(m[1])); index < len(t.Headers) {
+			return index
+		}
+	}
+	return -1
+}
+
+var columnLetterPattern = regexp.MustCompile(`(?i)^(?:column\s+|col\s+|sütun\s+)?([a-z]{1,3})$`)
+
+// ColumnLetter converts 0 -> "A", 25 -> "Z", 26 -> "AA"
+func ColumnLetter(index int) string {
+	letters := ""
+	for index >= 0 {
+		letters = string(rune('A'+index%26)) + letters
+		index = index/26 - 1
+	}
+	return letters
+}
+
+func letterIndex(letters string) int {
+	index := 0
+	for _, r := range letters {
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// inferColumnType picks the narrowest type every non-empty cell of the column fits
+func inferColumnType(rows [][]string, column int) ColumnType {
+	numbers, bools, dates, values := 0, 0, 0, 0
+	for _, row := range rows {
+		value := row[column]
+		if value == "" {
+			continue
+		}
+		values++
+		if _, ok := ParseNumber(value); ok {
+			numbers++
+		}
+		if _, ok := parseBool(value); ok {
+			bools++
+		}
+		if _, ok := ParseDate(value); ok {
+			dates++
+		}
+	}
+
+	switch {
+	case values == 0:
+		return ColumnText
+	case numbers == values:
+		return ColumnNumber
+	case bools == values:
+		return ColumnBool
+	case dates == values:
+		return ColumnDate
+	}
+	return ColumnText
+}
+
+var (
+	groupedCommaPattern = regexp.MustCompile(`^-?\d{1,3}(,\d{3})+(\.\d+)?$`)
+	groupedDotPattern   = regexp.MustCompile(`^-?\d{1,3}(\.\d{3}){2,}(,\d+)?$|^-?\d{1,3}(\.\d{3})+,\d+$`)
+)
+
+// ParseNumber reads numbers as they appear in spreadsheets and exports: currency symbols,
+// percent signs, "(12)" negatives and both 1,234.56 and 1.234,56 grouping
+func ParseNumber(value string) (float64, bool) {
+	s := strings.TrimSpace(value)
+	for _, symbol := range []string{"₺", "TL", "$", "€", "£", "%", " ", " "} {
+		s = strings.ReplaceAll(s, symbol, "")
+	}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = "-" + s[1:len(s)-1]
+	}
+	if s == "" || s == "-" {
+		return 0, false
+	}
+
+	switch {
+	case groupedCommaPattern.MatchString(s):
+		s = strings.ReplaceAll(s, ",", "")
+	case groupedDotPattern.MatchString(s):
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	case strings.Count(s, ",") == 1 && !strings.Contains(s, "."):
+		s = strings.Replace(s, ",", ".", 1) // Decimal comma: "3,5"
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02.01.2006",
+	"2.1.2006",
+	"02/01/2006",
+	"01/02/2006",
+	"2006/01/02",
+}
+
+// ParseDate accepts ISO dates and the common day-first formats (02.01.2006, 02/01/2006)
+func ParseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "evet", "doğru":
+		return true, true
+	case "false", "no", "hayır", "yanlış":
+		return false, true
+	}
+	return false, false
+}
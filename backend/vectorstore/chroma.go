@@ -0,0 +1,303 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Chroma stores vectors in a Chroma collection over its v2 REST API
+type Chroma struct {
+	baseURL        string
+	tenant         string
+	database       string
+	collectionName string
+	httpClient     *http.Client
+}
+
+type chromaCollection struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type chromaUpsertRequest struct {
+	IDs        []string                 `json:"ids"`
+	Embeddings [][]float64              `json:"embeddings"`
+	Documents  []string                 `json:"documents"`
+	Metadatas  []map[string]interface{} `json:"metadatas"`
+}
+
+type chromaQueryRequest struct {
+	QueryEmbeddings [][]float64 `json:"query_embeddings"`
+	NResults        int         `json:"n_results"`
+	Where           interface{} `json:"where,omitempty"`
+}
+
+type chromaQueryResponse struct {
+	IDs       [][]string                 `json:"ids"`
+	Documents [][]string                 `json:"documents"`
+	Metadatas [][]map[string]interface{} `json:"metadatas"`
+	Distances [][]float64                `json:"distances"`
+}
+
+// chromaGetResponse keeps the raw lists: depending on the version Chroma returns them
+// flat or nested one level deep
+type chromaGetResponse struct {
+	IDs        json.RawMessage `json:"ids"`
+	Documents  json.RawMessage `json:"documents"`
+	Metadatas  json.RawMessage `json:"metadatas"`
+	Embeddings json.RawMessage `json:"embeddings"`
+}
+
+// NewChroma creates a store backed by the named collection, which is created on first use
+func NewChroma(baseURL, tenant, database, collection string) *Chroma {
+	return &Chroma{
+		baseURL:        baseURL,
+		tenant:         tenant,
+		database:       database,
+		collectionName: collection,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// EnsureCollection gets or creates the collection and returns its UUID. It is checked on
+// every call, so a collection deleted behind our back is recreated.
+func (c *Chroma) EnsureCollection(ctx context.Context) (string, error) {
+	var collection chromaCollection
+	status, err := c.do(ctx, "GET", fmt.Sprintf("/api/v2/tenants/%s/databases/%s/collections/%s",
+		c.tenant, c.database, c.collectionName), nil, &collection, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return "", fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	// If collection doesn't exist (404), create it
+	if status == http.StatusNotFound {
+		if _, err := c.do(ctx, "POST", fmt.Sprintf("/api/v2/tenants/%s/databases/%s/collections", c.tenant, c.database),
+			map[string]interface{}{"name": c.collectionName}, &collection, http.StatusOK, http.StatusCreated); err != nil {
+			return "", fmt.Errorf("failed to create collection: %w", err)
+		}
+	}
+
+	return collection.ID, nil
+}
+
+// Upsert implements VectorStore
+func (c *Chroma) Upsert(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	reqBody := chromaUpsertRequest{
+		IDs:        make([]string, len(records)),
+		Embeddings: make([][]float64, len(records)),
+		Documents:  make([]string, len(records)),
+		Metadatas:  make([]map[string]interface{}, len(records)),
+	}
+	for i, record := range records {
+		reqBody.IDs[i] = record.ID
+		reqBody.Embeddings[i] = record.Embedding
+		reqBody.Documents[i] = record.Text
+		reqBody.Metadatas[i] = record.Metadata
+	}
+
+	if err := c.collectionCall(ctx, "upsert", reqBody, nil, http.StatusOK, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to add documents to chroma: %w", err)
+	}
+	return nil
+}
+
+// Query implements VectorStore
+func (c *Chroma) Query(ctx context.Context, embedding []float64, filter Filter, topK int) ([]Match, error) {
+	reqBody := chromaQueryRequest{
+		QueryEmbeddings: [][]float64{embedding},
+		NResults:        topK,
+		Where:           chromaWhere(filter),
+	}
+
+	var queryResp chromaQueryResponse
+	if err := c.collectionCall(ctx, "query", reqBody, &queryResp, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to query chroma: %w", err)
+	}
+
+	var matches []Match
+	if len(queryResp.Documents) > 0 {
+		for i := range queryResp.Documents[0] {
+			matches = append(matches, Match{
+				Record: Record{
+					ID:       queryResp.IDs[0][i],
+					Text:     queryResp.Documents[0][i],
+					Metadata: queryResp.Metadatas[0][i],
+				},
+				Distance: queryResp.Distances[0][i],
+			})
+		}
+	}
+	return matches, nil
+}
+
+// Get implements VectorStore
+func (c *Chroma) Get(ctx context.Context, filter Filter, withEmbeddings bool) ([]Record, error) {
+	include := []string{"documents", "metadatas"}
+	if withEmbeddings {
+		include = append(include, "embeddings")
+	}
+	reqBody := map[string]interface{}{"include": include}
+	if where := chromaWhere(filter); where != nil {
+		reqBody["where"] = where
+	}
+
+	var getResp chromaGetResponse
+	if err := c.collectionCall(ctx, "get", reqBody, &getResp, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to get chunks from chroma: %w", err)
+	}
+
+	ids := decodeList[string](getResp.IDs)
+	documents := decodeList[string](getResp.Documents)
+	metadatas := decodeList[map[string]interface{}](getResp.Metadatas)
+	embeddings := decodeList[[]float64](getResp.Embeddings)
+
+	records := make([]Record, len(ids))
+	for i, id := range ids {
+		records[i].ID = id
+		if i < len(documents) {
+			records[i].Text = documents[i]
+		}
+		if i < len(metadatas) {
+			records[i].Metadata = metadatas[i]
+		}
+		if i < len(embeddings) {
+			records[i].Embedding = embeddings[i]
+		}
+	}
+	return records, nil
+}
+
+// Delete implements VectorStore
+func (c *Chroma) Delete(ctx context.Context, filter Filter) error {
+	if len(filter) == 0 {
+		return fmt.Errorf("refusing to delete the whole collection")
+	}
+	if err := c.collectionCall(ctx, "delete", map[string]interface{}{"where": chromaWhere(filter)}, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to delete chunks from chroma: %w", err)
+	}
+	return nil
+}
+
+// DeleteIDs implements VectorStore
+func (c *Chroma) DeleteIDs(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := c.collectionCall(ctx, "delete", map[string]interface{}{"ids": ids}, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to delete chunks from chroma: %w", err)
+	}
+	return nil
+}
+
+// KeywordSearch implements VectorStore. Chroma has no keyword ranking, so the matching
+// records are fetched (without embeddings) and scored here.
+func (c *Chroma) KeywordSearch(ctx context.Context, keywords []string, filter Filter, topK int) ([]Match, error) {
+	records, err := c.Get(ctx, filter, false)
+	if err != nil {
+		return nil, err
+	}
+	return ScoreKeywords(records, keywords, topK), nil
+}
+
+// Close implements VectorStore
+func (c *Chroma) Close() error {
+	return nil
+}
+
+// collectionCall ensures the collection and calls one of its endpoints
+func (c *Chroma) collectionCall(ctx context.Context, endpoint string, payload, out interface{}, okStatus ...int) error {
+	collectionID, err := c.EnsureCollection(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ensure collection: %w", err)
+	}
+	_, err = c.do(ctx, "POST", fmt.Sprintf("/api/v2/tenants/%s/databases/%s/collections/%s/%s",
+		c.tenant, c.database, collectionID, endpoint), payload, out, okStatus...)
+	return err
+}
+
+// do sends a request and decodes the response into out (when set) for any of okStatus.
+// The returned status lets callers branch on expected non-200 answers such as 404.
+func (c *Chroma) do(ctx context.Context, method, path string, payload, out interface{}, okStatus ...int) (int, error) {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	for _, status := range okStatus {
+		if resp.StatusCode != status {
+			continue
+		}
+		if out != nil && status != http.StatusNotFound {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return status, fmt.Errorf("failed to decode response: %w", err)
+			}
+		}
+		return status, nil
+	}
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, fmt.Errorf("chroma api returned status %d: %s", resp.StatusCode, string(bodyBytes))
+}
+
+// chromaWhere converts a filter to a Chroma where clause; several keys need an explicit $and
+func chromaWhere(filter Filter) interface{} {
+	keys := filter.Keys()
+	switch len(keys) {
+	case 0:
+		return nil
+	case 1:
+		return map[string]interface{}{keys[0]: filter[keys[0]]}
+	}
+
+	clauses := make([]map[string]interface{}, len(keys))
+	for i, key := range keys {
+		clauses[i] = map[string]interface{}{key: filter[key]}
+	}
+	return map[string]interface{}{"$and": clauses}
+}
+
+// decodeList decodes a flat list, or the first inner list of a nested one
+func decodeList[T any](raw json.RawMessage) []T {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var flat []T
+	if err := json.Unmarshal(raw, &flat); err == nil {
+		return flat
+	}
+	var nested [][]T
+	if err := json.Unmarshal(raw, &nested); err == nil && len(nested) > 0 {
+		return nested[0]
+	}
+	return nil
+}
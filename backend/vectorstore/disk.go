@@ -0,0 +1,361 @@
+package vectorstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	diskLogName = "vectors.jsonl"
+	// The log is rewritten once it holds more than twice as many entries as live records
+	// (and at least this many), so re-indexing churn does not grow it forever
+	diskCompactMinEntries = 1024
+)
+
+// Disk is an embedded vector store for small deployments and tests. Records are kept in
+// memory and every change is appended (and fsynced) to a JSON-lines log in dir, which is
+// replayed on open. Queries are exact: every matching record is compared to the query.
+type Disk struct {
+	mu      sync.RWMutex
+	path    string
+	records map[string]Record
+	file    *os.File
+	entries int // Lines in the log
+}
+
+// diskEntry is one line of the log
+type diskEntry struct {
+	Op     string   `json:"op"` // "upsert" or "delete"
+	Record *Record  `json:"record,omitempty"`
+	IDs    []string `json:"ids,omitempty"`
+}
+
+// OpenDisk opens (or creates) the store in dir
+func OpenDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create vector store directory: %w", err)
+	}
+
+	d := &Disk{
+		path:    filepath.Join(dir, diskLogName),
+		records: make(map[string]Record),
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	if err := d.openLog(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load replays the log. A torn last line (crash during a write) is cut off.
+func (d *Disk) load() error {
+	f, err := os.Open(d.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open vector log: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("Warning: dropping incomplete last entry of %s", d.path)
+				return os.Truncate(d.path, offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read vector log: %w", err)
+		}
+
+		var entry diskEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("corrupt vector log entry at offset %d: %w", offset, err)
+		}
+		d.apply(entry)
+		d.entries++
+		offset += int64(len(line))
+	}
+}
+
+func (d *Disk) openLog() error {
+	f, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open vector log: %w", err)
+	}
+	d.file = f
+	return nil
+}
+
+func (d *Disk) apply(entry diskEntry) {
+	switch entry.Op {
+	case "upsert":
+		if entry.Record != nil {
+			d.records[entry.Record.ID] = *entry.Record
+		}
+	case "delete":
+		for _, id := range entry.IDs {
+			delete(d.records, id)
+		}
+	}
+}
+
+// write appends entries to the log and applies them once they are durable. Entries are
+// applied as decoded from their own JSON, so memory holds exactly what a reload would
+// (e.g. float64 instead of int metadata, like Chroma returns).
+func (d *Disk) write(entries []diskEntry) error {
+	if d.file == nil {
+		return fmt.Errorf("vector store is closed")
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode vector log entry: %w", err)
+		}
+	}
+	info, err := d.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat vector log: %w", err)
+	}
+	if _, err := d.file.Write(buf.Bytes()); err != nil {
+		// Cut off a partial write so later entries do not follow a broken line
+		d.file.Truncate(info.Size())
+		return fmt.Errorf("failed to write vector log: %w", err)
+	}
+	if err := d.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync vector log: %w", err)
+	}
+
+	decoder := json.NewDecoder(&buf)
+	for range entries {
+		var entry diskEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return fmt.Errorf("failed to decode vector log entry: %w", err)
+		}
+		d.apply(entry)
+		d.entries++
+	}
+
+	if d.entries > diskCompactMinEntries && d.entries > 2*len(d.records) {
+		if err := d.compact(); err != nil {
+			log.Printf("Warning: vector log compaction failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// compact rewrites the log with one upsert per live record, atomically via rename
+func (d *Disk) compact() error {
+	tmpPath := d.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, id := range d.sortedIDs() {
+		record := d.records[id]
+		if err := encoder.Encode(diskEntry{Op: "upsert", Record: &record}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := d.file.Close(); err != nil {
+		return err
+	}
+	d.file = nil
+	if err := os.Rename(tmpPath, d.path); err != nil {
+		// Keep appending to the old log
+		if openErr := d.openLog(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	d.entries = len(d.records)
+	return d.openLog()
+}
+
+func (d *Disk) sortedIDs() []string {
+	ids := make([]string, 0, len(d.records))
+	for id := range d.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Upsert implements VectorStore
+func (d *Disk) Upsert(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	entries := make([]diskEntry, len(records))
+	for i := range records {
+		entries[i] = diskEntry{Op: "upsert", Record: &records[i]}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(entries)
+}
+
+// Query implements VectorStore
+func (d *Disk) Query(ctx context.Context, embedding []float64, filter Filter, topK int) ([]Match, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var matches []Match
+	for _, record := range d.records {
+		if !filter.Matches(record.Metadata) {
+			continue
+		}
+		matches = append(matches, Match{
+			Record:   Record{ID: record.ID, Text: record.Text, Metadata: copyMetadata(record.Metadata)},
+			Distance: CosineDistance(embedding, record.Embedding),
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+// Get implements VectorStore. Records are returned in ID order.
+func (d *Disk) Get(ctx context.Context, filter Filter, withEmbeddings bool) ([]Record, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.matching(filter, withEmbeddings), nil
+}
+
+func (d *Disk) matching(filter Filter, withEmbeddings bool) []Record {
+	var records []Record
+	for _, id := range d.sortedIDs() {
+		record := d.records[id]
+		if !filter.Matches(record.Metadata) {
+			continue
+		}
+		record.Metadata = copyMetadata(record.Metadata)
+		if !withEmbeddings {
+			record.Embedding = nil
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Delete implements VectorStore
+func (d *Disk) Delete(ctx context.Context, filter Filter) error {
+	if len(filter) == 0 {
+		return fmt.Errorf("refusing to delete the whole collection")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var ids []string
+	for id, record := range d.records {
+		if filter.Matches(record.Metadata) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Strings(ids)
+	return d.write([]diskEntry{{Op: "delete", IDs: ids}})
+}
+
+// DeleteIDs implements VectorStore
+func (d *Disk) DeleteIDs(ctx context.Context, ids []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var existing []string
+	for _, id := range ids {
+		if _, ok := d.records[id]; ok {
+			existing = append(existing, id)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	return d.write([]diskEntry{{Op: "delete", IDs: existing}})
+}
+
+// KeywordSearch implements VectorStore
+func (d *Disk) KeywordSearch(ctx context.Context, keywords []string, filter Filter, topK int) ([]Match, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return ScoreKeywords(d.matching(filter, false), keywords, topK), nil
+}
+
+// Len returns the number of stored records
+func (d *Disk) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.records)
+}
+
+// Close implements VectorStore
+func (d *Disk) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
+
+// copyMetadata returns a shallow copy so callers cannot mutate stored records
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
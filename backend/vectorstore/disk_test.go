@@ -0,0 +1,174 @@
+package vectorstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openTestDisk(t *testing.T, dir string) *Disk {
+	t.Helper()
+	store, err := OpenDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func chunk(id, fileID, text string, embedding ...float64) Record {
+	return Record{
+		ID:        id,
+		Text:      text,
+		Embedding: embedding,
+		Metadata:  map[string]interface{}{"file_id": fileID, "chunk_index": 1},
+	}
+}
+
+func TestDiskPersistsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store := openTestDisk(t, dir)
+	if err := store.Upsert(ctx, []Record{
+		chunk("a_0", "a", "alpha", 1, 0),
+		chunk("a_1", "a", "beta", 0, 1),
+		chunk("b_0", "b", "gamma", 1, 1),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Upsert(ctx, []Record{chunk("a_1", "a", "beta v2", 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteIDs(ctx, []string{"b_0", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	reopened := openTestDisk(t, dir)
+	records, err := reopened.Get(ctx, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Text != "beta v2" || !reflect.DeepEqual(records[0].Embedding, []float64{1, 0}) {
+		t.Fatalf("unexpected records after reopen: %+v", records)
+	}
+}
+
+func TestDiskQueryFiltersAndRanksByCosineDistance(t *testing.T) {
+	ctx := context.Background()
+	store := openTestDisk(t, t.TempDir())
+	store.Upsert(ctx, []Record{
+		chunk("a_0", "a", "x", 1, 0),
+		chunk("a_1", "a", "y", 0.7, 0.7),
+		chunk("a_2", "a", "z", 0, 1),
+		chunk("b_0", "b", "w", 1, 0),
+	})
+
+	matches, err := store.Query(ctx, []float64{1, 0.1}, Filter{"file_id": "a"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].ID != "a_0" || matches[1].ID != "a_1" {
+		t.Fatalf("unexpected ranking: %+v", matches)
+	}
+	if matches[0].Distance >= matches[1].Distance {
+		t.Errorf("distances must increase: %f, %f", matches[0].Distance, matches[1].Distance)
+	}
+	if matches[0].Embedding != nil {
+		t.Error("query results should not carry embeddings")
+	}
+
+	// Integer filter values match metadata read back as float64
+	matches, _ = store.Query(ctx, []float64{1, 0}, Filter{"file_id": "b", "chunk_index": 1}, 5)
+	if len(matches) != 1 || matches[0].ID != "b_0" {
+		t.Fatalf("numeric filter did not match: %+v", matches)
+	}
+}
+
+func TestDiskDeleteByFilterAndKeywordSearch(t *testing.T) {
+	ctx := context.Background()
+	store := openTestDisk(t, t.TempDir())
+	store.Upsert(ctx, []Record{
+		chunk("a_0", "a", "Quarterly revenue grew", 1, 0),
+		chunk("a_1", "a", "Revenue and revenue targets", 0, 1),
+		chunk("b_0", "b", "revenue elsewhere", 1, 1),
+	})
+
+	matches, _ := store.KeywordSearch(ctx, []string{"revenue", "targets"}, Filter{"file_id": "a"}, 5)
+	if len(matches) != 2 || matches[0].ID != "a_1" {
+		t.Fatalf("unexpected keyword ranking: %+v", matches)
+	}
+
+	if err := store.Delete(ctx, Filter{"file_id": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 1 {
+		t.Fatalf("expected only b_0 to remain, have %d records", store.Len())
+	}
+	if err := store.Delete(ctx, nil); err == nil {
+		t.Error("deleting without a filter must be refused")
+	}
+}
+
+func TestDiskDropsTornLastEntry(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := openTestDisk(t, dir)
+	store.Upsert(ctx, []Record{chunk("a_0", "a", "kept", 1, 0)})
+	store.Close()
+
+	f, err := os.OpenFile(filepath.Join(dir, diskLogName), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"upsert","record":{"id":"a_1"`)
+	f.Close()
+
+	reopened := openTestDisk(t, dir)
+	if reopened.Len() != 1 {
+		t.Fatalf("expected the torn entry to be dropped, have %d records", reopened.Len())
+	}
+	// Appending after recovery must produce a readable log
+	reopened.Upsert(ctx, []Record{chunk("a_1", "a", "new", 0, 1)})
+	reopened.Close()
+	if again := openTestDisk(t, dir); again.Len() != 2 {
+		t.Fatalf("expected 2 records, have %d", again.Len())
+	}
+}
+
+func TestDiskCompactsChurn(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := openTestDisk(t, dir)
+	for i := 0; i < diskCompactMinEntries+10; i++ {
+		if err := store.Upsert(ctx, []Record{chunk("a_0", "a", "same id", 1, 0)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.entries > diskCompactMinEntries {
+		t.Fatalf("log was not compacted: %d entries", store.entries)
+	}
+	store.Close()
+
+	if again := openTestDisk(t, dir); again.Len() != 1 {
+		t.Fatalf("expected 1 record after compaction, have %d", again.Len())
+	}
+}
+
+func TestChromaWhere(t *testing.T) {
+	if chromaWhere(nil) != nil {
+		t.Error("empty filter should omit where")
+	}
+	single := chromaWhere(Filter{"file_id": "a"})
+	if !reflect.DeepEqual(single, map[string]interface{}{"file_id": "a"}) {
+		t.Errorf("unexpected single-key where: %v", single)
+	}
+	multi := chromaWhere(Filter{"file_id": "a", "embed_model": "m"})
+	want := map[string]interface{}{"$and": []map[string]interface{}{{"embed_model": "m"}, {"file_id": "a"}}}
+	if !reflect.DeepEqual(multi, want) {
+		t.Errorf("unexpected multi-key where: %v", multi)
+	}
+}
@@ -0,0 +1,176 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"nimbus-backend/config"
+)
+
+// Backend names accepted by VECTOR_STORE
+const (
+	BackendChroma = "chroma"
+	BackendDisk   = "disk" // Embedded store persisted under VECTOR_STORE_PATH
+)
+
+// Record is a stored chunk: its vector, text and scalar metadata
+type Record struct {
+	ID        string                 `json:"id"`
+	Embedding []float64              `json:"embedding,omitempty"`
+	Text      string                 `json:"text"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// Match is a search hit. Distance is lower for better matches.
+type Match struct {
+	Record
+	Distance float64
+}
+
+// Filter selects records whose metadata equals every given value. An empty filter
+// matches everything.
+type Filter map[string]interface{}
+
+// VectorStore stores chunk vectors with metadata
+type VectorStore interface {
+	// Upsert inserts or replaces records by ID
+	Upsert(ctx context.Context, records []Record) error
+	// Query returns the topK records closest to embedding among those matching filter
+	Query(ctx context.Context, embedding []float64, filter Filter, topK int) ([]Match, error)
+	// Get returns every record matching filter; embeddings only when withEmbeddings is set
+	Get(ctx context.Context, filter Filter, withEmbeddings bool) ([]Record, error)
+	// Delete removes every record matching filter
+	Delete(ctx context.Context, filter Filter) error
+	// DeleteIDs removes records by ID
+	DeleteIDs(ctx context.Context, ids []string) error
+	// KeywordSearch ranks records matching filter by keyword hits (see ScoreKeywords)
+	KeywordSearch(ctx context.Context, keywords []string, filter Filter, topK int) ([]Match, error)
+	// Close flushes and releases the store
+	Close() error
+}
+
+// New opens the store selected by cfg.VectorStore
+func New(cfg *config.Config) (VectorStore, error) {
+	switch strings.ToLower(cfg.VectorStore) {
+	case "", BackendChroma:
+		return NewChroma(cfg.ChromaBaseURL, cfg.ChromaTenant, cfg.ChromaDatabase, cfg.ChromaCollection), nil
+	case BackendDisk:
+		return OpenDisk(cfg.VectorStorePath)
+	}
+	return nil, fmt.Errorf("unknown vector store %q", cfg.VectorStore)
+}
+
+// Matches reports whether metadata satisfies the filter. Numbers compare by value, since
+// metadata read back from JSON holds float64 where int was written.
+func (f Filter) Matches(metadata map[string]interface{}) bool {
+	for key, want := range f {
+		got, ok := metadata[key]
+		if !ok || !valuesEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns the filter keys in sorted order
+func (f Filter) Keys() []string {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return a == b
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// CosineDistance is 1 - cosine similarity, the distance the file router uses as well
+func CosineDistance(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 2 // Incomparable vectors rank last
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 2
+	}
+	return 1 - dot/(math.Sqrt(na)*math.Sqrt(nb))
+}
+
+// ScoreKeywords ranks records by keyword hits: 10 points for a keyword in the text, 5 for
+// one in the key_terms metadata. Records without any hit are dropped; Distance is
+// 1000 - score so lower stays better.
+func ScoreKeywords(records []Record, keywords []string, topK int) []Match {
+	lowerKeywords := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		lowerKeywords[i] = strings.ToLower(keyword)
+	}
+
+	type scored struct {
+		match Match
+		score int
+	}
+	var hits []scored
+	for _, record := range records {
+		text := strings.ToLower(record.Text)
+		keyTerms, _ := record.Metadata["key_terms"].(string)
+		keyTerms = strings.ToLower(keyTerms)
+
+		score, matched := 0, 0
+		for _, keyword := range lowerKeywords {
+			inText := strings.Contains(text, keyword)
+			if inText {
+				score += 10 // Higher weight for text matches
+				matched++
+			}
+			if keyTerms != "" && strings.Contains(keyTerms, keyword) {
+				score += 5 // Lower weight for metadata matches
+				if !inText {
+					matched++
+				}
+			}
+		}
+
+		// Only include chunks that match at least one keyword
+		if matched > 0 {
+			hits = append(hits, scored{match: Match{Record: record, Distance: float64(1000 - score)}, score: score})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+
+	var matches []Match
+	for i := 0; i < len(hits) && i < topK; i++ {
+		matches = append(matches, hits[i].match)
+	}
+	return matches
+}
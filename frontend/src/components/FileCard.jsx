@@ -6,6 +6,7 @@ import {
   Box,
   Chip,
   CircularProgress,
+  LinearProgress,
 } from '@mui/material';
 import { motion } from 'framer-motion';
 import { useState } from 'react';
@@ -16,13 +17,16 @@ import DescriptionIcon from '@mui/icons-material/Description';
 import ArchiveIcon from '@mui/icons-material/Archive';
 import MoreVertIcon from '@mui/icons-material/MoreVert';
 import FileItemMenu from './FileItemMenu';
+import { useProcessingProgress, formatEta } from '../hooks/useProcessingProgress';
 import {
   isPreviewable,
   isAskableFile,
+  isQueryableStatus,
   formatFileSize,
   formatDate,
   formatContentType,
 } from '../utils/fileUtils';
+import { normalizeRole, canEditWithRole } from '../utils/roleUtils';
 
 const MotionCard = motion.create(Card);
 
@@ -82,6 +86,16 @@ const FileCard = ({
   // Word ve PDF dosyaları için Nimbus'a Sor seçeneğini göster
   const fileIsAskable = isAskableFile(file?.content_type, file?.filename);
 
+  // Canlı işleme ilerlemesi (SSE); bittiğinde chip'in durumu da güncellenir
+  const processingState = useProcessingProgress(
+    fileIsAskable ? file?.id : null,
+    file?.processing_status,
+    { stale: file?.content_stale }
+  );
+  const processingStatus = processingState?.status || file?.processing_status;
+  const contentStale = processingState ? !!processingState.content_stale : !!file?.content_stale;
+  const progress = processingState?.progress;
+
   const handleContextMenu = (e) => {
     e.preventDefault();
     e.stopPropagation();
@@ -161,27 +175,33 @@ const FileCard = ({
             variant="outlined"
             sx={{ fontSize: '0.7rem' }}
           />
-          {fileIsAskable && file.processing_status && (
+          {fileIsAskable && processingStatus && (
             <Chip
               label={
-                file.processing_status === 'processing'
+                processingStatus === 'processing'
                   ? t('ai.processing')
-                  : file.processing_status === 'completed'
+                  : contentStale && isQueryableStatus(processingStatus)
+                    ? t('ai.reindexing')
+                    : processingStatus === 'completed'
                     ? t('ai.ready')
-                    : file.processing_status === 'failed'
-                      ? t('ai.failed')
-                      : t('ai.pending')
+                    : processingStatus === 'partial'
+                      ? t('ai.partial', {
+                          count: processingState?.failed_chunk_count ?? file.failed_chunk_count ?? 0,
+                        })
+                      : processingStatus === 'failed'
+                        ? t('ai.failed')
+                        : t('ai.pending')
               }
               size="small"
               color={
-                file.processing_status === 'completed'
+                isQueryableStatus(processingStatus)
                   ? 'success'
-                  : file.processing_status === 'failed'
+                  : processingStatus === 'failed'
                     ? 'error'
                     : 'warning'
               }
               icon={
-                file.processing_status === 'processing' ? (
+                processingStatus === 'processing' ? (
                   <CircularProgress size={12} sx={{ color: 'inherit' }} />
                 ) : undefined
               }
@@ -191,9 +211,9 @@ const FileCard = ({
           {file.isShared && (
             <>
               <Chip
-                label={file.access_type === 'read' ? t('access.read') : t('access.write')}
+                label={t(`access.${normalizeRole(file.access_type)}`)}
                 size="small"
-                color={file.access_type === 'read' ? 'info' : 'warning'}
+                color={canEditWithRole(file.access_type) ? 'warning' : 'info'}
                 sx={{ fontSize: '0.7rem' }}
               />
               {file.owner && (
@@ -204,6 +224,18 @@ const FileCard = ({
             </>
           )}
         </Box>
+
+        {fileIsAskable && progress && (processingStatus === 'processing' || processingStatus === 'pending' || contentStale) && (
+          <Box sx={{ mt: 1 }}>
+            <LinearProgress variant="determinate" value={progress.percent || 0} sx={{ borderRadius: 1 }} />
+            <Typography variant="caption" color="text.secondary">
+              {t(`ai.stage_${progress.stage}`)}
+              {progress.stage === 'embedding' && progress.total > 0 && ` ${progress.current}/${progress.total}`}
+              {progress.eta_seconds != null &&
+                ` · ${t('ai.eta', { time: formatEta(progress.eta_seconds) })}`}
+            </Typography>
+          </Box>
+        )}
       </CardContent>
 
       <FileItemMenu
@@ -15,6 +15,7 @@ import LockOpenIcon from '@mui/icons-material/LockOpen';
 import ContentCopyIcon from '@mui/icons-material/ContentCopy';
 import { formatFileSize, formatDate, formatContentType } from '../utils/fileUtils';
 import { shareApi } from '../services/api';
+import { normalizeRole, canEditWithRole } from '../utils/roleUtils';
 
 const FileInfoPanel = ({ isOpen, onClose, file }) => {
   const { t } = useTranslation();
@@ -389,14 +390,14 @@ const FileInfoPanel = ({ isOpen, onClose, file }) => {
 
                       <InfoRow
                         icon={
-                          file.access_type === 'read' ? (
+                          !canEditWithRole(file.access_type) ? (
                             <LockIcon sx={{ color: 'white', fontSize: 20 }} />
                           ) : (
                             <LockOpenIcon sx={{ color: 'white', fontSize: 20 }} />
                           )
                         }
                         label="Erişim Seviyesi"
-                        value={file.access_type === 'read' ? 'Görüntüleme' : 'Düzenleme'}
+                        value={t(`access.${normalizeRole(file.access_type)}`)}
                         chip
                       />
                     </>
@@ -10,7 +10,7 @@ import InfoIcon from '@mui/icons-material/Info';
 import StarIcon from '@mui/icons-material/Star';
 import StarBorderIcon from '@mui/icons-material/StarBorder';
 import RestoreFromTrashIcon from '@mui/icons-material/RestoreFromTrash';
-import { isAskableFile, isEditable } from '../utils/fileUtils';
+import { isAskableFile, isEditable, isQueryableStatus } from '../utils/fileUtils';
 
 const FileItemMenu = ({
     anchorEl,
@@ -84,7 +84,7 @@ const FileItemMenu = ({
                 <MenuItem
                     key="ask-nimbus"
                     onClick={() => handleAction(onAskNimbus, item)}
-                    disabled={item?.processing_status !== 'completed'}
+                    disabled={!isQueryableStatus(item?.processing_status)}
                     sx={{ color: '#667eea' }}
                 >
                     <ListItemIcon>
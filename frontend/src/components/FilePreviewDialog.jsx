@@ -33,6 +33,7 @@ import {
 } from '../utils/fileUtils';
 import OnlyOfficeEditor from './OnlyOfficeEditor';
 import CodeEditor from './CodeEditor';
+import { canEditWithRole } from '../utils/roleUtils';
 
 const Transition = React.forwardRef(function Transition(props, ref) {
   return <Slide direction="up" ref={ref} {...props} />;
@@ -318,12 +319,12 @@ const FilePreviewDialog = ({ open, onClose, file, onDownload, onSave }) => {
             <CodeEditor
               file={file}
               content={codeContent}
-              readOnly={!fileIsEditable || (file?.isShared && file?.access_type === 'read')}
+              readOnly={!fileIsEditable || (file?.isShared && !canEditWithRole(file?.access_type))}
               onChange={content => {
                 // Content changed, will be saved on Ctrl+S
               }}
               onSave={
-                fileIsEditable && (!file?.isShared || file?.access_type !== 'read')
+                fileIsEditable && (!file?.isShared || canEditWithRole(file?.access_type))
                   ? handleCodeSave
                   : null
               }
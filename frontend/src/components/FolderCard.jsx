@@ -14,6 +14,7 @@ import { useTranslation } from 'react-i18next';
 import FolderIcon from '@mui/icons-material/Folder';
 import MoreVertIcon from '@mui/icons-material/MoreVert';
 import FileItemMenu from './FileItemMenu';
+import { normalizeRole, canEditWithRole } from '../utils/roleUtils';
 
 const MotionCard = motion.create(Card);
 const FolderCard = ({ folder, onOpen, onDelete, onEdit, onShare, onMove, onToggleStar, onRestore, onMenuOpen }) => {
@@ -107,9 +108,9 @@ const FolderCard = ({ folder, onOpen, onDelete, onEdit, onShare, onMove, onToggl
                 •
               </Typography>
               <Chip
-                label={folder.access_type === 'read' ? t('access.read') : t('access.write')}
+                label={t(`access.${normalizeRole(folder.access_type)}`)}
                 size="small"
-                color={folder.access_type === 'read' ? 'info' : 'warning'}
+                color={canEditWithRole(folder.access_type) ? 'warning' : 'info'}
                 sx={{ fontSize: '0.7rem' }}
               />
               {folder.owner && (
@@ -17,15 +17,43 @@ import remarkGfm from 'remark-gfm';
 import rehypeHighlight from 'rehype-highlight';
 import 'highlight.js/styles/github-dark.css';
 import CloseIcon from '@mui/icons-material/Close';
+import AddCommentIcon from '@mui/icons-material/AddComment';
+import ThumbUpIcon from '@mui/icons-material/ThumbUpOutlined';
+import ThumbDownIcon from '@mui/icons-material/ThumbDownOutlined';
 import SendIcon from '@mui/icons-material/Send';
 import SmartToyIcon from '@mui/icons-material/SmartToy';
 import DescriptionIcon from '@mui/icons-material/Description';
+import { isQueryableStatus } from '../utils/fileUtils';
+
+// Cited sources for a bot message: structured citations when available, legacy previews otherwise
+const citedSources = message => {
+  if (message.citations && message.citations.length > 0) {
+    const cited = message.citations.filter(c => c.cited);
+    return (cited.length > 0 ? cited : message.citations).map(c => {
+      const page =
+        c.page_start > 0
+          ? c.page_end > c.page_start
+            ? ` · p.${c.page_start}-${c.page_end}`
+            : ` · p.${c.page_start}`
+          : '';
+      const symbol = c.symbol ? ` · ${c.symbol}` : '';
+      const lines = c.line_start > 0 ? ` · L${c.line_start}-${c.line_end}` : '';
+      return {
+        label: `[${c.number}]${c.filename ? ` ${c.filename}` : ''}${page}${symbol}${lines}`,
+        preview: c.preview,
+      };
+    });
+  }
+  return (message.sources || []).map(source => ({ label: source, preview: source }));
+};
 
 const NimbusChatPanel = ({ isOpen, onClose, file }) => {
   const { t } = useTranslation();
   const [messages, setMessages] = useState([]);
   const [inputValue, setInputValue] = useState('');
   const [isTyping, setIsTyping] = useState(false);
+  // Thread being continued; null = the file's most recently active thread
+  const [conversationId, setConversationId] = useState(null);
 
   // Ultra ultra smooth renk döngüsü animasyonu
   const gradientAnimation = `
@@ -68,12 +96,55 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
 
   useEffect(() => {
     if (isOpen && file) {
-      // Load conversation history
-      loadConversationHistory();
+      // Load conversation history (a thread picked in the sidebar arrives as file.conversation_id)
+      setConversationId(file.conversation_id || null);
+      loadConversationHistory(file.conversation_id);
     }
   }, [isOpen, file]);
 
-  const loadConversationHistory = async () => {
+  const showWelcome = () => {
+    setMessages([
+      {
+        id: Date.now(),
+        text: t('ai.welcome', { filename: file.filename }),
+        isBot: true,
+        timestamp: new Date(),
+      },
+    ]);
+  };
+
+  // Rate a stored answer; a thumbs down may come with a corrected answer
+  const handleFeedback = async (message, rating) => {
+    if (!conversationId || !message.messageId || message.feedback === rating) return;
+    const feedback = { rating };
+    if (rating === 'down') {
+      const corrected = window.prompt(t('ai.feedback_correction'));
+      if (corrected) feedback.corrected_answer = corrected;
+    }
+    try {
+      const { fileApi } = await import('../services/api');
+      await fileApi.setMessageFeedback(conversationId, message.messageId, feedback);
+      setMessages(prev => prev.map(m => (m.id === message.id ? { ...m, feedback: rating } : m)));
+    } catch (error) {
+      console.error('Failed to save feedback:', error);
+      window.toast?.error(error.message);
+    }
+  };
+
+  const handleNewThread = async () => {
+    if (!file || isTyping) return;
+    try {
+      const { fileApi } = await import('../services/api');
+      const thread = await fileApi.createThread(file.id);
+      setConversationId(thread.id);
+      showWelcome();
+    } catch (error) {
+      console.error('Failed to create thread:', error);
+      window.toast?.error(error.message);
+    }
+  };
+
+  const loadConversationHistory = async threadId => {
     if (!file) return;
 
     // Check if file is processed
@@ -97,7 +168,7 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
         },
       ]);
       return;
-    } else if (file.processing_status !== 'completed') {
+    } else if (!isQueryableStatus(file.processing_status)) {
       setMessages([
         {
           id: Date.now(),
@@ -112,7 +183,10 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
     // File is processed, load conversation history
     try {
       const { fileApi } = await import('../services/api');
-      const conversation = await fileApi.getConversationHistory(file.id);
+      const conversation = await fileApi.getConversationHistory(file.id, threadId);
+      if (conversation.id) {
+        setConversationId(conversation.id);
+      }
 
       if (conversation.messages && conversation.messages.length > 0) {
         // Convert backend messages to frontend format
@@ -122,30 +196,19 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
           isBot: msg.role === 'assistant',
           timestamp: new Date(msg.timestamp),
           sources: msg.sources || undefined,
+          citations: msg.citations || undefined,
+          messageId: msg.id,
+          feedback: msg.feedback?.rating,
         }));
         setMessages(formattedMessages);
       } else {
         // No history, show welcome message
-        setMessages([
-          {
-            id: Date.now(),
-            text: t('ai.welcome', { filename: file.filename }),
-            isBot: true,
-            timestamp: new Date(),
-          },
-        ]);
+        showWelcome();
       }
     } catch (error) {
       console.error('Failed to load conversation history:', error);
       // Show welcome message on error
-      setMessages([
-        {
-          id: Date.now(),
-          text: t('ai.welcome', { filename: file.filename }),
-          isBot: true,
-          timestamp: new Date(),
-        },
-      ]);
+      showWelcome();
     }
   };
 
@@ -153,7 +216,7 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
     if (!inputValue.trim() || !file) return;
 
     // Check if file is processed
-    if (file.processing_status !== 'completed') {
+    if (!isQueryableStatus(file.processing_status)) {
       window.toast?.error(t('ai.not_processed'));
       return;
     }
@@ -174,18 +237,53 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
       // Import fileApi dynamically
       const { fileApi } = await import('../services/api');
 
-      // Query the document
-      const response = await fileApi.queryDocument(file.id, currentQuestion);
-
-      const botResponse = {
-        id: Date.now() + 1,
-        text: response.answer,
-        isBot: true,
-        timestamp: new Date(),
-        sources: response.sources,
-      };
+      // Query the document, rendering tokens as they arrive
+      const botId = Date.now() + 1;
+      let started = false;
+      const updateBot = patch =>
+        setMessages(prev => prev.map(m => (m.id === botId ? { ...m, ...patch(m) } : m)));
 
-      setMessages(prev => [...prev, botResponse]);
+      await fileApi.queryDocumentStream(file.id, currentQuestion, {
+        onMetadata: metadata => {
+          if (metadata.conversation_id) setConversationId(metadata.conversation_id);
+        },
+        onToken: delta => {
+          if (!started) {
+            started = true;
+            setIsTyping(false);
+            setMessages(prev => [
+              ...prev,
+              { id: botId, text: delta, isBot: true, timestamp: new Date() },
+            ]);
+            return;
+          }
+          updateBot(m => ({ text: m.text + delta }));
+        },
+        onDone: response => {
+          if (!started) {
+            started = true;
+            setMessages(prev => [
+              ...prev,
+              {
+                id: botId,
+                text: response.answer,
+                isBot: true,
+                timestamp: new Date(),
+                sources: response.sources,
+                citations: response.citations,
+                messageId: response.message_id,
+              },
+            ]);
+            return;
+          }
+          updateBot(() => ({
+            text: response.answer,
+            sources: response.sources,
+            citations: response.citations,
+            messageId: response.message_id,
+          }));
+        },
+      }, undefined, conversationId);
     } catch (error) {
       console.error('Query error:', error);
       const errorMessage = {
@@ -268,17 +366,31 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
                   </Typography>
                 </Box>
               </Box>
-              <IconButton
-                onClick={onClose}
-                sx={{
-                  color: 'white',
-                  '&:hover': {
-                    background: 'rgba(255,255,255,0.1)',
-                  },
-                }}
-              >
-                <CloseIcon />
-              </IconButton>
+              <Box sx={{ display: 'flex', gap: 0.5 }}>
+                <IconButton
+                  onClick={handleNewThread}
+                  title={t('ai.new_thread') || 'Yeni sohbet'}
+                  sx={{
+                    color: 'white',
+                    '&:hover': {
+                      background: 'rgba(255,255,255,0.1)',
+                    },
+                  }}
+                >
+                  <AddCommentIcon />
+                </IconButton>
+                <IconButton
+                  onClick={onClose}
+                  sx={{
+                    color: 'white',
+                    '&:hover': {
+                      background: 'rgba(255,255,255,0.1)',
+                    },
+                  }}
+                >
+                  <CloseIcon />
+                </IconButton>
+              </Box>
             </Box>
 
             {/* File Info */}
@@ -445,12 +557,13 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
                       <Typography variant="body2">{message.text}</Typography>
                     )}
                   </Box>
-                  {message.sources && message.sources.length > 0 && (
+                  {citedSources(message).length > 0 && (
                     <Box sx={{ mt: 1.5, display: 'flex', gap: 0.5, flexWrap: 'wrap' }}>
-                      {message.sources.map((source, idx) => (
+                      {citedSources(message).map((source, idx) => (
                         <Chip
                           key={idx}
-                          label={source}
+                          label={source.label}
+                          title={source.preview}
                           size="small"
                           sx={{
                             background: 'rgba(255,255,255,0.2)',
@@ -479,6 +592,27 @@ const NimbusChatPanel = ({ isOpen, onClose, file }) => {
                       minute: '2-digit',
                     })}
                   </Typography>
+                  {message.isBot && message.messageId && (
+                    <Box sx={{ display: 'flex', gap: 0.5, mt: 0.5 }}>
+                      {[
+                        ['up', ThumbUpIcon, t('ai.feedback_up')],
+                        ['down', ThumbDownIcon, t('ai.feedback_down')],
+                      ].map(([rating, Icon, label]) => (
+                        <IconButton
+                          key={rating}
+                          size="small"
+                          title={label}
+                          onClick={() => handleFeedback(message, rating)}
+                          sx={{
+                            color: message.feedback === rating ? 'white' : 'rgba(255,255,255,0.5)',
+                            p: 0.25,
+                          }}
+                        >
+                          <Icon sx={{ fontSize: 14 }} />
+                        </IconButton>
+                      ))}
+                    </Box>
+                  )}
                 </Paper>
               </motion.div>
             ))}
@@ -26,6 +26,7 @@ import LinkIcon from '@mui/icons-material/Link';
 import UserSearch from './UserSearch';
 import { shareApi } from '../services/api';
 import { useAuth } from '../contexts/AuthContext';
+import { ROLES, normalizeRole, canShareWithRole } from '../utils/roleUtils';
 
 const ShareDialog = ({ open, onClose, resource, resourceType }) => {
   const { t } = useTranslation();
@@ -62,11 +63,11 @@ const ShareDialog = ({ open, onClose, resource, resourceType }) => {
       if (data && data.access_list) {
         const userAccess = data.access_list.find(access => access.user_id === user.id);
         if (userAccess) {
-          setUserAccessLevel(userAccess.access_type); // read or write
+          setUserAccessLevel(normalizeRole(userAccess.access_type));
         } else if (data.user_id && user.id === data.user_id) {
           setUserAccessLevel('owner');
         } else {
-          setUserAccessLevel('read'); // Default for shared users
+          setUserAccessLevel('viewer'); // Default for shared users
         }
       } else {
         setUserAccessLevel('owner'); // Default for owners
@@ -101,7 +102,7 @@ const ShareDialog = ({ open, onClose, resource, resourceType }) => {
       // Add user to access list (this will update the file/folder's access_list)
       await shareApi.updateAccessPermission(resource.id, {
         user_id: selectedUser.id,
-        permission: 'read',
+        permission: 'viewer',
       });
 
       // Refresh the shares data to get updated access list
@@ -186,10 +187,10 @@ const ShareDialog = ({ open, onClose, resource, resourceType }) => {
 
   // Get access type for a specific user
   const getAccessTypeForUser = userId => {
-    if (!shares || !shares.access_list) return 'read';
+    if (!shares || !shares.access_list) return 'viewer';
 
     const accessEntry = shares.access_list.find(access => access.user_id === userId);
-    return accessEntry ? accessEntry.access_type : 'read';
+    return accessEntry ? normalizeRole(accessEntry.access_type) : 'viewer';
   };
 
   const sharedUsers = getSharedUsers();
@@ -214,8 +215,8 @@ const ShareDialog = ({ open, onClose, resource, resourceType }) => {
           </Box>
         ) : (
           <>
-            {/* User Search - Only show for owners and write users */}
-            {userAccessLevel === 'owner' || userAccessLevel === 'write' ? (
+            {/* User Search - Only show for owners and managers */}
+            {canShareWithRole(userAccessLevel) ? (
               <Box sx={{ mb: 3 }}>
                 <Typography variant="subtitle2" sx={{ mb: 1.5, fontWeight: 600 }}>
                   Kullanıcı Ekle
@@ -283,16 +284,19 @@ const ShareDialog = ({ open, onClose, resource, resourceType }) => {
                       },
                     }}
                     secondaryAction={
-                      userAccessLevel === 'owner' || userAccessLevel === 'write' ? (
+                      canShareWithRole(userAccessLevel) ? (
                         <Box sx={{ display: 'flex', alignItems: 'center', gap: 1 }}>
                           <Select
-                            value={userInfo.access_type}
+                            value={normalizeRole(userInfo.access_type)}
                             onChange={e => handlePermissionChange(userInfo.id, e.target.value)}
                             size="small"
                             sx={{ minWidth: 130 }}
                           >
-                            <MenuItem value="read">{t('share.viewer')}</MenuItem>
-                            <MenuItem value="write">{t('share.editor')}</MenuItem>
+                            {ROLES.map(role => (
+                              <MenuItem key={role} value={role}>
+                                {t(`share.${role}`)}
+                              </MenuItem>
+                            ))}
                           </Select>
                           <IconButton
                             edge="end"
@@ -304,7 +308,7 @@ const ShareDialog = ({ open, onClose, resource, resourceType }) => {
                         </Box>
                       ) : (
                         <Typography variant="body2" color="text.secondary">
-                          {userInfo.access_type === 'write' ? t('share.editor') : t('share.viewer')}
+                          {t(`share.${normalizeRole(userInfo.access_type)}`)}
                         </Typography>
                       )
                     }
@@ -34,6 +34,7 @@ import SmartToyIcon from '@mui/icons-material/SmartToy';
 import ExpandMoreIcon from '@mui/icons-material/ExpandMore';
 import ExpandLessIcon from '@mui/icons-material/ExpandLess';
 import DeleteIcon from '@mui/icons-material/Delete';
+import PushPinIcon from '@mui/icons-material/PushPin';
 import { formatRelativeTime } from '../utils/fileTypeUtils';
 
 const MotionBox = motion.create(Box);
@@ -91,6 +92,7 @@ const Sidebar = ({ onCreateFolder, onFileUpload, onMenuChange, selectedMenu, onC
         content_type: conversation.file.content_type,
         size: conversation.file.size,
         processing_status: 'completed', // Assume completed if conversation exists
+        conversation_id: conversation.id, // Open this thread rather than the latest one
       };
       onConversationClick(file);
       // Refresh conversations after opening
@@ -102,7 +104,7 @@ const Sidebar = ({ onCreateFolder, onFileUpload, onMenuChange, selectedMenu, onC
     e.stopPropagation();
     if (window.confirm(t('ai.delete_conversation_confirm') || 'Bu sohbet geçmişini silmek istediğinize emin misiniz?')) {
       try {
-        await fileApi.clearConversationHistory(conversation.file_id);
+        await fileApi.deleteThread(conversation.id);
         window.toast?.success(t('ai.conversation_deleted') || 'Sohbet geçmişi silindi');
         await loadConversations();
       } catch (error) {
@@ -112,6 +114,16 @@ const Sidebar = ({ onCreateFolder, onFileUpload, onMenuChange, selectedMenu, onC
     }
   };
 
+  const handleTogglePin = async (e, conversation) => {
+    e.stopPropagation();
+    try {
+      await fileApi.updateThread(conversation.id, { pinned: !conversation.pinned });
+      await loadConversations();
+    } catch (error) {
+      console.error('Failed to pin conversation:', error);
+    }
+  };
+
   useEffect(() => {
     setSelected(selectedMenu || 'home');
   }, [selectedMenu]);
@@ -422,7 +434,7 @@ const Sidebar = ({ onCreateFolder, onFileUpload, onMenuChange, selectedMenu, onC
                             whiteSpace: 'nowrap',
                           }}
                         >
-                          {conv.file.filename}
+                          {conv.title || conv.file.filename}
                         </Typography>
                       }
                       secondary={
@@ -434,12 +446,25 @@ const Sidebar = ({ onCreateFolder, onFileUpload, onMenuChange, selectedMenu, onC
                             display: 'block',
                           }}
                         >
-                          {conv.messages.length > 0
-                            ? formatRelativeTime(conv.updated_at)
+                          {conv.message_count > 0
+                            ? `${conv.file.filename} · ${formatRelativeTime(conv.updated_at)}`
                             : t('sidebar.no_messages') || 'Mesaj yok'}
                         </Typography>
                       }
                     />
+                    <IconButton
+                      size="small"
+                      onClick={e => handleTogglePin(e, conv)}
+                      sx={{
+                        color: conv.pinned ? 'white' : 'rgba(255,255,255,0.5)',
+                        '&:hover': {
+                          color: 'white',
+                          bgcolor: 'rgba(255,255,255,0.1)',
+                        },
+                      }}
+                    >
+                      <PushPinIcon sx={{ fontSize: 14 }} />
+                    </IconButton>
                     <IconButton
                       size="small"
                       onClick={e => handleDeleteConversation(e, conv)}
@@ -0,0 +1,55 @@
+import { useState, useEffect } from 'react';
+import { fileApi } from '../services/api';
+
+/**
+ * Processing progress hook
+ * Follows the processing SSE feed of a file while it is pending, processing or being
+ * re-indexed after an edit (stale) and returns the latest { status, progress, job } state
+ * (null until the first event)
+ */
+export const useProcessingProgress = (fileId, processingStatus, { stale, onFinished } = {}) => {
+  const [state, setState] = useState(null);
+  const active = processingStatus === 'pending' || processingStatus === 'processing' || !!stale;
+
+  useEffect(() => {
+    if (!fileId || !active) {
+      setState(null);
+      return undefined;
+    }
+
+    const controller = new AbortController();
+    fileApi
+      .streamProcessingStatus(
+        fileId,
+        {
+          onProgress: setState,
+          onDone: finalState => {
+            setState(finalState);
+            onFinished?.(finalState);
+          },
+        },
+        controller.signal
+      )
+      .catch(error => {
+        if (error.name !== 'AbortError') {
+          console.error('Processing progress stream failed:', error);
+        }
+      });
+
+    return () => controller.abort();
+    // onFinished is intentionally not a dependency; the stream only restarts per file/status
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [fileId, active]);
+
+  return state;
+};
+
+/**
+ * Formats an ETA in seconds as "1m 20s" / "45s"
+ */
+export const formatEta = seconds => {
+  if (seconds == null || seconds < 0) return '';
+  const minutes = Math.floor(seconds / 60);
+  const rest = seconds % 60;
+  return minutes > 0 ? `${minutes}m ${rest}s` : `${rest}s`;
+};
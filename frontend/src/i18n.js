@@ -102,6 +102,10 @@ const resources = {
       share: 'Paylaş',
       'access.read': 'Görüntüleme',
       'access.write': 'Düzenleme',
+      'access.viewer': 'Görüntüleme',
+      'access.commenter': 'Yorum',
+      'access.editor': 'Düzenleme',
+      'access.manager': 'Yönetim',
       confirm_delete: 'Bu dosyayı silmek istediğinizden emin misiniz?',
       delete_success: 'Dosya başarıyla silindi',
       delete_error: 'Dosya silinirken hata oluştu',
@@ -160,9 +164,24 @@ const resources = {
       'ai.ask_nimbus_error': "Nimbus'a Sor (Hata)",
       'ai.processing': 'İşleniyor...',
       'ai.ready': 'AI Hazır',
+      'ai.reindexing': 'Güncelleniyor...',
+      'ai.partial': 'AI Hazır ({{count}} parça atlandı)',
       'ai.failed': 'İşleme Hatası',
       'ai.pending': 'Beklemede',
+      'ai.stage_queued': 'Kuyrukta',
+      'ai.stage_extracting': 'Metin çıkarılıyor',
+      'ai.stage_normalizing': 'Metin düzenleniyor',
+      'ai.stage_chunking': 'Parçalara ayrılıyor',
+      'ai.stage_embedding': 'Vektörleştiriliyor',
+      'ai.stage_indexing': 'İndeksleniyor',
+      'ai.stage_completed': 'Tamamlandı',
+      'ai.stage_failed': 'Başarısız',
+      'ai.eta': '~{{time}} kaldı',
       'ai.welcome': 'Merhaba! "{{filename}}" dosyası hakkında ne öğrenmek istiyorsun?',
+      'ai.new_thread': 'Yeni sohbet',
+      'ai.feedback_up': 'İyi cevap',
+      'ai.feedback_down': 'Kötü cevap',
+      'ai.feedback_correction': 'Doğru cevap neydi? (isteğe bağlı)',
       'ai.processing_message':
         '"{{filename}}" dosyası şu anda işleniyor. Lütfen işlem tamamlanana kadar bekleyin.',
       'ai.failed_message':
@@ -247,7 +266,9 @@ const resources = {
       // Share Dialog
       'share.title': 'Paylaş',
       'share.viewer': 'Görüntüleyen',
+      'share.commenter': 'Yorumcu',
       'share.editor': 'Düzenleyen',
+      'share.manager': 'Yönetici',
       'share.no_permission':
         'Sadece görüntüleme yetkiniz var. Bu kaynağı paylaşamaz veya erişimleri düzenleyemezsiniz.',
       'share.public_hint':
@@ -376,6 +397,10 @@ const resources = {
       share: 'Share',
       'access.read': 'View',
       'access.write': 'Edit',
+      'access.viewer': 'View',
+      'access.commenter': 'Comment',
+      'access.editor': 'Edit',
+      'access.manager': 'Manage',
       confirm_delete: 'Are you sure you want to delete this file?',
       delete_success: 'File deleted successfully',
       delete_error: 'Error deleting file',
@@ -435,9 +460,24 @@ const resources = {
       'ai.ask_nimbus_error': 'Ask Nimbus (Error)',
       'ai.processing': 'Processing...',
       'ai.ready': 'AI Ready',
+      'ai.reindexing': 'Updating index...',
+      'ai.partial': 'AI Ready ({{count}} chunks skipped)',
       'ai.failed': 'Processing Error',
       'ai.pending': 'Pending',
+      'ai.stage_queued': 'Queued',
+      'ai.stage_extracting': 'Extracting text',
+      'ai.stage_normalizing': 'Normalizing',
+      'ai.stage_chunking': 'Chunking',
+      'ai.stage_embedding': 'Embedding',
+      'ai.stage_indexing': 'Indexing',
+      'ai.stage_completed': 'Completed',
+      'ai.stage_failed': 'Failed',
+      'ai.eta': '~{{time}} left',
       'ai.welcome': 'Hello! What would you like to know about "{{filename}}"?',
+      'ai.new_thread': 'New chat',
+      'ai.feedback_up': 'Good answer',
+      'ai.feedback_down': 'Bad answer',
+      'ai.feedback_correction': 'What would the correct answer be? (optional)',
       'ai.processing_message':
         'The file "{{filename}}" is currently being processed. Please wait until processing is complete.',
       'ai.failed_message':
@@ -523,7 +563,9 @@ const resources = {
       // Share Dialog
       'share.title': 'Share',
       'share.viewer': 'Viewer',
+      'share.commenter': 'Commenter',
       'share.editor': 'Editor',
+      'share.manager': 'Manager',
       'share.no_permission':
         'You only have view permission. You cannot share this resource or edit access.',
       'share.public_hint':
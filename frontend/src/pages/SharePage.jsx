@@ -15,6 +15,14 @@ import { shareApi } from '../services/api';
 import FolderIcon from '@mui/icons-material/Folder';
 import InsertDriveFileIcon from '@mui/icons-material/InsertDriveFile';
 import PersonIcon from '@mui/icons-material/Person';
+import { normalizeRole } from '../utils/roleUtils';
+
+const ROLE_LABELS = {
+  viewer: 'Görüntüleme',
+  commenter: 'Yorum',
+  editor: 'Düzenleme',
+  manager: 'Yönetim',
+};
 
 const SharePage = () => {
   const { publicLink } = useParams();
@@ -131,7 +139,7 @@ const SharePage = () => {
               variant="outlined"
             />
             <Chip
-              label={`Erişim: ${resource.resource.access_list?.find(a => a.user_id === user?.id)?.access_type === 'write' ? 'Düzenleme' : 'Görüntüleme'}`}
+              label={`Erişim: ${ROLE_LABELS[normalizeRole(resource.resource.access_list?.find(a => a.user_id === user?.id)?.access_type)]}`}
               color="primary"
               variant="outlined"
             />
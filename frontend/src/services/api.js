@@ -205,14 +205,79 @@ export const fileApi = {
     return api.post(`/files/${encodeURIComponent(fileId)}/process`, {});
   },
 
+  // Processing stage, progress (percent, embedded N/M) and ETA
+  getProcessingStatus: fileId => {
+    return api.get(`/files/${encodeURIComponent(fileId)}/processing`);
+  },
+
+  // Follow processing progress via Server-Sent Events until the file is completed or failed.
+  // handlers: { onProgress, onDone }; pass an AbortSignal to stop listening.
+  streamProcessingStatus: async (fileId, handlers = {}, signal) => {
+    const response = await fetch(
+      `${API_BASE_URL}/files/${encodeURIComponent(fileId)}/processing/stream`,
+      { headers: api.getAuthHeaders(), signal }
+    );
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to follow processing');
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+    let result = null;
+
+    const dispatch = rawEvent => {
+      let event = 'message';
+      let data = '';
+      rawEvent.split('\n').forEach(line => {
+        if (line.startsWith('event:')) event = line.slice(6).trim();
+        else if (line.startsWith('data:')) data += line.slice(5).trim();
+      });
+      if (!data) return;
+      const payload = JSON.parse(data);
+
+      switch (event) {
+        case 'progress':
+          handlers.onProgress?.(payload);
+          break;
+        case 'done':
+          result = payload;
+          handlers.onDone?.(payload);
+          break;
+        case 'error':
+          throw new Error(payload.error || 'Failed to follow processing');
+        default:
+          break;
+      }
+    };
+
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) break;
+      buffer += decoder.decode(value, { stream: true });
+
+      let boundary = buffer.indexOf('\n\n');
+      while (boundary !== -1) {
+        dispatch(buffer.slice(0, boundary));
+        buffer = buffer.slice(boundary + 2);
+        boundary = buffer.indexOf('\n\n');
+      }
+    }
+
+    return result;
+  },
+
   // Query document with AI
-  queryDocument: async (fileId, question) => {
+  queryDocument: async (fileId, question, conversationId) => {
     const response = await fetch(`${API_BASE_URL}/ai/query`, {
       method: 'POST',
       headers: api.getAuthHeaders(),
       body: JSON.stringify({
         file_id: fileId,
         question: question,
+        conversation_id: conversationId || undefined,
       }),
     });
 
@@ -224,10 +289,84 @@ export const fileApi = {
     return await response.json();
   },
 
-  // Get conversation history
-  getConversationHistory: async fileId => {
+  // Query document with AI, streaming the answer via Server-Sent Events.
+  // handlers: { onMetadata, onToken, onSources, onDone }; pass an AbortSignal to cancel.
+  // Without conversationId the most recently active thread of the file is continued.
+  queryDocumentStream: async (fileId, question, handlers = {}, signal, conversationId) => {
+    const response = await fetch(`${API_BASE_URL}/ai/query/stream`, {
+      method: 'POST',
+      headers: api.getAuthHeaders(),
+      body: JSON.stringify({
+        file_id: fileId,
+        question: question,
+        conversation_id: conversationId || undefined,
+      }),
+      signal,
+    });
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Query failed');
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+    let result = null;
+
+    const dispatch = rawEvent => {
+      let event = 'message';
+      let data = '';
+      rawEvent.split('\n').forEach(line => {
+        if (line.startsWith('event:')) event = line.slice(6).trim();
+        else if (line.startsWith('data:')) data += line.slice(5).trim();
+      });
+      if (!data) return;
+      const payload = JSON.parse(data);
+
+      switch (event) {
+        case 'metadata':
+          handlers.onMetadata?.(payload);
+          break;
+        case 'token':
+          handlers.onToken?.(payload.delta);
+          break;
+        case 'sources':
+          handlers.onSources?.(payload.sources);
+          break;
+        case 'done':
+          result = payload;
+          handlers.onDone?.(payload);
+          break;
+        case 'error':
+          throw new Error(payload.error || 'Query failed');
+        default:
+          break;
+      }
+    };
+
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) break;
+      buffer += decoder.decode(value, { stream: true });
+
+      let boundary = buffer.indexOf('\n\n');
+      while (boundary !== -1) {
+        dispatch(buffer.slice(0, boundary));
+        buffer = buffer.slice(boundary + 2);
+        boundary = buffer.indexOf('\n\n');
+      }
+    }
+
+    return result;
+  },
+
+  // Get conversation history (latest messages of a thread, most recent thread by default)
+  getConversationHistory: async (fileId, conversationId) => {
+    const params = new URLSearchParams({ file_id: fileId });
+    if (conversationId) params.set('conversation_id', conversationId);
     const response = await fetch(
-      `${API_BASE_URL}/ai/conversation?file_id=${encodeURIComponent(fileId)}`,
+      `${API_BASE_URL}/ai/conversation?${params}`,
       {
         method: 'GET',
         headers: api.getAuthHeaders(),
@@ -274,6 +413,108 @@ export const fileApi = {
 
     return await response.json();
   },
+
+  // List chat threads of a file
+  listThreads: async fileId => {
+    const response = await fetch(
+      `${API_BASE_URL}/ai/threads?file_id=${encodeURIComponent(fileId)}`,
+      {
+        method: 'GET',
+        headers: api.getAuthHeaders(),
+      }
+    );
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to fetch threads');
+    }
+
+    return await response.json();
+  },
+
+  // Start a new chat thread for a file (title is generated from the first question if empty)
+  createThread: async (fileId, title) => {
+    const response = await fetch(`${API_BASE_URL}/ai/threads`, {
+      method: 'POST',
+      headers: api.getAuthHeaders(),
+      body: JSON.stringify({ file_id: fileId, title: title || undefined }),
+    });
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to create thread');
+    }
+
+    return await response.json();
+  },
+
+  // Rename and/or pin a thread: updates = { title?, pinned? }
+  updateThread: async (threadId, updates) => {
+    const response = await fetch(`${API_BASE_URL}/ai/threads/${threadId}`, {
+      method: 'PATCH',
+      headers: api.getAuthHeaders(),
+      body: JSON.stringify(updates),
+    });
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to update thread');
+    }
+
+    return await response.json();
+  },
+
+  // Delete a thread with its messages
+  deleteThread: async threadId => {
+    const response = await fetch(`${API_BASE_URL}/ai/threads/${threadId}`, {
+      method: 'DELETE',
+      headers: api.getAuthHeaders(),
+    });
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to delete thread');
+    }
+
+    return await response.json();
+  },
+
+  // Rate an assistant answer: feedback = { rating: 'up' | 'down', reason?, corrected_answer? }
+  setMessageFeedback: async (threadId, messageId, feedback) => {
+    const response = await fetch(
+      `${API_BASE_URL}/ai/threads/${threadId}/messages/${messageId}/feedback`,
+      {
+        method: 'PUT',
+        headers: api.getAuthHeaders(),
+        body: JSON.stringify(feedback),
+      }
+    );
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to save feedback');
+    }
+
+    return await response.json();
+  },
+
+  // Get a page of thread messages; page 1 holds the newest messages
+  getThreadMessages: async (threadId, page = 1, limit = 50) => {
+    const response = await fetch(
+      `${API_BASE_URL}/ai/threads/${threadId}/messages?page=${page}&limit=${limit}`,
+      {
+        method: 'GET',
+        headers: api.getAuthHeaders(),
+      }
+    );
+
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to fetch thread messages');
+    }
+
+    return await response.json();
+  },
 };
 
 // Folder specific methods
@@ -391,3 +632,27 @@ export const userApi = {
     return api.get(`/users/search?q=${encodeURIComponent(query)}`);
   },
 };
+
+// Document processing job API
+export const jobApi = {
+  // List processing jobs (optionally filtered by file or status)
+  listJobs: ({ fileId, status, page = 1, limit = 50 } = {}) => {
+    const params = new URLSearchParams({ page, limit });
+    if (fileId) params.set('file_id', fileId);
+    if (status) params.set('status', status);
+    return api.get(`/jobs?${params.toString()}`);
+  },
+
+  getJob: jobId => {
+    return api.get(`/jobs/${encodeURIComponent(jobId)}`);
+  },
+
+  // Re-queue a dead or cancelled job
+  retryJob: jobId => {
+    return api.post(`/jobs/${encodeURIComponent(jobId)}/retry`, {});
+  },
+
+  cancelJob: jobId => {
+    return api.post(`/jobs/${encodeURIComponent(jobId)}/cancel`, {});
+  },
+};
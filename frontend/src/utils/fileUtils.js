@@ -275,28 +275,60 @@ export const isPreviewable = (contentType, filename = '') => {
   return previewableTypes.includes(fileType);
 };
 
+// Extensions the backend extractor registry can read (backend/extract/registry.go)
+const ASKABLE_EXTENSIONS = [
+  'pdf', 'docx', 'doc', 'xlsx', 'pptx', 'odt', 'ods', 'odp', 'rtf',
+  'html', 'htm', 'md', 'markdown', 'csv', 'tsv', 'txt', 'log',
+  'json', 'xml', 'yaml', 'yml', 'toml', 'ini', 'sql',
+  'go', 'py', 'java', 'cs', 'c', 'h', 'cpp', 'cc', 'hpp',
+  'js', 'jsx', 'mjs', 'ts', 'tsx', 'css', 'scss',
+  'kt', 'scala', 'rs', 'php', 'rb', 'pl', 'sh', 'bash',
+];
+
+const ASKABLE_CONTENT_TYPES = [
+  'pdf',
+  'wordprocessingml',
+  'msword',
+  'spreadsheetml',
+  'presentationml',
+  'opendocument',
+  'rtf',
+  'json',
+  'xml',
+  'yaml',
+  'javascript',
+  'typescript',
+];
+
 /**
- * Check if a file can be used with Nimbus AI (PDF and Word documents)
+ * Check if a file can be used with Nimbus AI (documents, spreadsheets, slides, text and code)
  * @param {string} contentType - MIME type of the file
  * @param {string} filename - Name of the file (optional)
  * @returns {boolean} True if file can be used with Nimbus AI
  */
 export const isAskableFile = (contentType, filename = '') => {
+  const extension = (filename || '').toLowerCase().split('.').pop();
+  if (filename && filename.includes('.') && ASKABLE_EXTENSIONS.includes(extension)) {
+    return true;
+  }
+
   if (!contentType) return false;
 
   const contentTypeLower = contentType.toLowerCase();
-  const filenameLower = filename.toLowerCase();
-
   return (
-    contentTypeLower.includes('pdf') ||
-    contentTypeLower.includes('document') ||
-    contentTypeLower.includes('wordprocessingml') ||
-    filenameLower.endsWith('.doc') ||
-    filenameLower.endsWith('.docx') ||
-    filenameLower.endsWith('.pdf')
+    contentTypeLower.startsWith('text/') ||
+    ASKABLE_CONTENT_TYPES.some((type) => contentTypeLower.includes(type))
   );
 };
 
+/**
+ * Check if a processed file can be queried. "partial" files were indexed with some chunks skipped.
+ * @param {string} processingStatus - processing_status of the file
+ * @returns {boolean} True if the file's chunks are searchable
+ */
+export const isQueryableStatus = processingStatus =>
+  processingStatus === 'completed' || processingStatus === 'partial';
+
 /**
  * Check if a file can be edited with OnlyOffice (Word, Excel, PowerPoint) or Monaco Editor (code files)
  * @param {string} contentType - MIME type of the file
@@ -0,0 +1,25 @@
+/**
+ * Access roles returned by the backend (legacy read/write values are mapped too)
+ */
+export const ROLES = ['viewer', 'commenter', 'editor', 'manager'];
+
+const ROLE_RANK = { viewer: 1, commenter: 2, editor: 3, manager: 4, owner: 5 };
+
+/**
+ * Normalize legacy read/write values to roles
+ */
+export const normalizeRole = (role) => {
+    if (role === 'read') return 'viewer';
+    if (role === 'write') return 'editor';
+    return role || 'viewer';
+};
+
+/**
+ * Whether the role can edit file content
+ */
+export const canEditWithRole = (role) => (ROLE_RANK[normalizeRole(role)] || 0) >= ROLE_RANK.editor;
+
+/**
+ * Whether the role can reshare (manager or owner)
+ */
+export const canShareWithRole = (role) => (ROLE_RANK[normalizeRole(role)] || 0) >= ROLE_RANK.manager;